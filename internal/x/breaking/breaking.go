@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"go.uber.org/zap"
+)
+
+// The IDs of the Failures returned from a Checker.
+//
+// These are stable and can be depended on.
+const (
+	// FilePackageChangedID says the package of a file changed.
+	FilePackageChangedID = "FILE_PACKAGE_CHANGED"
+	// MessageRemovedID says a message was removed.
+	MessageRemovedID = "MESSAGE_REMOVED"
+	// FieldRemovedID says a field was removed.
+	FieldRemovedID = "FIELD_REMOVED"
+	// FieldNumberChangedID says the number of a field changed.
+	FieldNumberChangedID = "FIELD_NUMBER_CHANGED"
+	// FieldTypeChangedID says the type of a field changed.
+	FieldTypeChangedID = "FIELD_TYPE_CHANGED"
+	// FieldLabelChangedID says the label of a field changed, ie a
+	// field became repeated or stopped being repeated.
+	FieldLabelChangedID = "FIELD_LABEL_CHANGED"
+	// EnumRemovedID says an enum was removed.
+	EnumRemovedID = "ENUM_REMOVED"
+	// EnumValueRemovedID says an enum value number was removed.
+	EnumValueRemovedID = "ENUM_VALUE_REMOVED"
+	// EnumValueRenamedID says an enum value number has a different name.
+	EnumValueRenamedID = "ENUM_VALUE_RENAMED"
+	// ServiceRemovedID says a service was removed.
+	ServiceRemovedID = "SERVICE_REMOVED"
+	// RPCRemovedID says an RPC was removed.
+	RPCRemovedID = "RPC_REMOVED"
+	// RPCRequestTypeChangedID says the request type of an RPC changed.
+	RPCRequestTypeChangedID = "RPC_REQUEST_TYPE_CHANGED"
+	// RPCResponseTypeChangedID says the response type of an RPC changed.
+	RPCResponseTypeChangedID = "RPC_RESPONSE_TYPE_CHANGED"
+	// RPCStreamingChangedID says the client or server streaming of an RPC changed.
+	RPCStreamingChangedID = "RPC_STREAMING_CHANGED"
+)
+
+// Checker checks for breaking changes between FileDescriptorSets.
+type Checker interface {
+	// Check the current FileDescriptorSets against the baseline FileDescriptorSets.
+	//
+	// Files are matched by name and types are matched by fully-qualified name
+	// across all given FileDescriptorSets. If a file is present in multiple
+	// FileDescriptorSets, the first one is used.
+	//
+	// If there are breaking changes, they are returned as Failures with
+	// one of the IDs above, and there is no error. Failures will be sorted.
+	Check(baseline []*descriptor.FileDescriptorSet, current []*descriptor.FileDescriptorSet) ([]*text.Failure, error)
+}
+
+// CheckerOption is an option for a new Checker.
+type CheckerOption func(*checker)
+
+// CheckerWithLogger returns a CheckerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func CheckerWithLogger(logger *zap.Logger) CheckerOption {
+	return func(checker *checker) {
+		checker.logger = logger
+	}
+}
+
+// NewChecker returns a new Checker.
+func NewChecker(options ...CheckerOption) Checker {
+	return newChecker(options...)
+}
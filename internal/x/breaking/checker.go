@@ -0,0 +1,451 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"github.com/tgrpc/prototool/internal/x/wkt"
+	"go.uber.org/zap"
+)
+
+type checker struct {
+	logger *zap.Logger
+}
+
+func newChecker(options ...CheckerOption) *checker {
+	checker := &checker{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(checker)
+	}
+	return checker
+}
+
+func (c *checker) Check(baseline []*descriptor.FileDescriptorSet, current []*descriptor.FileDescriptorSet) ([]*text.Failure, error) {
+	baselineIndex, err := newIndex(baseline)
+	if err != nil {
+		return nil, err
+	}
+	currentIndex, err := newIndex(current)
+	if err != nil {
+		return nil, err
+	}
+	state := &checkState{
+		baseline:       baselineIndex,
+		current:        currentIndex,
+		packageRenames: make(map[string]string),
+	}
+	state.checkFiles()
+	state.checkMessages()
+	state.checkEnums()
+	state.checkServices()
+	c.logger.Debug("breaking check done", zap.Int("failures", len(state.failures)))
+	text.SortFailures(state.failures)
+	return state.failures, nil
+}
+
+type checkState struct {
+	baseline *index
+	current  *index
+	// from old package to new package
+	packageRenames map[string]string
+	failures       []*text.Failure
+}
+
+func (s *checkState) checkFiles() {
+	for _, name := range sortedKeys(s.baseline.files) {
+		baselineFile := s.baseline.files[name]
+		currentFile, ok := s.current.files[name]
+		if !ok {
+			// the removal of the types in the file will be reported
+			continue
+		}
+		if baselineFile.GetPackage() != currentFile.GetPackage() {
+			s.addFailuref(newElement(currentFile).position(desc.FilePackageTag), FilePackageChangedID, "Package of file %q changed from %q to %q.", name, baselineFile.GetPackage(), currentFile.GetPackage())
+			s.packageRenames[baselineFile.GetPackage()] = currentFile.GetPackage()
+		}
+	}
+}
+
+func (s *checkState) checkMessages() {
+	for _, name := range sortedKeys(s.baseline.messages) {
+		baselineMessage := s.baseline.messages[name]
+		currentMessage, ok := s.current.messages[s.translate(name)]
+		if !ok {
+			s.addFailuref(s.removedPosition(name, baselineMessage.element), MessageRemovedID, "Message %q was removed.", displayName(name))
+			continue
+		}
+		s.checkFields(name, baselineMessage.message, currentMessage)
+	}
+}
+
+func (s *checkState) checkFields(name string, baselineMessage *descriptor.DescriptorProto, currentElement *messageElement) {
+	currentMessage := currentElement.message
+	numberToFieldIndex := make(map[int32]int, len(currentMessage.GetField()))
+	nameToFieldIndex := make(map[string]int, len(currentMessage.GetField()))
+	for i, field := range currentMessage.GetField() {
+		numberToFieldIndex[field.GetNumber()] = i
+		nameToFieldIndex[field.GetName()] = i
+	}
+	for _, baselineField := range baselineMessage.GetField() {
+		currentFieldIndex, ok := numberToFieldIndex[baselineField.GetNumber()]
+		if !ok {
+			if namedFieldIndex, ok := nameToFieldIndex[baselineField.GetName()]; ok {
+				s.addFailuref(currentElement.position(desc.MessageFieldTag, int32(namedFieldIndex)), FieldNumberChangedID, "Field %q on message %q changed number from %d to %d.", baselineField.GetName(), displayName(name), baselineField.GetNumber(), currentMessage.GetField()[namedFieldIndex].GetNumber())
+			} else {
+				s.addFailuref(currentElement.position(), FieldRemovedID, "Field %q with number %d on message %q was removed.", baselineField.GetName(), baselineField.GetNumber(), displayName(name))
+			}
+			continue
+		}
+		currentField := currentMessage.GetField()[currentFieldIndex]
+		currentFieldPosition := currentElement.position(desc.MessageFieldTag, int32(currentFieldIndex))
+		if baselineField.GetType() != currentField.GetType() || s.translate(baselineField.GetTypeName()) != currentField.GetTypeName() {
+			s.addFailuref(currentFieldPosition, FieldTypeChangedID, "Field %d on message %q changed type from %q to %q.", baselineField.GetNumber(), displayName(name), fieldTypeString(baselineField), fieldTypeString(currentField))
+		}
+		if baselineField.GetLabel() != currentField.GetLabel() {
+			s.addFailuref(currentFieldPosition, FieldLabelChangedID, "Field %d on message %q changed label from %q to %q.", baselineField.GetNumber(), displayName(name), fieldLabelString(baselineField), fieldLabelString(currentField))
+		}
+	}
+}
+
+func (s *checkState) checkEnums() {
+	for _, name := range sortedKeys(s.baseline.enums) {
+		baselineEnum := s.baseline.enums[name]
+		currentEnum, ok := s.current.enums[s.translate(name)]
+		if !ok {
+			s.addFailuref(s.removedPosition(name, baselineEnum.element), EnumRemovedID, "Enum %q was removed.", displayName(name))
+			continue
+		}
+		numberToNames := make(map[int32][]string, len(currentEnum.enum.GetValue()))
+		// the index of the first value with the number
+		numberToValueIndex := make(map[int32]int, len(currentEnum.enum.GetValue()))
+		for i, value := range currentEnum.enum.GetValue() {
+			if _, ok := numberToNames[value.GetNumber()]; !ok {
+				numberToValueIndex[value.GetNumber()] = i
+			}
+			numberToNames[value.GetNumber()] = append(numberToNames[value.GetNumber()], value.GetName())
+		}
+		for _, baselineValue := range baselineEnum.enum.GetValue() {
+			names, ok := numberToNames[baselineValue.GetNumber()]
+			if !ok {
+				s.addFailuref(currentEnum.position(), EnumValueRemovedID, "Enum value %q with number %d on enum %q was removed.", baselineValue.GetName(), baselineValue.GetNumber(), displayName(name))
+				continue
+			}
+			if !stringIn(baselineValue.GetName(), names) {
+				s.addFailuref(currentEnum.position(desc.EnumValueTag, int32(numberToValueIndex[baselineValue.GetNumber()])), EnumValueRenamedID, "Enum value %d on enum %q was renamed from %q to %q.", baselineValue.GetNumber(), displayName(name), baselineValue.GetName(), strings.Join(names, ", "))
+			}
+		}
+	}
+}
+
+func (s *checkState) checkServices() {
+	for _, name := range sortedKeys(s.baseline.services) {
+		baselineService := s.baseline.services[name]
+		currentService, ok := s.current.services[s.translate(name)]
+		if !ok {
+			s.addFailuref(s.removedPosition(name, baselineService.element), ServiceRemovedID, "Service %q was removed.", displayName(name))
+			continue
+		}
+		nameToMethodIndex := make(map[string]int, len(currentService.service.GetMethod()))
+		for i, method := range currentService.service.GetMethod() {
+			nameToMethodIndex[method.GetName()] = i
+		}
+		for _, baselineMethod := range baselineService.service.GetMethod() {
+			rpcName := displayName(name) + "." + baselineMethod.GetName()
+			currentMethodIndex, ok := nameToMethodIndex[baselineMethod.GetName()]
+			if !ok {
+				s.addFailuref(currentService.position(), RPCRemovedID, "RPC %q was removed.", rpcName)
+				continue
+			}
+			currentMethod := currentService.service.GetMethod()[currentMethodIndex]
+			currentMethodPosition := currentService.position(desc.ServiceMethodTag, int32(currentMethodIndex))
+			if s.translate(baselineMethod.GetInputType()) != currentMethod.GetInputType() {
+				s.addFailuref(currentMethodPosition, RPCRequestTypeChangedID, "RPC %q changed request type from %q to %q.", rpcName, displayName(baselineMethod.GetInputType()), displayName(currentMethod.GetInputType()))
+			}
+			if s.translate(baselineMethod.GetOutputType()) != currentMethod.GetOutputType() {
+				s.addFailuref(currentMethodPosition, RPCResponseTypeChangedID, "RPC %q changed response type from %q to %q.", rpcName, displayName(baselineMethod.GetOutputType()), displayName(currentMethod.GetOutputType()))
+			}
+			if baselineMethod.GetClientStreaming() != currentMethod.GetClientStreaming() || baselineMethod.GetServerStreaming() != currentMethod.GetServerStreaming() {
+				s.addFailuref(currentMethodPosition, RPCStreamingChangedID, "RPC %q changed streaming from %s to %s.", rpcName, streamingString(baselineMethod), streamingString(currentMethod))
+			}
+		}
+	}
+}
+
+// translate returns the fully-qualified name the given baseline fully-qualified
+// name should have in the current FileDescriptorSets, taking into account
+// packages that were moved.
+func (s *checkState) translate(name string) string {
+	if name == "" {
+		return name
+	}
+	if s.current.hasType(name) {
+		return name
+	}
+	// longest package first so that nested packages are handled
+	oldPackages := make([]string, 0, len(s.packageRenames))
+	for oldPackage := range s.packageRenames {
+		oldPackages = append(oldPackages, oldPackage)
+	}
+	sort.Slice(oldPackages, func(i int, j int) bool { return len(oldPackages[i]) > len(oldPackages[j]) })
+	for _, oldPackage := range oldPackages {
+		newPackage := s.packageRenames[oldPackage]
+		if relName, ok := trimPackage(name, oldPackage); ok {
+			if newPackage == "" {
+				return "." + relName
+			}
+			return "." + newPackage + "." + relName
+		}
+	}
+	return name
+}
+
+// removedPosition returns the position to report the removal of the
+// element with the given baseline fully-qualified name at, which is the
+// position of its parent message in the current FileDescriptorSets if
+// it was nested, or otherwise just the file it was in.
+func (s *checkState) removedPosition(name string, baselineElement element) scanner.Position {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		if parentMessage, ok := s.current.messages[s.translate(name[:i])]; ok {
+			return parentMessage.position()
+		}
+	}
+	return scanner.Position{Filename: baselineElement.file.GetName()}
+}
+
+func (s *checkState) addFailuref(position scanner.Position, id string, format string, args ...interface{}) {
+	s.failures = append(s.failures, text.NewFailuref(position, id, format, args...))
+}
+
+// element is a message, enum, or service in a file.
+type element struct {
+	file *descriptor.FileDescriptorProto
+	// the SourceCodeInfo path of the element in the file
+	path []int32
+}
+
+func newElement(file *descriptor.FileDescriptorProto, path ...int32) element {
+	return element{
+		file: file,
+		path: path,
+	}
+}
+
+// position returns the position of the element, or the position of
+// the child of the element at the given path relative to the element.
+//
+// If there is no source code info for the path, the position will
+// only have the filename set.
+func (e element) position(path ...int32) scanner.Position {
+	position := scanner.Position{
+		Filename: e.file.GetName(),
+	}
+	fullPath := make([]int32, 0, len(e.path)+len(path))
+	fullPath = append(fullPath, e.path...)
+	fullPath = append(fullPath, path...)
+	if line, column, ok := desc.GetLineAndColumn(e.file, fullPath...); ok {
+		position.Line = line
+		position.Column = column
+	}
+	return position
+}
+
+type messageElement struct {
+	element
+	message *descriptor.DescriptorProto
+}
+
+type enumElement struct {
+	element
+	enum *descriptor.EnumDescriptorProto
+}
+
+type serviceElement struct {
+	element
+	service *descriptor.ServiceDescriptorProto
+}
+
+// all names are fully-qualified with a leading "."
+type index struct {
+	files    map[string]*descriptor.FileDescriptorProto
+	messages map[string]*messageElement
+	enums    map[string]*enumElement
+	services map[string]*serviceElement
+}
+
+func newIndex(fileDescriptorSets []*descriptor.FileDescriptorSet) (*index, error) {
+	index := &index{
+		files:    make(map[string]*descriptor.FileDescriptorProto),
+		messages: make(map[string]*messageElement),
+		enums:    make(map[string]*enumElement),
+		services: make(map[string]*serviceElement),
+	}
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			name := fileDescriptorProto.GetName()
+			if name == "" {
+				return nil, fmt.Errorf("no name on FileDescriptorProto")
+			}
+			// the Well-Known Types come and go with imports, and are not
+			// changed by users, so we do not check them
+			if _, ok := wkt.FilenameMap[name]; ok {
+				continue
+			}
+			// the same file can be in multiple FileDescriptorSets
+			// because of imports, use the first one
+			if _, ok := index.files[name]; ok {
+				continue
+			}
+			index.files[name] = fileDescriptorProto
+			prefix := ""
+			if fileDescriptorProto.GetPackage() != "" {
+				prefix = "." + fileDescriptorProto.GetPackage()
+			}
+			index.addMessages(fileDescriptorProto, prefix, nil, desc.FileMessageTypeTag, fileDescriptorProto.GetMessageType())
+			index.addEnums(fileDescriptorProto, prefix, nil, desc.FileEnumTypeTag, fileDescriptorProto.GetEnumType())
+			for i, service := range fileDescriptorProto.GetService() {
+				index.services[prefix+"."+service.GetName()] = &serviceElement{
+					element: newElement(fileDescriptorProto, desc.FileServiceTag, int32(i)),
+					service: service,
+				}
+			}
+		}
+	}
+	return index, nil
+}
+
+// parentPath is the SourceCodeInfo path of the parent of the messages,
+// and tag is the field number of the messages in the parent.
+func (i *index) addMessages(file *descriptor.FileDescriptorProto, prefix string, parentPath []int32, tag int32, messages []*descriptor.DescriptorProto) {
+	for j, message := range messages {
+		name := prefix + "." + message.GetName()
+		path := desc.AppendPath(parentPath, tag, int32(j))
+		i.messages[name] = &messageElement{
+			element: newElement(file, path...),
+			message: message,
+		}
+		i.addMessages(file, name, path, desc.MessageNestedTypeTag, message.GetNestedType())
+		i.addEnums(file, name, path, desc.MessageEnumTypeTag, message.GetEnumType())
+	}
+}
+
+// parentPath is the SourceCodeInfo path of the parent of the enums,
+// and tag is the field number of the enums in the parent.
+func (i *index) addEnums(file *descriptor.FileDescriptorProto, prefix string, parentPath []int32, tag int32, enums []*descriptor.EnumDescriptorProto) {
+	for j, enum := range enums {
+		i.enums[prefix+"."+enum.GetName()] = &enumElement{
+			element: newElement(file, desc.AppendPath(parentPath, tag, int32(j))...),
+			enum:    enum,
+		}
+	}
+}
+
+func (i *index) hasType(name string) bool {
+	if _, ok := i.messages[name]; ok {
+		return true
+	}
+	if _, ok := i.enums[name]; ok {
+		return true
+	}
+	_, ok := i.services[name]
+	return ok
+}
+
+// returns the name relative to the package if the name is in the package
+func trimPackage(name string, pkg string) (string, bool) {
+	if pkg == "" {
+		return strings.TrimPrefix(name, "."), true
+	}
+	prefix := "." + pkg + "."
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix), true
+}
+
+func fieldTypeString(field *descriptor.FieldDescriptorProto) string {
+	if field.GetTypeName() != "" {
+		return displayName(field.GetTypeName())
+	}
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+func fieldLabelString(field *descriptor.FieldDescriptorProto) string {
+	return strings.ToLower(strings.TrimPrefix(field.GetLabel().String(), "LABEL_"))
+}
+
+func streamingString(method *descriptor.MethodDescriptorProto) string {
+	switch {
+	case method.GetClientStreaming() && method.GetServerStreaming():
+		return "bidirectional streaming"
+	case method.GetClientStreaming():
+		return "client streaming"
+	case method.GetServerStreaming():
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
+
+func displayName(name string) string {
+	return strings.TrimPrefix(name, ".")
+}
+
+func stringIn(s string, slice []string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch t := m.(type) {
+	case map[string]*descriptor.FileDescriptorProto:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	case map[string]*messageElement:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	case map[string]*enumElement:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	case map[string]*serviceElement:
+		for key := range t {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package breaking
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+func TestCheckNoChanges(t *testing.T) {
+	failures, err := NewChecker().Check(
+		newTestFileDescriptorSets(newTestFileDescriptorProto("foo/foo.proto", "foo")),
+		newTestFileDescriptorSets(newTestFileDescriptorProto("foo/foo.proto", "foo")),
+	)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+func TestCheckFields(t *testing.T) {
+	current := newTestFileDescriptorProto("foo/foo.proto", "foo")
+	message := current.MessageType[0]
+	// removed
+	message.Field = message.Field[1:]
+	// changed number
+	message.Field[0].Number = proto.Int32(5)
+	// changed type
+	message.Field[1].Type = descriptor.FieldDescriptorProto_TYPE_INT64.Enum()
+	// changed label
+	message.Field[2].Label = descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	assertCheck(
+		t,
+		newTestFileDescriptorProto("foo/foo.proto", "foo"),
+		current,
+		FieldLabelChangedID,
+		FieldNumberChangedID,
+		FieldRemovedID,
+		FieldTypeChangedID,
+	)
+}
+
+func TestCheckEnums(t *testing.T) {
+	current := newTestFileDescriptorProto("foo/foo.proto", "foo")
+	enum := current.EnumType[0]
+	enum.Value[1].Name = proto.String("HELLO_TWO")
+	enum.Value = enum.Value[:2]
+	assertCheck(
+		t,
+		newTestFileDescriptorProto("foo/foo.proto", "foo"),
+		current,
+		EnumValueRemovedID,
+		EnumValueRenamedID,
+	)
+}
+
+func TestCheckServices(t *testing.T) {
+	current := newTestFileDescriptorProto("foo/foo.proto", "foo")
+	service := current.Service[0]
+	service.Method[0].OutputType = proto.String(".foo.Bar")
+	service.Method[1].ClientStreaming = proto.Bool(true)
+	service.Method = service.Method[:2]
+	assertCheck(
+		t,
+		newTestFileDescriptorProto("foo/foo.proto", "foo"),
+		current,
+		RPCRemovedID,
+		RPCResponseTypeChangedID,
+		RPCStreamingChangedID,
+	)
+}
+
+func TestCheckRemovedTypes(t *testing.T) {
+	current := newTestFileDescriptorProto("foo/foo.proto", "foo")
+	current.MessageType = current.MessageType[:1]
+	current.EnumType = nil
+	current.Service = nil
+	assertCheck(
+		t,
+		newTestFileDescriptorProto("foo/foo.proto", "foo"),
+		current,
+		EnumRemovedID,
+		MessageRemovedID,
+		ServiceRemovedID,
+	)
+}
+
+func TestCheckPackageChanged(t *testing.T) {
+	// only the package move is reported, the types are
+	// compared relative to the new package
+	assertCheck(
+		t,
+		newTestFileDescriptorProto("foo/foo.proto", "foo"),
+		newTestFileDescriptorProto("foo/foo.proto", "bar"),
+		FilePackageChangedID,
+	)
+}
+
+func TestCheckPositions(t *testing.T) {
+	current := newTestFileDescriptorProto("foo/foo.proto", "foo")
+	current.SourceCodeInfo = &descriptor.SourceCodeInfo{
+		Location: []*descriptor.SourceCodeInfo_Location{
+			newTestLocation([]int32{4, 0}, 4, 0),
+			newTestLocation([]int32{4, 0, 2, 1}, 6, 2),
+			newTestLocation([]int32{6, 0}, 14, 0),
+		},
+	}
+	current.MessageType[0].Field[1].Type = descriptor.FieldDescriptorProto_TYPE_INT64.Enum()
+	current.MessageType[0].Field = append(current.MessageType[0].Field[:2], current.MessageType[0].Field[3:]...)
+	current.Service[0].Method = current.Service[0].Method[:2]
+	// the enum has no source code info, so only the filename is set
+	current.EnumType[0].Value = current.EnumType[0].Value[:2]
+	failures, err := NewChecker().Check(
+		newTestFileDescriptorSets(newTestFileDescriptorProto("foo/foo.proto", "foo")),
+		newTestFileDescriptorSets(current),
+	)
+	require.NoError(t, err)
+	var positions []string
+	for _, failure := range failures {
+		positions = append(positions, failure.ID+" "+failure.Filename+":"+strconv.Itoa(failure.Line)+":"+strconv.Itoa(failure.Column))
+	}
+	assert.Equal(
+		t,
+		[]string{
+			EnumValueRemovedID + " foo/foo.proto:0:0",
+			FieldRemovedID + " foo/foo.proto:5:1",
+			FieldTypeChangedID + " foo/foo.proto:7:3",
+			RPCRemovedID + " foo/foo.proto:15:1",
+		},
+		positions,
+	)
+}
+
+func assertCheck(t *testing.T, baseline *descriptor.FileDescriptorProto, current *descriptor.FileDescriptorProto, expectedIDs ...string) {
+	failures, err := NewChecker().Check(
+		newTestFileDescriptorSets(baseline),
+		newTestFileDescriptorSets(current),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, expectedIDs, getIDs(failures))
+}
+
+func getIDs(failures []*text.Failure) []string {
+	var ids []string
+	for _, failure := range failures {
+		ids = append(ids, failure.ID)
+	}
+	return ids
+}
+
+func newTestFileDescriptorSets(fileDescriptorProtos ...*descriptor.FileDescriptorProto) []*descriptor.FileDescriptorSet {
+	return []*descriptor.FileDescriptorSet{
+		{
+			File: fileDescriptorProtos,
+		},
+	}
+}
+
+func newTestLocation(path []int32, line int32, column int32) *descriptor.SourceCodeInfo_Location {
+	return &descriptor.SourceCodeInfo_Location{
+		Path: path,
+		Span: []int32{line, column, column + 1},
+	}
+}
+
+func newTestFileDescriptorProto(name string, pkg string) *descriptor.FileDescriptorProto {
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String(name),
+		Package: proto.String(pkg),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptor.FieldDescriptorProto{
+					newTestFieldDescriptorProto("one", 1, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					newTestFieldDescriptorProto("two", 2, descriptor.FieldDescriptorProto_TYPE_STRING, ""),
+					newTestFieldDescriptorProto("three", 3, descriptor.FieldDescriptorProto_TYPE_INT32, ""),
+					newTestFieldDescriptorProto("four", 4, descriptor.FieldDescriptorProto_TYPE_MESSAGE, "."+pkg+".Bar"),
+				},
+			},
+			{
+				Name: proto.String("Bar"),
+			},
+		},
+		EnumType: []*descriptor.EnumDescriptorProto{
+			{
+				Name: proto.String("Hello"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					newTestEnumValueDescriptorProto("HELLO_INVALID", 0),
+					newTestEnumValueDescriptorProto("HELLO_ONE", 1),
+					newTestEnumValueDescriptorProto("HELLO_THREE", 3),
+				},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptor.MethodDescriptorProto{
+					newTestMethodDescriptorProto("One", "."+pkg+".Foo", "."+pkg+".Foo"),
+					newTestMethodDescriptorProto("Two", "."+pkg+".Foo", "."+pkg+".Foo"),
+					newTestMethodDescriptorProto("Three", "."+pkg+".Foo", "."+pkg+".Foo"),
+				},
+			},
+		},
+	}
+}
+
+func newTestFieldDescriptorProto(name string, number int32, fieldType descriptor.FieldDescriptorProto_Type, typeName string) *descriptor.FieldDescriptorProto {
+	fieldDescriptorProto := &descriptor.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		Type:   fieldType.Enum(),
+	}
+	if typeName != "" {
+		fieldDescriptorProto.TypeName = proto.String(typeName)
+	}
+	return fieldDescriptorProto
+}
+
+func newTestEnumValueDescriptorProto(name string, number int32) *descriptor.EnumValueDescriptorProto {
+	return &descriptor.EnumValueDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+	}
+}
+
+func newTestMethodDescriptorProto(name string, inputType string, outputType string) *descriptor.MethodDescriptorProto {
+	return &descriptor.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(inputType),
+		OutputType: proto.String(outputType),
+	}
+}
@@ -55,6 +55,17 @@ protoc_version: {{.ProtocVersion}}
 # Setting this will ignore unused imports.
 {{.V}}allow_unused_imports: true
 
+# Compile directives.
+# These are commented out even with --uncomment since the go backend
+# cannot be used with the gen directives below.
+#compile:
+  # The backend to compile with. Valid values are protoc and go.
+  # The protoc backend downloads protoc and shells out to it, and is the default.
+  # The go backend compiles your Protobuf files in-process without protoc, which
+  # allows compiling offline and in hermetic sandboxes. The go backend cannot be
+  # used with gen, and does not fail on unused imports.
+  #backend: go
+
 # Lint directives.
 {{.V}}lint:
   # Linter * files to ignore.
@@ -77,7 +88,9 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}    - ENUM_NAMES_CAPITALIZED
 
   # The lint group to use.
-  # The only valid value as of now is default, which is also the default value.
+  # The built-in groups are default, strict, google and minimal, and any group
+  # defined in groups below can also be used. The default value is default.
+  # Run prototool list-all-lint-groups to see all available lint groups.
 {{.V}}  group: default
 
   # Linters to include that are not in the lint group.
@@ -88,6 +101,85 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}  exclude_ids:
 {{.V}}    - ENUM_NAMES_CAMEL_CASE
 
+  # User-defined lint rules. These are used in addition to the linters of the
+  # lint group, and their ids can be used with ids, include_ids, exclude_ids
+  # and ignore_id_to_files like any other linter.
+  # Each rule sets exactly one of naming_regex, required_options/forbidden_options,
+  # allowed_imports/forbidden_imports, or
+  # allowed_import_packages/forbidden_import_packages.
+{{.V}}  rules:
+      # The id of the rule. This must not be the id of a built-in linter.
+{{.V}}    - id: SERVICE_NAMES_API_SUFFIX
+      # The message to print for lint failures.
+{{.V}}      message: Service names must end with API.
+      # The kind of element to check. Valid kinds are file, package, message,
+      # field, enum, enum_value, service, rpc. Import rules do not have a kind.
+{{.V}}      kind: service
+      # The regex that all names of elements of this kind must match.
+      # For files, the base name of the file is matched.
+{{.V}}      naming_regex: ^[A-Z][A-Za-z0-9]*API$
+
+{{.V}}    - id: FILE_OPTIONS_REQUIRE_OBJC_CLASS_PREFIX
+{{.V}}      message: The file option objc_class_prefix must be set, and cc_enable_arenas must not be set.
+{{.V}}      kind: file
+      # The options that must be set on all elements of this kind.
+      # Custom options are specified with parentheses, for example (foo.bar).
+{{.V}}      required_options:
+{{.V}}        - objc_class_prefix
+      # The options that must not be set on any element of this kind.
+{{.V}}      forbidden_options:
+{{.V}}        - cc_enable_arenas
+
+{{.V}}    - id: IMPORTS_NOT_INTERNAL
+{{.V}}      message: Files in internal must not be imported.
+      # If set, all imports must start with one of these prefixes.
+      #allowed_imports:
+      #  - google/protobuf/
+      # No import can start with one of these prefixes.
+{{.V}}      forbidden_imports:
+{{.V}}        - internal/
+
+{{.V}}    - id: PUBLIC_PACKAGES_NOT_INTERNAL
+{{.V}}      message: Public packages must not import internal packages.
+      # The packages whose files this rule applies to, including their
+      # sub-packages. If not set, the rule applies to all files.
+{{.V}}      packages:
+{{.V}}        - uber.public
+      # If set, all imported files must be in one of these packages or their
+      # sub-packages. Imports of files in the same package are always allowed.
+      #allowed_import_packages:
+      #  - google.protobuf
+      # No imported file can be in one of these packages or their sub-packages.
+{{.V}}      forbidden_import_packages:
+{{.V}}        - uber.internal
+
+  # User-defined lint groups. These can be used as the group above, and as the
+  # base of other groups.
+{{.V}}  groups:
+      # The name of the group. This must not be the name of a built-in group.
+{{.V}}    - name: team
+      # The group to start from. If not set, the group starts with no linters.
+{{.V}}      base: google
+      # Linters to add to the base group.
+{{.V}}      add:
+{{.V}}        - ENUMS_HAVE_COMMENTS
+{{.V}}        - SERVICE_NAMES_API_SUFFIX
+      # Linters to remove from the base group.
+{{.V}}      remove:
+{{.V}}        - SYNTAX_PROTO3
+
+  # The messages, enums, and packages that are used even if no service or
+  # extension in the ProtoSet reaches them, for the FILES_NO_UNUSED_TYPES
+  # linter. A package or message includes all types within it.
+{{.V}}  unused_roots:
+{{.V}}    - uber.foo.v1.ExportedEvent
+
+  # The path to a file containing a serialized FileDescriptorSet of a
+  # previous version of the ProtoSet, such as your last release, to check
+  # deleted fields and enum values against for the DELETED_FIELDS_RESERVED
+  # linter.
+{{.V}}  baseline: etc/baseline.bin
+
 # Format directives.
 {{.V}}format:
   # The indent to use. This should be Xt or Xs, where X >= 1 and "t"
@@ -163,7 +255,31 @@ protoc_version: {{.ProtocVersion}}
 {{.V}}      output: ../../.gen/proto/go
 
 {{.V}}    - name: java
-{{.V}}      output: ../../.gen/proto/java`))
+{{.V}}      output: ../../.gen/proto/java
+
+# gRPC directives for prototool grpc.
+# These are commented out even with --uncomment since the certificate
+# paths are only examples.
+#grpc:
+  # TLS settings. TLS is used if enabled is set or any other setting is set.
+  # These can be overridden with the --tls flags, and TLS can be
+  # turned off with --plaintext.
+  #tls:
+    # Use TLS with the system root CA certificates.
+    #enabled: true
+    # The path to the PEM-encoded CA certificates to verify the server with
+    # instead of the system root CA certificates.
+    #ca_cert: certs/ca.pem
+    # The paths to the PEM-encoded client certificate and private key for
+    # mutual TLS. These must either both be set or both be unset.
+    #cert: certs/client.pem
+    #key: certs/client.key
+    # The name to verify the server certificate against instead of the host
+    # of the server address.
+    #server_name: foo.example.com
+    # Do not verify the server certificate.
+    # ** Only use this for testing. **
+    #insecure_skip_verify: true`))
 
 type tmplData struct {
 	V             string
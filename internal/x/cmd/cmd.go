@@ -31,6 +31,8 @@ import (
 	"github.com/spf13/cobra/doc"
 	"github.com/spf13/pflag"
 	"github.com/tgrpc/prototool/internal/x/exec"
+	"github.com/tgrpc/prototool/internal/x/grpc"
+	"github.com/tgrpc/prototool/internal/x/settings"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -141,6 +143,7 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		},
 	}
 	flags.bindDirMode(compileCmd.PersistentFlags())
+	flags.bindErrorFormat(compileCmd.PersistentFlags())
 
 	genCmd := &cobra.Command{
 		Use:   "gen dirOrProtoFiles...",
@@ -181,6 +184,16 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	}
 	flags.bindDirMode(serviceDescriptorProtoCmd.PersistentFlags())
 
+	nextFieldNumberCmd := &cobra.Command{
+		Use:   "next-field-number dirOrProtoFiles... messagePath",
+		Short: "Print the next available field number for the message path.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.NextFieldNumber(args) })
+		},
+	}
+	flags.bindDirMode(nextFieldNumberCmd.PersistentFlags())
+
 	protocCommandsCmd := &cobra.Command{
 		Use:   "protoc-commands dirOrProtoFiles...",
 		Short: "Print the commands that would be run on compile or gen.",
@@ -195,10 +208,12 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		Use:   "lint dirOrProtoFiles...",
 		Short: "Lint proto files and compile with protoc to check for failures.",
 		Run: func(cmd *cobra.Command, args []string) {
-			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Lint(args) })
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Lint(args, flags.fix) })
 		},
 	}
 	flags.bindDirMode(lintCmd.PersistentFlags())
+	flags.bindErrorFormat(lintCmd.PersistentFlags())
+	flags.bindFix(lintCmd.PersistentFlags())
 
 	listLintersCmd := &cobra.Command{
 		Use:   "list-linters",
@@ -236,6 +251,17 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 		},
 	}
 
+	breakCheckCmd := &cobra.Command{
+		Use:   "break-check dirOrProtoFiles...",
+		Short: "Compile and check for breaking changes against a baseline.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.BreakCheck(args, flags.from) })
+		},
+	}
+	flags.bindFrom(breakCheckCmd.PersistentFlags())
+	flags.bindDirMode(breakCheckCmd.PersistentFlags())
+	flags.bindErrorFormat(breakCheckCmd.PersistentFlags())
+
 	formatCmd := &cobra.Command{
 		Use:   "format dirOrProtoFiles...",
 		Short: "Format a proto file and compile with protoc to check for failures.",
@@ -248,6 +274,27 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	flags.bindOverwrite(formatCmd.PersistentFlags())
 	flags.bindDiffMode(formatCmd.PersistentFlags())
 	flags.bindLintMode(formatCmd.PersistentFlags())
+	flags.bindErrorFormat(formatCmd.PersistentFlags())
+
+	watchCmd := &cobra.Command{
+		Use:   "watch [dirPath]",
+		Short: "Watch the directory and compile, optionally generate, and lint the affected directories when files change.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Watch(args, flags.watchGen) })
+		},
+	}
+	flags.bindWatchGen(watchCmd.PersistentFlags())
+
+	lspCmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server for Protobuf files that communicates over stdin and stdout.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.LSP(flags.formatOnSave) })
+		},
+	}
+	flags.bindLSPFormatOnSave(lspCmd.PersistentFlags())
 
 	binaryToJSONCmd := &cobra.Command{
 		Use:   "binary-to-json dirOrProtoFiles... messagePath data",
@@ -269,6 +316,65 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	}
 	flags.bindDirMode(jsonToBinaryCmd.PersistentFlags())
 
+	convertCmd := &cobra.Command{
+		Use:   "convert dirOrProtoFiles... messagePath data",
+		Short: "Convert the data for the message path between binary, json, text, and yaml.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Convert(args, flags.getConvertOptions()) })
+		},
+	}
+	flags.bindConvertFrom(convertCmd.PersistentFlags())
+	flags.bindConvertTo(convertCmd.PersistentFlags())
+	flags.bindJSONOrigName(convertCmd.PersistentFlags())
+	flags.bindJSONEnumsAsInts(convertCmd.PersistentFlags())
+	flags.bindJSONEmitDefaults(convertCmd.PersistentFlags())
+	flags.bindDirMode(convertCmd.PersistentFlags())
+
+	exampleJSONCmd := &cobra.Command{
+		Use:   "example-json dirOrProtoFiles... messagePath|package.service/Method",
+		Short: "Print example JSON for the message path, or for the request of the method.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.ExampleJSON(args) })
+		},
+	}
+	flags.bindDirMode(exampleJSONCmd.PersistentFlags())
+
+	docCmd := &cobra.Command{
+		Use:   "doc dirOrProtoFiles...",
+		Short: "Compile and print API reference documentation generated from the comments in the proto files.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Doc(args, flags.docFormat) })
+		},
+	}
+	flags.bindDocFormat(docCmd.PersistentFlags())
+	flags.bindDirMode(docCmd.PersistentFlags())
+
+	graphCmd := &cobra.Command{
+		Use:   "graph dirOrProtoFiles...",
+		Short: "Compile and print the import graph of the proto files.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.Graph(args, flags.graphFormat, flags.graphGranularity)
+			})
+		},
+	}
+	flags.bindGraphFormat(graphCmd.PersistentFlags())
+	flags.bindGraphGranularity(graphCmd.PersistentFlags())
+	flags.bindDirMode(graphCmd.PersistentFlags())
+
+	renameCmd := &cobra.Command{
+		Use:   "rename dirOrProtoFiles... oldFullyQualifiedName newName",
+		Short: "Rename a message, enum, enum value, field, service, or RPC and all references to it, and then format the changed files.",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error { return runner.Rename(args, flags.force) })
+		},
+	}
+	flags.bindForce(renameCmd.PersistentFlags())
+	flags.bindDirMode(renameCmd.PersistentFlags())
+
 	allCmd := &cobra.Command{
 		Use:   "all dirOrProtoFiles...",
 		Short: "Compile, then format and overwrite, then re-compile and generate, then lint, stopping if any step fails.",
@@ -281,13 +387,14 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	flags.bindDisableFormat(allCmd.PersistentFlags())
 	flags.bindDisableLint(allCmd.PersistentFlags())
 	flags.bindDirMode(allCmd.PersistentFlags())
+	flags.bindErrorFormat(allCmd.PersistentFlags())
 
 	grpcCmd := &cobra.Command{
 		Use:   "grpc dirOrProtoFiles... serverAddress package.service/Method requestData",
 		Short: "Call a gRPC endpoint.",
 		Run: func(cmd *cobra.Command, args []string) {
 			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
-				return runner.GRPC(args, flags.headers, flags.callTimeout, flags.connectTimeout, flags.keepaliveTime)
+				return runner.GRPC(args, flags.getGRPCOptions())
 			})
 		},
 	}
@@ -295,8 +402,37 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	flags.bindCallTimeout(grpcCmd.PersistentFlags())
 	flags.bindConnectTimeout(grpcCmd.PersistentFlags())
 	flags.bindKeepaliveTime(grpcCmd.PersistentFlags())
+	flags.bindReflection(grpcCmd.PersistentFlags())
+	flags.bindTLS(grpcCmd.PersistentFlags())
+	flags.bindTLSCACert(grpcCmd.PersistentFlags())
+	flags.bindTLSCert(grpcCmd.PersistentFlags())
+	flags.bindTLSKey(grpcCmd.PersistentFlags())
+	flags.bindTLSServerName(grpcCmd.PersistentFlags())
+	flags.bindTLSInsecureSkipVerify(grpcCmd.PersistentFlags())
+	flags.bindPlaintext(grpcCmd.PersistentFlags())
+	flags.bindMessageInfo(grpcCmd.PersistentFlags())
+	flags.bindVerbose(grpcCmd.PersistentFlags())
+	flags.bindBench(grpcCmd.PersistentFlags())
+	flags.bindBenchConcurrency(grpcCmd.PersistentFlags())
+	flags.bindBenchConnections(grpcCmd.PersistentFlags())
+	flags.bindBenchTotal(grpcCmd.PersistentFlags())
+	flags.bindBenchDuration(grpcCmd.PersistentFlags())
+	flags.bindBenchRate(grpcCmd.PersistentFlags())
 	flags.bindDirMode(grpcCmd.PersistentFlags())
 
+	mockServerCmd := &cobra.Command{
+		Use:   "mock-server dirOrProtoFiles...",
+		Short: "Run a mock gRPC server for all services with responses from an optional fixture file.",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkCmd(exitCodeAddr, stdin, stdout, stderr, flags, func(runner exec.Runner) error {
+				return runner.MockServer(args, flags.port, flags.fixture)
+			})
+		},
+	}
+	flags.bindPort(mockServerCmd.PersistentFlags())
+	flags.bindFixture(mockServerCmd.PersistentFlags())
+	flags.bindDirMode(mockServerCmd.PersistentFlags())
+
 	rootCmd := &cobra.Command{Use: "prototool"}
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(initCmd)
@@ -308,22 +444,33 @@ func getRootCommand(exitCodeAddr *int, args []string, stdin io.Reader, stdout io
 	rootCmd.AddCommand(descriptorProtoCmd)
 	rootCmd.AddCommand(fieldDescriptorProtoCmd)
 	rootCmd.AddCommand(serviceDescriptorProtoCmd)
+	rootCmd.AddCommand(nextFieldNumberCmd)
 	rootCmd.AddCommand(protocCommandsCmd)
 	rootCmd.AddCommand(lintCmd)
 	rootCmd.AddCommand(listLintersCmd)
 	rootCmd.AddCommand(listAllLintersCmd)
 	rootCmd.AddCommand(listLintGroupCmd)
 	rootCmd.AddCommand(listAllLintGroupsCmd)
+	rootCmd.AddCommand(breakCheckCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(lspCmd)
 	rootCmd.AddCommand(formatCmd)
 	rootCmd.AddCommand(binaryToJSONCmd)
 	rootCmd.AddCommand(jsonToBinaryCmd)
+	rootCmd.AddCommand(convertCmd)
+	rootCmd.AddCommand(exampleJSONCmd)
+	rootCmd.AddCommand(docCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(renameCmd)
 	rootCmd.AddCommand(allCmd)
 	rootCmd.AddCommand(grpcCmd)
+	rootCmd.AddCommand(mockServerCmd)
 
 	flags.bindDebug(rootCmd.PersistentFlags())
 	flags.bindCachePath(rootCmd.PersistentFlags())
 	flags.bindProtocURL(rootCmd.PersistentFlags())
 	flags.bindPrintFields(rootCmd.PersistentFlags())
+	flags.bindJobs(rootCmd.PersistentFlags())
 
 	rootCmd.SetArgs(args)
 	rootCmd.SetOutput(stdout)
@@ -358,6 +505,7 @@ func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags
 	}
 	runnerOptions := []exec.RunnerOption{
 		exec.RunnerWithLogger(logger),
+		exec.RunnerWithErrorOutput(stderr),
 	}
 	if flags.cachePath != "" {
 		runnerOptions = append(
@@ -377,6 +525,18 @@ func getRunner(stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags
 			exec.RunnerWithPrintFields(flags.printFields),
 		)
 	}
+	if flags.errorFormat != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithErrorFormat(flags.errorFormat),
+		)
+	}
+	if flags.jobs > 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithJobs(flags.jobs),
+		)
+	}
 	if flags.dirMode {
 		runnerOptions = append(
 			runnerOptions,
@@ -417,22 +577,55 @@ func printAndGetErrorExitCode(err error, stdout io.Writer) int {
 }
 
 type flags struct {
-	debug          bool
-	cachePath      string
-	protocURL      string
-	printFields    string
-	dirMode        bool
-	overwrite      bool
-	diffMode       bool
-	lintMode       bool
-	disableFormat  bool
-	disableLint    bool
-	gen            bool
-	headers        []string
-	callTimeout    string
-	connectTimeout string
-	keepaliveTime  string
-	uncomment      bool
+	debug            bool
+	cachePath        string
+	protocURL        string
+	printFields      string
+	errorFormat      string
+	jobs             int
+	dirMode          bool
+	overwrite        bool
+	diffMode         bool
+	lintMode         bool
+	disableFormat    bool
+	disableLint      bool
+	gen              bool
+	watchGen         bool
+	formatOnSave     bool
+	headers          []string
+	callTimeout      string
+	connectTimeout   string
+	keepaliveTime    string
+	reflection       bool
+	tls              bool
+	tlsCACert        string
+	tlsCert          string
+	tlsKey           string
+	tlsServerName    string
+	tlsInsecure      bool
+	plaintext        bool
+	messageInfo      bool
+	verbose          bool
+	bench            bool
+	benchConcurrency int
+	benchConnections int
+	benchTotal       int
+	benchDuration    time.Duration
+	benchRate        int
+	port             int
+	fixture          string
+	uncomment        bool
+	from             string
+	convertFrom      string
+	convertTo        string
+	jsonOrigName     bool
+	jsonEnumsAsInts  bool
+	jsonEmitDefaults bool
+	docFormat        string
+	graphFormat      string
+	graphGranularity string
+	fix              bool
+	force            bool
 }
 
 func (f *flags) bindDebug(flagSet *pflag.FlagSet) {
@@ -451,6 +644,14 @@ func (f *flags) bindPrintFields(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.printFields, "print-fields", "filename:line:column:message", "The colon-separated fields to print out on error.")
 }
 
+func (f *flags) bindJobs(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.jobs, "jobs", 0, "The maximum number of directories to compile at once. Defaults to the number of CPUs.")
+}
+
+func (f *flags) bindErrorFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.errorFormat, "error-format", "text", "The format to print failures in, one of text, json, json-lines, sarif, or checkstyle. The text format uses --print-fields.")
+}
+
 func (f *flags) bindDirMode(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.dirMode, "dir-mode", false, "Run as if the directory the file was given, but only print the errors from the file. Useful for integration with editors.")
 }
@@ -479,6 +680,14 @@ func (f *flags) bindGen(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.gen, "gen", false, "Print the commands that would be run on gen instead of compile.")
 }
 
+func (f *flags) bindWatchGen(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.watchGen, "gen", false, "Also generate stubs when files are added or modified.")
+}
+
+func (f *flags) bindLSPFormatOnSave(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.formatOnSave, "format-on-save", false, "Format files before they are saved, for editors that support it.")
+}
+
 func (f *flags) bindHeaders(flagSet *pflag.FlagSet) {
 	flagSet.StringSliceVarP(&f.headers, "header", "H", []string{}, "Additional request headers in 'name:value' format.")
 	for _, h := range f.headers {
@@ -487,7 +696,7 @@ func (f *flags) bindHeaders(flagSet *pflag.FlagSet) {
 }
 
 func (f *flags) bindCallTimeout(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.callTimeout, "call-timeout", "60s", "The maximum time to for all calls to be completed.")
+	flagSet.StringVar(&f.callTimeout, "call-timeout", "60s", "The maximum time to for all calls to be completed. Set to 0 for no timeout, for example for long-running streams.")
 }
 
 func (f *flags) bindConnectTimeout(flagSet *pflag.FlagSet) {
@@ -498,6 +707,169 @@ func (f *flags) bindKeepaliveTime(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.keepaliveTime, "keepalive-time", "", "The maximum idle time after which a keepalive probe is sent.")
 }
 
+func (f *flags) bindReflection(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.reflection, "reflection", false, "Use the gRPC server reflection API to resolve the method. If no files or directories are given, only server reflection is used.")
+}
+
+func (f *flags) bindTLS(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.tls, "tls", false, "Use TLS with the system root CA certificates. TLS is also used if any other TLS flag is set.")
+}
+
+func (f *flags) bindTLSCACert(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.tlsCACert, "tls-ca-cert", "", "The path to the PEM-encoded CA certificates to verify the server with instead of the system root CA certificates.")
+}
+
+func (f *flags) bindTLSCert(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.tlsCert, "tls-cert", "", "The path to the PEM-encoded client certificate for mutual TLS.")
+}
+
+func (f *flags) bindTLSKey(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.tlsKey, "tls-key", "", "The path to the PEM-encoded client private key for mutual TLS.")
+}
+
+func (f *flags) bindTLSServerName(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.tlsServerName, "tls-server-name", "", "The name to verify the server certificate against instead of the host of the server address.")
+}
+
+func (f *flags) bindTLSInsecureSkipVerify(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.tlsInsecure, "tls-insecure-skip-verify", false, "Do not verify the server certificate. This should only be used for testing.")
+}
+
+func (f *flags) bindPlaintext(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.plaintext, "plaintext", false, "Do not use TLS, even if TLS is enabled in the config file. This cannot be used with the other TLS flags.")
+}
+
+func (f *flags) bindMessageInfo(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.messageInfo, "message-info", false, "Print each response in a JSON object with the sequence number and receive time of the response.")
+}
+
+func (f *flags) bindVerbose(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.verbose, "verbose", false, "Also print the request headers, response headers, response trailers, and status with any error details to stderr.")
+}
+
+func (f *flags) bindPort(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.port, "port", 0, "The port to serve on. If 0, a free port is used and logged.")
+}
+
+func (f *flags) bindFixture(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fixture, "fixture", "", "The YAML or JSON fixture file with responses keyed by method. Methods and requests with no matching response get a zero-valued response.")
+}
+
+func (f *flags) bindBench(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.bench, "bench", false, "Benchmark the unary method by calling it repeatedly and print a report of the latencies, throughput, and status codes instead of the responses.")
+}
+
+func (f *flags) bindBenchConcurrency(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.benchConcurrency, "bench-concurrency", grpc.DefaultBenchConcurrency, "The number of requests to make concurrently with --bench.")
+}
+
+func (f *flags) bindBenchConnections(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.benchConnections, "bench-connections", grpc.DefaultBenchConnections, "The number of connections to share between the concurrent requests with --bench.")
+}
+
+func (f *flags) bindBenchTotal(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.benchTotal, "bench-total", 0, fmt.Sprintf("The total number of requests to make with --bench. Defaults to %d if --bench-duration is not set.", grpc.DefaultBenchTotal))
+}
+
+func (f *flags) bindBenchDuration(flagSet *pflag.FlagSet) {
+	flagSet.DurationVar(&f.benchDuration, "bench-duration", 0, "The duration to make requests for with --bench, such as 10s. If --bench-total is also set, stops at whichever is reached first.")
+}
+
+func (f *flags) bindBenchRate(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.benchRate, "bench-rate", 0, "The maximum number of requests per second with --bench. Defaults to no limit.")
+}
+
+func (f *flags) getGRPCOptions() exec.GRPCOptions {
+	return exec.GRPCOptions{
+		Headers:        f.headers,
+		CallTimeout:    f.callTimeout,
+		ConnectTimeout: f.connectTimeout,
+		KeepaliveTime:  f.keepaliveTime,
+		Reflection:     f.reflection,
+		TLSConfig:      f.getGRPCTLSConfig(),
+		Plaintext:      f.plaintext,
+		MessageInfo:    f.messageInfo,
+		Verbose:        f.verbose,
+		Bench:          f.bench,
+		BenchConfig:    f.getGRPCBenchConfig(),
+	}
+}
+
+func (f *flags) getGRPCBenchConfig() grpc.BenchConfig {
+	return grpc.BenchConfig{
+		Concurrency: f.benchConcurrency,
+		Connections: f.benchConnections,
+		Total:       f.benchTotal,
+		Duration:    f.benchDuration,
+		Rate:        f.benchRate,
+	}
+}
+
+func (f *flags) getGRPCTLSConfig() settings.GRPCTLSConfig {
+	return settings.GRPCTLSConfig{
+		Enabled:            f.tls,
+		CACertPath:         f.tlsCACert,
+		CertPath:           f.tlsCert,
+		KeyPath:            f.tlsKey,
+		ServerName:         f.tlsServerName,
+		InsecureSkipVerify: f.tlsInsecure,
+	}
+}
+
 func (f *flags) bindUncomment(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.uncomment, "uncomment", false, "Uncomment the example config settings.")
 }
+
+func (f *flags) bindFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.from, "from", "", "The baseline to check against, either a directory to compile or a file containing a serialized FileDescriptorSet.")
+}
+
+func (f *flags) bindConvertFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.convertFrom, "from", "json", "The format to convert from, one of binary, json, text, or yaml.")
+}
+
+func (f *flags) bindConvertTo(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.convertTo, "to", "json", "The format to convert to, one of binary, json, text, or yaml.")
+}
+
+func (f *flags) bindJSONOrigName(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.jsonOrigName, "json-orig-name", false, "Use the field names in the Protobuf files instead of the lowerCamelCase JSON names when converting to json or yaml.")
+}
+
+func (f *flags) bindJSONEnumsAsInts(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.jsonEnumsAsInts, "json-enums-as-ints", false, "Print enum values as numbers instead of names when converting to json or yaml.")
+}
+
+func (f *flags) bindJSONEmitDefaults(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.jsonEmitDefaults, "json-emit-defaults", false, "Print fields with default values when converting to json or yaml.")
+}
+
+func (f *flags) getConvertOptions() exec.ConvertOptions {
+	return exec.ConvertOptions{
+		From:             f.convertFrom,
+		To:               f.convertTo,
+		JSONOrigName:     f.jsonOrigName,
+		JSONEnumsAsInts:  f.jsonEnumsAsInts,
+		JSONEmitDefaults: f.jsonEmitDefaults,
+	}
+}
+
+func (f *flags) bindDocFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.docFormat, "format", "markdown", "The format to print documentation in, one of html, json, or markdown.")
+}
+
+func (f *flags) bindGraphFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.graphFormat, "format", "dot", "The format to print the graph in, one of dot, json, or mermaid.")
+}
+
+func (f *flags) bindGraphGranularity(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.graphGranularity, "granularity", "file", "The nodes of the graph, one of file or package.")
+}
+
+func (f *flags) bindFix(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.fix, "fix", false, "Apply the suggested fixes for lint failures, and then format the fixed files.")
+}
+
+func (f *flags) bindForce(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.force, "force", false, "Rename even if the JSON name of a field or enum value changes.")
+}
@@ -22,19 +22,40 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"io"
 	"io/ioutil"
+	"math/big"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tgrpc/prototool/internal/x/cmd/testdata/grpc/gen/grpcpb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
 )
 
 const cleanEnvKey = "PROTOTOOL_TEST_CLEAN_CACHE"
@@ -157,6 +178,95 @@ func TestLint(t *testing.T) {
 		testdata/lint/samedirjavapkg/foo2.proto:1:1:FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR`,
 		"testdata/lint/samedirjavapkg",
 	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/descriptors/foo.proto:8:3:MESSAGE_FIELD_TYPES_NOT_DEPRECATED
+		testdata/lint/descriptors/foo.proto:9:3:MESSAGE_FIELD_TYPES_NOT_DEPRECATED
+		testdata/lint/descriptors/foo.proto:26:11:REQUEST_RESPONSE_TYPES_NOT_SHARED_ACROSS_SERVICES
+		testdata/lint/descriptors/foo.proto:26:29:REQUEST_RESPONSE_TYPES_NOT_SHARED_ACROSS_SERVICES
+		testdata/lint/descriptors/other/other.proto:8:11:REQUEST_RESPONSE_TYPES_NOT_SHARED_ACROSS_SERVICES
+		testdata/lint/descriptors/other/other.proto:8:33:REQUEST_RESPONSE_TYPES_NOT_SHARED_ACROSS_SERVICES`,
+		"testdata/lint/descriptors",
+	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/rules/foo.proto:1:1:FILE_OPTIONS_REQUIRE_OBJC_CLASS_PREFIX
+		testdata/lint/rules/foo.proto:5:1:IMPORTS_NOT_INTERNAL
+		testdata/lint/rules/foo.proto:7:1:FILE_OPTIONS_REQUIRE_OBJC_CLASS_PREFIX
+		testdata/lint/rules/foo.proto:25:3:RPC_NAMES_GET_PREFIX
+		testdata/lint/rules/foo.proto:28:1:SERVICE_NAMES_API_SUFFIX`,
+		"testdata/lint/rules",
+	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/layers/a/v1/a.proto:5:1:PUBLIC_NOT_INTERNAL
+		testdata/lint/layers/a/v1/a.proto:6:1:PACKAGES_NO_IMPORT_CYCLES:Import "c/c.proto" creates the package import cycle a.v1 -> c -> a.v1.
+		testdata/lint/layers/c/c.proto:5:1:PACKAGES_NO_IMPORT_CYCLES:Import "a/v1/a2.proto" creates the package import cycle c -> a.v1 -> c.`,
+		"testdata/lint/layers",
+	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/unused/baz/baz.proto:5:1:FILES_NO_UNUSED_TYPES:Message "baz.Baz" is not reachable from any service, extension, or configured root.
+		testdata/lint/unused/foo/v1/foo.proto:6:1:FILES_NO_UNUSED_IMPORTS:Import "baz/baz.proto" was not used.
+		testdata/lint/unused/foo/v1/foo.proto:23:3:FILES_NO_UNUSED_TYPES:Message "foo.v1.Foo.Nested" is not reachable from any service, extension, or configured root.
+		testdata/lint/unused/foo/v1/foo.proto:35:1:FILES_NO_UNUSED_TYPES:Message "foo.v1.Unused" is not reachable from any service, extension, or configured root.
+		testdata/lint/unused/foo/v1/foo.proto:39:1:FILES_NO_UNUSED_TYPES:Message "foo.v1.Referenced" is not reachable from any service, extension, or configured root.
+		testdata/lint/unused/foo/v1/foo.proto:41:1:FILES_NO_UNUSED_TYPES:Enum "foo.v1.Status" is not reachable from any service, extension, or configured root.
+		testdata/lint/unused/foo/v1/foo.proto:50:1:FILES_NO_UNUSED_TYPES:Message "foo.v1.Hidden" is not reachable from any service, extension, or configured root.`,
+		"testdata/lint/unused",
+	)
+	assertLintBaseline(
+		t,
+		255,
+		`testdata/lint/reserved/foo/v1/foo.proto:5:1:DELETED_FIELDS_RESERVED:Field "five" with number 5 was deleted from message "foo.v1.Foo" without reserving its number.
+		testdata/lint/reserved/foo/v1/foo.proto:5:1:DELETED_FIELDS_RESERVED:Field "four" with number 4 was deleted from message "foo.v1.Foo" without reserving its name.
+		testdata/lint/reserved/foo/v1/foo.proto:5:1:DELETED_FIELDS_RESERVED:Field "three" with number 3 was deleted from message "foo.v1.Foo" without reserving its number and name.
+		testdata/lint/reserved/foo/v1/foo.proto:6:3:DELETED_FIELDS_RESERVED:Field "c" with number 3 was deleted from message "foo.v1.Foo.Nested" without reserving its name.
+		testdata/lint/reserved/foo/v1/foo.proto:16:3:DELETED_FIELDS_RESERVED:Field "count" with number 8 was deleted from message "foo.v1.Foo" and its number was reused by field "tags" with a different type or label.
+		testdata/lint/reserved/foo/v1/foo.proto:19:1:DELETED_FIELDS_RESERVED:Enum value "STATUS_THREE" with number 3 was deleted from enum "foo.v1.Status" without reserving its number and name.
+		testdata/lint/reserved/foo/v1/foo.proto:19:1:DELETED_FIELDS_RESERVED:Enum value "STATUS_TWO" with number 2 was deleted from enum "foo.v1.Status" without reserving its name.`,
+		"testdata/lint/reserved",
+		"testdata/lint/reserved_baseline",
+	)
+	assertLintBaseline(
+		t,
+		1,
+		`could not read lint baseline`,
+		"testdata/lint/reserved",
+		"",
+	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/groups/foo.proto:5:1:ENUMS_HAVE_COMMENTS
+		testdata/lint/groups/foo.proto:12:3:MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE`,
+		"testdata/lint/groups",
+	)
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/groups_rules/foo.proto:6:3:MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE`,
+		"testdata/lint/groups_rules",
+	)
+	// user-defined groups are validated even if they are not used
+	assertDo(t, 1, `unknown linter NOT_A_LINTER added in lint group unused`, "lint", "testdata/lint/groups_invalid")
+	assertDoLintFiles(
+		t,
+		false,
+		`testdata/lint/directives/bar.proto:1:1:FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR
+		testdata/lint/directives/bar.proto:3:1:COMMENTS_NO_UNUSED_DIRECTIVES
+		testdata/lint/directives/foo.proto:1:1:FILE_OPTIONS_JAVA_PACKAGE_SAME_IN_DIR
+		testdata/lint/directives/foo.proto:16:3:ENUM_ZERO_VALUES_INVALID
+		testdata/lint/directives/foo.proto:20:1:COMMENTS_NO_UNUSED_DIRECTIVES
+		testdata/lint/directives/foo.proto:24:3:COMMENTS_NO_UNUSED_DIRECTIVES
+		testdata/lint/directives/foo.proto:26:3:COMMENTS_NO_UNUSED_DIRECTIVES
+		testdata/lint/directives/foo.proto:28:3:MESSAGE_FIELD_NAMES_LOWER_SNAKE_CASE`,
+		"testdata/lint/directives",
+	)
 	assertDoLintFile(
 		t,
 		false,
@@ -192,6 +302,40 @@ func TestLint(t *testing.T) {
 	)
 }
 
+func TestErrorFormat(t *testing.T) {
+	t.Parallel()
+	assertDo(
+		t,
+		255,
+		`{"filename":"testdata/lint/syntax_proto2.proto","line":1,"column":1,"id":"SYNTAX_PROTO3","message":`,
+		"lint", "--error-format", "json-lines", "testdata/lint/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		255,
+		`<?xml version="1.0" encoding="UTF-8"?>
+		<checkstyle version="4.3">
+		<file name="testdata/lint/syntax_proto2.proto">
+		<error line="1" column="1" severity="error" message=
+		</file>
+		</checkstyle>`,
+		"lint", "--error-format", "checkstyle", "testdata/lint/syntax_proto2.proto",
+	)
+	assertDo(
+		t,
+		0,
+		`[]`,
+		"compile", "--error-format", "json", "testdata/foo/success.proto",
+	)
+}
+
+func TestLintFix(t *testing.T) {
+	t.Parallel()
+	assertLintFix(t, "testdata/fix/foo.proto")
+	assertLintFix(t, "testdata/fix/no_newline.proto")
+	assertLintFixDir(t, "testdata/fix/references")
+}
+
 func TestGoldenFormat(t *testing.T) {
 	t.Parallel()
 	assertGoldenFormat(t, false, "testdata/format/bar/bar.proto")
@@ -205,6 +349,205 @@ func TestJSONToBinaryToJSON(t *testing.T) {
 	assertJSONToBinaryToJSON(t, "testdata/foo/success.proto", "foo.Baz", `{"hello":100}`)
 }
 
+func TestConvert(t *testing.T) {
+	t.Parallel()
+	assertDo(t,
+		0,
+		`
+		hello: 100
+		dep: <
+		hello: 1
+		>
+		`,
+		"convert", "--from", "json", "--to", "text", "testdata/foo/success.proto", "foo.Baz", `{"hello":100,"dep":{"hello":1}}`,
+	)
+	assertDo(t,
+		0,
+		`
+		hello: "100"
+		dep:
+		hello: "1"
+		timestamp: null
+		`,
+		"convert", "--from", "text", "--to", "yaml", "--json-emit-defaults", "testdata/foo/success.proto", "foo.Baz", `hello: 100 dep: < hello: 1 >`,
+	)
+	assertDo(t,
+		1,
+		`unknown format "xml", must be one of binary, json, text, or yaml`,
+		"convert", "--from", "xml", "testdata/foo/success.proto", "foo.Baz", `{}`,
+	)
+}
+
+func TestExampleJSON(t *testing.T) {
+	t.Parallel()
+	assertDo(t,
+		0,
+		`
+		{
+		"hello": "0",
+		"dep": {
+		"hello": "0"
+		},
+		"timestamp": "1970-01-01T00:00:00Z"
+		}
+		`,
+		"example-json", "testdata/foo/success.proto", "foo.Baz",
+	)
+	assertDo(t,
+		0,
+		`
+		{
+		"value": ""
+		}
+		`,
+		"example-json", "testdata/grpc/grpc.proto", "grpc.ExcitedService/Exclamation",
+	)
+	assertDo(t,
+		1,
+		`service grpc.ExcitedService does not have method Unknown`,
+		"example-json", "testdata/grpc/grpc.proto", "grpc.ExcitedService/Unknown",
+	)
+}
+
+func TestDoc(t *testing.T) {
+	t.Parallel()
+	assertDo(t,
+		0,
+		`
+		# API Reference
+
+		## Table of Contents
+
+		- [grpc](#grpc)
+		- [ExcitedService](#grpc.ExcitedService)
+		- [ExclamationRequest](#grpc.ExclamationRequest)
+		- [ExclamationResponse](#grpc.ExclamationResponse)
+
+		<a name="grpc"></a>
+		## grpc
+
+		Files: `+"`grpc.proto`"+`
+
+		### Services
+
+		<a name="grpc.ExcitedService"></a>
+		#### ExcitedService
+
+		ExcitedService is a service with exciting transformations.
+
+		| Method | Request | Response | Description |
+		| ------ | ------- | -------- | ----------- |
+		| Exclamation | [ExclamationRequest](#grpc.ExclamationRequest) | [ExclamationResponse](#grpc.ExclamationResponse) | Exclamation adds an exclamation to the request value. |
+		| ExclamationClientStream | stream [ExclamationRequest](#grpc.ExclamationRequest) | [ExclamationResponse](#grpc.ExclamationResponse) | ExclamationClientStream adds an exclamation to the combined request values. |
+		| ExclamationServerStream | [ExclamationRequest](#grpc.ExclamationRequest) | stream [ExclamationResponse](#grpc.ExclamationResponse) | ExclamationServerStream adds an exclamation to the request value<br>and streams each character as a response. |
+		| ExclamationBidiStream | stream [ExclamationRequest](#grpc.ExclamationRequest) | stream [ExclamationResponse](#grpc.ExclamationResponse) | ExclamationBidiStream adds an exclamation to the each request value. |
+
+		### Messages
+
+		<a name="grpc.ExclamationRequest"></a>
+		#### ExclamationRequest
+
+		| Field | Number | Type | Label | Description |
+		| ----- | ------ | ---- | ----- | ----------- |
+		| value | 1 | string |  |  |
+
+		<a name="grpc.ExclamationResponse"></a>
+		#### ExclamationResponse
+
+		| Field | Number | Type | Label | Description |
+		| ----- | ------ | ---- | ----- | ----------- |
+		| value | 1 | string |  |  |
+		`,
+		"doc", "testdata/grpc/grpc.proto",
+	)
+	assertDo(t,
+		1,
+		`unknown format "pdf", must be one of html, json, or markdown`,
+		"doc", "testdata/grpc/grpc.proto", "--format", "pdf",
+	)
+}
+
+func TestGraph(t *testing.T) {
+	t.Parallel()
+	assertDo(t,
+		0,
+		`
+		digraph imports {
+		"a/v1/a.proto";
+		"a/v1/a2.proto";
+		"b/internal/internal.proto";
+		"c/c.proto";
+		"a/v1/a.proto" -> "b/internal/internal.proto";
+		"a/v1/a.proto" -> "c/c.proto";
+		"c/c.proto" -> "a/v1/a2.proto";
+		}
+		`,
+		"graph", "testdata/lint/layers",
+	)
+	assertDo(t,
+		0,
+		`
+		graph LR
+		n0["a.v1"]
+		n1["b.internal"]
+		n2["c"]
+		n0 --> n1
+		n0 --> n2
+		n2 --> n0
+		`,
+		"graph", "testdata/lint/layers", "--granularity", "package", "--format", "mermaid",
+	)
+	assertDo(t,
+		0,
+		`
+		digraph imports {
+		"a/v1/a.proto";
+		"b/internal/internal.proto" [style=dashed];
+		"c/c.proto" [style=dashed];
+		"a/v1/a.proto" -> "b/internal/internal.proto";
+		"a/v1/a.proto" -> "c/c.proto";
+		}
+		`,
+		"graph", "testdata/lint/layers/a/v1/a.proto",
+	)
+}
+
+func TestRename(t *testing.T) {
+	t.Parallel()
+	assertRename(t, "testdata/rename", "bar.v1.Bar", "Baz")
+	assertDo(t,
+		1,
+		`renaming field foo.v1.Foo.id to foo_id changes its JSON name from "id" to "fooId", set the json_name option to keep the JSON name or use --force to rename anyway`,
+		"rename", "testdata/rename", "foo.v1.Foo.id", "foo_id",
+	)
+	assertDo(t,
+		1,
+		`no message, enum, enum value, field, service, or RPC for path foo.v1.Unknown`,
+		"rename", "testdata/rename", "foo.v1.Unknown", "Bar",
+	)
+	// enum values are scoped to the package, so this does not compile
+	assertRenameUndone(t, "testdata/rename_conflict", "foo.v1.Hello.HELLO_ONE", "GOODBYE_ONE", "--force")
+}
+
+func TestBreakCheck(t *testing.T) {
+	t.Parallel()
+	assertDo(t,
+		255,
+		`testdata/breaking/current/foo/foo.proto:7:3:FIELD_TYPE_CHANGED:Field 2 on message "foo.Foo" changed type from "int32" to "int64".
+		testdata/breaking/current/foo/foo.proto:10:1:RPC_REMOVED:RPC "foo.FooService.Put" was removed.`,
+		"break-check", "testdata/breaking/current", "--from", "testdata/breaking/baseline",
+	)
+	assertDo(t, 0, "", "break-check", "testdata/breaking/baseline", "--from", "testdata/breaking/baseline")
+}
+
+func TestNextFieldNumber(t *testing.T) {
+	t.Parallel()
+	assertDo(t, 0, "101", "next-field-number", "testdata/fieldnumbers/foo.proto", "foo.Foo")
+	assertDo(t, 0, "20000", "next-field-number", "testdata/fieldnumbers/foo.proto", "foo.Bar")
+	assertDo(t, 0, "6", "next-field-number", "testdata/fieldnumbers/foo.proto", "foo.Baz")
+	assertDo(t, 1, "foo.Full: no field numbers available", "next-field-number", "testdata/fieldnumbers/foo.proto", "foo.Full")
+}
+
 func TestGRPC(t *testing.T) {
 	t.Parallel()
 	assertGRPC(t,
@@ -273,6 +616,358 @@ func TestGRPC(t *testing.T) {
 	)
 }
 
+func TestGRPCStreaming(t *testing.T) {
+	t.Parallel()
+	assertGRPCWithFlags(t,
+		0,
+		`
+		{
+			"sequence": 1,
+			"time": "
+			"message": {
+				"value": "h"
+			}
+		}
+		{
+			"sequence": 2,
+			"time": "
+			"message": {
+				"value": "i"
+			}
+		}
+		{
+			"sequence": 3,
+			"time": "
+			"message": {
+				"value": "!"
+			}
+		}
+		`,
+		"grpc.ExcitedService/ExclamationServerStream",
+		strings.NewReader(`{"value":"hi"}`),
+		"--message-info",
+	)
+	assertGRPCWithFlags(t,
+		1,
+		`
+		{
+			"value": "hello!"
+		}
+		rpc error: code = InvalidArgument desc = error requested
+		`,
+		"grpc.ExcitedService/ExclamationBidiStream",
+		strings.NewReader(`{"value":"hello"}
+		{"value":"error"}
+		{"value":"salutations"}`),
+	)
+	// the input is never closed, as with an interactive stdin, so this
+	// only returns if reading requests stops when the server ends the stream
+	pipeReader, pipeWriter := io.Pipe()
+	defer func() { _ = pipeWriter.Close() }()
+	go func() {
+		_, _ = pipeWriter.Write([]byte(`{"value":"hello"}` + "\n"))
+		_, _ = pipeWriter.Write([]byte(`{"value":"close"}` + "\n"))
+	}()
+	assertGRPCWithFlags(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		"grpc.ExcitedService/ExclamationBidiStream",
+		pipeReader,
+		"--call-timeout", "0",
+	)
+}
+
+func TestGRPCVerbose(t *testing.T) {
+	t.Parallel()
+	assertGRPCVerbose(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		`
+		Request headers:
+		foo: bar
+		Response headers:
+		content-type: application/grpc
+		excited-header: header
+		Response trailers:
+		excited-trailer: trailer
+		Status:
+		{
+			"code": "OK"
+		}
+		`,
+		"grpc.ExcitedService/Exclamation",
+		strings.NewReader(`{"value":"hello"}`),
+		"-H", "foo:bar",
+	)
+	assertGRPCVerbose(t,
+		1,
+		`
+		{
+			"value": "hello!"
+		}
+		rpc error: code = InvalidArgument desc = error requested
+		`,
+		`
+		Request headers:
+		(empty)
+		Response headers:
+		content-type: application/grpc
+		Response trailers:
+		grpc-status-details-bin: CAMSD2Vycm9yIHJlcXVlc3RlZBpHCil0eXBlLmdvb2dsZWFwaXMuY29tL2dvb2dsZS5ycGMuQmFkUmVxdWVzdBIaChgKBXZhbHVlEg9lcnJvciByZXF1ZXN0ZWQ=
+		Status:
+		{
+			"code": "InvalidArgument",
+			"message": "error requested",
+			"details": [
+				{
+					"@type": "type.googleapis.com/google.rpc.BadRequest",
+					"fieldViolations": [
+						{
+							"field": "value",
+							"description": "error requested"
+						}
+					]
+				}
+			]
+		}
+		`,
+		"grpc.ExcitedService/ExclamationBidiStream",
+		strings.NewReader(`{"value":"hello"}
+		{"value":"error"}`),
+	)
+}
+
+func TestGRPCBench(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	stdout, exitCode := testDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello {{.RequestNumber}}"}
+		{"value":"goodbye {{.WorkerNumber}}"}`),
+		"grpc", "--bench", "--bench-total", "20", "--bench-concurrency", "4", "--bench-connections", "2",
+		"testdata/grpc/grpc.proto", excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-",
+	)
+	assert.Equal(t, 0, exitCode, stdout)
+	for _, expected := range []string{
+		"Summary:",
+		"Count:        20",
+		"Response time histogram:",
+		"Latency distribution:",
+		"99% in ",
+		"Status code distribution:",
+		"[OK]  20 responses",
+	} {
+		assert.Contains(t, stdout, expected)
+	}
+	assert.NotContains(t, stdout, "Error distribution:")
+
+	assertGRPCWithFlags(t,
+		1,
+		`could not read request 1`,
+		"grpc.ExcitedService/Exclamation",
+		strings.NewReader(`{"value":{{.RequestNumber}}}`),
+		"--bench",
+	)
+	assertGRPCWithFlags(t,
+		1,
+		`benchmarks are only supported for unary methods but grpc.ExcitedService/ExclamationServerStream is a streaming method`,
+		"grpc.ExcitedService/ExclamationServerStream",
+		strings.NewReader(`{"value":"hello"}`),
+		"--bench",
+	)
+	assertGRPCWithFlags(t,
+		1,
+		`bench rate must be at most 1000000000 requests per second but was 1000000001`,
+		"grpc.ExcitedService/Exclamation",
+		strings.NewReader(`{"value":"hello"}`),
+		"--bench", "--bench-rate", "1000000001",
+	)
+
+	// the headers are sent to the server reflection API
+	reflectionTestCase := startExcitedTestCase(t, grpc.StreamInterceptor(requireReflectionAuthorization))
+	defer reflectionTestCase.Close()
+	stdout, exitCode = testDoStdin(
+		t,
+		strings.NewReader(`{"value":"hello"}`),
+		"grpc", "--bench", "--bench-total", "2", "--reflection", "-H", "authorization:secret",
+		reflectionTestCase.Address(), "grpc.ExcitedService/Exclamation", "-",
+	)
+	assert.Equal(t, 0, exitCode, stdout)
+	assert.Contains(t, stdout, "[OK]  2 responses")
+}
+
+func TestGRPCReflection(t *testing.T) {
+	t.Parallel()
+	assertGRPCReflection(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		"",
+		"grpc.ExcitedService/Exclamation",
+		`{"value":"hello"}`,
+	)
+	assertGRPCReflection(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		{
+			"value": "salutations!"
+		}
+		`,
+		"",
+		"grpc.ExcitedService/ExclamationBidiStream",
+		`{"value":"hello"}
+		{"value":"salutations"}`,
+	)
+	// the service is not in the local file, so server reflection is used
+	assertGRPCReflection(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		"testdata/foo/success.proto",
+		"grpc.ExcitedService/Exclamation",
+		`{"value":"hello"}`,
+	)
+	assertGRPCReflection(t,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		"testdata/grpc/grpc.proto",
+		"grpc.ExcitedService/Exclamation",
+		`{"value":"hello"}`,
+	)
+}
+
+func TestGRPCReflectionHeaders(t *testing.T) {
+	t.Parallel()
+	excitedTestCase := startExcitedTestCase(t, grpc.StreamInterceptor(requireReflectionAuthorization))
+	defer excitedTestCase.Close()
+	assertDoStdin(t,
+		strings.NewReader(`{"value":"hello"}`),
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		"grpc", "--reflection", "-H", "authorization:secret", excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-",
+	)
+	assertDoStdin(t,
+		strings.NewReader(`{"value":"hello"}`),
+		1,
+		`rpc error: code = Unauthenticated desc = failed to query for service descriptor "grpc.ExcitedService": authorization required`,
+		"grpc", "--reflection", excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-",
+	)
+}
+
+func TestGRPCTLS(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	caCertPool := writeTestCerts(t, tmpDirPath)
+	serverCertificate, err := tls.LoadX509KeyPair(filepath.Join(tmpDirPath, "server.pem"), filepath.Join(tmpDirPath, "server.key"))
+	require.NoError(t, err)
+	excitedTestCase := startExcitedTestCase(
+		t,
+		grpc.Creds(
+			credentials.NewTLS(
+				&tls.Config{
+					Certificates: []tls.Certificate{serverCertificate},
+					ClientAuth:   tls.RequireAndVerifyClientCert,
+					ClientCAs:    caCertPool,
+				},
+			),
+		),
+	)
+	defer excitedTestCase.Close()
+
+	caCertFlags := []string{"--tls-ca-cert", filepath.Join(tmpDirPath, "ca.pem"), "--tls-server-name", "excited.test"}
+	clientCertFlags := []string{"--tls-cert", filepath.Join(tmpDirPath, "client.pem"), "--tls-key", filepath.Join(tmpDirPath, "client.key")}
+	assertGRPCTLS(t,
+		excitedTestCase,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		append(caCertFlags, clientCertFlags...)...,
+	)
+	assertGRPCTLS(t,
+		excitedTestCase,
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		append([]string{"--tls-insecure-skip-verify"}, clientCertFlags...)...,
+	)
+	// the server requires a client certificate
+	assertGRPCTLSFailure(t, excitedTestCase, caCertFlags...)
+	// the server certificate is not valid for the address
+	assertGRPCTLSFailure(t, excitedTestCase, append([]string{"--tls-ca-cert", filepath.Join(tmpDirPath, "ca.pem")}, clientCertFlags...)...)
+	// plaintext
+	assertGRPCTLSFailure(t, excitedTestCase)
+}
+
+func TestGRPCPlaintext(t *testing.T) {
+	t.Parallel()
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	data, err := ioutil.ReadFile("testdata/grpc/grpc.proto")
+	require.NoError(t, err)
+	filePath := filepath.Join(tmpDirPath, "grpc.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, data, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDirPath, "prototool.yaml"), []byte("grpc:\n  tls:\n    enabled: true\n"), 0644))
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+
+	getArgs := func(flags ...string) []string {
+		args := append([]string{"grpc", "--connect-timeout", "1s"}, flags...)
+		return append(args, filePath, excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-")
+	}
+	// TLS is enabled in the config file
+	_, exitCode := testDoStdin(t, strings.NewReader(`{"value":"hello"}`), getArgs()...)
+	assert.NotEqual(t, 0, exitCode)
+	assertDoStdin(t, strings.NewReader(`{"value":"hello"}`),
+		0,
+		`
+		{
+			"value": "hello!"
+		}
+		`,
+		getArgs("--plaintext")...,
+	)
+	assertDoStdin(t, strings.NewReader(`{"value":"hello"}`),
+		1,
+		`plaintext cannot be used with TLS settings`,
+		getArgs("--plaintext", "--tls")...,
+	)
+}
+
 func assertDoCompileFiles(t *testing.T, expectSuccess bool, expectedLinePrefixes string, filePaths ...string) {
 	lines := getCleanLines(expectedLinePrefixes)
 	expectedExitCode := 0
@@ -303,6 +998,198 @@ func assertDoLintFiles(t *testing.T, expectSuccess bool, expectedLinePrefixes st
 	assertDo(t, expectedExitCode, strings.Join(lines, "\n"), append([]string{"lint"}, filePaths...)...)
 }
 
+// assertLintFix copies the file to a temporary directory, fixes the copy,
+// and checks that the result matches the golden file
+func assertLintFix(t *testing.T, filePath string) {
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	tmpFilePath := filepath.Join(tmpDirPath, filepath.Base(filePath))
+	require.NoError(t, ioutil.WriteFile(tmpFilePath, data, 0644))
+	assertDo(t, 0, "", "lint", "--fix", tmpFilePath)
+	fixed, err := ioutil.ReadFile(tmpFilePath)
+	require.NoError(t, err)
+	golden, err := ioutil.ReadFile(filePath + ".golden")
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(fixed))
+}
+
+// assertLintFixDir copies the directory to a temporary directory, fixes
+// the copy, and checks that the results match the golden files
+func assertLintFixDir(t *testing.T, dirPath string) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	fileInfos, err := ioutil.ReadDir(dirPath)
+	require.NoError(t, err)
+	var goldenFileNames []string
+	for _, fileInfo := range fileInfos {
+		if strings.HasSuffix(fileInfo.Name(), ".golden") {
+			goldenFileNames = append(goldenFileNames, fileInfo.Name())
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dirPath, fileInfo.Name()))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDirPath, fileInfo.Name()), data, 0644))
+	}
+	require.NotEmpty(t, goldenFileNames)
+	assertDo(t, 0, "", "lint", "--fix", tmpDirPath)
+	for _, goldenFileName := range goldenFileNames {
+		golden, err := ioutil.ReadFile(filepath.Join(dirPath, goldenFileName))
+		require.NoError(t, err)
+		fixed, err := ioutil.ReadFile(filepath.Join(tmpDirPath, strings.TrimSuffix(goldenFileName, ".golden")))
+		require.NoError(t, err)
+		assert.Equal(t, string(golden), string(fixed), goldenFileName)
+	}
+}
+
+// assertLintBaseline copies the directory to a temporary directory, writes
+// the FileDescriptorSet of the files in the baseline directory to baseline.bin
+// in the copy if the baseline directory is set, and checks the lint output
+// of the copy with the paths of the copy replaced by the directory
+func assertLintBaseline(t *testing.T, expectedExitCode int, expectedLinePrefixes string, dirPath string, baselineDirPath string) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	copyDir(t, dirPath, tmpDirPath)
+	if baselineDirPath != "" {
+		writeFileDescriptorSet(t, baselineDirPath, filepath.Join(tmpDirPath, "baseline.bin"))
+	}
+	workDirPath, err := os.Getwd()
+	require.NoError(t, err)
+	relTmpDirPath, err := filepath.Rel(workDirPath, tmpDirPath)
+	require.NoError(t, err)
+	stdout, exitCode := testDo(t, "lint", tmpDirPath)
+	assert.Equal(t, expectedExitCode, exitCode)
+	assertLinePrefixes(t, expectedLinePrefixes, strings.Replace(stdout, relTmpDirPath, dirPath, -1))
+}
+
+// copyDir copies the files in the directory and its sub-directories
+// to the other directory
+func copyDir(t *testing.T, fromDirPath string, toDirPath string) {
+	require.NoError(t, filepath.Walk(fromDirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relFilePath, err := filepath.Rel(fromDirPath, filePath)
+		if err != nil {
+			return err
+		}
+		if fileInfo.IsDir() {
+			return os.MkdirAll(filepath.Join(toDirPath, relFilePath), 0755)
+		}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(toDirPath, relFilePath), data, 0644)
+	}))
+}
+
+// writeFileDescriptorSet parses the Protobuf files in the directory and its
+// sub-directories and writes the serialized FileDescriptorSet to the file
+func writeFileDescriptorSet(t *testing.T, dirPath string, filePath string) {
+	var protoFilePaths []string
+	require.NoError(t, filepath.Walk(dirPath, func(protoFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if filepath.Ext(protoFilePath) != ".proto" {
+			return nil
+		}
+		relProtoFilePath, err := filepath.Rel(dirPath, protoFilePath)
+		if err != nil {
+			return err
+		}
+		protoFilePaths = append(protoFilePaths, relProtoFilePath)
+		return nil
+	}))
+	parser := &protoparse.Parser{ImportPaths: []string{dirPath}}
+	fileDescriptors, err := parser.ParseFiles(protoFilePaths...)
+	require.NoError(t, err)
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	for _, fileDescriptor := range fileDescriptors {
+		fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptor.AsFileDescriptorProto())
+	}
+	data, err := proto.Marshal(fileDescriptorSet)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filePath, data, 0644))
+}
+
+// assertRename copies the directory to a temporary directory, renames in
+// the copy, and checks that every file with a golden file matches it
+func assertRename(t *testing.T, dirPath string, oldFullyQualifiedName string, newName string) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	var goldenRelFilePaths []string
+	require.NoError(t, filepath.Walk(dirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil || fileInfo.IsDir() {
+			return err
+		}
+		relFilePath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(relFilePath, ".golden") {
+			goldenRelFilePaths = append(goldenRelFilePaths, relFilePath)
+			return nil
+		}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		tmpFilePath := filepath.Join(tmpDirPath, relFilePath)
+		if err := os.MkdirAll(filepath.Dir(tmpFilePath), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(tmpFilePath, data, 0644)
+	}))
+	assertDo(t, 0, "", "rename", tmpDirPath, oldFullyQualifiedName, newName)
+	for _, goldenRelFilePath := range goldenRelFilePaths {
+		renamed, err := ioutil.ReadFile(filepath.Join(tmpDirPath, strings.TrimSuffix(goldenRelFilePath, ".golden")))
+		require.NoError(t, err)
+		golden, err := ioutil.ReadFile(filepath.Join(dirPath, goldenRelFilePath))
+		require.NoError(t, err)
+		assert.Equal(t, string(golden), string(renamed), goldenRelFilePath)
+	}
+}
+
+// assertRenameUndone copies the directory to a temporary directory, renames
+// in the copy, and checks that the renamed files do not compile and that
+// the files are not changed
+func assertRenameUndone(t *testing.T, dirPath string, oldFullyQualifiedName string, newName string, flags ...string) {
+	tmpDirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmpDirPath) }()
+	copyDir(t, dirPath, tmpDirPath)
+	args := append([]string{"rename"}, flags...)
+	stdout, exitCode := testDo(t, append(args, tmpDirPath, oldFullyQualifiedName, newName)...)
+	assert.Equal(t, 255, exitCode, stdout)
+	assert.True(t, strings.HasSuffix(stdout, "the renamed files do not compile, so the rename was undone"), stdout)
+	require.NoError(t, filepath.Walk(dirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil || fileInfo.IsDir() {
+			return err
+		}
+		relFilePath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return err
+		}
+		original, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		restored, err := ioutil.ReadFile(filepath.Join(tmpDirPath, relFilePath))
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, string(original), string(restored), relFilePath)
+		return nil
+	}))
+}
+
 func assertGoldenFormat(t *testing.T, expectSuccess bool, filePath string) {
 	output, exitCode := testDo(t, "format", filePath)
 	expectedExitCode := 0
@@ -315,6 +1202,68 @@ func assertGoldenFormat(t *testing.T, expectSuccess bool, filePath string) {
 	assert.Equal(t, strings.TrimSpace(string(golden)), output)
 }
 
+func assertGRPCTLS(t *testing.T, excitedTestCase *excitedTestCase, expectedExitCode int, expectedLinePrefixes string, flags ...string) {
+	args := append([]string{"grpc", "--connect-timeout", "1s"}, flags...)
+	args = append(args, "testdata/grpc/grpc.proto", excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-")
+	assertDoStdin(t, strings.NewReader(`{"value":"hello"}`), expectedExitCode, expectedLinePrefixes, args...)
+}
+
+func assertGRPCTLSFailure(t *testing.T, excitedTestCase *excitedTestCase, flags ...string) {
+	args := append([]string{"grpc", "--connect-timeout", "1s"}, flags...)
+	args = append(args, "testdata/grpc/grpc.proto", excitedTestCase.Address(), "grpc.ExcitedService/Exclamation", "-")
+	_, exitCode := testDoStdin(t, strings.NewReader(`{"value":"hello"}`), args...)
+	assert.NotEqual(t, 0, exitCode, strings.Join(flags, " "))
+}
+
+// writeTestCerts writes a self-signed CA certificate to ca.pem, a server
+// certificate for excited.test and key to server.pem and server.key, and
+// a client certificate and key to client.pem and client.key, all signed
+// by the CA, and returns a pool with the CA certificate.
+func writeTestCerts(t *testing.T, dirPath string) *x509.CertPool {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "prototool test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caData, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caData)
+	require.NoError(t, err)
+	writeTestPEM(t, filepath.Join(dirPath, "ca.pem"), "CERTIFICATE", caData)
+	for i, name := range []string{"server", "client"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			DNSNames:     []string{"excited.test"},
+		}
+		data, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyData, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		writeTestPEM(t, filepath.Join(dirPath, name+".pem"), "CERTIFICATE", data)
+		writeTestPEM(t, filepath.Join(dirPath, name+".key"), "EC PRIVATE KEY", keyData)
+	}
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(caCert)
+	return caCertPool
+}
+
+func writeTestPEM(t *testing.T, filePath string, blockType string, data []byte) {
+	require.NoError(t, ioutil.WriteFile(filePath, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600))
+}
+
 func assertJSONToBinaryToJSON(t *testing.T, filePath string, messagePath string, jsonData string) {
 	stdout, exitCode := testDo(t, "json-to-binary", filePath, messagePath, jsonData)
 	assert.Equal(t, 0, exitCode)
@@ -329,6 +1278,54 @@ func assertGRPC(t *testing.T, expectedExitCode int, expectedLinePrefixes string,
 	assertDoStdin(t, strings.NewReader(jsonData), expectedExitCode, expectedLinePrefixes, "grpc", filePath, excitedTestCase.Address(), method, "-")
 }
 
+func assertGRPCWithFlags(t *testing.T, expectedExitCode int, expectedLinePrefixes string, method string, stdin io.Reader, flags ...string) {
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	args := append([]string{"grpc"}, flags...)
+	args = append(args, "testdata/grpc/grpc.proto", excitedTestCase.Address(), method, "-")
+	assertDoStdin(t, stdin, expectedExitCode, expectedLinePrefixes, args...)
+}
+
+// assertGRPCVerbose calls the method with --verbose and checks that
+// the responses are printed to stdout and the headers, trailers,
+// and status are printed to stderr.
+func assertGRPCVerbose(t *testing.T, expectedExitCode int, expectedStdout string, expectedStderr string, method string, stdin io.Reader, flags ...string) {
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	args := append([]string{"grpc", "--verbose"}, flags...)
+	args = append(args, "testdata/grpc/grpc.proto", excitedTestCase.Address(), method, "-")
+	testDownload(t)
+	stdout, stderr, exitCode := testDoInternalWithStderr(stdin, args...)
+	assert.Equal(t, expectedExitCode, exitCode)
+	assert.Equal(t, getCleanLines(expectedStdout), getCleanLines(stdout))
+	assert.Equal(t, getCleanLines(expectedStderr), getCleanLines(stderr))
+}
+
+// assertGRPCReflection is like assertGRPC but uses server reflection,
+// filePath can be empty to only use server reflection.
+func assertGRPCReflection(t *testing.T, expectedExitCode int, expectedLinePrefixes string, filePath string, method string, jsonData string) {
+	excitedTestCase := startExcitedTestCase(t)
+	defer excitedTestCase.Close()
+	args := []string{"grpc", "--reflection"}
+	if filePath != "" {
+		args = append(args, filePath)
+	}
+	args = append(args, excitedTestCase.Address(), method, "-")
+	assertDoStdin(t, strings.NewReader(jsonData), expectedExitCode, expectedLinePrefixes, args...)
+}
+
+// requireReflectionAuthorization requires the authorization header for
+// the server reflection API.
+func requireReflectionAuthorization(server interface{}, serverStream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if strings.HasPrefix(info.FullMethod, "/grpc.reflection.") {
+		md, _ := metadata.FromIncomingContext(serverStream.Context())
+		if values := md["authorization"]; len(values) != 1 || values[0] != "secret" {
+			return status.Error(codes.Unauthenticated, "authorization required")
+		}
+	}
+	return handler(server, serverStream)
+}
+
 func assertDoStdin(t *testing.T, stdin io.Reader, expectedExitCode int, expectedLinePrefixes string, args ...string) {
 	assertDoInternal(t, stdin, expectedExitCode, expectedLinePrefixes, args...)
 }
@@ -365,12 +1362,13 @@ type excitedTestCase struct {
 	excitedServer *excitedServer
 }
 
-func startExcitedTestCase(t *testing.T) *excitedTestCase {
+func startExcitedTestCase(t *testing.T, serverOptions ...grpc.ServerOption) *excitedTestCase {
 	listener, err := getFreeListener()
 	require.NoError(t, err)
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(serverOptions...)
 	excitedServer := newExcitedServer()
 	grpcpb.RegisterExcitedServiceServer(grpcServer, excitedServer)
+	reflectionpb.RegisterServerReflectionServer(grpcServer, newExcitedReflectionServer())
 	go func() { _ = grpcServer.Serve(listener) }()
 	return &excitedTestCase{
 		listener:      listener,
@@ -399,6 +1397,12 @@ func newExcitedServer() *excitedServer {
 }
 
 func (s *excitedServer) Exclamation(ctx context.Context, request *grpcpb.ExclamationRequest) (*grpcpb.ExclamationResponse, error) {
+	if err := grpc.SetHeader(ctx, metadata.Pairs("excited-header", "header")); err != nil {
+		return nil, err
+	}
+	if err := grpc.SetTrailer(ctx, metadata.Pairs("excited-trailer", "trailer")); err != nil {
+		return nil, err
+	}
 	return &grpcpb.ExclamationResponse{
 		Value: request.Value + "!",
 	}, nil
@@ -430,11 +1434,32 @@ func (s *excitedServer) ExclamationServerStream(request *grpcpb.ExclamationReque
 	})
 }
 
+// ExclamationBidiStream ends the stream when it receives the value close,
+// and returns an error when it receives the value error.
 func (s *excitedServer) ExclamationBidiStream(streamServer grpcpb.ExcitedService_ExclamationBidiStreamServer) error {
 	for request, err := streamServer.Recv(); err != io.EOF; request, err = streamServer.Recv() {
 		if err != nil {
 			return err
 		}
+		switch request.Value {
+		case "close":
+			return nil
+		case "error":
+			errorStatus, err := status.New(codes.InvalidArgument, "error requested").WithDetails(
+				&errdetails.BadRequest{
+					FieldViolations: []*errdetails.BadRequest_FieldViolation{
+						{
+							Field:       "value",
+							Description: "error requested",
+						},
+					},
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return errorStatus.Err()
+		}
 		if err := streamServer.Send(&grpcpb.ExclamationResponse{
 			Value: request.Value + "!",
 		}); err != nil {
@@ -444,13 +1469,89 @@ func (s *excitedServer) ExclamationBidiStream(streamServer grpcpb.ExcitedService
 	return nil
 }
 
+// excitedReflectionServer is a minimal gRPC server reflection implementation
+// for the excited service. The generated code registers the file descriptor
+// with gogo/protobuf, so this serves it directly.
+type excitedReflectionServer struct{}
+
+func newExcitedReflectionServer() *excitedReflectionServer {
+	return &excitedReflectionServer{}
+}
+
+func (s *excitedReflectionServer) ServerReflectionInfo(streamServer reflectionpb.ServerReflection_ServerReflectionInfoServer) error {
+	for request, err := streamServer.Recv(); err != io.EOF; request, err = streamServer.Recv() {
+		if err != nil {
+			return err
+		}
+		response := &reflectionpb.ServerReflectionResponse{
+			ValidHost:       request.Host,
+			OriginalRequest: request,
+		}
+		switch messageRequest := request.MessageRequest.(type) {
+		case *reflectionpb.ServerReflectionRequest_ListServices:
+			response.MessageResponse = &reflectionpb.ServerReflectionResponse_ListServicesResponse{
+				ListServicesResponse: &reflectionpb.ListServiceResponse{
+					Service: []*reflectionpb.ServiceResponse{
+						{Name: "grpc.ExcitedService"},
+					},
+				},
+			}
+		case *reflectionpb.ServerReflectionRequest_FileByFilename:
+			setExcitedFileDescriptorResponse(response, messageRequest.FileByFilename == "grpc.proto")
+		case *reflectionpb.ServerReflectionRequest_FileContainingSymbol:
+			setExcitedFileDescriptorResponse(response, strings.HasPrefix(messageRequest.FileContainingSymbol, "grpc."))
+		default:
+			setExcitedErrorResponse(response, codes.Unimplemented, "unimplemented")
+		}
+		if err := streamServer.Send(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setExcitedFileDescriptorResponse(response *reflectionpb.ServerReflectionResponse, found bool) {
+	if !found {
+		setExcitedErrorResponse(response, codes.NotFound, "not found")
+		return
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(gogoproto.FileDescriptor("grpc.proto")))
+	if err != nil {
+		setExcitedErrorResponse(response, codes.Internal, err.Error())
+		return
+	}
+	data, err := ioutil.ReadAll(gzipReader)
+	if err != nil {
+		setExcitedErrorResponse(response, codes.Internal, err.Error())
+		return
+	}
+	response.MessageResponse = &reflectionpb.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &reflectionpb.FileDescriptorResponse{
+			FileDescriptorProto: [][]byte{data},
+		},
+	}
+}
+
+func setExcitedErrorResponse(response *reflectionpb.ServerReflectionResponse, code codes.Code, message string) {
+	response.MessageResponse = &reflectionpb.ServerReflectionResponse_ErrorResponse{
+		ErrorResponse: &reflectionpb.ErrorResponse{
+			ErrorCode:    int32(code),
+			ErrorMessage: message,
+		},
+	}
+}
+
 // do not use these in tests
 
 func assertDoInternal(t *testing.T, stdin io.Reader, expectedExitCode int, expectedLinePrefixes string, args ...string) {
 	stdout, exitCode := testDoStdin(t, stdin, args...)
 	assert.Equal(t, expectedExitCode, exitCode)
+	assertLinePrefixes(t, expectedLinePrefixes, stdout)
+}
+
+func assertLinePrefixes(t *testing.T, expectedLinePrefixes string, output string) {
 	expectedLinePrefixesSplit := getCleanLines(expectedLinePrefixes)
-	outputSplit := getCleanLines(stdout)
+	outputSplit := getCleanLines(output)
 	require.Equal(t, len(expectedLinePrefixesSplit), len(outputSplit), strings.Join(outputSplit, "\n"))
 	for i, expectedLinePrefix := range expectedLinePrefixesSplit {
 		assert.True(t, strings.HasPrefix(outputSplit[i], expectedLinePrefix), "%s %d %s", expectedLinePrefix, i, strings.Join(outputSplit, "\n"))
@@ -473,6 +1574,16 @@ func testDownload(t *testing.T) {
 }
 
 func testDoInternal(stdin io.Reader, args ...string) (string, int) {
+	return testDoInternalWithStderrWriter(stdin, os.Stderr, args...)
+}
+
+func testDoInternalWithStderr(stdin io.Reader, args ...string) (string, string, int) {
+	stderrBuffer := bytes.NewBuffer(nil)
+	stdout, exitCode := testDoInternalWithStderrWriter(stdin, stderrBuffer, args...)
+	return stdout, strings.TrimSpace(stderrBuffer.String()), exitCode
+}
+
+func testDoInternalWithStderrWriter(stdin io.Reader, stderr io.Writer, args ...string) (string, int) {
 	args = append(args,
 		//"--debug",
 		"--print-fields", "filename:line:column:id:message",
@@ -481,7 +1592,7 @@ func testDoInternal(stdin io.Reader, args ...string) (string, int) {
 		stdin = os.Stdin
 	}
 	buffer := bytes.NewBuffer(nil)
-	exitCode := Do(args, stdin, buffer, os.Stderr)
+	exitCode := Do(args, stdin, buffer, stderr)
 	return strings.TrimSpace(buffer.String()), exitCode
 }
 
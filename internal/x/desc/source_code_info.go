@@ -0,0 +1,145 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package desc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// The field numbers in descriptor.proto that make up SourceCodeInfo paths.
+//
+// See the documentation for SourceCodeInfo in descriptor.proto for paths.
+const (
+	// FilePackageTag is the package field of FileDescriptorProto.
+	FilePackageTag = 2
+	// FileDependencyTag is the dependency field of FileDescriptorProto.
+	FileDependencyTag = 3
+	// FileMessageTypeTag is the message_type field of FileDescriptorProto.
+	FileMessageTypeTag = 4
+	// FileEnumTypeTag is the enum_type field of FileDescriptorProto.
+	FileEnumTypeTag = 5
+	// FileServiceTag is the service field of FileDescriptorProto.
+	FileServiceTag = 6
+	// FileExtensionTag is the extension field of FileDescriptorProto.
+	FileExtensionTag = 7
+	// MessageFieldTag is the field field of DescriptorProto.
+	MessageFieldTag = 2
+	// MessageNestedTypeTag is the nested_type field of DescriptorProto.
+	MessageNestedTypeTag = 3
+	// MessageEnumTypeTag is the enum_type field of DescriptorProto.
+	MessageEnumTypeTag = 4
+	// MessageExtensionTag is the extension field of DescriptorProto.
+	MessageExtensionTag = 6
+	// FieldExtendeeTag is the extendee field of FieldDescriptorProto.
+	FieldExtendeeTag = 2
+	// FieldTypeNameTag is the type_name field of FieldDescriptorProto.
+	FieldTypeNameTag = 6
+	// EnumValueTag is the value field of EnumDescriptorProto.
+	EnumValueTag = 2
+	// ServiceMethodTag is the method field of ServiceDescriptorProto.
+	ServiceMethodTag = 2
+	// MethodInputTypeTag is the input_type field of MethodDescriptorProto.
+	MethodInputTypeTag = 2
+	// MethodOutputTypeTag is the output_type field of MethodDescriptorProto.
+	MethodOutputTypeTag = 3
+	// NameTag is the name field of all descriptors that have a name.
+	NameTag = 1
+)
+
+// GetLineAndColumn returns the one-based line and column of the element at
+// the given SourceCodeInfo path in the file, or false if the file has no
+// source code info for the path.
+func GetLineAndColumn(fileDescriptorProto *descriptor.FileDescriptorProto, path ...int32) (int, int, bool) {
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		if int32SliceEqual(location.Path, path) && len(location.Span) >= 2 {
+			// spans are zero-based
+			return int(location.Span[0]) + 1, int(location.Span[1]) + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// GetPathToLocation returns the SourceCodeInfo locations of the file
+// keyed by GetPathKey of their paths.
+func GetPathToLocation(fileDescriptorProto *descriptor.FileDescriptorProto) map[string]*descriptor.SourceCodeInfo_Location {
+	pathToLocation := make(map[string]*descriptor.SourceCodeInfo_Location)
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		key := GetPathKey(location.Path)
+		// the first location is the one with comments if a path has multiple locations
+		if _, ok := pathToLocation[key]; !ok {
+			pathToLocation[key] = location
+		}
+	}
+	return pathToLocation
+}
+
+// GetPathKey returns a string for the SourceCodeInfo path
+// that can be used as a map key.
+func GetPathKey(path []int32) string {
+	elements := make([]string, len(path))
+	for i, element := range path {
+		elements[i] = strconv.Itoa(int(element))
+	}
+	return strings.Join(elements, ".")
+}
+
+// AppendPath returns a new SourceCodeInfo path, so that
+// the given path is not modified.
+func AppendPath(path []int32, elements ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elements))
+	newPath = append(newPath, path...)
+	return append(newPath, elements...)
+}
+
+// GetComments removes the space after the comment markers from each line
+// of the leading or trailing comments of a location, keeping any further
+// indentation.
+func GetComments(comments string) string {
+	lines := strings.Split(comments, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// GetFullName returns the name qualified by the scope, which is
+// either a package or the full name of a parent element.
+func GetFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func int32SliceEqual(one []int32, two []int32) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for i := range one {
+		if one[i] != two[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package doc generates API reference documentation from compiled
+// Protobuf files.
+//
+// Documentation is grouped by package, and includes the services, methods,
+// messages, fields, enums, and enum values of each package with their
+// leading comments, or trailing comments if there are no leading comments.
+// Elements with the deprecated option are marked as deprecated, and
+// references to messages and enums that are documented are linked, where
+// the anchor of each element is its fully-qualified name.
+package doc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+// Format is a documentation format.
+type Format int
+
+const (
+	// FormatMarkdown is Markdown.
+	FormatMarkdown Format = iota
+	// FormatHTML is a single HTML page.
+	FormatHTML
+	// FormatJSON is JSON.
+	FormatJSON
+)
+
+var (
+	formatToString = map[Format]string{
+		FormatMarkdown: "markdown",
+		FormatHTML:     "html",
+		FormatJSON:     "json",
+	}
+	stringToFormat = map[string]Format{
+		"markdown": FormatMarkdown,
+		"md":       FormatMarkdown,
+		"html":     FormatHTML,
+		"json":     FormatJSON,
+	}
+)
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := formatToString[f]; ok {
+		return s
+	}
+	return strconv.Itoa(int(f))
+}
+
+// ParseFormat parses the Format.
+//
+// The empty string is not a valid Format.
+func ParseFormat(s string) (Format, error) {
+	format, ok := stringToFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown format %q, must be one of html, json, or markdown", s)
+	}
+	return format, nil
+}
+
+// Generator generates documentation.
+type Generator interface {
+	// Generate generates documentation for the given files in the given Format.
+	//
+	// The files are expected to be compiled with source info, otherwise
+	// there will be no comments. Only messages and enums in the given
+	// files are linked, references to other types are printed as is.
+	Generate(fileDescriptorProtos []*descriptor.FileDescriptorProto, format Format) ([]byte, error)
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithLogger returns a GeneratorOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func GeneratorWithLogger(logger *zap.Logger) GeneratorOption {
+	return func(generator *generator) {
+		generator.logger = logger
+	}
+}
+
+// NewGenerator returns a new Generator.
+func NewGenerator(options ...GeneratorOption) Generator {
+	return newGenerator(options...)
+}
@@ -0,0 +1,434 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"go.uber.org/zap"
+)
+
+type generator struct {
+	logger *zap.Logger
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(fileDescriptorProtos []*descriptor.FileDescriptorProto, format Format) ([]byte, error) {
+	packageDocs := getPackageDocs(fileDescriptorProtos)
+	g.logger.Debug("generating documentation", zap.Int("packages", len(packageDocs)), zap.Stringer("format", format))
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(packageDocs), nil
+	case FormatHTML:
+		return renderHTML(packageDocs)
+	case FormatJSON:
+		data, err := json.MarshalIndent(&docs{Packages: packageDocs}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+type docs struct {
+	Packages []*packageDoc `json:"packages"`
+}
+
+type packageDoc struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Files       []string      `json:"files"`
+	Services    []*serviceDoc `json:"services,omitempty"`
+	Messages    []*messageDoc `json:"messages,omitempty"`
+	Enums       []*enumDoc    `json:"enums,omitempty"`
+}
+
+type serviceDoc struct {
+	Name        string       `json:"name"`
+	FullName    string       `json:"full_name"`
+	File        string       `json:"file"`
+	Description string       `json:"description,omitempty"`
+	Deprecated  bool         `json:"deprecated,omitempty"`
+	Methods     []*methodDoc `json:"methods,omitempty"`
+}
+
+type methodDoc struct {
+	Name              string   `json:"name"`
+	Description       string   `json:"description,omitempty"`
+	Deprecated        bool     `json:"deprecated,omitempty"`
+	RequestType       *typeRef `json:"request_type"`
+	RequestStreaming  bool     `json:"request_streaming,omitempty"`
+	ResponseType      *typeRef `json:"response_type"`
+	ResponseStreaming bool     `json:"response_streaming,omitempty"`
+}
+
+type messageDoc struct {
+	// The name relative to the package, such as Foo.Bar for nested messages.
+	Name        string      `json:"name"`
+	FullName    string      `json:"full_name"`
+	File        string      `json:"file"`
+	Description string      `json:"description,omitempty"`
+	Deprecated  bool        `json:"deprecated,omitempty"`
+	Fields      []*fieldDoc `json:"fields,omitempty"`
+}
+
+type fieldDoc struct {
+	Name     string `json:"name"`
+	JSONName string `json:"json_name,omitempty"`
+	Number   int32  `json:"number"`
+	// Empty for singular proto3 fields and map fields.
+	Label string `json:"label,omitempty"`
+	// The value type for map fields.
+	Type        *typeRef `json:"type"`
+	KeyType     string   `json:"key_type,omitempty"`
+	Oneof       string   `json:"oneof,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Deprecated  bool     `json:"deprecated,omitempty"`
+}
+
+type enumDoc struct {
+	Name        string          `json:"name"`
+	FullName    string          `json:"full_name"`
+	File        string          `json:"file"`
+	Description string          `json:"description,omitempty"`
+	Deprecated  bool            `json:"deprecated,omitempty"`
+	Values      []*enumValueDoc `json:"values,omitempty"`
+}
+
+type enumValueDoc struct {
+	Name        string `json:"name"`
+	Number      int32  `json:"number"`
+	Description string `json:"description,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+}
+
+// typeRef is a reference to a scalar type, message, or enum.
+type typeRef struct {
+	// The name to display, which is relative to the package of the
+	// referencing element if the type is in the same package.
+	Name string `json:"name"`
+	// Empty for scalar types.
+	FullName string `json:"full_name,omitempty"`
+	// The anchor of the type if the type is documented.
+	Link string `json:"link,omitempty"`
+}
+
+func getPackageDocs(fileDescriptorProtos []*descriptor.FileDescriptorProto) []*packageDoc {
+	// the names of all messages and enums that are documented, so that
+	// references to them can be linked
+	documentedTypeNames := make(map[string]struct{})
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		addDocumentedTypeNames(documentedTypeNames, fileDescriptorProto.GetPackage(), fileDescriptorProto.GetMessageType(), fileDescriptorProto.GetEnumType())
+	}
+	nameToPackageDoc := make(map[string]*packageDoc)
+	for _, fileDescriptorProto := range sortFileDescriptorProtos(fileDescriptorProtos) {
+		packageName := fileDescriptorProto.GetPackage()
+		if _, ok := nameToPackageDoc[packageName]; !ok {
+			nameToPackageDoc[packageName] = &packageDoc{
+				Name: packageName,
+			}
+		}
+		newFileDocBuilder(documentedTypeNames, fileDescriptorProto).addFile(nameToPackageDoc[packageName])
+	}
+	packageDocs := make([]*packageDoc, 0, len(nameToPackageDoc))
+	for _, packageDoc := range nameToPackageDoc {
+		packageDocs = append(packageDocs, packageDoc)
+	}
+	sort.Slice(packageDocs, func(i int, j int) bool { return packageDocs[i].Name < packageDocs[j].Name })
+	return packageDocs
+}
+
+func addDocumentedTypeNames(
+	documentedTypeNames map[string]struct{},
+	scope string,
+	descriptorProtos []*descriptor.DescriptorProto,
+	enumDescriptorProtos []*descriptor.EnumDescriptorProto,
+) {
+	for _, descriptorProto := range descriptorProtos {
+		if descriptorProto.GetOptions().GetMapEntry() {
+			continue
+		}
+		fullName := desc.GetFullName(scope, descriptorProto.GetName())
+		documentedTypeNames[fullName] = struct{}{}
+		addDocumentedTypeNames(documentedTypeNames, fullName, descriptorProto.GetNestedType(), descriptorProto.GetEnumType())
+	}
+	for _, enumDescriptorProto := range enumDescriptorProtos {
+		documentedTypeNames[desc.GetFullName(scope, enumDescriptorProto.GetName())] = struct{}{}
+	}
+}
+
+// fileDocBuilder adds the elements of a single file to a packageDoc.
+type fileDocBuilder struct {
+	documentedTypeNames map[string]struct{}
+	fileDescriptorProto *descriptor.FileDescriptorProto
+	pathToLocation      map[string]*descriptor.SourceCodeInfo_Location
+	// the map entry messages of the file by fully-qualified name
+	mapEntries map[string]*descriptor.DescriptorProto
+}
+
+func newFileDocBuilder(documentedTypeNames map[string]struct{}, fileDescriptorProto *descriptor.FileDescriptorProto) *fileDocBuilder {
+	pathToLocation := desc.GetPathToLocation(fileDescriptorProto)
+	mapEntries := make(map[string]*descriptor.DescriptorProto)
+	addMapEntries(mapEntries, fileDescriptorProto.GetPackage(), fileDescriptorProto.GetMessageType())
+	return &fileDocBuilder{
+		documentedTypeNames: documentedTypeNames,
+		fileDescriptorProto: fileDescriptorProto,
+		pathToLocation:      pathToLocation,
+		mapEntries:          mapEntries,
+	}
+}
+
+func addMapEntries(mapEntries map[string]*descriptor.DescriptorProto, scope string, descriptorProtos []*descriptor.DescriptorProto) {
+	for _, descriptorProto := range descriptorProtos {
+		fullName := desc.GetFullName(scope, descriptorProto.GetName())
+		if descriptorProto.GetOptions().GetMapEntry() {
+			mapEntries[fullName] = descriptorProto
+		}
+		addMapEntries(mapEntries, fullName, descriptorProto.GetNestedType())
+	}
+}
+
+func (b *fileDocBuilder) addFile(packageDoc *packageDoc) {
+	packageDoc.Files = append(packageDoc.Files, b.fileDescriptorProto.GetName())
+	if packageDoc.Description == "" {
+		packageDoc.Description = b.getDescription([]int32{desc.FilePackageTag})
+	}
+	for i, serviceDescriptorProto := range b.fileDescriptorProto.GetService() {
+		packageDoc.Services = append(packageDoc.Services, b.getServiceDoc(serviceDescriptorProto, []int32{desc.FileServiceTag, int32(i)}))
+	}
+	packageDoc.Messages = append(packageDoc.Messages, b.getMessageDocs("", b.fileDescriptorProto.GetMessageType(), []int32{desc.FileMessageTypeTag})...)
+	packageDoc.Enums = append(packageDoc.Enums, b.getEnumDocs("", b.fileDescriptorProto.GetEnumType(), []int32{desc.FileEnumTypeTag})...)
+	// enums nested in messages are added after the top-level enums
+	packageDoc.Enums = append(packageDoc.Enums, b.getNestedEnumDocs("", b.fileDescriptorProto.GetMessageType(), []int32{desc.FileMessageTypeTag})...)
+}
+
+func (b *fileDocBuilder) getServiceDoc(serviceDescriptorProto *descriptor.ServiceDescriptorProto, path []int32) *serviceDoc {
+	serviceDoc := &serviceDoc{
+		Name:        serviceDescriptorProto.GetName(),
+		FullName:    desc.GetFullName(b.fileDescriptorProto.GetPackage(), serviceDescriptorProto.GetName()),
+		File:        b.fileDescriptorProto.GetName(),
+		Description: b.getDescription(path),
+		Deprecated:  serviceDescriptorProto.GetOptions().GetDeprecated(),
+	}
+	for i, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+		serviceDoc.Methods = append(serviceDoc.Methods, &methodDoc{
+			Name:              methodDescriptorProto.GetName(),
+			Description:       b.getDescription(desc.AppendPath(path, desc.ServiceMethodTag, int32(i))),
+			Deprecated:        methodDescriptorProto.GetOptions().GetDeprecated(),
+			RequestType:       b.getTypeRef(methodDescriptorProto.GetInputType()),
+			RequestStreaming:  methodDescriptorProto.GetClientStreaming(),
+			ResponseType:      b.getTypeRef(methodDescriptorProto.GetOutputType()),
+			ResponseStreaming: methodDescriptorProto.GetServerStreaming(),
+		})
+	}
+	return serviceDoc
+}
+
+// getMessageDocs returns the docs for the messages and all their nested
+// messages, where map entries are not included.
+func (b *fileDocBuilder) getMessageDocs(parentName string, descriptorProtos []*descriptor.DescriptorProto, path []int32) []*messageDoc {
+	var messageDocs []*messageDoc
+	for i, descriptorProto := range descriptorProtos {
+		if descriptorProto.GetOptions().GetMapEntry() {
+			continue
+		}
+		messagePath := desc.AppendPath(path, int32(i))
+		name := desc.GetFullName(parentName, descriptorProto.GetName())
+		messageDoc := &messageDoc{
+			Name:        name,
+			FullName:    desc.GetFullName(b.fileDescriptorProto.GetPackage(), name),
+			File:        b.fileDescriptorProto.GetName(),
+			Description: b.getDescription(messagePath),
+			Deprecated:  descriptorProto.GetOptions().GetDeprecated(),
+		}
+		for j, fieldDescriptorProto := range descriptorProto.GetField() {
+			messageDoc.Fields = append(messageDoc.Fields, b.getFieldDoc(descriptorProto, fieldDescriptorProto, desc.AppendPath(messagePath, desc.MessageFieldTag, int32(j))))
+		}
+		messageDocs = append(messageDocs, messageDoc)
+		messageDocs = append(messageDocs, b.getMessageDocs(name, descriptorProto.GetNestedType(), desc.AppendPath(messagePath, desc.MessageNestedTypeTag))...)
+	}
+	return messageDocs
+}
+
+func (b *fileDocBuilder) getFieldDoc(descriptorProto *descriptor.DescriptorProto, fieldDescriptorProto *descriptor.FieldDescriptorProto, path []int32) *fieldDoc {
+	fieldDoc := &fieldDoc{
+		Name:        fieldDescriptorProto.GetName(),
+		JSONName:    fieldDescriptorProto.GetJsonName(),
+		Number:      fieldDescriptorProto.GetNumber(),
+		Label:       b.getLabel(fieldDescriptorProto),
+		Type:        b.getFieldTypeRef(fieldDescriptorProto),
+		Description: b.getDescription(path),
+		Deprecated:  fieldDescriptorProto.GetOptions().GetDeprecated(),
+	}
+	if fieldDescriptorProto.OneofIndex != nil {
+		if oneofIndex := int(fieldDescriptorProto.GetOneofIndex()); oneofIndex < len(descriptorProto.GetOneofDecl()) {
+			fieldDoc.Oneof = descriptorProto.GetOneofDecl()[oneofIndex].GetName()
+		}
+	}
+	if mapEntry, ok := b.mapEntries[strings.TrimPrefix(fieldDescriptorProto.GetTypeName(), ".")]; ok && len(mapEntry.GetField()) == 2 {
+		fieldDoc.Label = ""
+		fieldDoc.KeyType = b.getFieldTypeRef(mapEntry.GetField()[0]).Name
+		fieldDoc.Type = b.getFieldTypeRef(mapEntry.GetField()[1])
+	}
+	return fieldDoc
+}
+
+func (b *fileDocBuilder) getLabel(fieldDescriptorProto *descriptor.FieldDescriptorProto) string {
+	switch fieldDescriptorProto.GetLabel() {
+	case descriptor.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated"
+	case descriptor.FieldDescriptorProto_LABEL_REQUIRED:
+		return "required"
+	case descriptor.FieldDescriptorProto_LABEL_OPTIONAL:
+		// optional is implicit in proto3
+		if b.fileDescriptorProto.GetSyntax() == "proto3" || fieldDescriptorProto.OneofIndex != nil {
+			return ""
+		}
+		return "optional"
+	default:
+		return ""
+	}
+}
+
+func (b *fileDocBuilder) getFieldTypeRef(fieldDescriptorProto *descriptor.FieldDescriptorProto) *typeRef {
+	switch fieldDescriptorProto.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptor.FieldDescriptorProto_TYPE_ENUM,
+		descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return b.getTypeRef(fieldDescriptorProto.GetTypeName())
+	default:
+		return &typeRef{
+			Name: strings.ToLower(strings.TrimPrefix(fieldDescriptorProto.GetType().String(), "TYPE_")),
+		}
+	}
+}
+
+func (b *fileDocBuilder) getTypeRef(typeName string) *typeRef {
+	fullName := strings.TrimPrefix(typeName, ".")
+	typeRef := &typeRef{
+		Name:     fullName,
+		FullName: fullName,
+	}
+	if packageName := b.fileDescriptorProto.GetPackage(); packageName != "" && strings.HasPrefix(fullName, packageName+".") {
+		typeRef.Name = strings.TrimPrefix(fullName, packageName+".")
+	}
+	if _, ok := b.documentedTypeNames[fullName]; ok {
+		typeRef.Link = "#" + fullName
+	}
+	return typeRef
+}
+
+func (b *fileDocBuilder) getEnumDocs(parentName string, enumDescriptorProtos []*descriptor.EnumDescriptorProto, path []int32) []*enumDoc {
+	var enumDocs []*enumDoc
+	for i, enumDescriptorProto := range enumDescriptorProtos {
+		enumPath := desc.AppendPath(path, int32(i))
+		name := desc.GetFullName(parentName, enumDescriptorProto.GetName())
+		enumDoc := &enumDoc{
+			Name:        name,
+			FullName:    desc.GetFullName(b.fileDescriptorProto.GetPackage(), name),
+			File:        b.fileDescriptorProto.GetName(),
+			Description: b.getDescription(enumPath),
+			Deprecated:  enumDescriptorProto.GetOptions().GetDeprecated(),
+		}
+		for j, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+			enumDoc.Values = append(enumDoc.Values, &enumValueDoc{
+				Name:        enumValueDescriptorProto.GetName(),
+				Number:      enumValueDescriptorProto.GetNumber(),
+				Description: b.getDescription(desc.AppendPath(enumPath, desc.EnumValueTag, int32(j))),
+				Deprecated:  enumValueDescriptorProto.GetOptions().GetDeprecated(),
+			})
+		}
+		enumDocs = append(enumDocs, enumDoc)
+	}
+	return enumDocs
+}
+
+// getNestedEnumDocs returns the docs for the enums nested in the messages
+// and all their nested messages.
+func (b *fileDocBuilder) getNestedEnumDocs(parentName string, descriptorProtos []*descriptor.DescriptorProto, path []int32) []*enumDoc {
+	var enumDocs []*enumDoc
+	for i, descriptorProto := range descriptorProtos {
+		messagePath := desc.AppendPath(path, int32(i))
+		name := desc.GetFullName(parentName, descriptorProto.GetName())
+		enumDocs = append(enumDocs, b.getEnumDocs(name, descriptorProto.GetEnumType(), desc.AppendPath(messagePath, desc.MessageEnumTypeTag))...)
+		enumDocs = append(enumDocs, b.getNestedEnumDocs(name, descriptorProto.GetNestedType(), desc.AppendPath(messagePath, desc.MessageNestedTypeTag))...)
+	}
+	return enumDocs
+}
+
+// getDescription returns the leading comments for the path, or the
+// trailing comments if there are no leading comments.
+func (b *fileDocBuilder) getDescription(path []int32) string {
+	location, ok := b.pathToLocation[desc.GetPathKey(path)]
+	if !ok {
+		return ""
+	}
+	if comments := desc.GetComments(location.GetLeadingComments()); comments != "" {
+		return comments
+	}
+	return desc.GetComments(location.GetTrailingComments())
+}
+
+func getPackageTitle(packageDoc *packageDoc) string {
+	if packageDoc.Name == "" {
+		return "(no package)"
+	}
+	return packageDoc.Name
+}
+
+func getPackageAnchor(packageDoc *packageDoc) string {
+	if packageDoc.Name == "" {
+		return "no-package"
+	}
+	return packageDoc.Name
+}
+
+// getFieldLabel returns the label of the field, including the oneof
+// the field is in, if any.
+func getFieldLabel(fieldDoc *fieldDoc) string {
+	if fieldDoc.Oneof != "" {
+		return "oneof " + fieldDoc.Oneof
+	}
+	return fieldDoc.Label
+}
+
+func sortFileDescriptorProtos(fileDescriptorProtos []*descriptor.FileDescriptorProto) []*descriptor.FileDescriptorProto {
+	sorted := make([]*descriptor.FileDescriptorProto, len(fileDescriptorProtos))
+	copy(sorted, fileDescriptorProtos)
+	sort.Slice(sorted, func(i int, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	return sorted
+}
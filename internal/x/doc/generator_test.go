@@ -0,0 +1,325 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDocProtos = map[string]string{
+	"foo/foo.proto": `syntax = "proto3";
+
+// Package foo is for foos.
+package foo;
+
+import "bar/bar.proto";
+import "google/protobuf/timestamp.proto";
+
+// FooService does foo things.
+service FooService {
+  // GetFoo gets a Foo.
+  rpc GetFoo(GetFooRequest) returns (Foo);
+  rpc WatchFoos(GetFooRequest) returns (stream Foo) {
+    option deprecated = true;
+  }
+}
+
+// Foo is a foo.
+//
+// It has | pipes.
+message Foo {
+  // The ID.
+  string id = 1;
+  repeated bar.Bar bars = 2; // The bars.
+  map<string, Hello> names = 3;
+  google.protobuf.Timestamp time = 4 [deprecated = true];
+  oneof value {
+    Nested nested = 5;
+    int64 number = 6;
+  }
+  // Nested is nested.
+  message Nested {
+    Kind kind = 1;
+    enum Kind {
+      KIND_INVALID = 0;
+    }
+  }
+}
+
+message GetFooRequest {
+  option deprecated = true;
+  string id = 1;
+}
+
+// Hello says hello.
+enum Hello {
+  HELLO_INVALID = 0;
+  // World <b>.
+  HELLO_WORLD = 1 [deprecated = true];
+}
+`,
+	"bar/bar.proto": `syntax = "proto2";
+
+package bar;
+
+// Bar is a bar.
+message Bar {
+  optional string name = 1;
+  required int32 count = 2;
+}
+`,
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	data, err := newGenerator().Generate(testGetFileDescriptorProtos(t), FormatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`# API Reference
+
+## Table of Contents
+
+- [bar](#bar)
+  - [Bar](#bar.Bar)
+- [foo](#foo)
+  - [FooService](#foo.FooService)
+  - [Foo](#foo.Foo)
+  - [Foo.Nested](#foo.Foo.Nested)
+  - [GetFooRequest](#foo.GetFooRequest)
+  - [Hello](#foo.Hello)
+  - [Foo.Nested.Kind](#foo.Foo.Nested.Kind)
+
+<a name="bar"></a>
+## bar
+
+Files: `+"`bar/bar.proto`"+`
+
+### Messages
+
+<a name="bar.Bar"></a>
+#### Bar
+
+Bar is a bar.
+
+| Field | Number | Type | Label | Description |
+| ----- | ------ | ---- | ----- | ----------- |
+| name | 1 | string | optional |  |
+| count | 2 | int32 | required |  |
+
+<a name="foo"></a>
+## foo
+
+Package foo is for foos.
+
+Files: `+"`foo/foo.proto`"+`
+
+### Services
+
+<a name="foo.FooService"></a>
+#### FooService
+
+FooService does foo things.
+
+| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+| GetFoo | [GetFooRequest](#foo.GetFooRequest) | [Foo](#foo.Foo) | GetFoo gets a Foo. |
+| WatchFoos | [GetFooRequest](#foo.GetFooRequest) | stream [Foo](#foo.Foo) | **Deprecated.** |
+
+### Messages
+
+<a name="foo.Foo"></a>
+#### Foo
+
+Foo is a foo.
+
+It has | pipes.
+
+| Field | Number | Type | Label | Description |
+| ----- | ------ | ---- | ----- | ----------- |
+| id | 1 | string |  | The ID. |
+| bars | 2 | [bar.Bar](#bar.Bar) | repeated | The bars. |
+| names | 3 | map<string, [Hello](#foo.Hello)> |  |  |
+| time | 4 | google.protobuf.Timestamp |  | **Deprecated.** |
+| nested | 5 | [Foo.Nested](#foo.Foo.Nested) | oneof value |  |
+| number | 6 | int64 | oneof value |  |
+
+<a name="foo.Foo.Nested"></a>
+#### Foo.Nested
+
+Nested is nested.
+
+| Field | Number | Type | Label | Description |
+| ----- | ------ | ---- | ----- | ----------- |
+| kind | 1 | [Foo.Nested.Kind](#foo.Foo.Nested.Kind) |  |  |
+
+<a name="foo.GetFooRequest"></a>
+#### GetFooRequest
+
+**Deprecated.**
+
+| Field | Number | Type | Label | Description |
+| ----- | ------ | ---- | ----- | ----------- |
+| id | 1 | string |  |  |
+
+### Enums
+
+<a name="foo.Hello"></a>
+#### Hello
+
+Hello says hello.
+
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+| HELLO_INVALID | 0 |  |
+| HELLO_WORLD | 1 | **Deprecated.** World <b>. |
+
+<a name="foo.Foo.Nested.Kind"></a>
+#### Foo.Nested.Kind
+
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+| KIND_INVALID | 0 |  |
+`,
+		string(data),
+	)
+}
+
+func TestGenerateJSON(t *testing.T) {
+	data, err := newGenerator().Generate(testGetFileDescriptorProtos(t), FormatJSON)
+	require.NoError(t, err)
+	docs := &docs{}
+	require.NoError(t, json.Unmarshal(data, docs))
+	require.Len(t, docs.Packages, 2)
+	fooPackageDoc := docs.Packages[1]
+	assert.Equal(t, "foo", fooPackageDoc.Name)
+	assert.Equal(t, "Package foo is for foos.", fooPackageDoc.Description)
+	require.Len(t, fooPackageDoc.Services, 1)
+	require.Len(t, fooPackageDoc.Services[0].Methods, 2)
+	assert.Equal(
+		t,
+		&methodDoc{
+			Name:       "WatchFoos",
+			Deprecated: true,
+			RequestType: &typeRef{
+				Name:     "GetFooRequest",
+				FullName: "foo.GetFooRequest",
+				Link:     "#foo.GetFooRequest",
+			},
+			ResponseType: &typeRef{
+				Name:     "Foo",
+				FullName: "foo.Foo",
+				Link:     "#foo.Foo",
+			},
+			ResponseStreaming: true,
+		},
+		fooPackageDoc.Services[0].Methods[1],
+	)
+	require.Len(t, fooPackageDoc.Messages, 3)
+	require.Len(t, fooPackageDoc.Messages[0].Fields, 6)
+	assert.Equal(
+		t,
+		&fieldDoc{
+			Name:     "names",
+			JSONName: "names",
+			Number:   3,
+			Type: &typeRef{
+				Name:     "Hello",
+				FullName: "foo.Hello",
+				Link:     "#foo.Hello",
+			},
+			KeyType: "string",
+		},
+		fooPackageDoc.Messages[0].Fields[2],
+	)
+	assert.Equal(
+		t,
+		&fieldDoc{
+			Name:     "time",
+			JSONName: "time",
+			Number:   4,
+			Type: &typeRef{
+				Name:     "google.protobuf.Timestamp",
+				FullName: "google.protobuf.Timestamp",
+			},
+			Deprecated: true,
+		},
+		fooPackageDoc.Messages[0].Fields[3],
+	)
+}
+
+func TestGenerateHTML(t *testing.T) {
+	data, err := newGenerator().Generate(testGetFileDescriptorProtos(t), FormatHTML)
+	require.NoError(t, err)
+	html := string(data)
+	assert.True(t, strings.HasPrefix(html, "<!DOCTYPE html>\n"), html)
+	assert.True(t, strings.HasSuffix(html, "</html>\n"), html)
+	for _, expected := range []string{
+		`<h2 id="foo">foo</h2>`,
+		`<h4 id="foo.Foo.Nested">Foo.Nested</h4>`,
+		`<td>stream <a href="#foo.Foo">Foo</a></td>`,
+		`<td>map&lt;string, <a href="#foo.Hello">Hello</a>&gt;</td>`,
+		`<td>google.protobuf.Timestamp</td>`,
+		`<p class="description">World &lt;b&gt;.</p>`,
+		`<p class="deprecated">Deprecated.</p>`,
+	} {
+		assert.Contains(t, html, expected)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, expected := range map[string]Format{
+		"markdown": FormatMarkdown,
+		"md":       FormatMarkdown,
+		"HTML":     FormatHTML,
+		"json":     FormatJSON,
+	} {
+		format, err := ParseFormat(s)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+	_, err := ParseFormat("")
+	assert.Error(t, err)
+	_, err = ParseFormat("pdf")
+	assert.Error(t, err)
+}
+
+// testGetFileDescriptorProtos returns the files in testDocProtos, but not
+// the imported well-known types.
+func testGetFileDescriptorProtos(t *testing.T) []*descriptor.FileDescriptorProto {
+	parser := protoparse.Parser{
+		Accessor:              protoparse.FileContentsFromMap(testDocProtos),
+		IncludeSourceCodeInfo: true,
+	}
+	fileDescriptors, err := parser.ParseFiles("foo/foo.proto", "bar/bar.proto")
+	require.NoError(t, err)
+	fileDescriptorProtos := make([]*descriptor.FileDescriptorProto, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		fileDescriptorProtos[i] = fileDescriptor.AsFileDescriptorProto()
+	}
+	return fileDescriptorProtos
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("html").Funcs(template.FuncMap{
+	"packageTitle":  getPackageTitle,
+	"packageAnchor": getPackageAnchor,
+	"fieldLabel":    getFieldLabel,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Reference</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.description { white-space: pre-wrap; }
+.deprecated { color: #b00; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>API Reference</h1>
+<h2>Table of Contents</h2>
+<ul>
+{{- range .}}
+<li><a href="#{{packageAnchor .}}">{{packageTitle .}}</a>
+<ul>
+{{- range .Services}}
+<li><a href="#{{.FullName}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Messages}}
+<li><a href="#{{.FullName}}">{{.Name}}</a></li>
+{{- end}}
+{{- range .Enums}}
+<li><a href="#{{.FullName}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+{{- range .}}
+<h2 id="{{packageAnchor .}}">{{packageTitle .}}</h2>
+{{- if .Description}}
+<p class="description">{{.Description}}</p>
+{{- end}}
+<p>Files:{{range $i, $file := .Files}}{{if $i}},{{end}} <code>{{$file}}</code>{{end}}</p>
+{{- if .Services}}
+<h3>Services</h3>
+{{- end}}
+{{- range .Services}}
+<h4 id="{{.FullName}}">{{.Name}}</h4>
+{{- template "description" .}}
+{{- if .Methods}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{- range .Methods}}
+<tr><td>{{.Name}}</td><td>{{if .RequestStreaming}}stream {{end}}{{template "typeRef" .RequestType}}</td><td>{{if .ResponseStreaming}}stream {{end}}{{template "typeRef" .ResponseType}}</td><td>{{template "description" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Messages}}
+<h3>Messages</h3>
+{{- end}}
+{{- range .Messages}}
+<h4 id="{{.FullName}}">{{.Name}}</h4>
+{{- template "description" .}}
+{{- if .Fields}}
+<table>
+<tr><th>Field</th><th>Number</th><th>Type</th><th>Label</th><th>Description</th></tr>
+{{- range .Fields}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{if .KeyType}}map&lt;{{.KeyType}}, {{template "typeRef" .Type}}&gt;{{else}}{{template "typeRef" .Type}}{{end}}</td><td>{{fieldLabel .}}</td><td>{{template "description" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Enums}}
+<h3>Enums</h3>
+{{- end}}
+{{- range .Enums}}
+<h4 id="{{.FullName}}">{{.Name}}</h4>
+{{- template "description" .}}
+{{- if .Values}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{- range .Values}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{template "description" .}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{define "typeRef"}}{{if .Link}}<a href="{{.Link}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{end}}
+{{- define "description"}}
+{{- if .Deprecated}}<p class="deprecated">Deprecated.</p>{{end}}
+{{- if .Description}}<p class="description">{{.Description}}</p>{{end}}
+{{- end}}`))
+
+func renderHTML(packageDocs []*packageDoc) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := htmlTemplate.Execute(buffer, packageDocs); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
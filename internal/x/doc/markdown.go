@@ -0,0 +1,167 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func renderMarkdown(packageDocs []*packageDoc) []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("# API Reference\n\n")
+	buffer.WriteString("## Table of Contents\n\n")
+	for _, packageDoc := range packageDocs {
+		fmt.Fprintf(buffer, "- [%s](#%s)\n", getPackageTitle(packageDoc), getPackageAnchor(packageDoc))
+		for _, serviceDoc := range packageDoc.Services {
+			fmt.Fprintf(buffer, "  - [%s](#%s)\n", serviceDoc.Name, serviceDoc.FullName)
+		}
+		for _, messageDoc := range packageDoc.Messages {
+			fmt.Fprintf(buffer, "  - [%s](#%s)\n", messageDoc.Name, messageDoc.FullName)
+		}
+		for _, enumDoc := range packageDoc.Enums {
+			fmt.Fprintf(buffer, "  - [%s](#%s)\n", enumDoc.Name, enumDoc.FullName)
+		}
+	}
+	buffer.WriteString("\n")
+	for _, packageDoc := range packageDocs {
+		fmt.Fprintf(buffer, "<a name=\"%s\"></a>\n## %s\n\n", getPackageAnchor(packageDoc), getPackageTitle(packageDoc))
+		writeMarkdownDescription(buffer, packageDoc.Description, false)
+		files := make([]string, len(packageDoc.Files))
+		for i, file := range packageDoc.Files {
+			files[i] = "`" + file + "`"
+		}
+		fmt.Fprintf(buffer, "Files: %s\n\n", strings.Join(files, ", "))
+		if len(packageDoc.Services) > 0 {
+			buffer.WriteString("### Services\n\n")
+		}
+		for _, serviceDoc := range packageDoc.Services {
+			fmt.Fprintf(buffer, "<a name=\"%s\"></a>\n#### %s\n\n", serviceDoc.FullName, serviceDoc.Name)
+			writeMarkdownDescription(buffer, serviceDoc.Description, serviceDoc.Deprecated)
+			if len(serviceDoc.Methods) > 0 {
+				buffer.WriteString("| Method | Request | Response | Description |\n")
+				buffer.WriteString("| ------ | ------- | -------- | ----------- |\n")
+				for _, methodDoc := range serviceDoc.Methods {
+					fmt.Fprintf(
+						buffer,
+						"| %s | %s | %s | %s |\n",
+						methodDoc.Name,
+						getMarkdownStreamingTypeRef(methodDoc.RequestType, methodDoc.RequestStreaming),
+						getMarkdownStreamingTypeRef(methodDoc.ResponseType, methodDoc.ResponseStreaming),
+						getMarkdownTableDescription(methodDoc.Description, methodDoc.Deprecated),
+					)
+				}
+				buffer.WriteString("\n")
+			}
+		}
+		if len(packageDoc.Messages) > 0 {
+			buffer.WriteString("### Messages\n\n")
+		}
+		for _, messageDoc := range packageDoc.Messages {
+			fmt.Fprintf(buffer, "<a name=\"%s\"></a>\n#### %s\n\n", messageDoc.FullName, messageDoc.Name)
+			writeMarkdownDescription(buffer, messageDoc.Description, messageDoc.Deprecated)
+			if len(messageDoc.Fields) > 0 {
+				buffer.WriteString("| Field | Number | Type | Label | Description |\n")
+				buffer.WriteString("| ----- | ------ | ---- | ----- | ----------- |\n")
+				for _, fieldDoc := range messageDoc.Fields {
+					fmt.Fprintf(
+						buffer,
+						"| %s | %d | %s | %s | %s |\n",
+						fieldDoc.Name,
+						fieldDoc.Number,
+						getMarkdownFieldType(fieldDoc),
+						getFieldLabel(fieldDoc),
+						getMarkdownTableDescription(fieldDoc.Description, fieldDoc.Deprecated),
+					)
+				}
+				buffer.WriteString("\n")
+			}
+		}
+		if len(packageDoc.Enums) > 0 {
+			buffer.WriteString("### Enums\n\n")
+		}
+		for _, enumDoc := range packageDoc.Enums {
+			fmt.Fprintf(buffer, "<a name=\"%s\"></a>\n#### %s\n\n", enumDoc.FullName, enumDoc.Name)
+			writeMarkdownDescription(buffer, enumDoc.Description, enumDoc.Deprecated)
+			if len(enumDoc.Values) > 0 {
+				buffer.WriteString("| Name | Number | Description |\n")
+				buffer.WriteString("| ---- | ------ | ----------- |\n")
+				for _, enumValueDoc := range enumDoc.Values {
+					fmt.Fprintf(
+						buffer,
+						"| %s | %d | %s |\n",
+						enumValueDoc.Name,
+						enumValueDoc.Number,
+						getMarkdownTableDescription(enumValueDoc.Description, enumValueDoc.Deprecated),
+					)
+				}
+				buffer.WriteString("\n")
+			}
+		}
+	}
+	return append(bytes.TrimRight(buffer.Bytes(), "\n"), '\n')
+}
+
+func writeMarkdownDescription(buffer *bytes.Buffer, description string, deprecated bool) {
+	if deprecated {
+		buffer.WriteString("**Deprecated.**\n\n")
+	}
+	if description != "" {
+		buffer.WriteString(description)
+		buffer.WriteString("\n\n")
+	}
+}
+
+func getMarkdownStreamingTypeRef(typeRef *typeRef, streaming bool) string {
+	if streaming {
+		return "stream " + getMarkdownTypeRef(typeRef)
+	}
+	return getMarkdownTypeRef(typeRef)
+}
+
+func getMarkdownFieldType(fieldDoc *fieldDoc) string {
+	if fieldDoc.KeyType != "" {
+		return fmt.Sprintf("map<%s, %s>", fieldDoc.KeyType, getMarkdownTypeRef(fieldDoc.Type))
+	}
+	return getMarkdownTypeRef(fieldDoc.Type)
+}
+
+func getMarkdownTypeRef(typeRef *typeRef) string {
+	if typeRef.Link == "" {
+		return typeRef.Name
+	}
+	return fmt.Sprintf("[%s](%s)", typeRef.Name, typeRef.Link)
+}
+
+// getMarkdownTableDescription returns the description on a single line
+// for use in a table cell.
+func getMarkdownTableDescription(description string, deprecated bool) string {
+	description = strings.Replace(description, "|", "\\|", -1)
+	description = strings.Replace(description, "\n", "<br>", -1)
+	if deprecated {
+		if description == "" {
+			return "**Deprecated.**"
+		}
+		return "**Deprecated.** " + description
+	}
+	return description
+}
@@ -23,6 +23,8 @@ package exec
 import (
 	"io"
 
+	"github.com/tgrpc/prototool/internal/x/grpc"
+	"github.com/tgrpc/prototool/internal/x/settings"
 	"go.uber.org/zap"
 )
 
@@ -37,6 +39,52 @@ func (e *ExitError) Error() string {
 	return e.Message
 }
 
+// GRPCOptions are the options for Runner.GRPC.
+type GRPCOptions struct {
+	// Headers are the headers to send, each in the form key:value.
+	Headers []string
+	// CallTimeout is the duration string for the call timeout.
+	// The default is grpc.DefaultCallTimeout.
+	CallTimeout string
+	// ConnectTimeout is the duration string for the connect timeout.
+	ConnectTimeout string
+	// KeepaliveTime is the duration string for the keepalive time.
+	KeepaliveTime string
+	// Reflection says to use server reflection to resolve the method.
+	Reflection bool
+	// TLSConfig overrides the TLS settings in the config file.
+	TLSConfig settings.GRPCTLSConfig
+	// Plaintext says to not use TLS even if it is enabled in the config file.
+	// TLSConfig must not enable TLS if this is set.
+	Plaintext bool
+	// MessageInfo says to print message information with the response.
+	MessageInfo bool
+	// Verbose says to print the request and response headers and trailers
+	// to the error output.
+	Verbose bool
+	// Bench says to benchmark the method instead of calling it once.
+	Bench bool
+	// BenchConfig is the benchmark configuration if Bench is set.
+	BenchConfig grpc.BenchConfig
+}
+
+// ConvertOptions are the options for Runner.Convert.
+type ConvertOptions struct {
+	// From is the format to convert from, one of binary, json, text, or yaml.
+	From string
+	// To is the format to convert to, one of binary, json, text, or yaml.
+	To string
+	// JSONOrigName says to use the field names in the Protobuf files
+	// instead of the lowerCamelCase JSON names when converting to json or yaml.
+	JSONOrigName bool
+	// JSONEnumsAsInts says to print enum values as numbers instead of
+	// names when converting to json or yaml.
+	JSONEnumsAsInts bool
+	// JSONEmitDefaults says to print fields with default values when
+	// converting to json or yaml.
+	JSONEmitDefaults bool
+}
+
 // Runner runs commands.
 type Runner interface {
 	Init(args []string, uncomment bool) error
@@ -49,17 +97,27 @@ type Runner interface {
 	DescriptorProto(args []string) error
 	FieldDescriptorProto(args []string) error
 	ServiceDescriptorProto(args []string) error
+	NextFieldNumber(args []string) error
 	ProtocCommands(args []string, genCommands bool) error
-	Lint(args []string) error
+	Lint(args []string, fix bool) error
 	ListLinters() error
 	ListAllLinters() error
 	ListLintGroup(group string) error
 	ListAllLintGroups() error
+	BreakCheck(args []string, from string) error
+	Watch(args []string, doGen bool) error
+	LSP(formatOnSave bool) error
 	Format(args []string, overwrite bool, diffMode bool, lintMode bool) error
 	BinaryToJSON(args []string) error
 	JSONToBinary(args []string) error
+	Convert(args []string, options ConvertOptions) error
+	ExampleJSON(args []string) error
+	Doc(args []string, format string) error
+	Graph(args []string, format string, granularity string) error
+	Rename(args []string, force bool) error
 	All(args []string, disableFormat bool, disableLint bool) error
-	GRPC(args []string, headers []string, callTimeout string, connectTimeout string, keepaliveTime string) error
+	GRPC(args []string, options GRPCOptions) error
+	MockServer(args []string, port int, fixturePath string) error
 }
 
 // RunnerOption is an option for a new Runner.
@@ -74,6 +132,17 @@ func RunnerWithLogger(logger *zap.Logger) RunnerOption {
 	}
 }
 
+// RunnerWithErrorOutput returns a RunnerOption that writes output that is
+// not the result of the command, such as the headers printed by
+// grpc --verbose, to the given writer.
+//
+// The default is to use the output.
+func RunnerWithErrorOutput(errorOutput io.Writer) RunnerOption {
+	return func(runner *runner) {
+		runner.errorOutput = errorOutput
+	}
+}
+
 // RunnerWithCachePath returns a RunnerOption that uses the given cache path.
 func RunnerWithCachePath(cachePath string) RunnerOption {
 	return func(runner *runner) {
@@ -96,6 +165,15 @@ func RunnerWithPrintFields(printFields string) RunnerOption {
 	}
 }
 
+// RunnerWithErrorFormat returns a RunnerOption that uses the given error format
+// to print failures, one of text, json, json-lines, sarif, or checkstyle.
+// The default is text, which uses the print fields.
+func RunnerWithErrorFormat(errorFormat string) RunnerOption {
+	return func(runner *runner) {
+		runner.errorFormat = errorFormat
+	}
+}
+
 // RunnerWithDirMode returns a RunnerOption that will act as if the file
 // given is the directory of the file, but only print the failures
 // from that file.
@@ -105,6 +183,16 @@ func RunnerWithDirMode() RunnerOption {
 	}
 }
 
+// RunnerWithJobs returns a RunnerOption that compiles at most the given
+// number of directories at once.
+//
+// The default is runtime.NumCPU().
+func RunnerWithJobs(jobs int) RunnerOption {
+	return func(runner *runner) {
+		runner.jobs = jobs
+	}
+}
+
 // NewRunner returns a new Runner.
 func NewRunner(workDirPath string, input io.Reader, output io.Writer, options ...RunnerOption) Runner {
 	return newRunner(workDirPath, input, output, options...)
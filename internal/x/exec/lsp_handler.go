@@ -0,0 +1,112 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package exec
+
+import (
+	"path/filepath"
+
+	"github.com/tgrpc/prototool/internal/x/file"
+	"github.com/tgrpc/prototool/internal/x/lint"
+	"github.com/tgrpc/prototool/internal/x/lsp"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+// lspHandler implements lsp.Handler with a runner.
+type lspHandler struct {
+	runner *runner
+}
+
+func newLSPHandler(runner *runner) *lspHandler {
+	return &lspHandler{
+		runner: runner,
+	}
+}
+
+func (h *lspHandler) Check(filePath string) (*lsp.CheckResult, error) {
+	protoSets, err := h.runner.protoSetProvider.GetForDir(h.runner.workDirPath, filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	compileResult, err := h.runner.newCompiler(false, true).Compile(protoSets...)
+	if err != nil {
+		return nil, err
+	}
+	if len(compileResult.Failures) > 0 {
+		return &lsp.CheckResult{
+			Failures:    h.absFailures(compileResult.Failures),
+			RuneColumns: hasRuneColumns(protoSets),
+		}, nil
+	}
+	failures, err := h.runner.newLintRunner().Run(compileResult.FileDescriptorSets, protoSets...)
+	if err != nil {
+		return nil, err
+	}
+	var descriptorFiles []*lint.DescriptorFile
+	for _, protoSet := range protoSets {
+		dirPathToDescriptorFiles, err := lint.GetDirPathToDescriptorFiles(protoSet, compileResult.FileDescriptorSets)
+		if err != nil {
+			return nil, err
+		}
+		for _, iDescriptorFiles := range dirPathToDescriptorFiles {
+			descriptorFiles = append(descriptorFiles, iDescriptorFiles...)
+		}
+	}
+	return &lsp.CheckResult{
+		Failures:        h.absFailures(failures),
+		DescriptorFiles: descriptorFiles,
+		RuneColumns:     hasRuneColumns(protoSets),
+	}, nil
+}
+
+func (h *lspHandler) Format(filePath string, data []byte) ([]byte, []*text.Failure, error) {
+	config, err := h.runner.getConfig(filepath.Dir(filePath))
+	if err != nil {
+		return nil, nil, err
+	}
+	return h.runner.newTransformer().Transform(config, data)
+}
+
+func (h *lspHandler) ReloadConfigs() {
+	h.runner.resetProviders()
+}
+
+// absFailures makes the filenames of the failures absolute, as the
+// failures use the display paths, which are relative to the working directory.
+func (h *lspHandler) absFailures(failures []*text.Failure) []*text.Failure {
+	for _, failure := range failures {
+		if failure.Filename != "" && !filepath.IsAbs(failure.Filename) {
+			failure.Filename = filepath.Join(h.runner.workDirPath, failure.Filename)
+		}
+	}
+	return failures
+}
+
+// hasRuneColumns returns true if the ProtoSets are compiled with the
+// Golang compile backend, which counts columns in runes instead of bytes.
+func hasRuneColumns(protoSets []*file.ProtoSet) bool {
+	for _, protoSet := range protoSets {
+		if protoSet.Config.Compile.Backend == settings.CompileBackendGo {
+			return true
+		}
+	}
+	return false
+}
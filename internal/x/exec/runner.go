@@ -21,12 +21,13 @@
 package exec
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -37,22 +38,42 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/breaking"
 	"github.com/tgrpc/prototool/internal/x/cfginit"
 	"github.com/tgrpc/prototool/internal/x/diff"
+	"github.com/tgrpc/prototool/internal/x/doc"
 	"github.com/tgrpc/prototool/internal/x/extract"
 	"github.com/tgrpc/prototool/internal/x/file"
 	"github.com/tgrpc/prototool/internal/x/format"
+	"github.com/tgrpc/prototool/internal/x/graph"
 	"github.com/tgrpc/prototool/internal/x/grpc"
 	"github.com/tgrpc/prototool/internal/x/lint"
+	"github.com/tgrpc/prototool/internal/x/lsp"
+	"github.com/tgrpc/prototool/internal/x/mock"
 	"github.com/tgrpc/prototool/internal/x/protoc"
 	"github.com/tgrpc/prototool/internal/x/reflect"
+	"github.com/tgrpc/prototool/internal/x/rename"
 	"github.com/tgrpc/prototool/internal/x/settings"
 	"github.com/tgrpc/prototool/internal/x/text"
 	"github.com/tgrpc/prototool/internal/x/vars"
+	"github.com/tgrpc/prototool/internal/x/watch"
 	"go.uber.org/zap"
 )
 
+// maxFixIterations is the maximum number of times lint fixes are applied.
+const maxFixIterations = 10
+
+const (
+	// firstReservedFieldNumber and lastReservedFieldNumber are the
+	// bounds of the field numbers reserved for the protobuf implementation.
+	firstReservedFieldNumber = 19000
+	lastReservedFieldNumber  = 19999
+	// maxFieldNumber is the maximum field number.
+	maxFieldNumber = 536870911
+)
+
 var jsonMarshaler = &jsonpb.Marshaler{Indent: "  "}
 
 type runner struct {
@@ -61,11 +82,17 @@ type runner struct {
 	workDirPath      string
 	input            io.Reader
 	output           io.Writer
+	errorOutput      io.Writer
 	logger           *zap.Logger
 	cachePath        string
 	protocURL        string
 	printFields      string
+	errorFormat      string
 	dirMode          bool
+	jobs             int
+
+	// failures buffered to print at once for ErrorFormats that are documents
+	documentFailures []*text.Failure
 }
 
 func newRunner(workDirPath string, input io.Reader, output io.Writer, options ...RunnerOption) *runner {
@@ -77,13 +104,21 @@ func newRunner(workDirPath string, input io.Reader, output io.Writer, options ..
 	for _, option := range options {
 		option(runner)
 	}
-	runner.configProvider = settings.NewConfigProvider(
-		settings.ConfigProviderWithLogger(runner.logger),
+	if runner.errorOutput == nil {
+		runner.errorOutput = output
+	}
+	runner.resetProviders()
+	return runner
+}
+
+// resetProviders creates new providers, which clears any cached configs.
+func (r *runner) resetProviders() {
+	r.configProvider = settings.NewConfigProvider(
+		settings.ConfigProviderWithLogger(r.logger),
 	)
-	runner.protoSetProvider = file.NewProtoSetProvider(
-		file.ProtoSetProviderWithLogger(runner.logger),
+	r.protoSetProvider = file.NewProtoSetProvider(
+		file.ProtoSetProviderWithLogger(r.logger),
 	)
-	return runner
 }
 
 func (r *runner) Version() error {
@@ -149,7 +184,10 @@ func (r *runner) Clean() error {
 	if err != nil {
 		return err
 	}
-	return r.newDownloader(config).Delete()
+	if err := r.newDownloader(config).Delete(); err != nil {
+		return err
+	}
+	return r.newCompiler(false, false).DeleteCache()
 }
 
 func (r *runner) Files(args []string) error {
@@ -169,7 +207,8 @@ func (r *runner) Files(args []string) error {
 	return nil
 }
 
-func (r *runner) Compile(args []string) error {
+func (r *runner) Compile(args []string) (retErr error) {
+	defer r.printDocumentFailures(&retErr)
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
@@ -288,6 +327,74 @@ func (r *runner) ServiceDescriptorProto(args []string) error {
 	return r.println(data)
 }
 
+func (r *runner) NextFieldNumber(args []string) error {
+	if len(args) < 1 {
+		return nil
+	}
+	path := args[len(args)-1]
+	args = args[:len(args)-1]
+
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	message, err := r.newGetter().GetMessage(fileDescriptorSets, path)
+	if err != nil {
+		return err
+	}
+	if message == nil {
+		return fmt.Errorf("nil message")
+	}
+	fieldNumber, err := getNextFieldNumber(message.DescriptorProto)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return r.println(fmt.Sprintf("%d", fieldNumber))
+}
+
+// getNextFieldNumber returns the smallest field number greater than all
+// the field numbers and reserved numbers of the message that is not in
+// an extension range or the range reserved for the protobuf implementation.
+func getNextFieldNumber(message *descriptor.DescriptorProto) (int32, error) {
+	fieldNumber := int32(1)
+	for _, field := range message.Field {
+		if field.GetNumber() >= fieldNumber {
+			fieldNumber = field.GetNumber() + 1
+		}
+	}
+	// the ends of reserved and extension ranges are exclusive
+	for _, reservedRange := range message.ReservedRange {
+		if reservedRange.GetEnd() > fieldNumber {
+			fieldNumber = reservedRange.GetEnd()
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, extensionRange := range message.ExtensionRange {
+			if extensionRange.GetStart() <= fieldNumber && fieldNumber < extensionRange.GetEnd() {
+				fieldNumber = extensionRange.GetEnd()
+				changed = true
+			}
+		}
+		if firstReservedFieldNumber <= fieldNumber && fieldNumber <= lastReservedFieldNumber {
+			fieldNumber = lastReservedFieldNumber + 1
+			changed = true
+		}
+	}
+	if fieldNumber > maxFieldNumber {
+		return 0, fmt.Errorf("no field numbers available")
+	}
+	return fieldNumber, nil
+}
+
 func (r *runner) compile(doGen bool, doFileDescriptorSet bool, meta *meta) ([]*descriptor.FileDescriptorSet, error) {
 	compileResult, err := r.newCompiler(doGen, doFileDescriptorSet).Compile(meta.ProtoSets...)
 	if err != nil {
@@ -320,20 +427,117 @@ func (r *runner) ProtocCommands(args []string, genCommands bool) error {
 	return nil
 }
 
-func (r *runner) Lint(args []string) error {
+func (r *runner) Lint(args []string, fix bool) (retErr error) {
+	defer r.printDocumentFailures(&retErr)
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
 	}
 	r.printAffectedFiles(meta)
-	if _, err := r.compile(false, false, meta); err != nil {
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
 		return err
 	}
-	return r.lint(meta)
+	if fix {
+		if err := r.fix(meta, fileDescriptorSets); err != nil {
+			return err
+		}
+		// make sure the fixed files still compile, and lint with the fixed files
+		fileDescriptorSets, err = r.compile(false, true, meta)
+		if err != nil {
+			return err
+		}
+	}
+	return r.lint(meta, fileDescriptorSets)
 }
 
-func (r *runner) lint(meta *meta) error {
-	failures, err := r.newLintRunner().Run(meta.ProtoSets...)
+// fix applies the edits from lint failures to the files, and then formats
+// the files that were edited.
+//
+// Edits that overlap are only applied on the next run of the linters,
+// so this runs the linters until no edits are applied, up to maxFixIterations,
+// compiling the files again after each run that applied edits.
+func (r *runner) fix(meta *meta, fileDescriptorSets []*descriptor.FileDescriptorSet) error {
+	displayPathToProtoFile := make(map[string]*file.ProtoFile)
+	displayPathToConfig := make(map[string]settings.Config)
+	for _, protoSet := range meta.ProtoSets {
+		for _, protoFiles := range protoSet.DirPathToFiles {
+			for _, protoFile := range protoFiles {
+				displayPathToProtoFile[protoFile.DisplayPath] = protoFile
+				displayPathToConfig[protoFile.DisplayPath] = protoSet.Config
+			}
+		}
+	}
+	fixedDisplayPathMap := make(map[string]struct{})
+	for i := 0; i < maxFixIterations; i++ {
+		failures, err := r.newLintRunner().Run(fileDescriptorSets, meta.ProtoSets...)
+		if err != nil {
+			return err
+		}
+		displayPathToEdits := make(map[string][]*text.Edit)
+		for _, failure := range failures {
+			for _, edit := range failure.Edits {
+				displayPath := failure.Filename
+				if edit.Filename != "" {
+					displayPath = edit.Filename
+				}
+				include, err := meta.includesFilename(displayPath)
+				if err != nil {
+					return err
+				}
+				if include {
+					displayPathToEdits[displayPath] = append(displayPathToEdits[displayPath], edit)
+				}
+			}
+		}
+		numApplied := 0
+		for displayPath, edits := range displayPathToEdits {
+			protoFile, ok := displayPathToProtoFile[displayPath]
+			if !ok {
+				continue
+			}
+			data, err := ioutil.ReadFile(protoFile.Path)
+			if err != nil {
+				return err
+			}
+			data, fileNumApplied, err := text.ApplyEdits(data, edits)
+			if err != nil {
+				return fmt.Errorf("could not fix %s: %v", displayPath, err)
+			}
+			if fileNumApplied == 0 {
+				continue
+			}
+			r.logger.Debug("applied lint fixes", zap.String("file", displayPath), zap.Int("edits", fileNumApplied))
+			if err := ioutil.WriteFile(protoFile.Path, data, os.ModePerm); err != nil {
+				return err
+			}
+			fixedDisplayPathMap[displayPath] = struct{}{}
+			numApplied += fileNumApplied
+		}
+		if numApplied == 0 {
+			break
+		}
+		// the linters that use the compiled files need the edited files
+		fileDescriptorSets, err = r.compile(false, true, meta)
+		if err != nil {
+			return err
+		}
+	}
+	fixedDisplayPaths := make([]string, 0, len(fixedDisplayPathMap))
+	for displayPath := range fixedDisplayPathMap {
+		fixedDisplayPaths = append(fixedDisplayPaths, displayPath)
+	}
+	sort.Strings(fixedDisplayPaths)
+	for _, displayPath := range fixedDisplayPaths {
+		if err := r.formatFile(true, false, false, meta, displayPathToConfig[displayPath], displayPathToProtoFile[displayPath]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) lint(meta *meta, fileDescriptorSets []*descriptor.FileDescriptorSet) error {
+	failures, err := r.newLintRunner().Run(fileDescriptorSets, meta.ProtoSets...)
 	if err != nil {
 		return err
 	}
@@ -359,19 +563,41 @@ func (r *runner) ListLinters() error {
 }
 
 func (r *runner) ListAllLinters() error {
-	return r.printCheckers(lint.AllCheckers)
+	config, err := r.getConfig(r.workDirPath)
+	if err != nil {
+		return err
+	}
+	ruleCheckers, err := lint.GetRuleCheckers(config.Lint)
+	if err != nil {
+		return err
+	}
+	return r.printCheckers(append(ruleCheckers, lint.AllCheckers...))
 }
 
 func (r *runner) ListLintGroup(group string) error {
-	checkers, ok := lint.GroupToCheckers[strings.ToLower(group)]
+	groupToCheckers, err := r.getGroupToCheckers()
+	if err != nil {
+		return err
+	}
+	checkers, ok := groupToCheckers[strings.ToLower(group)]
 	if !ok {
 		return newExitErrorf(255, "unknown lint group: %s", strings.ToLower(group))
 	}
-	return r.printCheckers(checkers)
+	// printCheckers sorts in place
+	return r.printCheckers(append([]lint.Checker(nil), checkers...))
 }
 
 func (r *runner) ListAllLintGroups() error {
-	for group := range lint.GroupToCheckers {
+	groupToCheckers, err := r.getGroupToCheckers()
+	if err != nil {
+		return err
+	}
+	groups := make([]string, 0, len(groupToCheckers))
+	for group := range groupToCheckers {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
 		if err := r.println(group); err != nil {
 			return err
 		}
@@ -379,7 +605,99 @@ func (r *runner) ListAllLintGroups() error {
 	return nil
 }
 
-func (r *runner) Format(args []string, overwrite bool, diffMode bool, lintMode bool) error {
+func (r *runner) getGroupToCheckers() (map[string][]lint.Checker, error) {
+	config, err := r.getConfig(r.workDirPath)
+	if err != nil {
+		return nil, err
+	}
+	return lint.GetGroupToCheckers(config.Lint)
+}
+
+func (r *runner) BreakCheck(args []string, from string) (retErr error) {
+	defer r.printDocumentFailures(&retErr)
+	if from == "" {
+		return errors.New("must set the baseline with --from")
+	}
+	baseline, err := r.getBaselineFileDescriptorSets(from)
+	if err != nil {
+		return err
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	current, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	failures, err := r.newBreakingChecker().Check(baseline, current)
+	if err != nil {
+		return err
+	}
+	if err := setBreakingFailureDisplayPaths(meta, current, failures); err != nil {
+		return err
+	}
+	if err := r.printFailures("", meta, failures...); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return newExitErrorf(255, "")
+	}
+	return nil
+}
+
+// setBreakingFailureDisplayPaths sets the filenames of the failures,
+// which are the names of the current FileDescriptorProtos, to the
+// display paths of the files, so that they match the other commands.
+func setBreakingFailureDisplayPaths(meta *meta, current []*descriptor.FileDescriptorSet, failures []*text.Failure) error {
+	nameToDisplayPath := make(map[string]string)
+	for _, protoSet := range meta.ProtoSets {
+		dirPathToDescriptorFiles, err := lint.GetDirPathToDescriptorFiles(protoSet, current)
+		if err != nil {
+			return err
+		}
+		for _, descriptorFiles := range dirPathToDescriptorFiles {
+			for _, descriptorFile := range descriptorFiles {
+				nameToDisplayPath[descriptorFile.GetName()] = descriptorFile.DisplayPath
+			}
+		}
+	}
+	for _, failure := range failures {
+		if displayPath, ok := nameToDisplayPath[failure.Filename]; ok {
+			failure.Filename = displayPath
+		}
+	}
+	return nil
+}
+
+// from is either a directory to compile, or a file
+// with a serialized FileDescriptorSet
+func (r *runner) getBaselineFileDescriptorSets(from string) ([]*descriptor.FileDescriptorSet, error) {
+	fileInfo, err := os.Stat(from)
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.Mode().IsDir() {
+		meta, err := r.getMeta([]string{from})
+		if err != nil {
+			return nil, err
+		}
+		return r.compile(false, true, meta)
+	}
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("could not read FileDescriptorSet from %s: %v", from, err)
+	}
+	return []*descriptor.FileDescriptorSet{fileDescriptorSet}, nil
+}
+
+func (r *runner) Format(args []string, overwrite bool, diffMode bool, lintMode bool) (retErr error) {
+	defer r.printDocumentFailures(&retErr)
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
@@ -458,6 +776,149 @@ func (r *runner) formatFile(overwrite bool, diffMode bool, lintMode bool, meta *
 	return nil
 }
 
+func (r *runner) Watch(args []string, doGen bool) error {
+	dirPath := "."
+	if len(args) > 0 {
+		dirPath = args[0]
+	}
+	absDirPath, err := absClean(dirPath)
+	if err != nil {
+		return err
+	}
+	r.watchRun(dirPath, newWatchActions(doGen, nil))
+	return r.newWatcher(getParentConfigFilePaths(absDirPath)...).Watch(
+		context.Background(),
+		dirPath,
+		func(filePaths []string) error {
+			r.watchRun(dirPath, newWatchActions(doGen, filePaths))
+			return nil
+		},
+	)
+}
+
+// watchActions are the actions to run for changed files.
+type watchActions struct {
+	// the changed files, or empty for all files
+	filePaths []string
+	// whether a config file changed, so the configs must be reloaded
+	// and all files must be run
+	configChanged bool
+	// whether to generate, which is only done if files were added or
+	// modified, as generating does not remove the stubs of removed files
+	gen bool
+}
+
+// newWatchActions returns the actions to run for the changed files,
+// or for all files if filePaths is empty.
+func newWatchActions(doGen bool, filePaths []string) *watchActions {
+	watchActions := &watchActions{
+		filePaths: filePaths,
+		gen:       doGen && len(filePaths) == 0,
+	}
+	for _, filePath := range filePaths {
+		if filepath.Base(filePath) == settings.DefaultConfigFilename {
+			watchActions.configChanged = true
+		}
+		if _, err := os.Stat(filePath); err == nil {
+			watchActions.gen = doGen
+		}
+	}
+	return watchActions
+}
+
+// getParentConfigFilePaths returns the paths of the config files that
+// may apply to the directory from its parent directories.
+func getParentConfigFilePaths(dirPath string) []string {
+	var filePaths []string
+	for {
+		parentDirPath := filepath.Dir(dirPath)
+		if parentDirPath == dirPath {
+			return filePaths
+		}
+		filePaths = append(filePaths, filepath.Join(parentDirPath, settings.DefaultConfigFilename))
+		dirPath = parentDirPath
+	}
+}
+
+// watchRun runs the actions, printing errors instead of returning
+// them so that watching continues.
+func (r *runner) watchRun(dirPath string, watchActions *watchActions) {
+	if err := r.watchRunErr(dirPath, watchActions); err != nil {
+		if _, ok := err.(*ExitError); ok {
+			r.logger.Info("done with failures")
+			return
+		}
+		r.logger.Error("failed", zap.Error(err))
+		return
+	}
+	r.logger.Info("done")
+}
+
+// watchRunErr compiles, optionally generates, and lints the ProtoSets
+// with changed files, or all ProtoSets if there are no changed files
+// or a config file changed.
+//
+// All directories of a ProtoSet are run, so that the files that import
+// a changed file are also compiled and linted.
+func (r *runner) watchRunErr(dirPath string, watchActions *watchActions) error {
+	if watchActions.configChanged {
+		r.logger.Info("config changed, reloading configs")
+		r.resetProviders()
+	}
+	meta, err := r.getMeta([]string{dirPath})
+	if err != nil {
+		return err
+	}
+	if len(watchActions.filePaths) > 0 && !watchActions.configChanged {
+		meta.ProtoSets = getAffectedProtoSets(meta.ProtoSets, watchActions.filePaths)
+	}
+	var dirPaths []string
+	for _, protoSet := range meta.ProtoSets {
+		for iDirPath := range protoSet.DirPathToFiles {
+			dirPaths = append(dirPaths, iDirPath)
+		}
+	}
+	if len(dirPaths) == 0 {
+		return nil
+	}
+	sort.Strings(dirPaths)
+	r.logger.Info("running", zap.Strings("dirs", dirPaths), zap.Bool("gen", watchActions.gen))
+	fileDescriptorSets, err := r.compile(watchActions.gen, true, meta)
+	if err != nil {
+		return err
+	}
+	return r.lint(meta, fileDescriptorSets)
+}
+
+// getAffectedProtoSets returns the ProtoSets that contain any of the
+// given files, which may have been removed.
+func getAffectedProtoSets(protoSets []*file.ProtoSet, filePaths []string) []*file.ProtoSet {
+	var affectedProtoSets []*file.ProtoSet
+	for _, protoSet := range protoSets {
+		for _, filePath := range filePaths {
+			if isInProtoSet(protoSet, filePath) {
+				affectedProtoSets = append(affectedProtoSets, protoSet)
+				break
+			}
+		}
+	}
+	return affectedProtoSets
+}
+
+// isInProtoSet returns true if the file is in one of the directories of
+// the ProtoSet, or is under the directory of the config of the ProtoSet,
+// which is the case for removed files in directories with no files left.
+func isInProtoSet(protoSet *file.ProtoSet, filePath string) bool {
+	if _, ok := protoSet.DirPathToFiles[filepath.Dir(filePath)]; ok {
+		return true
+	}
+	return protoSet.Config.DirPath != "" && strings.HasPrefix(filePath, protoSet.Config.DirPath+string(filepath.Separator))
+}
+
+func (r *runner) LSP(formatOnSave bool) error {
+	return r.newLSPServer(formatOnSave).Serve(r.input, r.output)
+}
+
 func (r *runner) BinaryToJSON(args []string) error {
 	if len(args) < 2 {
 		return nil
@@ -520,13 +981,283 @@ func (r *runner) JSONToBinary(args []string) error {
 	return err
 }
 
-func (r *runner) All(args []string, disableFormat bool, disableLint bool) error {
+func (r *runner) Convert(args []string, options ConvertOptions) error {
+	if len(args) < 2 {
+		return nil
+	}
+	fromFormat, err := reflect.ParseFormat(options.From)
+	if err != nil {
+		return err
+	}
+	toFormat, err := reflect.ParseFormat(options.To)
+	if err != nil {
+		return err
+	}
+	path := args[len(args)-2]
+	data, err := r.getInputData(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+	args = args[:len(args)-2]
+
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	var handlerOptions []reflect.HandlerOption
+	if options.JSONOrigName {
+		handlerOptions = append(handlerOptions, reflect.HandlerWithJSONOrigName())
+	}
+	if options.JSONEnumsAsInts {
+		handlerOptions = append(handlerOptions, reflect.HandlerWithJSONEnumsAsInts())
+	}
+	if options.JSONEmitDefaults {
+		handlerOptions = append(handlerOptions, reflect.HandlerWithJSONEmitDefaults())
+	}
+	out, err := r.newReflectHandler(handlerOptions...).Convert(fileDescriptorSets, path, data, fromFormat, toFormat)
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(out)
+	return err
+}
+
+func (r *runner) ExampleJSON(args []string) error {
+	if len(args) < 1 {
+		return nil
+	}
+	path := args[len(args)-1]
+	args = args[:len(args)-1]
+
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	out, err := r.newReflectHandler().ExampleJSON(fileDescriptorSets, path)
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(out)
+	return err
+}
+
+func (r *runner) Doc(args []string, format string) error {
+	docFormat, err := doc.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	// only document the given files, not their imports
+	fileDescriptorProtos, err := getFileDescriptorProtos(meta, fileDescriptorSets)
+	if err != nil {
+		return err
+	}
+	out, err := r.newDocGenerator().Generate(fileDescriptorProtos, docFormat)
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(out)
+	return err
+}
+
+func (r *runner) Graph(args []string, format string, granularity string) error {
+	graphFormat, err := graph.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	graphGranularity, err := graph.ParseGranularity(granularity)
+	if err != nil {
+		return err
+	}
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
 	}
 	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	fileDescriptorProtos, err := getFileDescriptorProtos(meta, fileDescriptorSets)
+	if err != nil {
+		return err
+	}
+	out, err := r.newGraphPrinter().Print(fileDescriptorSets, fileDescriptorProtos, graphGranularity, graphFormat)
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(out)
+	return err
+}
+
+func (r *runner) Rename(args []string, force bool) (retErr error) {
+	if len(args) < 2 {
+		return nil
+	}
+	fullyQualifiedName := args[len(args)-2]
+	newName := args[len(args)-1]
+	args = args[:len(args)-2]
+
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
+		return err
+	}
+	if len(fileDescriptorSets) == 0 {
+		return fmt.Errorf("no FileDescriptorSets returned")
+	}
+	var files []*rename.File
+	displayPathToProtoFile := make(map[string]*file.ProtoFile)
+	displayPathToConfig := make(map[string]settings.Config)
+	displayPathToData := make(map[string][]byte)
+	for _, protoSet := range meta.ProtoSets {
+		dirPathToDescriptors, err := lint.GetDirPathToDescriptors(protoSet)
+		if err != nil {
+			return err
+		}
+		for dirPath, descriptors := range dirPathToDescriptors {
+			for i, protoDescriptor := range descriptors {
+				protoFile := protoSet.DirPathToFiles[dirPath][i]
+				if _, ok := displayPathToProtoFile[protoFile.DisplayPath]; ok {
+					continue
+				}
+				data, err := ioutil.ReadFile(protoFile.Path)
+				if err != nil {
+					return err
+				}
+				files = append(files, &rename.File{
+					Descriptor: protoDescriptor,
+					Data:       data,
+				})
+				displayPathToProtoFile[protoFile.DisplayPath] = protoFile
+				displayPathToConfig[protoFile.DisplayPath] = protoSet.Config
+				displayPathToData[protoFile.DisplayPath] = data
+			}
+		}
+	}
+	displayPathToEdits, err := r.newRenamer().Rename(fileDescriptorSets, files, fullyQualifiedName, newName, force)
+	if err != nil {
+		return err
+	}
+	displayPaths := make([]string, 0, len(displayPathToEdits))
+	for displayPath := range displayPathToEdits {
+		displayPaths = append(displayPaths, displayPath)
+	}
+	sort.Strings(displayPaths)
+	// apply all edits before writing any files so that a failure
+	// does not leave a partial rename
+	displayPathToRenamedData := make(map[string][]byte, len(displayPaths))
+	for _, displayPath := range displayPaths {
+		edits := displayPathToEdits[displayPath]
+		data, numApplied, err := text.ApplyEdits(displayPathToData[displayPath], edits)
+		if err != nil {
+			return fmt.Errorf("could not rename in %s: %v", displayPath, err)
+		}
+		if numApplied != len(edits) {
+			return fmt.Errorf("could not rename in %s: applied %d of %d edits", displayPath, numApplied, len(edits))
+		}
+		displayPathToRenamedData[displayPath] = data
+	}
+	// restore the original files if writing, formatting, or compiling
+	// the renamed files fails so that a failure does not leave a
+	// partial rename
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		for _, displayPath := range displayPaths {
+			if err := ioutil.WriteFile(displayPathToProtoFile[displayPath].Path, displayPathToData[displayPath], os.ModePerm); err != nil {
+				r.logger.Error("could not restore file", zap.String("file", displayPath), zap.Error(err))
+			}
+		}
+	}()
+	for _, displayPath := range displayPaths {
+		protoFile := displayPathToProtoFile[displayPath]
+		r.logger.Debug("renamed", zap.String("file", displayPath), zap.Int("edits", len(displayPathToEdits[displayPath])))
+		if err := ioutil.WriteFile(protoFile.Path, displayPathToRenamedData[displayPath], os.ModePerm); err != nil {
+			return err
+		}
+		if err := r.formatFile(true, false, false, meta, displayPathToConfig[displayPath], protoFile); err != nil {
+			return err
+		}
+	}
+	// make sure the renamed files still compile
 	if _, err := r.compile(false, false, meta); err != nil {
+		if exitError, ok := err.(*ExitError); ok && exitError.Message == "" {
+			// the compile failures were printed
+			return newExitErrorf(exitError.Code, "the renamed files do not compile, so the rename was undone")
+		}
+		return err
+	}
+	return nil
+}
+
+// getFileDescriptorProtos gets the compiled files for the ProtoSets from
+// the FileDescriptorSets, without the files they import.
+func getFileDescriptorProtos(meta *meta, fileDescriptorSets []*descriptor.FileDescriptorSet) ([]*descriptor.FileDescriptorProto, error) {
+	var fileDescriptorProtos []*descriptor.FileDescriptorProto
+	seenFileNames := make(map[string]struct{})
+	for _, protoSet := range meta.ProtoSets {
+		dirPathToDescriptorFiles, err := lint.GetDirPathToDescriptorFiles(protoSet, fileDescriptorSets)
+		if err != nil {
+			return nil, err
+		}
+		for _, descriptorFiles := range dirPathToDescriptorFiles {
+			for _, descriptorFile := range descriptorFiles {
+				if _, ok := seenFileNames[descriptorFile.GetName()]; ok {
+					continue
+				}
+				seenFileNames[descriptorFile.GetName()] = struct{}{}
+				fileDescriptorProtos = append(fileDescriptorProtos, descriptorFile.FileDescriptorProto)
+			}
+		}
+	}
+	return fileDescriptorProtos, nil
+}
+
+func (r *runner) All(args []string, disableFormat bool, disableLint bool) (retErr error) {
+	defer r.printDocumentFailures(&retErr)
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.compile(false, true, meta)
+	if err != nil {
 		return err
 	}
 	if !disableFormat {
@@ -538,12 +1269,12 @@ func (r *runner) All(args []string, disableFormat bool, disableLint bool) error
 		return err
 	}
 	if !disableLint {
-		return r.lint(meta)
+		return r.lint(meta, fileDescriptorSets)
 	}
 	return nil
 }
 
-func (r *runner) GRPC(args []string, headers []string, callTimeout string, connectTimeout string, keepaliveTime string) error {
+func (r *runner) GRPC(args []string, options GRPCOptions) error {
 	if len(args) < 3 {
 		return nil
 	}
@@ -551,38 +1282,122 @@ func (r *runner) GRPC(args []string, headers []string, callTimeout string, conne
 	method := args[len(args)-2]
 	reader := r.getInputReader(args[len(args)-1])
 	args = args[:len(args)-3]
+	if options.Plaintext && options.TLSConfig.IsEnabled() {
+		return fmt.Errorf("plaintext cannot be used with TLS settings")
+	}
 
 	parsedHeaders := make(map[string]string)
-	for _, header := range headers {
+	for _, header := range options.Headers {
 		split := strings.SplitN(header, ":", 2)
 		if len(split) != 2 {
 			return fmt.Errorf("headers must be key:value but got %s", header)
 		}
 		parsedHeaders[split[0]] = split[1]
 	}
-	var parsedCallTimeout time.Duration
+	parsedCallTimeout := grpc.DefaultCallTimeout
 	var parsedConnectTimeout time.Duration
 	var parsedKeepaliveTime time.Duration
 	var err error
-	if callTimeout != "" {
-		parsedCallTimeout, err = time.ParseDuration(callTimeout)
+	if options.CallTimeout != "" {
+		parsedCallTimeout, err = time.ParseDuration(options.CallTimeout)
 		if err != nil {
 			return err
 		}
 	}
-	if connectTimeout != "" {
-		parsedConnectTimeout, err = time.ParseDuration(connectTimeout)
+	if options.ConnectTimeout != "" {
+		parsedConnectTimeout, err = time.ParseDuration(options.ConnectTimeout)
 		if err != nil {
 			return err
 		}
 	}
-	if keepaliveTime != "" {
-		parsedKeepaliveTime, err = time.ParseDuration(keepaliveTime)
+	if options.KeepaliveTime != "" {
+		parsedKeepaliveTime, err = time.ParseDuration(options.KeepaliveTime)
 		if err != nil {
 			return err
 		}
 	}
 
+	var fileDescriptorSets []*descriptor.FileDescriptorSet
+	var config settings.Config
+	// with reflection, only compile if files or directories were given
+	if !options.Reflection || len(args) > 0 {
+		meta, err := r.getMeta(args)
+		if err != nil {
+			return err
+		}
+		r.printAffectedFiles(meta)
+		fileDescriptorSets, err = r.compile(false, true, meta)
+		if err != nil {
+			return err
+		}
+		if len(fileDescriptorSets) == 0 && !options.Reflection {
+			return fmt.Errorf("no FileDescriptorSets returned")
+		}
+		if len(meta.ProtoSets) > 0 {
+			config = meta.ProtoSets[0].Config
+		}
+	} else {
+		config, err = r.getConfig(r.workDirPath)
+		if err != nil {
+			return err
+		}
+	}
+	tlsConfig := r.mergeGRPCTLSConfig(config.GRPC.TLS, options.TLSConfig)
+	if options.Plaintext {
+		tlsConfig = settings.GRPCTLSConfig{}
+	}
+	handler, err := r.newGRPCHandler(
+		parsedHeaders,
+		parsedCallTimeout,
+		parsedConnectTimeout,
+		parsedKeepaliveTime,
+		tlsConfig,
+		options,
+	)
+	if err != nil {
+		return err
+	}
+	if options.Bench {
+		return handler.Bench(fileDescriptorSets, address, method, reader, r.output, options.BenchConfig)
+	}
+	return handler.Invoke(fileDescriptorSets, address, method, reader, r.output)
+}
+
+// mergeGRPCTLSConfig returns the TLS config from the config file with
+// any fields set in the TLS config from flags overriding it.
+//
+// Relative paths in the TLS config from flags are relative to the
+// working directory.
+func (r *runner) mergeGRPCTLSConfig(configTLSConfig settings.GRPCTLSConfig, flagsTLSConfig settings.GRPCTLSConfig) settings.GRPCTLSConfig {
+	tlsConfig := configTLSConfig
+	if flagsTLSConfig.Enabled {
+		tlsConfig.Enabled = true
+	}
+	if flagsTLSConfig.CACertPath != "" {
+		tlsConfig.CACertPath = r.getAbsPath(flagsTLSConfig.CACertPath)
+	}
+	if flagsTLSConfig.CertPath != "" || flagsTLSConfig.KeyPath != "" {
+		tlsConfig.CertPath = r.getAbsPath(flagsTLSConfig.CertPath)
+		tlsConfig.KeyPath = r.getAbsPath(flagsTLSConfig.KeyPath)
+	}
+	if flagsTLSConfig.ServerName != "" {
+		tlsConfig.ServerName = flagsTLSConfig.ServerName
+	}
+	if flagsTLSConfig.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	return tlsConfig
+}
+
+func (r *runner) MockServer(args []string, port int, fixturePath string) error {
+	var fixtureData []byte
+	if fixturePath != "" {
+		var err error
+		fixtureData, err = ioutil.ReadFile(r.getAbsPath(fixturePath))
+		if err != nil {
+			return err
+		}
+	}
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
@@ -595,12 +1410,18 @@ func (r *runner) GRPC(args []string, headers []string, callTimeout string, conne
 	if len(fileDescriptorSets) == 0 {
 		return fmt.Errorf("no FileDescriptorSets returned")
 	}
-	return r.newGRPCHandler(
-		parsedHeaders,
-		parsedCallTimeout,
-		parsedConnectTimeout,
-		parsedKeepaliveTime,
-	).Invoke(fileDescriptorSets, address, method, reader, r.output)
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return err
+	}
+	return r.newMockServer(fixtureData).Serve(context.Background(), fileDescriptorSets, listener)
+}
+
+func (r *runner) getAbsPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.workDirPath, path)
 }
 
 func (r *runner) newDownloader(config settings.Config) protoc.Downloader {
@@ -638,6 +1459,12 @@ func (r *runner) newCompiler(doGen bool, doFileDescriptorSet bool) protoc.Compil
 			protoc.CompilerWithProtocURL(r.protocURL),
 		)
 	}
+	if r.jobs > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithJobs(r.jobs),
+		)
+	}
 	if doGen {
 		compilerOptions = append(
 			compilerOptions,
@@ -659,21 +1486,77 @@ func (r *runner) newLintRunner() lint.Runner {
 	)
 }
 
+func (r *runner) newBreakingChecker() breaking.Checker {
+	return breaking.NewChecker(
+		breaking.CheckerWithLogger(r.logger),
+	)
+}
+
 func (r *runner) newTransformer() format.Transformer {
 	return format.NewTransformer(
 		format.TransformerWithLogger(r.logger),
 	)
 }
 
+func (r *runner) newWatcher(configFilePaths ...string) watch.Watcher {
+	return watch.NewWatcher(
+		watch.WatcherWithLogger(r.logger),
+		watch.WatcherWithFilter(func(filePath string) bool {
+			return filepath.Ext(filePath) == ".proto" || filepath.Base(filePath) == settings.DefaultConfigFilename
+		}),
+		watch.WatcherWithFilePaths(configFilePaths...),
+	)
+}
+
+func (r *runner) newLSPServer(formatOnSave bool) lsp.Server {
+	serverOptions := []lsp.ServerOption{
+		lsp.ServerWithLogger(r.logger),
+	}
+	if formatOnSave {
+		serverOptions = append(serverOptions, lsp.ServerWithFormatOnSave())
+	}
+	return lsp.NewServer(newLSPHandler(r), serverOptions...)
+}
+
+func (r *runner) newMockServer(fixtureData []byte) mock.Server {
+	return mock.NewServer(
+		mock.ServerWithLogger(r.logger),
+		mock.ServerWithFixtureData(fixtureData),
+	)
+}
+
 func (r *runner) newGetter() extract.Getter {
 	return extract.NewGetter(
 		extract.GetterWithLogger(r.logger),
 	)
 }
 
-func (r *runner) newReflectHandler() reflect.Handler {
+func (r *runner) newDocGenerator() doc.Generator {
+	return doc.NewGenerator(
+		doc.GeneratorWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newGraphPrinter() graph.Printer {
+	return graph.NewPrinter(
+		graph.PrinterWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newRenamer() rename.Renamer {
+	return rename.NewRenamer(
+		rename.RenamerWithLogger(r.logger),
+	)
+}
+
+func (r *runner) newReflectHandler(options ...reflect.HandlerOption) reflect.Handler {
 	return reflect.NewHandler(
-		reflect.HandlerWithLogger(r.logger),
+		append(
+			[]reflect.HandlerOption{
+				reflect.HandlerWithLogger(r.logger),
+			},
+			options...,
+		)...,
 	)
 }
 
@@ -682,23 +1565,46 @@ func (r *runner) newGRPCHandler(
 	callTimeout time.Duration,
 	connectTimeout time.Duration,
 	keepaliveTime time.Duration,
-) grpc.Handler {
+	tlsConfig settings.GRPCTLSConfig,
+	options GRPCOptions,
+) (grpc.Handler, error) {
 	handlerOptions := []grpc.HandlerOption{
 		grpc.HandlerWithLogger(r.logger),
 	}
 	for key, value := range headers {
 		handlerOptions = append(handlerOptions, grpc.HandlerWithHeader(key, value))
 	}
-	if callTimeout != 0 {
-		handlerOptions = append(handlerOptions, grpc.HandlerWithCallTimeout(callTimeout))
-	}
+	// a call timeout of 0 means no call timeout
+	handlerOptions = append(handlerOptions, grpc.HandlerWithCallTimeout(callTimeout))
 	if connectTimeout != 0 {
 		handlerOptions = append(handlerOptions, grpc.HandlerWithConnectTimeout(connectTimeout))
 	}
 	if keepaliveTime != 0 {
 		handlerOptions = append(handlerOptions, grpc.HandlerWithKeepaliveTime(keepaliveTime))
 	}
-	return grpc.NewHandler(handlerOptions...)
+	if options.Reflection {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithReflection())
+	}
+	if options.MessageInfo {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithMessageInfo())
+	}
+	if options.Verbose {
+		handlerOptions = append(handlerOptions, grpc.HandlerWithVerbose(r.errorOutput))
+	}
+	if tlsConfig.IsEnabled() {
+		clientTLSConfig, err := grpc.NewClientTLSConfig(
+			tlsConfig.CACertPath,
+			tlsConfig.CertPath,
+			tlsConfig.KeyPath,
+			tlsConfig.ServerName,
+			tlsConfig.InsecureSkipVerify,
+		)
+		if err != nil {
+			return nil, err
+		}
+		handlerOptions = append(handlerOptions, grpc.HandlerWithTLSConfig(clientTLSConfig))
+	}
+	return grpc.NewHandler(handlerOptions...), nil
 }
 
 func (r *runner) getConfig(dirPath string) (settings.Config, error) {
@@ -710,6 +1616,24 @@ type meta struct {
 	InDirModeSingleFilename string
 }
 
+// includesFilename returns true if output for the filename should be
+// included, ie we are not in dir mode or the filename is the single file.
+func (m *meta) includesFilename(filename string) (bool, error) {
+	if m.InDirModeSingleFilename == "" || m.InDirModeSingleFilename == filename {
+		return true, nil
+	}
+	// TODO: the compiler may not return the rel path due to logic in bestFilePath
+	absSingleFilename, err := absClean(m.InDirModeSingleFilename)
+	if err != nil {
+		return false, err
+	}
+	absFilename, err := absClean(filename)
+	if err != nil {
+		return false, err
+	}
+	return absSingleFilename == absFilename, nil
+}
+
 func (r *runner) getMeta(args []string) (*meta, error) {
 	if len(args) == 0 {
 		// TODO: does not fit in with workDirPath paradigm
@@ -786,33 +1710,51 @@ func (r *runner) printFailures(filename string, meta *meta, failures ...*text.Fa
 	if err != nil {
 		return err
 	}
+	errorFormat, err := text.ParseErrorFormat(r.errorFormat)
+	if err != nil {
+		return err
+	}
 	text.SortFailures(failures)
-	bufWriter := bufio.NewWriter(r.output)
+	var printFailures []*text.Failure
 	for _, failure := range failures {
-		shouldPrint := false
-		if meta.InDirModeSingleFilename == "" || meta.InDirModeSingleFilename == failure.Filename {
-			shouldPrint = true
-		} else if meta.InDirModeSingleFilename != "" {
-			// TODO: the compiler may not return the rel path due to logic in bestFilePath
-			absSingleFilename, err := absClean(meta.InDirModeSingleFilename)
-			if err != nil {
-				return err
-			}
-			absFailureFilename, err := absClean(failure.Filename)
-			if err != nil {
-				return err
-			}
-			if absSingleFilename == absFailureFilename {
-				shouldPrint = true
-			}
+		shouldPrint, err := meta.includesFilename(failure.Filename)
+		if err != nil {
+			return err
 		}
 		if shouldPrint {
-			if err := failure.Fprintln(bufWriter, failureFields...); err != nil {
-				return err
-			}
+			printFailures = append(printFailures, failure)
 		}
 	}
-	return bufWriter.Flush()
+	if errorFormat.IsDocument() {
+		r.documentFailures = append(r.documentFailures, printFailures...)
+		return nil
+	}
+	return text.WriteFailures(r.output, errorFormat, printFailures, failureFields...)
+}
+
+// printDocumentFailures prints the failures buffered by printFailures
+// if the ErrorFormat is a document, which is printed even if there are
+// no failures. This should be deferred by commands that print failures,
+// with the address of the returned error, which is set if there is
+// an error printing and no other error was returned.
+//
+// If the returned error is not an ExitError, the command did not
+// complete, so nothing is printed.
+func (r *runner) printDocumentFailures(retErrAddr *error) {
+	if *retErrAddr != nil {
+		if _, ok := (*retErrAddr).(*ExitError); !ok {
+			return
+		}
+	}
+	errorFormat, err := text.ParseErrorFormat(r.errorFormat)
+	if err == nil && errorFormat.IsDocument() {
+		text.SortFailures(r.documentFailures)
+		err = text.WriteFailures(r.output, errorFormat, r.documentFailures)
+		r.documentFailures = nil
+	}
+	if err != nil && *retErrAddr == nil {
+		*retErrAddr = err
+	}
 }
 
 func (r *runner) printCheckers(checkers []lint.Checker) error {
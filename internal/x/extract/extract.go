@@ -44,6 +44,15 @@ type Message struct {
 	FileDescriptorSet   *descriptor.FileDescriptorSet
 }
 
+// Enum is an extracted enum.
+type Enum struct {
+	*descriptor.EnumDescriptorProto
+
+	FullyQualifiedPath  string
+	FileDescriptorProto *descriptor.FileDescriptorProto
+	FileDescriptorSet   *descriptor.FileDescriptorSet
+}
+
 // Service is an extracted service.
 type Service struct {
 	*descriptor.ServiceDescriptorProto
@@ -62,6 +71,8 @@ type Getter interface {
 	GetField(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Field, error)
 	// Get the message that matches the path.
 	GetMessage(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Message, error)
+	// Get the enum that matches the path.
+	GetEnum(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Enum, error)
 	// Get the service that matches the path.
 	GetService(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Service, error)
 }
@@ -121,6 +121,49 @@ func (g *getter) GetMessage(fileDescriptorSets []*descriptor.FileDescriptorSet,
 	}, nil
 }
 
+func (g *getter) GetEnum(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Enum, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	if path[0] == '.' {
+		path = path[1:]
+	}
+	var enumDescriptorProto *descriptor.EnumDescriptorProto
+	var fileDescriptorProto *descriptor.FileDescriptorProto
+	var fileDescriptorSet *descriptor.FileDescriptorSet
+	for _, iFileDescriptorSet := range fileDescriptorSets {
+		for _, iFileDescriptorProto := range iFileDescriptorSet.File {
+			iEnumDescriptorProto, err := findEnumDescriptorProto(path, iFileDescriptorProto)
+			if err != nil {
+				return nil, err
+			}
+			if iEnumDescriptorProto != nil {
+				if enumDescriptorProto != nil {
+					return nil, fmt.Errorf("duplicate enums for path %s", path)
+				}
+				enumDescriptorProto = iEnumDescriptorProto
+				fileDescriptorProto = iFileDescriptorProto
+			}
+		}
+		// return first fileDescriptorSet that matches
+		// as opposed to duplicate check within fileDescriptorSet, we easily could
+		// have multiple fileDescriptorSets that match
+		if enumDescriptorProto != nil {
+			fileDescriptorSet = iFileDescriptorSet
+			break
+		}
+	}
+	if enumDescriptorProto == nil {
+		return nil, fmt.Errorf("no enum for path %s", path)
+	}
+	return &Enum{
+		EnumDescriptorProto: enumDescriptorProto,
+		FullyQualifiedPath:  "." + path,
+		FileDescriptorProto: fileDescriptorProto,
+		FileDescriptorSet:   fileDescriptorSet,
+	}, nil
+}
+
 func (g *getter) GetService(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) (*Service, error) {
 	if len(path) == 0 {
 		return nil, fmt.Errorf("empty path")
@@ -204,6 +247,48 @@ func findDescriptorProtoInSlice(path string, nestedName string, descriptorProtos
 	return foundDescriptorProto, nil
 }
 
+func findEnumDescriptorProto(path string, fileDescriptorProto *descriptor.FileDescriptorProto) (*descriptor.EnumDescriptorProto, error) {
+	if fileDescriptorProto.GetPackage() == "" {
+		return nil, fmt.Errorf("no package on FileDescriptorProto")
+	}
+	if !strings.HasPrefix(path, fileDescriptorProto.GetPackage()) {
+		return nil, nil
+	}
+	return findEnumDescriptorProtoInSlices(path, fileDescriptorProto.GetPackage(), fileDescriptorProto.GetEnumType(), fileDescriptorProto.GetMessageType())
+}
+
+func findEnumDescriptorProtoInSlices(path string, nestedName string, enumDescriptorProtos []*descriptor.EnumDescriptorProto, descriptorProtos []*descriptor.DescriptorProto) (*descriptor.EnumDescriptorProto, error) {
+	var foundEnumDescriptorProto *descriptor.EnumDescriptorProto
+	for _, enumDescriptorProto := range enumDescriptorProtos {
+		if enumDescriptorProto.GetName() == "" {
+			return nil, fmt.Errorf("no name on EnumDescriptorProto")
+		}
+		if path == nestedName+"."+enumDescriptorProto.GetName() {
+			if foundEnumDescriptorProto != nil {
+				return nil, fmt.Errorf("duplicate enums for path %s", path)
+			}
+			foundEnumDescriptorProto = enumDescriptorProto
+		}
+	}
+	for _, descriptorProto := range descriptorProtos {
+		if descriptorProto.GetName() == "" {
+			return nil, fmt.Errorf("no name on DescriptorProto")
+		}
+		fullName := nestedName + "." + descriptorProto.GetName()
+		nestedFoundEnumDescriptorProto, err := findEnumDescriptorProtoInSlices(path, fullName, descriptorProto.GetEnumType(), descriptorProto.GetNestedType())
+		if err != nil {
+			return nil, err
+		}
+		if nestedFoundEnumDescriptorProto != nil {
+			if foundEnumDescriptorProto != nil {
+				return nil, fmt.Errorf("duplicate enums for path %s", path)
+			}
+			foundEnumDescriptorProto = nestedFoundEnumDescriptorProto
+		}
+	}
+	return foundEnumDescriptorProto, nil
+}
+
 func findServiceDescriptorProto(path string, fileDescriptorProto *descriptor.FileDescriptorProto) (*descriptor.ServiceDescriptorProto, error) {
 	if fileDescriptorProto.GetPackage() == "" {
 		return nil, fmt.Errorf("no package on FileDescriptorProto")
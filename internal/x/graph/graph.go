@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package graph prints import graphs of compiled Protobuf files.
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+// Format is a graph format.
+type Format int
+
+const (
+	// FormatDOT is the Graphviz DOT language.
+	FormatDOT Format = iota
+	// FormatJSON is JSON with a list of nodes and a list of edges.
+	FormatJSON
+	// FormatMermaid is a Mermaid flowchart.
+	FormatMermaid
+)
+
+var (
+	formatToString = map[Format]string{
+		FormatDOT:     "dot",
+		FormatJSON:    "json",
+		FormatMermaid: "mermaid",
+	}
+	stringToFormat = map[string]Format{
+		"dot":     FormatDOT,
+		"json":    FormatJSON,
+		"mermaid": FormatMermaid,
+	}
+)
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := formatToString[f]; ok {
+		return s
+	}
+	return strconv.Itoa(int(f))
+}
+
+// ParseFormat parses the Format.
+//
+// The empty string is not a valid Format.
+func ParseFormat(s string) (Format, error) {
+	format, ok := stringToFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown format %q, must be one of dot, json, or mermaid", s)
+	}
+	return format, nil
+}
+
+// Granularity is what the nodes of a graph are.
+type Granularity int
+
+const (
+	// GranularityFile has a node for each file.
+	GranularityFile Granularity = iota
+	// GranularityPackage has a node for each package.
+	GranularityPackage
+)
+
+var (
+	granularityToString = map[Granularity]string{
+		GranularityFile:    "file",
+		GranularityPackage: "package",
+	}
+	stringToGranularity = map[string]Granularity{
+		"file":    GranularityFile,
+		"package": GranularityPackage,
+	}
+)
+
+// String implements fmt.Stringer.
+func (g Granularity) String() string {
+	if s, ok := granularityToString[g]; ok {
+		return s
+	}
+	return strconv.Itoa(int(g))
+}
+
+// ParseGranularity parses the Granularity.
+//
+// The empty string is not a valid Granularity.
+func ParseGranularity(s string) (Granularity, error) {
+	granularity, ok := stringToGranularity[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown granularity %q, must be one of file or package", s)
+	}
+	return granularity, nil
+}
+
+// Printer prints import graphs.
+type Printer interface {
+	// Print returns the import graph of the given files in the given Format.
+	//
+	// The graph has a node for each given file or package, and for each
+	// file or package they import, where nodes that are only imported are
+	// marked as external. There is an edge for each import of a given file,
+	// and for GranularityPackage, imports of files in the same package
+	// are not included.
+	//
+	// The FileDescriptorSets are expected to contain the given files
+	// and all their imports.
+	Print(fileDescriptorSets []*descriptor.FileDescriptorSet, fileDescriptorProtos []*descriptor.FileDescriptorProto, granularity Granularity, format Format) ([]byte, error)
+}
+
+// PrinterOption is an option for a new Printer.
+type PrinterOption func(*printer)
+
+// PrinterWithLogger returns a PrinterOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func PrinterWithLogger(logger *zap.Logger) PrinterOption {
+	return func(printer *printer) {
+		printer.logger = logger
+	}
+}
+
+// NewPrinter returns a new Printer.
+func NewPrinter(options ...PrinterOption) Printer {
+	return newPrinter(options...)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+type printer struct {
+	logger *zap.Logger
+}
+
+func newPrinter(options ...PrinterOption) *printer {
+	printer := &printer{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(printer)
+	}
+	return printer
+}
+
+func (p *printer) Print(fileDescriptorSets []*descriptor.FileDescriptorSet, fileDescriptorProtos []*descriptor.FileDescriptorProto, granularity Granularity, format Format) ([]byte, error) {
+	graph, err := getGraph(fileDescriptorSets, fileDescriptorProtos, granularity)
+	if err != nil {
+		return nil, err
+	}
+	p.logger.Debug("printing graph", zap.Int("nodes", len(graph.Nodes)), zap.Int("edges", len(graph.Edges)), zap.Stringer("format", format))
+	switch format {
+	case FormatDOT:
+		return printDOT(graph), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatMermaid:
+		return printMermaid(graph), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+type graph struct {
+	Nodes []*node `json:"nodes"`
+	Edges []*edge `json:"edges"`
+}
+
+type node struct {
+	Name string `json:"name"`
+	// True if the node is only imported.
+	External bool `json:"external,omitempty"`
+}
+
+type edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// getGraph returns the graph with the nodes and edges sorted by name.
+func getGraph(fileDescriptorSets []*descriptor.FileDescriptorSet, fileDescriptorProtos []*descriptor.FileDescriptorProto, granularity Granularity) (*graph, error) {
+	fileNameToPackage := make(map[string]string)
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			fileNameToPackage[fileDescriptorProto.GetName()] = fileDescriptorProto.GetPackage()
+		}
+	}
+	getNodeName := func(fileName string) (string, error) {
+		if granularity == GranularityFile {
+			return fileName, nil
+		}
+		packageName, ok := fileNameToPackage[fileName]
+		if !ok {
+			return "", fmt.Errorf("could not find compiled descriptor for %s", fileName)
+		}
+		return packageName, nil
+	}
+
+	// the value is true if the node is external
+	nameToExternal := make(map[string]bool)
+	edgeSet := make(map[edge]struct{})
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		from, err := getNodeName(fileDescriptorProto.GetName())
+		if err != nil {
+			return nil, err
+		}
+		nameToExternal[from] = false
+		for _, dependency := range fileDescriptorProto.GetDependency() {
+			to, err := getNodeName(dependency)
+			if err != nil {
+				return nil, err
+			}
+			if from == to {
+				continue
+			}
+			if _, ok := nameToExternal[to]; !ok {
+				nameToExternal[to] = true
+			}
+			edgeSet[edge{From: from, To: to}] = struct{}{}
+		}
+	}
+
+	graph := &graph{
+		Nodes: make([]*node, 0, len(nameToExternal)),
+		Edges: make([]*edge, 0, len(edgeSet)),
+	}
+	for name, external := range nameToExternal {
+		graph.Nodes = append(graph.Nodes, &node{Name: name, External: external})
+	}
+	sort.Slice(graph.Nodes, func(i int, j int) bool { return graph.Nodes[i].Name < graph.Nodes[j].Name })
+	for edge := range edgeSet {
+		edge := edge
+		graph.Edges = append(graph.Edges, &edge)
+	}
+	sort.Slice(graph.Edges, func(i int, j int) bool {
+		if graph.Edges[i].From == graph.Edges[j].From {
+			return graph.Edges[i].To < graph.Edges[j].To
+		}
+		return graph.Edges[i].From < graph.Edges[j].From
+	})
+	return graph, nil
+}
+
+func printDOT(graph *graph) []byte {
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("digraph imports {\n")
+	for _, node := range graph.Nodes {
+		if node.External {
+			fmt.Fprintf(buffer, "  %s [style=dashed];\n", strconv.Quote(node.Name))
+		} else {
+			fmt.Fprintf(buffer, "  %s;\n", strconv.Quote(node.Name))
+		}
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(buffer, "  %s -> %s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To))
+	}
+	buffer.WriteString("}\n")
+	return buffer.Bytes()
+}
+
+// Mermaid node IDs cannot contain most punctuation, so nodes get IDs
+// by index and the names are used as labels.
+func printMermaid(graph *graph) []byte {
+	nameToID := make(map[string]string, len(graph.Nodes))
+	var externalIDs []string
+	buffer := bytes.NewBuffer(nil)
+	buffer.WriteString("graph LR\n")
+	for i, node := range graph.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		nameToID[node.Name] = id
+		if node.External {
+			externalIDs = append(externalIDs, id)
+		}
+		fmt.Fprintf(buffer, "  %s[%s]\n", id, strconv.Quote(node.Name))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(buffer, "  %s --> %s\n", nameToID[edge.From], nameToID[edge.To])
+	}
+	if len(externalIDs) > 0 {
+		buffer.WriteString("  classDef external stroke-dasharray: 5 5\n")
+		for _, id := range externalIDs {
+			fmt.Fprintf(buffer, "  class %s external\n", id)
+		}
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package graph
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testGraphProtos = map[string]string{
+	"a/v1/a.proto": `syntax = "proto3";
+package a.v1;
+import "a/v1/a2.proto";
+import "b/v1/b.proto";
+import "google/protobuf/timestamp.proto";
+message A {
+  A2 a2 = 1;
+  b.v1.B b = 2;
+  google.protobuf.Timestamp time = 3;
+}
+`,
+	"a/v1/a2.proto": `syntax = "proto3";
+package a.v1;
+message A2 {}
+`,
+	"b/v1/b.proto": `syntax = "proto3";
+package b.v1;
+import "a/v1/a2.proto";
+message B {
+  a.v1.A2 a2 = 1;
+}
+`,
+}
+
+func TestPrintFileDOT(t *testing.T) {
+	testPrint(
+		t,
+		GranularityFile,
+		FormatDOT,
+		`digraph imports {
+  "a/v1/a.proto";
+  "a/v1/a2.proto";
+  "b/v1/b.proto";
+  "google/protobuf/timestamp.proto" [style=dashed];
+  "a/v1/a.proto" -> "a/v1/a2.proto";
+  "a/v1/a.proto" -> "b/v1/b.proto";
+  "a/v1/a.proto" -> "google/protobuf/timestamp.proto";
+  "b/v1/b.proto" -> "a/v1/a2.proto";
+}
+`,
+	)
+}
+
+func TestPrintPackageJSON(t *testing.T) {
+	testPrint(
+		t,
+		GranularityPackage,
+		FormatJSON,
+		`{
+  "nodes": [
+    {
+      "name": "a.v1"
+    },
+    {
+      "name": "b.v1"
+    },
+    {
+      "name": "google.protobuf",
+      "external": true
+    }
+  ],
+  "edges": [
+    {
+      "from": "a.v1",
+      "to": "b.v1"
+    },
+    {
+      "from": "a.v1",
+      "to": "google.protobuf"
+    },
+    {
+      "from": "b.v1",
+      "to": "a.v1"
+    }
+  ]
+}
+`,
+	)
+}
+
+func TestPrintPackageMermaid(t *testing.T) {
+	testPrint(
+		t,
+		GranularityPackage,
+		FormatMermaid,
+		`graph LR
+  n0["a.v1"]
+  n1["b.v1"]
+  n2["google.protobuf"]
+  n0 --> n1
+  n0 --> n2
+  n1 --> n0
+  classDef external stroke-dasharray: 5 5
+  class n2 external
+`,
+	)
+}
+
+func TestParse(t *testing.T) {
+	format, err := ParseFormat("DOT")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatDOT, format)
+	_, err = ParseFormat("svg")
+	assert.Error(t, err)
+	granularity, err := ParseGranularity("package")
+	assert.NoError(t, err)
+	assert.Equal(t, GranularityPackage, granularity)
+	_, err = ParseGranularity("")
+	assert.Error(t, err)
+}
+
+func testPrint(t *testing.T, granularity Granularity, format Format, expected string) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(testGraphProtos),
+	}
+	fileDescriptors, err := parser.ParseFiles("a/v1/a.proto", "a/v1/a2.proto", "b/v1/b.proto")
+	require.NoError(t, err)
+	fileDescriptorProtos := make([]*descriptor.FileDescriptorProto, len(fileDescriptors))
+	for i, fileDescriptor := range fileDescriptors {
+		fileDescriptorProtos[i] = fileDescriptor.AsFileDescriptorProto()
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{
+		File: fileDescriptorProtos,
+	}
+	// the imported well-known type is only in the FileDescriptorSet
+	for _, dependency := range fileDescriptors[0].GetDependencies() {
+		if dependency.GetName() == "google/protobuf/timestamp.proto" {
+			fileDescriptorSet.File = append(fileDescriptorSet.File, dependency.AsFileDescriptorProto())
+		}
+	}
+	data, err := newPrinter().Print([]*descriptor.FileDescriptorSet{fileDescriptorSet}, fileDescriptorProtos, granularity, format)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
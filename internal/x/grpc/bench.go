@@ -0,0 +1,334 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func (h *handler) Bench(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer, benchConfig BenchConfig) error {
+	benchConfig = getBenchConfigWithDefaults(benchConfig)
+	if err := validateBenchConfig(benchConfig); err != nil {
+		return err
+	}
+	var descriptorSource grpcurl.DescriptorSource
+	var err error
+	if !h.reflection {
+		descriptorSource, err = h.getDescriptorSourceForMethod(fileDescriptorSets, method)
+		if err != nil {
+			return err
+		}
+	} else if _, err := getServiceForMethod(method); err != nil {
+		return err
+	}
+	inputData, err := ioutil.ReadAll(inputReader)
+	if err != nil {
+		return err
+	}
+	clientConns := make([]*grpc.ClientConn, 0, benchConfig.Connections)
+	defer func() {
+		for _, clientConn := range clientConns {
+			_ = clientConn.Close()
+		}
+	}()
+	for i := 0; i < benchConfig.Connections; i++ {
+		clientConn, err := h.dial(address)
+		if err != nil {
+			return err
+		}
+		clientConns = append(clientConns, clientConn)
+	}
+	if h.reflection {
+		ctx, cancel := h.getReflectionContext()
+		defer cancel()
+		reflectionClient := h.newReflectionClient(ctx, clientConns[0])
+		defer reflectionClient.Reset()
+		descriptorSource = h.getReflectionDescriptorSource(ctx, reflectionClient, fileDescriptorSets, method)
+	}
+	methodDescriptor, err := getMethodDescriptor(descriptorSource, method)
+	if err != nil {
+		return err
+	}
+	if methodDescriptor.IsClientStreaming() || methodDescriptor.IsServerStreaming() {
+		return fmt.Errorf("benchmarks are only supported for unary methods but %s is a streaming method", method)
+	}
+	requestProvider, err := newBenchRequestProvider(inputData, methodDescriptor.GetInputType())
+	if err != nil {
+		return err
+	}
+	h.logger.Debug("starting benchmark", zap.String("method", method), zap.Any("config", benchConfig))
+	results, duration := h.runBench(clientConns, methodDescriptor, requestProvider, benchConfig)
+	return newBenchReport(results, duration).print(outputWriter)
+}
+
+// runBench runs the benchmark and returns the results and the total duration.
+func (h *handler) runBench(clientConns []*grpc.ClientConn, methodDescriptor *desc.MethodDescriptor, requestProvider *benchRequestProvider, benchConfig BenchConfig) ([]*benchResult, time.Duration) {
+	ctx, cancel := getBenchContext(benchConfig.Duration)
+	defer cancel()
+	var rateC <-chan time.Time
+	if benchConfig.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(benchConfig.Rate))
+		defer ticker.Stop()
+		rateC = ticker.C
+	}
+	headers := grpcurl.MetadataFromHeaders(h.headers)
+	var nextRequestNumber int64
+	var results []*benchResult
+	var lock sync.Mutex
+	var waitGroup sync.WaitGroup
+	start := time.Now()
+	for workerNumber := 0; workerNumber < benchConfig.Concurrency; workerNumber++ {
+		workerNumber := workerNumber
+		// the connections are shared between the workers
+		stub := grpcdynamic.NewStub(clientConns[workerNumber%len(clientConns)])
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for {
+				requestNumber := atomic.AddInt64(&nextRequestNumber, 1) - 1
+				if benchConfig.Total > 0 && requestNumber >= int64(benchConfig.Total) {
+					return
+				}
+				if rateC != nil {
+					select {
+					case <-rateC:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				result := h.benchInvoke(stub, methodDescriptor, requestProvider, headers, requestNumber, workerNumber)
+				lock.Lock()
+				results = append(results, result)
+				lock.Unlock()
+			}
+		}()
+	}
+	waitGroup.Wait()
+	return results, time.Since(start)
+}
+
+func (h *handler) benchInvoke(stub grpcdynamic.Stub, methodDescriptor *desc.MethodDescriptor, requestProvider *benchRequestProvider, headers metadata.MD, requestNumber int64, workerNumber int) *benchResult {
+	request, err := requestProvider.getRequest(requestNumber, workerNumber)
+	if err != nil {
+		return newUnsentBenchResult(err)
+	}
+	// the call timeout is not tied to the benchmark duration so that
+	// in-flight requests complete when the duration is over
+	ctx, cancel := h.getCallContext()
+	defer cancel()
+	ctx = metadata.NewOutgoingContext(ctx, headers)
+	start := time.Now()
+	_, err = stub.InvokeRpc(ctx, methodDescriptor, request)
+	return newBenchResult(time.Since(start), err)
+}
+
+func getBenchContext(duration time.Duration) (context.Context, context.CancelFunc) {
+	if duration == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), duration)
+}
+
+func getBenchConfigWithDefaults(benchConfig BenchConfig) BenchConfig {
+	if benchConfig.Concurrency <= 0 {
+		benchConfig.Concurrency = DefaultBenchConcurrency
+	}
+	if benchConfig.Connections <= 0 {
+		benchConfig.Connections = DefaultBenchConnections
+	}
+	if benchConfig.Total <= 0 {
+		benchConfig.Total = 0
+		if benchConfig.Duration <= 0 {
+			benchConfig.Total = DefaultBenchTotal
+		}
+	}
+	if benchConfig.Total > 0 && benchConfig.Concurrency > benchConfig.Total {
+		benchConfig.Concurrency = benchConfig.Total
+	}
+	return benchConfig
+}
+
+func validateBenchConfig(benchConfig BenchConfig) error {
+	// the interval between requests must be at least a nanosecond
+	if benchConfig.Rate > int(time.Second) {
+		return fmt.Errorf("bench rate must be at most %d requests per second but was %d", int(time.Second), benchConfig.Rate)
+	}
+	return nil
+}
+
+func getMethodDescriptor(descriptorSource grpcurl.DescriptorSource, method string) (*desc.MethodDescriptor, error) {
+	servicePath, err := getServiceForMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	methodName := strings.TrimPrefix(method, servicePath+"/")
+	symbol, err := descriptorSource.FindSymbol(servicePath)
+	if err != nil {
+		return nil, err
+	}
+	serviceDescriptor, ok := symbol.(*desc.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", servicePath)
+	}
+	methodDescriptor := serviceDescriptor.FindMethodByName(methodName)
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("service %s does not have method %s", servicePath, methodName)
+	}
+	return methodDescriptor, nil
+}
+
+// benchRequestProvider provides the requests for a benchmark.
+type benchRequestProvider struct {
+	messageDescriptor *desc.MessageDescriptor
+	// nil if the input is not a template
+	template *template.Template
+	// only set if the input is not a template
+	requests []*dynamic.Message
+}
+
+type benchRequestTemplateData struct {
+	RequestNumber int64
+	WorkerNumber  int
+	Timestamp     string
+	TimestampUnix int64
+}
+
+func newBenchRequestProvider(inputData []byte, messageDescriptor *desc.MessageDescriptor) (*benchRequestProvider, error) {
+	benchRequestProvider := &benchRequestProvider{
+		messageDescriptor: messageDescriptor,
+	}
+	if !bytes.Contains(inputData, []byte("{{")) {
+		requests, err := parseBenchRequests(inputData, messageDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		benchRequestProvider.requests = requests
+		return benchRequestProvider, nil
+	}
+	requestTemplate, err := template.New("request").Option("missingkey=error").Parse(string(inputData))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse request template: %v", err)
+	}
+	benchRequestProvider.template = requestTemplate
+	// fail fast if the template does not produce valid requests
+	if _, err := benchRequestProvider.getRequest(0, 0); err != nil {
+		return nil, err
+	}
+	return benchRequestProvider, nil
+}
+
+// getRequest gets the request for the request number, cycling through the
+// requests in the input.
+func (p *benchRequestProvider) getRequest(requestNumber int64, workerNumber int) (*dynamic.Message, error) {
+	requests := p.requests
+	if p.template != nil {
+		now := time.Now()
+		buffer := bytes.NewBuffer(nil)
+		if err := p.template.Execute(buffer, &benchRequestTemplateData{
+			RequestNumber: requestNumber,
+			WorkerNumber:  workerNumber,
+			Timestamp:     now.Format(time.RFC3339Nano),
+			TimestampUnix: now.Unix(),
+		}); err != nil {
+			return nil, fmt.Errorf("could not execute request template: %v", err)
+		}
+		var err error
+		requests, err = parseBenchRequests(buffer.Bytes(), p.messageDescriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return requests[requestNumber%int64(len(requests))], nil
+}
+
+func parseBenchRequests(data []byte, messageDescriptor *desc.MessageDescriptor) ([]*dynamic.Message, error) {
+	var requests []*dynamic.Message
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var rawMessage json.RawMessage
+		if err := decoder.Decode(&rawMessage); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("could not read request %d: %v", len(requests)+1, err)
+		}
+		request := dynamic.NewMessage(messageDescriptor)
+		if err := request.UnmarshalJSON(rawMessage); err != nil {
+			return nil, fmt.Errorf("could not read request %d: %v", len(requests)+1, err)
+		}
+		requests = append(requests, request)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no requests in input")
+	}
+	return requests, nil
+}
+
+type benchResult struct {
+	latency time.Duration
+	// nil if the request succeeded
+	status *status.Status
+	// false if the request could not be created, in which case
+	// there is no latency
+	sent bool
+}
+
+func newBenchResult(latency time.Duration, err error) *benchResult {
+	benchResult := &benchResult{
+		latency: latency,
+		sent:    true,
+	}
+	if err != nil {
+		benchResult.status = status.Convert(err)
+	}
+	return benchResult
+}
+
+// newUnsentBenchResult returns the result for a request that could not be
+// created, which is counted as an error but not in the latencies.
+func newUnsentBenchResult(err error) *benchResult {
+	return &benchResult{
+		status: status.Convert(err),
+	}
+}
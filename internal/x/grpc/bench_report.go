@@ -0,0 +1,232 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	benchHistogramBuckets = 10
+	benchHistogramWidth   = 40
+)
+
+var benchPercentiles = []int{10, 25, 50, 75, 90, 95, 99}
+
+type benchReport struct {
+	count             int
+	duration          time.Duration
+	fastest           time.Duration
+	slowest           time.Duration
+	average           time.Duration
+	requestsPerSecond float64
+	histogram         []*benchCount
+	percentiles       []*benchCount
+	codeCounts        []*benchCount
+	errorCounts       []*benchCount
+}
+
+// benchCount is a count with a name, or a latency with a name for percentiles.
+type benchCount struct {
+	name    string
+	count   int
+	latency time.Duration
+}
+
+func newBenchReport(results []*benchResult, duration time.Duration) *benchReport {
+	benchReport := &benchReport{
+		count:    len(results),
+		duration: duration,
+	}
+	if len(results) == 0 {
+		return benchReport
+	}
+	latencies := make([]time.Duration, 0, len(results))
+	var totalLatency time.Duration
+	codeToCount := make(map[codes.Code]int)
+	errorToCount := make(map[string]int)
+	for _, result := range results {
+		if result.sent {
+			latencies = append(latencies, result.latency)
+			totalLatency += result.latency
+		}
+		if result.status == nil {
+			codeToCount[codes.OK]++
+		} else {
+			codeToCount[result.status.Code()]++
+			errorToCount[result.status.Err().Error()]++
+		}
+	}
+	if duration > 0 {
+		benchReport.requestsPerSecond = float64(len(results)) / duration.Seconds()
+	}
+	// there are no latencies if no requests could be created
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i int, j int) bool { return latencies[i] < latencies[j] })
+		benchReport.fastest = latencies[0]
+		benchReport.slowest = latencies[len(latencies)-1]
+		benchReport.average = totalLatency / time.Duration(len(latencies))
+		benchReport.histogram = getBenchHistogram(latencies)
+		for _, percentile := range benchPercentiles {
+			// the smallest latency that at least percentile% of latencies are at or below
+			index := (percentile*len(latencies)+99)/100 - 1
+			benchReport.percentiles = append(benchReport.percentiles, &benchCount{
+				name:    fmt.Sprintf("%d%%", percentile),
+				latency: latencies[index],
+			})
+		}
+	}
+	sortedCodes := make([]codes.Code, 0, len(codeToCount))
+	for code := range codeToCount {
+		sortedCodes = append(sortedCodes, code)
+	}
+	sort.Slice(sortedCodes, func(i int, j int) bool { return sortedCodes[i] < sortedCodes[j] })
+	for _, code := range sortedCodes {
+		benchReport.codeCounts = append(benchReport.codeCounts, &benchCount{
+			name:  code.String(),
+			count: codeToCount[code],
+		})
+	}
+	for errString, count := range errorToCount {
+		benchReport.errorCounts = append(benchReport.errorCounts, &benchCount{
+			name:  errString,
+			count: count,
+		})
+	}
+	sort.Slice(benchReport.errorCounts, func(i int, j int) bool {
+		if benchReport.errorCounts[i].count != benchReport.errorCounts[j].count {
+			return benchReport.errorCounts[i].count > benchReport.errorCounts[j].count
+		}
+		return benchReport.errorCounts[i].name < benchReport.errorCounts[j].name
+	})
+	return benchReport
+}
+
+// getBenchHistogram returns the histogram of the sorted latencies, where each
+// bucket has the upper bound of the bucket as the latency.
+func getBenchHistogram(latencies []time.Duration) []*benchCount {
+	fastest := latencies[0]
+	slowest := latencies[len(latencies)-1]
+	numBuckets := benchHistogramBuckets
+	if slowest == fastest {
+		numBuckets = 1
+	}
+	histogram := make([]*benchCount, numBuckets)
+	for i := range histogram {
+		histogram[i] = &benchCount{
+			latency: fastest + (slowest-fastest)*time.Duration(i+1)/time.Duration(numBuckets),
+		}
+	}
+	// the last bucket always has the slowest latency as the upper bound
+	histogram[numBuckets-1].latency = slowest
+	bucketIndex := 0
+	for _, latency := range latencies {
+		for latency > histogram[bucketIndex].latency {
+			bucketIndex++
+		}
+		histogram[bucketIndex].count++
+	}
+	return histogram
+}
+
+func (r *benchReport) print(writer io.Writer) error {
+	buffer := bytes.NewBuffer(nil)
+	fmt.Fprintln(buffer, "Summary:")
+	fmt.Fprintf(buffer, "  Count:        %d\n", r.count)
+	fmt.Fprintf(buffer, "  Total:        %s\n", formatBenchLatency(r.duration))
+	if r.count > 0 {
+		hasLatencies := len(r.histogram) > 0
+		if hasLatencies {
+			fmt.Fprintf(buffer, "  Slowest:      %s\n", formatBenchLatency(r.slowest))
+			fmt.Fprintf(buffer, "  Fastest:      %s\n", formatBenchLatency(r.fastest))
+			fmt.Fprintf(buffer, "  Average:      %s\n", formatBenchLatency(r.average))
+		}
+		fmt.Fprintf(buffer, "  Requests/sec: %.2f\n", r.requestsPerSecond)
+		if hasLatencies {
+			r.printHistogram(buffer)
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "Latency distribution:")
+			for _, percentile := range r.percentiles {
+				fmt.Fprintf(buffer, "  %s in %s\n", percentile.name, formatBenchLatency(percentile.latency))
+			}
+		}
+		fmt.Fprintln(buffer)
+		fmt.Fprintln(buffer, "Status code distribution:")
+		printBenchCounts(buffer, r.codeCounts, func(benchCount *benchCount) string {
+			return fmt.Sprintf("[%s]", benchCount.name)
+		}, func(benchCount *benchCount) string {
+			return fmt.Sprintf("%d responses", benchCount.count)
+		})
+		if len(r.errorCounts) > 0 {
+			fmt.Fprintln(buffer)
+			fmt.Fprintln(buffer, "Error distribution:")
+			printBenchCounts(buffer, r.errorCounts, func(benchCount *benchCount) string {
+				return fmt.Sprintf("[%d]", benchCount.count)
+			}, func(benchCount *benchCount) string {
+				return benchCount.name
+			})
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func (r *benchReport) printHistogram(buffer *bytes.Buffer) {
+	fmt.Fprintln(buffer)
+	fmt.Fprintln(buffer, "Response time histogram:")
+	maxCount := 0
+	for _, bucket := range r.histogram {
+		if bucket.count > maxCount {
+			maxCount = bucket.count
+		}
+	}
+	printBenchCounts(buffer, r.histogram, func(benchCount *benchCount) string {
+		return fmt.Sprintf("%s [%d]", formatBenchLatency(benchCount.latency), benchCount.count)
+	}, func(benchCount *benchCount) string {
+		return "|" + strings.Repeat("#", benchCount.count*benchHistogramWidth/maxCount)
+	})
+}
+
+// printBenchCounts prints the counts with the labels aligned.
+func printBenchCounts(buffer *bytes.Buffer, benchCounts []*benchCount, getLabel func(*benchCount) string, getValue func(*benchCount) string) {
+	labels := make([]string, len(benchCounts))
+	maxLabelLen := 0
+	for i, benchCount := range benchCounts {
+		labels[i] = getLabel(benchCount)
+		if len(labels[i]) > maxLabelLen {
+			maxLabelLen = len(labels[i])
+		}
+	}
+	for i, benchCount := range benchCounts {
+		fmt.Fprintf(buffer, "  %-*s  %s\n", maxLabelLen, labels[i], getValue(benchCount))
+	}
+}
+
+func formatBenchLatency(latency time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(latency)/float64(time.Millisecond))
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBenchReport(t *testing.T) {
+	var results []*benchResult
+	for i := 1; i <= 10; i++ {
+		results = append(results, newBenchResult(time.Duration(i)*time.Millisecond, nil))
+	}
+	results = append(
+		results,
+		newBenchResult(20*time.Millisecond, status.Error(codes.Unavailable, "unavailable")),
+		newBenchResult(20*time.Millisecond, status.Error(codes.Unavailable, "unavailable")),
+		newBenchResult(11*time.Millisecond, errors.New("foo")),
+		// not in the latencies
+		newUnsentBenchResult(errors.New("bar")),
+	)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, newBenchReport(results, time.Second).print(buffer))
+	assert.Equal(
+		t,
+		strings.TrimPrefix(`
+Summary:
+  Count:        14
+  Total:        1000.00ms
+  Slowest:      20.00ms
+  Fastest:      1.00ms
+  Average:      8.15ms
+  Requests/sec: 14.00
+
+Response time histogram:
+  2.90ms [2]   |########################################
+  4.80ms [2]   |########################################
+  6.70ms [2]   |########################################
+  8.60ms [2]   |########################################
+  10.50ms [2]  |########################################
+  12.40ms [1]  |####################
+  14.30ms [0]  |
+  16.20ms [0]  |
+  18.10ms [0]  |
+  20.00ms [2]  |########################################
+
+Latency distribution:
+  10% in 2.00ms
+  25% in 4.00ms
+  50% in 7.00ms
+  75% in 10.00ms
+  90% in 20.00ms
+  95% in 20.00ms
+  99% in 20.00ms
+
+Status code distribution:
+  [OK]           10 responses
+  [Unknown]      2 responses
+  [Unavailable]  2 responses
+
+Error distribution:
+  [2]  rpc error: code = Unavailable desc = unavailable
+  [1]  rpc error: code = Unknown desc = bar
+  [1]  rpc error: code = Unknown desc = foo
+`, "\n"),
+		buffer.String(),
+	)
+
+	buffer = bytes.NewBuffer(nil)
+	require.NoError(t, newBenchReport(nil, time.Second).print(buffer))
+	assert.Equal(t, "Summary:\n  Count:        0\n  Total:        1000.00ms\n", buffer.String())
+
+	buffer = bytes.NewBuffer(nil)
+	require.NoError(t, newBenchReport([]*benchResult{newUnsentBenchResult(errors.New("bar"))}, time.Second).print(buffer))
+	assert.Equal(
+		t,
+		strings.TrimPrefix(`
+Summary:
+  Count:        1
+  Total:        1000.00ms
+  Requests/sec: 1.00
+
+Status code distribution:
+  [Unknown]  1 responses
+
+Error distribution:
+  [1]  rpc error: code = Unknown desc = bar
+`, "\n"),
+		buffer.String(),
+	)
+}
+
+func TestGetBenchConfigWithDefaults(t *testing.T) {
+	assert.Equal(
+		t,
+		BenchConfig{
+			Concurrency: DefaultBenchConcurrency,
+			Connections: DefaultBenchConnections,
+			Total:       DefaultBenchTotal,
+		},
+		getBenchConfigWithDefaults(BenchConfig{}),
+	)
+	assert.Equal(
+		t,
+		BenchConfig{
+			Concurrency: DefaultBenchConcurrency,
+			Connections: DefaultBenchConnections,
+			Duration:    time.Second,
+		},
+		getBenchConfigWithDefaults(BenchConfig{Duration: time.Second}),
+	)
+	assert.Equal(
+		t,
+		BenchConfig{
+			Concurrency: 2,
+			Connections: 3,
+			Total:       2,
+			Rate:        5,
+		},
+		getBenchConfigWithDefaults(BenchConfig{Concurrency: 4, Connections: 3, Total: 2, Rate: 5}),
+	)
+}
+
+func TestValidateBenchConfig(t *testing.T) {
+	assert.NoError(t, validateBenchConfig(BenchConfig{Rate: int(time.Second)}))
+	assert.Error(t, validateBenchConfig(BenchConfig{Rate: int(time.Second) + 1}))
+}
@@ -21,6 +21,7 @@
 package grpc
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io"
 	"time"
@@ -34,11 +35,60 @@ const (
 	DefaultCallTimeout = 60 * time.Second
 	// DefaultConnectTimeout is the default connect timeout.
 	DefaultConnectTimeout = 10 * time.Second
+	// DefaultBenchConcurrency is the default number of concurrent requests for a benchmark.
+	DefaultBenchConcurrency = 10
+	// DefaultBenchConnections is the default number of connections for a benchmark.
+	DefaultBenchConnections = 1
+	// DefaultBenchTotal is the default total number of requests for a benchmark
+	// if there is no duration.
+	DefaultBenchTotal = 200
 )
 
+// BenchConfig is the config for a benchmark.
+type BenchConfig struct {
+	// The number of requests to make concurrently.
+	// If 0, DefaultBenchConcurrency is used.
+	Concurrency int
+	// The number of connections to make, which are shared between
+	// the concurrent requests.
+	// If 0, DefaultBenchConnections is used.
+	Connections int
+	// The total number of requests to make.
+	// If 0 and Duration is 0, DefaultBenchTotal is used.
+	// If 0 and Duration is set, requests are made until the duration is over.
+	Total int
+	// The duration to make requests for.
+	// If both Total and Duration are set, the benchmark stops at whichever
+	// is reached first.
+	Duration time.Duration
+	// The maximum number of requests to make per second across all
+	// concurrent requests.
+	// If 0, there is no rate limit.
+	// Must be at most 1000000000, which is one request per nanosecond.
+	Rate int
+}
+
 // Handler handles gRPC calls.
 type Handler interface {
+	// Invoke the method on the server at the address.
+	//
+	// The FileDescriptorSets are used to resolve the method, and must not
+	// be empty unless the Handler was created with HandlerWithReflection.
+	//
+	// Requests are read from the input as JSON objects, usually one per
+	// line, as they arrive, and responses are written to the output as
+	// they are received, so the input can be an interactive stream.
 	Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer) error
+	// Bench invokes the unary method on the server at the address repeatedly
+	// using the BenchConfig, and writes a report of the latencies, throughput,
+	// and status codes to the output.
+	//
+	// The input is one or more JSON requests, which are used in turn. The input
+	// can be a text/template, which is executed for each request with the
+	// fields RequestNumber, starting at 0, WorkerNumber, starting at 0,
+	// Timestamp, in RFC 3339 format, and TimestampUnix, in seconds, for
+	// example {"id":"{{.RequestNumber}}"}.
+	Bench(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer, benchConfig BenchConfig) error
 }
 
 // HandlerOption is an option for a new Handler.
@@ -55,7 +105,8 @@ func HandlerWithLogger(logger *zap.Logger) HandlerOption {
 
 // HandlerWithCallTimeout returns a HandlerOption that has the given call timeout.
 //
-// Each invocation must be completed within this time.
+// Each invocation must be completed within this time. If 0, there is
+// no call timeout, for example for long-running streams.
 //
 // The default is to use DefaultCallTimeout.
 func HandlerWithCallTimeout(callTimeout time.Duration) HandlerOption {
@@ -82,6 +133,59 @@ func HandlerWithKeepaliveTime(keepaliveTime time.Duration) HandlerOption {
 	}
 }
 
+// HandlerWithReflection returns a HandlerOption that uses the gRPC server
+// reflection API of the server to resolve services and methods.
+//
+// If FileDescriptorSets are also passed to Invoke, these are used first,
+// and the server reflection API is used for anything not found in them.
+// The FileDescriptorSets can be empty.
+//
+// The default is to only use the FileDescriptorSets.
+func HandlerWithReflection() HandlerOption {
+	return func(handler *handler) {
+		handler.reflection = true
+	}
+}
+
+// HandlerWithTLSConfig returns a HandlerOption that uses TLS with the given config.
+//
+// Use NewClientTLSConfig to create a config from certificate files.
+//
+// The default is to not use TLS.
+func HandlerWithTLSConfig(tlsConfig *tls.Config) HandlerOption {
+	return func(handler *handler) {
+		handler.tlsConfig = tlsConfig
+	}
+}
+
+// HandlerWithMessageInfo returns a HandlerOption that prints each response
+// wrapped in a JSON object with the sequence number of the response starting
+// at 1, the time the response was received, and the response message, for
+// example {"sequence":1,"time":"2018-04-11T00:00:00Z","message":{"value":"h"}}.
+//
+// The default is to print only the response message.
+func HandlerWithMessageInfo() HandlerOption {
+	return func(handler *handler) {
+		handler.messageInfo = true
+	}
+}
+
+// HandlerWithVerbose returns a HandlerOption that also prints the request
+// headers, response headers, response trailers, and status of the call
+// to the given writer, separately from the responses so that the
+// responses can still be piped to other programs.
+//
+// The status is printed as JSON with the code, message, and details, where
+// details with known types such as the google.rpc error details are
+// printed as JSON.
+//
+// The default is to print only the responses.
+func HandlerWithVerbose(verboseOutput io.Writer) HandlerOption {
+	return func(handler *handler) {
+		handler.verboseOutput = verboseOutput
+	}
+}
+
 // HandlerWithHeader returns a HandlerOption that adds the given key/value header.
 func HandlerWithHeader(key string, value string) HandlerOption {
 	return func(handler *handler) {
@@ -96,3 +200,14 @@ func HandlerWithHeader(key string, value string) HandlerOption {
 func NewHandler(options ...HandlerOption) Handler {
 	return newHandler(options...)
 }
+
+// NewClientTLSConfig returns a new TLS config for a gRPC client.
+//
+// The files are PEM-encoded. If caCertPath is empty, the system root CA
+// certificates are used to verify the server. certPath and keyPath are the
+// client certificate and key for mutual TLS, and must either both be set
+// or both be empty. If serverName is set, the server certificate is
+// verified against it instead of the host of the server address.
+func NewClientTLSConfig(caCertPath string, certPath string, keyPath string, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
+	return newClientTLSConfig(caCertPath, certPath, keyPath, serverName, insecureSkipVerify)
+}
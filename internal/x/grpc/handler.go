@@ -22,7 +22,7 @@ package grpc
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"strings"
@@ -30,11 +30,15 @@ import (
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/tgrpc/prototool/internal/x/desc"
 	"github.com/tgrpc/prototool/internal/x/extract"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
 type handler struct {
@@ -43,20 +47,22 @@ type handler struct {
 	connectTimeout time.Duration
 	keepaliveTime  time.Duration
 	headers        []string
+	reflection     bool
+	tlsConfig      *tls.Config
+	messageInfo    bool
+	verboseOutput  io.Writer
 
 	getter extract.Getter
 }
 
 func newHandler(options ...HandlerOption) *handler {
 	handler := &handler{
-		logger: zap.NewNop(),
+		logger:      zap.NewNop(),
+		callTimeout: DefaultCallTimeout,
 	}
 	for _, option := range options {
 		option(handler)
 	}
-	if handler.callTimeout == 0 {
-		handler.callTimeout = DefaultCallTimeout
-	}
 	if handler.connectTimeout == 0 {
 		handler.connectTimeout = DefaultConnectTimeout
 	}
@@ -68,8 +74,14 @@ func newHandler(options ...HandlerOption) *handler {
 }
 
 func (h *handler) Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, address string, method string, inputReader io.Reader, outputWriter io.Writer) error {
-	descriptorSource, err := h.getDescriptorSourceForMethod(fileDescriptorSets, method)
-	if err != nil {
+	var descriptorSource grpcurl.DescriptorSource
+	var err error
+	if !h.reflection {
+		descriptorSource, err = h.getDescriptorSourceForMethod(fileDescriptorSets, method)
+		if err != nil {
+			return err
+		}
+	} else if _, err := getServiceForMethod(method); err != nil {
 		return err
 	}
 	clientConn, err := h.dial(address)
@@ -77,9 +89,17 @@ func (h *handler) Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, add
 		return err
 	}
 	defer func() { _ = clientConn.Close() }()
-	invocationEventHandler := newInvocationEventHandler(outputWriter, h.logger)
-	ctx, cancel := context.WithTimeout(context.Background(), h.callTimeout)
+	requestReader := newRequestReader(inputReader)
+	defer requestReader.Close()
+	// stop reading requests once the call is done
+	invocationEventHandler := newInvocationEventHandler(outputWriter, h.verboseOutput, h.logger, h.messageInfo, requestReader.Close)
+	ctx, cancel := h.getCallContext()
 	defer cancel()
+	if h.reflection {
+		reflectionClient := h.newReflectionClient(ctx, clientConn)
+		defer reflectionClient.Reset()
+		descriptorSource = h.getReflectionDescriptorSource(ctx, reflectionClient, fileDescriptorSets, method)
+	}
 	if err := grpcurl.InvokeRpc(
 		ctx,
 		descriptorSource,
@@ -87,17 +107,39 @@ func (h *handler) Invoke(fileDescriptorSets []*descriptor.FileDescriptorSet, add
 		method,
 		h.headers,
 		invocationEventHandler,
-		decodeFunc(inputReader),
+		requestReader.Next,
 	); err != nil {
 		return err
 	}
 	return invocationEventHandler.Err()
 }
 
+func (h *handler) getCallContext() (context.Context, context.CancelFunc) {
+	if h.callTimeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), h.callTimeout)
+}
+
+// getReflectionContext returns the context for resolving a method with
+// server reflection separately from the call, which uses the call timeout,
+// or the connect timeout if there is no call timeout, so that an
+// unresponsive reflection API does not block forever.
+func (h *handler) getReflectionContext() (context.Context, context.CancelFunc) {
+	if h.callTimeout == 0 {
+		return context.WithTimeout(context.Background(), h.connectTimeout)
+	}
+	return context.WithTimeout(context.Background(), h.callTimeout)
+}
+
 func (h *handler) dial(address string) (*grpc.ClientConn, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), h.connectTimeout)
 	defer cancel()
-	return grpcurl.BlockingDial(ctx, "tcp", address, nil, h.getDialOptions()...)
+	var transportCredentials credentials.TransportCredentials
+	if h.tlsConfig != nil {
+		transportCredentials = credentials.NewTLS(h.tlsConfig)
+	}
+	return grpcurl.BlockingDial(ctx, "tcp", address, transportCredentials, h.getDialOptions()...)
 }
 
 func (h *handler) getDialOptions() []grpc.DialOption {
@@ -116,6 +158,14 @@ func (h *handler) getDialOptions() []grpc.DialOption {
 	return dialOptions
 }
 
+// newReflectionClient returns a client for the server reflection API of
+// the server that sends the headers, as servers can require headers such
+// as credentials for the reflection API like for any other method.
+func (h *handler) newReflectionClient(ctx context.Context, clientConn *grpc.ClientConn) *grpcreflect.Client {
+	ctx = metadata.NewOutgoingContext(ctx, grpcurl.MetadataFromHeaders(h.headers))
+	return grpcreflect.NewClient(ctx, reflectionpb.NewServerReflectionClient(clientConn))
+}
+
 func (h *handler) getDescriptorSourceForMethod(fileDescriptorSets []*descriptor.FileDescriptorSet, method string) (grpcurl.DescriptorSource, error) {
 	servicePath, err := getServiceForMethod(method)
 	if err != nil {
@@ -132,6 +182,22 @@ func (h *handler) getDescriptorSourceForMethod(fileDescriptorSets []*descriptor.
 	return grpcurl.DescriptorSourceFromFileDescriptorSet(fileDescriptorSet)
 }
 
+// getReflectionDescriptorSource returns a DescriptorSource that uses the
+// local FileDescriptorSets if the service for the method is in them, and
+// otherwise the server reflection API.
+func (h *handler) getReflectionDescriptorSource(ctx context.Context, reflectionClient *grpcreflect.Client, fileDescriptorSets []*descriptor.FileDescriptorSet, method string) grpcurl.DescriptorSource {
+	reflectionDescriptorSource := grpcurl.DescriptorSourceFromServer(ctx, reflectionClient)
+	if len(fileDescriptorSets) == 0 {
+		return reflectionDescriptorSource
+	}
+	localDescriptorSource, err := h.getDescriptorSourceForMethod(fileDescriptorSets, method)
+	if err != nil {
+		h.logger.Debug("could not find method in local files, using server reflection", zap.String("method", method), zap.Error(err))
+		return reflectionDescriptorSource
+	}
+	return newMultiDescriptorSource(localDescriptorSource, reflectionDescriptorSource)
+}
+
 func getServiceForMethod(method string) (string, error) {
 	split := strings.Split(method, "/")
 	if len(split) != 2 {
@@ -139,14 +205,3 @@ func getServiceForMethod(method string) (string, error) {
 	}
 	return split[0], nil
 }
-
-func decodeFunc(reader io.Reader) func() ([]byte, error) {
-	decoder := json.NewDecoder(reader)
-	return func() ([]byte, error) {
-		var rawMessage json.RawMessage
-		if err := decoder.Decode(&rawMessage); err != nil {
-			return nil, err
-		}
-		return rawMessage, nil
-	}
-}
@@ -21,47 +21,90 @@
 package grpc
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"io"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/jhump/protoreflect/desc"
 	"go.uber.org/zap"
+	// register the google.rpc error details so they can be printed as JSON
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-var jsonpbMarshaler = &jsonpb.Marshaler{Indent: "  "}
+var (
+	jsonpbMarshaler        = &jsonpb.Marshaler{Indent: "  "}
+	jsonpbCompactMarshaler = &jsonpb.Marshaler{}
+)
 
 type invocationEventHandler struct {
-	output io.Writer
-	logger *zap.Logger
-	err    error
+	output        io.Writer
+	verboseOutput io.Writer
+	logger        *zap.Logger
+	messageInfo   bool
+	onDone        func()
+	sequence      int
+	err           error
 }
 
-func newInvocationEventHandler(output io.Writer, logger *zap.Logger) *invocationEventHandler {
+// newInvocationEventHandler returns a new invocationEventHandler.
+//
+// verboseOutput can be nil, in which case headers, trailers,
+// and the status are not printed.
+// onDone is called when the call is done.
+func newInvocationEventHandler(output io.Writer, verboseOutput io.Writer, logger *zap.Logger, messageInfo bool, onDone func()) *invocationEventHandler {
 	return &invocationEventHandler{
-		output: output,
-		logger: logger,
+		output:        output,
+		verboseOutput: verboseOutput,
+		logger:        logger,
+		messageInfo:   messageInfo,
+		onDone:        onDone,
 	}
 }
 
 func (i *invocationEventHandler) OnResolveMethod(*desc.MethodDescriptor) {}
 
-func (i *invocationEventHandler) OnSendHeaders(metadata.MD) {}
+func (i *invocationEventHandler) OnSendHeaders(md metadata.MD) {
+	if i.verboseOutput != nil {
+		i.verbosePrintln("Request headers:")
+		i.printMetadata(md)
+	}
+}
 
-func (i *invocationEventHandler) OnReceiveHeaders(metadata.MD) {}
+func (i *invocationEventHandler) OnReceiveHeaders(md metadata.MD) {
+	if i.verboseOutput != nil {
+		i.verbosePrintln("Response headers:")
+		i.printMetadata(md)
+	}
+}
 
 func (i *invocationEventHandler) OnReceiveResponse(message proto.Message) {
+	i.sequence++
+	if i.messageInfo {
+		i.println(i.marshalWithInfo(message, time.Now()))
+		return
+	}
 	i.println(i.marshal(message))
 }
 
-func (i *invocationEventHandler) OnReceiveTrailers(s *status.Status, _ metadata.MD) {
+func (i *invocationEventHandler) OnReceiveTrailers(s *status.Status, md metadata.MD) {
+	i.onDone()
+	if i.verboseOutput != nil {
+		i.verbosePrintln("Response trailers:")
+		i.printMetadata(md)
+		i.verbosePrintln("Status:")
+		i.verbosePrintln(i.marshalStatus(s))
+	}
 	if err := s.Err(); err != nil {
-		// TODO(pedge): not great for streaming
-		i.err = err
 		// printed by returning the error in handler
-		//i.println(err.Error())
+		// for streams, this is after all responses received before the error
+		i.err = err
 	}
 }
 
@@ -78,11 +121,110 @@ func (i *invocationEventHandler) marshal(message proto.Message) string {
 	return s
 }
 
+func (i *invocationEventHandler) marshalWithInfo(message proto.Message, receiveTime time.Time) string {
+	s, err := jsonpbCompactMarshaler.MarshalToString(message)
+	if err != nil {
+		i.logger.Error("marshal error", zap.Error(err))
+		return ""
+	}
+	data, err := json.MarshalIndent(
+		&messageWithInfo{
+			Sequence: i.sequence,
+			Time:     receiveTime.UTC().Format(time.RFC3339Nano),
+			Message:  json.RawMessage(s),
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		i.logger.Error("marshal error", zap.Error(err))
+		return ""
+	}
+	return string(data)
+}
+
+// marshalStatus marshals the status as JSON, with the code as a string
+// and the details as JSON if their types are known, for example
+// the google.rpc error details such as ErrorInfo and BadRequest.
+func (i *invocationEventHandler) marshalStatus(s *status.Status) string {
+	statusJSON := &statusJSON{
+		Code:    s.Code().String(),
+		Message: s.Message(),
+	}
+	for _, detail := range s.Proto().GetDetails() {
+		detailString, err := jsonpbCompactMarshaler.MarshalToString(detail)
+		if err != nil {
+			// the type is not known, so print the raw value, which
+			// is base64-encoded by encoding/json
+			i.logger.Debug("could not marshal status detail", zap.String("type_url", detail.GetTypeUrl()), zap.Error(err))
+			data, err := json.Marshal(&unknownDetailJSON{TypeURL: detail.GetTypeUrl(), Value: detail.GetValue()})
+			if err != nil {
+				i.logger.Error("marshal error", zap.Error(err))
+				return ""
+			}
+			detailString = string(data)
+		}
+		statusJSON.Details = append(statusJSON.Details, json.RawMessage(detailString))
+	}
+	data, err := json.MarshalIndent(statusJSON, "", "  ")
+	if err != nil {
+		i.logger.Error("marshal error", zap.Error(err))
+		return ""
+	}
+	return string(data)
+}
+
+func (i *invocationEventHandler) printMetadata(md metadata.MD) {
+	if len(md) == 0 {
+		i.verbosePrintln("(empty)")
+		return
+	}
+	keys := make([]string, 0, len(md))
+	for key := range md {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range md[key] {
+			// binary headers can have any bytes
+			if strings.HasSuffix(key, "-bin") {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+			i.verbosePrintln(key + ": " + value)
+		}
+	}
+}
+
 func (i *invocationEventHandler) println(s string) {
+	i.fprintln(i.output, s)
+}
+
+func (i *invocationEventHandler) verbosePrintln(s string) {
+	i.fprintln(i.verboseOutput, s)
+}
+
+func (i *invocationEventHandler) fprintln(writer io.Writer, s string) {
 	if s == "" {
 		return
 	}
-	if _, err := i.output.Write([]byte(s + "\n")); err != nil {
+	if _, err := writer.Write([]byte(s + "\n")); err != nil {
 		i.logger.Error("write error", zap.Error(err))
 	}
 }
+
+type messageWithInfo struct {
+	Sequence int             `json:"sequence"`
+	Time     string          `json:"time"`
+	Message  json.RawMessage `json:"message"`
+}
+
+type statusJSON struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+type unknownDetailJSON struct {
+	TypeURL string `json:"@type"`
+	Value   []byte `json:"value"`
+}
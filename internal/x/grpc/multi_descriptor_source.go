@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"sort"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// multiDescriptorSource is a grpcurl.DescriptorSource that looks up
+// descriptors in each DescriptorSource in order, using the first that
+// has the descriptor.
+type multiDescriptorSource struct {
+	descriptorSources []grpcurl.DescriptorSource
+}
+
+func newMultiDescriptorSource(descriptorSources ...grpcurl.DescriptorSource) *multiDescriptorSource {
+	return &multiDescriptorSource{
+		descriptorSources: descriptorSources,
+	}
+}
+
+func (m *multiDescriptorSource) ListServices() ([]string, error) {
+	serviceMap := make(map[string]struct{})
+	for _, descriptorSource := range m.descriptorSources {
+		services, err := descriptorSource.ListServices()
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range services {
+			serviceMap[service] = struct{}{}
+		}
+	}
+	services := make([]string, 0, len(serviceMap))
+	for service := range serviceMap {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+func (m *multiDescriptorSource) FindSymbol(fullyQualifiedName string) (desc.Descriptor, error) {
+	var firstErr error
+	for _, descriptorSource := range m.descriptorSources {
+		descriptor, err := descriptorSource.FindSymbol(fullyQualifiedName)
+		if err == nil {
+			return descriptor, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func (m *multiDescriptorSource) AllExtensionsForType(typeName string) ([]*desc.FieldDescriptor, error) {
+	var fieldDescriptors []*desc.FieldDescriptor
+	seen := make(map[string]struct{})
+	for _, descriptorSource := range m.descriptorSources {
+		extensions, err := descriptorSource.AllExtensionsForType(typeName)
+		if err != nil {
+			// not all sources will know about the type, for example
+			// servers that do not support listing extensions
+			continue
+		}
+		for _, extension := range extensions {
+			if _, ok := seen[extension.GetFullyQualifiedName()]; ok {
+				continue
+			}
+			seen[extension.GetFullyQualifiedName()] = struct{}{}
+			fieldDescriptors = append(fieldDescriptors, extension)
+		}
+	}
+	return fieldDescriptors, nil
+}
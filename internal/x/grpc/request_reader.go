@@ -0,0 +1,94 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// requestReader reads JSON requests from an input as they arrive.
+//
+// Requests are read in the background, so that the call can end while
+// waiting for more input, for example when the server ends a bidirectional
+// stream while reading from an interactive stdin. The background read is
+// abandoned in this case, as reads cannot be interrupted.
+type requestReader struct {
+	results  chan *requestResult
+	done     chan struct{}
+	doneOnce sync.Once
+	count    int
+}
+
+type requestResult struct {
+	data []byte
+	err  error
+}
+
+func newRequestReader(reader io.Reader) *requestReader {
+	requestReader := &requestReader{
+		results: make(chan *requestResult),
+		done:    make(chan struct{}),
+	}
+	go requestReader.read(reader)
+	return requestReader
+}
+
+// Next returns the next request, or io.EOF if there are no more
+// requests or Close was called.
+func (r *requestReader) Next() ([]byte, error) {
+	select {
+	case result := <-r.results:
+		if result.err == io.EOF {
+			return nil, io.EOF
+		}
+		if result.err != nil {
+			return nil, fmt.Errorf("could not read request %d: %v", r.count+1, result.err)
+		}
+		r.count++
+		return result.data, nil
+	case <-r.done:
+		return nil, io.EOF
+	}
+}
+
+// Close stops reading requests.
+func (r *requestReader) Close() {
+	r.doneOnce.Do(func() { close(r.done) })
+}
+
+func (r *requestReader) read(reader io.Reader) {
+	decoder := json.NewDecoder(reader)
+	for {
+		var rawMessage json.RawMessage
+		err := decoder.Decode(&rawMessage)
+		select {
+		case r.results <- &requestResult{data: rawMessage, err: err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
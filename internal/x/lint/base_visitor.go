@@ -63,6 +63,13 @@ func (v baseAddVisitor) AddFailuref(position scanner.Position, format string, ar
 	v.add(text.NewFailuref(position, "", format, args...))
 }
 
+// AddFailureWithEditsf adds a failure with edits that fix the failure.
+func (v baseAddVisitor) AddFailureWithEditsf(position scanner.Position, edits []*text.Edit, format string, args ...interface{}) {
+	failure := text.NewFailuref(position, "", format, args...)
+	failure.Edits = edits
+	v.add(failure)
+}
+
 // extendedVisitor extends the proto.Visitor interface.
 // extendedVisitors are expected to be called with one file at a time,
 // and are not thread-safe.
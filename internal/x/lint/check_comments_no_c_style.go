@@ -21,6 +21,8 @@
 package lint
 
 import (
+	"bytes"
+	"strings"
 	"text/scanner"
 
 	"github.com/emicklei/proto"
@@ -143,8 +145,62 @@ func (v commentsNoCStyleVisitor) checkComments(position scanner.Position, commen
 	for _, comment := range comments {
 		if comment != nil {
 			if comment.Cstyle {
-				v.AddFailuref(position, "C-Style comments are not allowed.")
+				v.AddFailureWithEditsf(position, getCommentsNoCStyleEdits(comment), "C-Style comments are not allowed.")
 			}
 		}
 	}
 }
+
+// getCommentsNoCStyleEdits returns the edits to convert the comment to
+// a C++-style comment.
+//
+// The parser does not keep the original text of comments, so this is
+// reconstructed from the comment lines, and the edits will not be applied
+// if this does not match the original text, for example if the comment
+// was merged with adjacent comments.
+func getCommentsNoCStyleEdits(comment *proto.Comment) []*text.Edit {
+	lines := make([]string, len(comment.Lines))
+	for i, line := range comment.Lines {
+		line = strings.TrimSpace(line)
+		if i > 0 {
+			// handle the common style of prefixing lines with *
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+		lines[i] = line
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	buffer := bytes.NewBuffer(nil)
+	for i, line := range lines {
+		if i > 0 {
+			_, _ = buffer.WriteString("\n")
+		}
+		_, _ = buffer.WriteString("//")
+		if line != "" {
+			_, _ = buffer.WriteString(" ")
+			_, _ = buffer.WriteString(line)
+		}
+	}
+	_, _ = buffer.WriteString("\n")
+	// edits at the same position are alternatives where the first
+	// that matches is applied, so if the comment ends a line this
+	// replaces the newline as well, otherwise the newline is needed
+	// so that any text after the comment is not commented out
+	var edits []*text.Edit
+	for _, prefix := range []string{"/*", "/**"} {
+		oldText := prefix + strings.Join(comment.Lines, "\n") + "*/"
+		for _, suffix := range []string{"\n", ""} {
+			edits = append(edits, &text.Edit{
+				Line:    comment.Position.Line,
+				Column:  comment.Position.Column,
+				OldText: oldText + suffix,
+				NewText: buffer.String(),
+			})
+		}
+	}
+	return edits
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var deletedFieldsReservedChecker = NewAddDescriptorChecker(
+	"DELETED_FIELDS_RESERVED",
+	"Verifies that the numbers and names of message fields and enum values deleted relative to the configured baseline are reserved.",
+	checkDeletedFieldsReserved,
+)
+
+func checkDeletedFieldsReserved(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	if len(descriptorFiles) == 0 {
+		return nil
+	}
+	// only read here so that a bad baseline path only breaks this checker
+	baseline, err := getBaseline(descriptorFiles[0])
+	if err != nil {
+		return err
+	}
+	if baseline == nil {
+		return nil
+	}
+	for _, descriptorFile := range descriptorFiles {
+		prefix := getTypeNamePrefix(descriptorFile.FileDescriptorProto)
+		for i, message := range descriptorFile.MessageType {
+			checkDeletedFieldsReservedForMessage(add, descriptorFile, baseline.typeNameToMessage, baseline.typeNameToEnum, prefix, message, []int32{desc.FileMessageTypeTag, int32(i)})
+		}
+		for i, enum := range descriptorFile.EnumType {
+			checkDeletedFieldsReservedForEnum(add, descriptorFile, baseline.typeNameToEnum, prefix, enum, []int32{desc.FileEnumTypeTag, int32(i)})
+		}
+	}
+	return nil
+}
+
+// baseline is the messages and enums of the lint baseline keyed by type name.
+type baseline struct {
+	typeNameToMessage map[string]*descriptor.DescriptorProto
+	typeNameToEnum    map[string]*descriptor.EnumDescriptorProto
+}
+
+// getBaseline returns the baseline for the ProtoSet of the file, reading
+// it only once per lint run.
+func getBaseline(descriptorFile *DescriptorFile) (*baseline, error) {
+	cache := descriptorFile.protoSetCache
+	if cache == nil {
+		return readBaseline(descriptorFile.LintConfig.BaselinePath)
+	}
+	cache.baselineOnce.Do(func() {
+		cache.baseline, cache.baselineErr = readBaseline(descriptorFile.LintConfig.BaselinePath)
+	})
+	return cache.baseline, cache.baselineErr
+}
+
+func addBaselineMessage(
+	typeNameToBaselineMessage map[string]*descriptor.DescriptorProto,
+	typeNameToBaselineEnum map[string]*descriptor.EnumDescriptorProto,
+	prefix string,
+	message *descriptor.DescriptorProto,
+) {
+	typeName := prefix + "." + message.GetName()
+	typeNameToBaselineMessage[typeName] = message
+	for _, nestedMessage := range message.NestedType {
+		addBaselineMessage(typeNameToBaselineMessage, typeNameToBaselineEnum, typeName, nestedMessage)
+	}
+	for _, nestedEnum := range message.EnumType {
+		typeNameToBaselineEnum[typeName+"."+nestedEnum.GetName()] = nestedEnum
+	}
+}
+
+func checkDeletedFieldsReservedForMessage(
+	add func(*text.Failure),
+	descriptorFile *DescriptorFile,
+	typeNameToBaselineMessage map[string]*descriptor.DescriptorProto,
+	typeNameToBaselineEnum map[string]*descriptor.EnumDescriptorProto,
+	prefix string,
+	message *descriptor.DescriptorProto,
+	path []int32,
+) {
+	typeName := prefix + "." + message.GetName()
+	if baselineMessage, ok := typeNameToBaselineMessage[typeName]; ok {
+		numberToFieldIndex := make(map[int32]int, len(message.Field))
+		names := make(map[string]struct{}, len(message.Field)+len(message.ReservedName))
+		for i, field := range message.Field {
+			numberToFieldIndex[field.GetNumber()] = i
+			names[field.GetName()] = struct{}{}
+		}
+		for _, reservedName := range message.ReservedName {
+			names[reservedName] = struct{}{}
+		}
+		for _, baselineField := range baselineMessage.Field {
+			if fieldIndex, ok := numberToFieldIndex[baselineField.GetNumber()]; ok {
+				// a field with the same number is a rename if it is the same on
+				// the wire, otherwise the number was reused by a new field
+				field := message.Field[fieldIndex]
+				if field.GetType() != baselineField.GetType() || field.GetTypeName() != baselineField.GetTypeName() || field.GetLabel() != baselineField.GetLabel() {
+					add(text.NewFailuref(
+						descriptorFile.Position(desc.AppendPath(path, desc.MessageFieldTag, int32(fieldIndex))...),
+						"",
+						"Field %q with number %d was deleted from message %q and its number was reused by field %q with a different type or label.",
+						baselineField.GetName(),
+						baselineField.GetNumber(),
+						trimLeadingDot(typeName),
+						field.GetName(),
+					))
+				}
+				continue
+			}
+			// reserved ranges for messages are exclusive of the end
+			numberReserved := false
+			for _, reservedRange := range message.ReservedRange {
+				if reservedRange.GetStart() <= baselineField.GetNumber() && baselineField.GetNumber() < reservedRange.GetEnd() {
+					numberReserved = true
+					break
+				}
+			}
+			_, nameReserved := names[baselineField.GetName()]
+			if unreserved := getUnreserved(numberReserved, nameReserved); unreserved != "" {
+				add(text.NewFailuref(
+					descriptorFile.Position(path...),
+					"",
+					"Field %q with number %d was deleted from message %q without reserving its %s.",
+					baselineField.GetName(),
+					baselineField.GetNumber(),
+					trimLeadingDot(typeName),
+					unreserved,
+				))
+			}
+		}
+	}
+	for i, nestedMessage := range message.NestedType {
+		checkDeletedFieldsReservedForMessage(add, descriptorFile, typeNameToBaselineMessage, typeNameToBaselineEnum, typeName, nestedMessage, desc.AppendPath(path, desc.MessageNestedTypeTag, int32(i)))
+	}
+	for i, nestedEnum := range message.EnumType {
+		checkDeletedFieldsReservedForEnum(add, descriptorFile, typeNameToBaselineEnum, typeName, nestedEnum, desc.AppendPath(path, desc.MessageEnumTypeTag, int32(i)))
+	}
+}
+
+func checkDeletedFieldsReservedForEnum(
+	add func(*text.Failure),
+	descriptorFile *DescriptorFile,
+	typeNameToBaselineEnum map[string]*descriptor.EnumDescriptorProto,
+	prefix string,
+	enum *descriptor.EnumDescriptorProto,
+	path []int32,
+) {
+	typeName := prefix + "." + enum.GetName()
+	baselineEnum, ok := typeNameToBaselineEnum[typeName]
+	if !ok {
+		return
+	}
+	numbers := make(map[int32]struct{}, len(enum.Value))
+	names := make(map[string]struct{}, len(enum.Value)+len(enum.ReservedName))
+	for _, value := range enum.Value {
+		numbers[value.GetNumber()] = struct{}{}
+		names[value.GetName()] = struct{}{}
+	}
+	for _, reservedName := range enum.ReservedName {
+		names[reservedName] = struct{}{}
+	}
+	for _, baselineValue := range baselineEnum.Value {
+		// enum values only have a number on the wire, so a value
+		// with the same number is always a rename
+		if _, ok := numbers[baselineValue.GetNumber()]; ok {
+			continue
+		}
+		// reserved ranges for enums are inclusive of the end
+		numberReserved := false
+		for _, reservedRange := range enum.ReservedRange {
+			if reservedRange.GetStart() <= baselineValue.GetNumber() && baselineValue.GetNumber() <= reservedRange.GetEnd() {
+				numberReserved = true
+				break
+			}
+		}
+		_, nameReserved := names[baselineValue.GetName()]
+		if unreserved := getUnreserved(numberReserved, nameReserved); unreserved != "" {
+			add(text.NewFailuref(
+				descriptorFile.Position(path...),
+				"",
+				"Enum value %q with number %d was deleted from enum %q without reserving its %s.",
+				baselineValue.GetName(),
+				baselineValue.GetNumber(),
+				trimLeadingDot(typeName),
+				unreserved,
+			))
+		}
+	}
+}
+
+func getUnreserved(numberReserved bool, nameReserved bool) string {
+	switch {
+	case !numberReserved && !nameReserved:
+		return "number and name"
+	case !numberReserved:
+		return "number"
+	case !nameReserved:
+		return "name"
+	default:
+		return ""
+	}
+}
+
+// readBaseline reads the serialized FileDescriptorSet at the path and
+// indexes its messages and enums, or returns nil if the path is empty.
+func readBaseline(path string) (*baseline, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read lint baseline %s: %v", path, err)
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, fileDescriptorSet); err != nil {
+		return nil, fmt.Errorf("could not read lint baseline %s: %v", path, err)
+	}
+	baseline := &baseline{
+		typeNameToMessage: make(map[string]*descriptor.DescriptorProto),
+		typeNameToEnum:    make(map[string]*descriptor.EnumDescriptorProto),
+	}
+	for _, fileDescriptorProto := range fileDescriptorSet.File {
+		prefix := getTypeNamePrefix(fileDescriptorProto)
+		for _, message := range fileDescriptorProto.MessageType {
+			addBaselineMessage(baseline.typeNameToMessage, baseline.typeNameToEnum, prefix, message)
+		}
+		for _, enum := range fileDescriptorProto.EnumType {
+			baseline.typeNameToEnum[prefix+"."+enum.GetName()] = enum
+		}
+	}
+	return baseline, nil
+}
@@ -33,11 +33,14 @@ var enumFieldNamesUpperSnakeCaseChecker = NewAddChecker(
 )
 
 func checkEnumFieldNamesUpperSnakeCase(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
-	return runVisitor(enumFieldNamesUpperSnakeCaseVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+	return runVisitor(enumFieldNamesUpperSnakeCaseVisitor{baseAddVisitor: newBaseAddVisitor(add), descriptors: descriptors}, descriptors)
 }
 
 type enumFieldNamesUpperSnakeCaseVisitor struct {
 	baseAddVisitor
+
+	// all descriptors, to rename references in
+	descriptors []*proto.Proto
 }
 
 func (v enumFieldNamesUpperSnakeCaseVisitor) VisitMessage(message *proto.Message) {
@@ -54,6 +57,10 @@ func (v enumFieldNamesUpperSnakeCaseVisitor) VisitEnum(enum *proto.Enum) {
 
 func (v enumFieldNamesUpperSnakeCaseVisitor) VisitEnumField(field *proto.EnumField) {
 	if !strs.IsUpperSnakeCase(field.Name) {
-		v.AddFailuref(field.Position, "Field name %q must be UPPER_SNAKE_CASE.", field.Name)
+		var edits []*text.Edit
+		if expectedName := strs.ToUpperSnakeCase(field.Name); strs.IsUpperSnakeCase(expectedName) {
+			edits = getEnumFieldRenameEdits(v.descriptors, field, expectedName)
+		}
+		v.AddFailureWithEditsf(field.Position, edits, "Field name %q must be UPPER_SNAKE_CASE.", field.Name)
 	}
 }
@@ -35,12 +35,14 @@ var enumFieldPrefixesChecker = NewAddChecker(
 )
 
 func checkEnumFieldPrefixes(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
-	return runVisitor(&enumFieldPrefixesVisitor{baseAddVisitor: newBaseAddVisitor(add)}, descriptors)
+	return runVisitor(&enumFieldPrefixesVisitor{baseAddVisitor: newBaseAddVisitor(add), descriptors: descriptors}, descriptors)
 }
 
 type enumFieldPrefixesVisitor struct {
 	baseAddVisitor
 
+	// all descriptors, to rename references in
+	descriptors []*proto.Proto
 	nestedNames []string
 }
 
@@ -63,6 +65,24 @@ func (v *enumFieldPrefixesVisitor) VisitEnum(enum *proto.Enum) {
 func (v *enumFieldPrefixesVisitor) VisitEnumField(enumField *proto.EnumField) {
 	expectedPrefix := strings.Join(v.nestedNames, "_") + "_"
 	if !strings.HasPrefix(enumField.Name, expectedPrefix) {
-		v.AddFailuref(enumField.Position, "Enum field %q is expected to have the prefix %q.", enumField.Name, expectedPrefix)
+		v.AddFailureWithEditsf(
+			enumField.Position,
+			getEnumFieldRenameEdits(v.descriptors, enumField, v.getExpectedName(enumField.Name)),
+			"Enum field %q is expected to have the prefix %q.",
+			enumField.Name,
+			expectedPrefix,
+		)
 	}
 }
+
+// getExpectedName returns the name with the expected prefix, handling
+// names that already have part of the prefix, for example BAZ_INVALID
+// for enum Baz nested in message Bar becomes BAR_BAZ_INVALID.
+func (v *enumFieldPrefixesVisitor) getExpectedName(name string) string {
+	for i := 1; i < len(v.nestedNames); i++ {
+		if strings.HasPrefix(name, strings.Join(v.nestedNames[i:], "_")+"_") {
+			return strings.Join(v.nestedNames[:i], "_") + "_" + name
+		}
+	}
+	return strings.Join(v.nestedNames, "_") + "_" + name
+}
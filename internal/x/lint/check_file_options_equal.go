@@ -30,9 +30,7 @@ import (
 var fileOptionsEqualGoPackagePbSuffixChecker = NewAddChecker(
 	"FILE_OPTIONS_EQUAL_GO_PACKAGE_PB_SUFFIX",
 	`Verifies that the file option "go_package" is equal to $(basename PACKAGE)pb.`,
-	newCheckFileOptionsEqual("go_package", func(pkg *proto.Package) string {
-		return packageBasename(pkg.Name) + "pb"
-	}),
+	newCheckFileOptionsEqual("go_package", getGoPackagePbSuffix),
 )
 
 var fileOptionsEqualJavaMultipleFilesTrueChecker = NewAddChecker(
@@ -117,3 +115,7 @@ func packageBasename(pkg string) string {
 	split := strings.Split(pkg, ".")
 	return split[len(split)-1]
 }
+
+func getGoPackagePbSuffix(pkg *proto.Package) string {
+	return packageBasename(pkg.Name) + "pb"
+}
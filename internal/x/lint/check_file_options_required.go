@@ -21,6 +21,7 @@
 package lint
 
 import (
+	"fmt"
 	"text/scanner"
 
 	"github.com/emicklei/proto"
@@ -30,32 +31,35 @@ import (
 var fileOptionsRequireGoPackageChecker = NewAddChecker(
 	"FILE_OPTIONS_REQUIRE_GO_PACKAGE",
 	`Verifies that the file option "go_package" is set.`,
-	newCheckFileOptionsRequire("go_package"),
+	newCheckFileOptionsRequire("go_package", getGoPackagePbSuffix),
 )
 
 var fileOptionsRequireJavaMultipleFilesChecker = NewAddChecker(
 	"FILE_OPTIONS_REQUIRE_JAVA_MULTIPLE_FILES",
 	`Verifies that the file option "java_multiple_files" is set.`,
-	newCheckFileOptionsRequire("java_multiple_files"),
+	newCheckFileOptionsRequire("java_multiple_files", nil),
 )
 
 var fileOptionsRequireJavaOuterClassnameChecker = NewAddChecker(
 	"FILE_OPTIONS_REQUIRE_JAVA_OUTER_CLASSNAME",
 	`Verifies that the file option "java_outer_classname" is set.`,
-	newCheckFileOptionsRequire("java_outer_classname"),
+	newCheckFileOptionsRequire("java_outer_classname", nil),
 )
 
 var fileOptionsRequireJavaPackageChecker = NewAddChecker(
 	"FILE_OPTIONS_REQUIRE_JAVA_PACKAGE",
 	`Verifies that the file option "java_package" is set.`,
-	newCheckFileOptionsRequire("java_package"),
+	newCheckFileOptionsRequire("java_package", nil),
 )
 
-func newCheckFileOptionsRequire(fileOption string) func(func(*text.Failure), string, []*proto.Proto) error {
+// if expectedValueFunc is not nil, failures will have an edit that
+// inserts the option with the string value returned from expectedValueFunc
+func newCheckFileOptionsRequire(fileOption string, expectedValueFunc func(*proto.Package) string) func(func(*text.Failure), string, []*proto.Proto) error {
 	return func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
 		return runVisitor(&fileOptionsRequireVisitor{
-			baseAddVisitor: newBaseAddVisitor(add),
-			fileOption:     fileOption,
+			baseAddVisitor:    newBaseAddVisitor(add),
+			fileOption:        fileOption,
+			expectedValueFunc: expectedValueFunc,
 		}, descriptors)
 	}
 }
@@ -63,18 +67,25 @@ func newCheckFileOptionsRequire(fileOption string) func(func(*text.Failure), str
 type fileOptionsRequireVisitor struct {
 	baseAddVisitor
 
-	fileOption string
+	fileOption        string
+	expectedValueFunc func(*proto.Package) string
 
 	filename string
+	pkg      *proto.Package
 	seen     bool
 }
 
 func (v *fileOptionsRequireVisitor) OnStart(descriptor *proto.Proto) error {
 	v.filename = descriptor.Filename
+	v.pkg = nil
 	v.seen = false
 	return nil
 }
 
+func (v *fileOptionsRequireVisitor) VisitPackage(element *proto.Package) {
+	v.pkg = element
+}
+
 func (v *fileOptionsRequireVisitor) VisitOption(element *proto.Option) {
 	// TODO: not validating this is a file option, or are we since we're not recursing on other elements?
 	if element.Name == v.fileOption {
@@ -84,7 +95,18 @@ func (v *fileOptionsRequireVisitor) VisitOption(element *proto.Option) {
 
 func (v *fileOptionsRequireVisitor) Finally() error {
 	if !v.seen {
-		v.AddFailuref(scanner.Position{Filename: v.filename}, "File option %q is required.", v.fileOption)
+		var edits []*text.Edit
+		if v.expectedValueFunc != nil && v.pkg != nil {
+			// insert on the line after the package so that comments
+			// attached to the package are not moved, the formatter
+			// will put the option in the correct place
+			edits = append(edits, &text.Edit{
+				Line:    v.pkg.Position.Line + 1,
+				Column:  1,
+				NewText: fmt.Sprintf("option %s = %q;\n", v.fileOption, v.expectedValueFunc(v.pkg)),
+			})
+		}
+		v.AddFailureWithEditsf(scanner.Position{Filename: v.filename}, edits, "File option %q is required.", v.fileOption)
 	}
 	return nil
 }
@@ -0,0 +1,257 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var filesNoUnusedImportsChecker = NewAddDescriptorChecker(
+	"FILES_NO_UNUSED_IMPORTS",
+	"Verifies that every import is used by a type reference, an extension, or a custom option.",
+	checkFilesNoUnusedImports,
+)
+
+func checkFilesNoUnusedImports(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	for _, descriptorFile := range descriptorFiles {
+		usage := newFileUsage(descriptorFile.FileDescriptorSet)
+		if err := usage.addFile(descriptorFile.FileDescriptorProto); err != nil {
+			return err
+		}
+		// public imports are used by the files that import this file,
+		// and weak imports are allowed to be missing
+		skipIndexes := make(map[int32]struct{})
+		for _, index := range descriptorFile.PublicDependency {
+			skipIndexes[index] = struct{}{}
+		}
+		for _, index := range descriptorFile.WeakDependency {
+			skipIndexes[index] = struct{}{}
+		}
+		for i, dependency := range descriptorFile.Dependency {
+			if _, ok := skipIndexes[int32(i)]; ok {
+				continue
+			}
+			if !usage.isDependencyUsed(dependency) {
+				add(text.NewFailuref(
+					descriptorFile.Position(desc.FileDependencyTag, int32(i)),
+					"",
+					"Import %q was not used.",
+					dependency,
+				))
+			}
+		}
+	}
+	return nil
+}
+
+type extensionKey struct {
+	extendee string
+	number   int32
+}
+
+// fileUsage tracks the files that the types, extensions, and options
+// of a file use.
+type fileUsage struct {
+	fileNameToFileDescriptorProto map[string]*descriptor.FileDescriptorProto
+	// names have a leading dot to match field type names
+	typeNameToFileName  map[string]string
+	extensionToFileName map[extensionKey]string
+	usedFileNames       map[string]struct{}
+}
+
+func newFileUsage(fileDescriptorSet *descriptor.FileDescriptorSet) *fileUsage {
+	usage := &fileUsage{
+		fileNameToFileDescriptorProto: make(map[string]*descriptor.FileDescriptorProto),
+		typeNameToFileName:            make(map[string]string),
+		extensionToFileName:           make(map[extensionKey]string),
+		usedFileNames:                 make(map[string]struct{}),
+	}
+	for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		fileName := fileDescriptorProto.GetName()
+		usage.fileNameToFileDescriptorProto[fileName] = fileDescriptorProto
+		prefix := getTypeNamePrefix(fileDescriptorProto)
+		for _, message := range fileDescriptorProto.MessageType {
+			usage.addTypeNamesForMessage(fileName, prefix, message)
+		}
+		for _, enum := range fileDescriptorProto.EnumType {
+			usage.typeNameToFileName[prefix+"."+enum.GetName()] = fileName
+		}
+		usage.addExtensions(fileName, fileDescriptorProto.Extension)
+	}
+	return usage
+}
+
+func (u *fileUsage) addTypeNamesForMessage(fileName string, prefix string, message *descriptor.DescriptorProto) {
+	name := prefix + "." + message.GetName()
+	u.typeNameToFileName[name] = fileName
+	for _, nestedMessage := range message.NestedType {
+		u.addTypeNamesForMessage(fileName, name, nestedMessage)
+	}
+	for _, enum := range message.EnumType {
+		u.typeNameToFileName[name+"."+enum.GetName()] = fileName
+	}
+	u.addExtensions(fileName, message.Extension)
+}
+
+func (u *fileUsage) addExtensions(fileName string, extensions []*descriptor.FieldDescriptorProto) {
+	for _, extension := range extensions {
+		u.extensionToFileName[extensionKey{extendee: extension.GetExtendee(), number: extension.GetNumber()}] = fileName
+	}
+}
+
+// addFile marks the files used by the file as used.
+func (u *fileUsage) addFile(fileDescriptorProto *descriptor.FileDescriptorProto) error {
+	if err := u.addOptions(".google.protobuf.FileOptions", fileDescriptorProto.GetOptions()); err != nil {
+		return err
+	}
+	for _, message := range fileDescriptorProto.MessageType {
+		if err := u.addMessage(message); err != nil {
+			return err
+		}
+	}
+	for _, enum := range fileDescriptorProto.EnumType {
+		if err := u.addEnum(enum); err != nil {
+			return err
+		}
+	}
+	for _, service := range fileDescriptorProto.Service {
+		if err := u.addOptions(".google.protobuf.ServiceOptions", service.GetOptions()); err != nil {
+			return err
+		}
+		for _, method := range service.Method {
+			u.addTypeName(method.GetInputType())
+			u.addTypeName(method.GetOutputType())
+			if err := u.addOptions(".google.protobuf.MethodOptions", method.GetOptions()); err != nil {
+				return err
+			}
+		}
+	}
+	return u.addFields(fileDescriptorProto.Extension)
+}
+
+func (u *fileUsage) addMessage(message *descriptor.DescriptorProto) error {
+	if err := u.addOptions(".google.protobuf.MessageOptions", message.GetOptions()); err != nil {
+		return err
+	}
+	if err := u.addFields(message.Field); err != nil {
+		return err
+	}
+	if err := u.addFields(message.Extension); err != nil {
+		return err
+	}
+	for _, oneof := range message.OneofDecl {
+		if err := u.addOptions(".google.protobuf.OneofOptions", oneof.GetOptions()); err != nil {
+			return err
+		}
+	}
+	for _, nestedMessage := range message.NestedType {
+		if err := u.addMessage(nestedMessage); err != nil {
+			return err
+		}
+	}
+	for _, enum := range message.EnumType {
+		if err := u.addEnum(enum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *fileUsage) addFields(fields []*descriptor.FieldDescriptorProto) error {
+	for _, field := range fields {
+		u.addTypeName(field.GetTypeName())
+		u.addTypeName(field.GetExtendee())
+		if err := u.addOptions(".google.protobuf.FieldOptions", field.GetOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *fileUsage) addEnum(enum *descriptor.EnumDescriptorProto) error {
+	if err := u.addOptions(".google.protobuf.EnumOptions", enum.GetOptions()); err != nil {
+		return err
+	}
+	for _, value := range enum.Value {
+		if err := u.addOptions(".google.protobuf.EnumValueOptions", value.GetOptions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *fileUsage) addTypeName(typeName string) {
+	if fileName, ok := u.typeNameToFileName[typeName]; ok {
+		u.usedFileNames[fileName] = struct{}{}
+	}
+}
+
+// addOptions marks the files that define the custom options set in the
+// options message as used.
+func (u *fileUsage) addOptions(extendee string, options proto.Message) error {
+	// the options getters return typed nil pointers
+	if options == nil || reflect.ValueOf(options).IsNil() {
+		return nil
+	}
+	extensionDescs, err := proto.ExtensionDescs(options)
+	if err != nil {
+		return err
+	}
+	for _, extensionDesc := range extensionDescs {
+		if fileName, ok := u.extensionToFileName[extensionKey{extendee: extendee, number: extensionDesc.Field}]; ok {
+			u.usedFileNames[fileName] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// isDependencyUsed returns true if the dependency or any file it
+// publicly imports, directly or transitively, is used.
+func (u *fileUsage) isDependencyUsed(dependency string) bool {
+	seenFileNames := make(map[string]struct{})
+	queue := []string{dependency}
+	for len(queue) > 0 {
+		fileName := queue[0]
+		queue = queue[1:]
+		if _, ok := seenFileNames[fileName]; ok {
+			continue
+		}
+		seenFileNames[fileName] = struct{}{}
+		if _, ok := u.usedFileNames[fileName]; ok {
+			return true
+		}
+		fileDescriptorProto, ok := u.fileNameToFileDescriptorProto[fileName]
+		if !ok {
+			continue
+		}
+		for _, index := range fileDescriptorProto.PublicDependency {
+			if int(index) < len(fileDescriptorProto.Dependency) {
+				queue = append(queue, fileDescriptorProto.Dependency[index])
+			}
+		}
+	}
+	return false
+}
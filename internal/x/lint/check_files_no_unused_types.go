@@ -0,0 +1,237 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var filesNoUnusedTypesChecker = NewAddDescriptorChecker(
+	"FILES_NO_UNUSED_TYPES",
+	"Verifies that all messages and enums are reachable from a service, an extension, or a configured root in the ProtoSet.",
+	checkFilesNoUnusedTypes,
+)
+
+func checkFilesNoUnusedTypes(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	if len(descriptorFiles) == 0 {
+		return nil
+	}
+	// the roots are the services, extensions, and configured roots of
+	// the entire ProtoSet, but the types they reach can be in any file
+	unusedRoots := descriptorFiles[0].LintConfig.UnusedRoots
+	typeNameToMessage := make(map[string]*descriptor.DescriptorProto)
+	for _, fileDescriptorProto := range getAllFileDescriptorProtos(descriptorFiles) {
+		for _, message := range fileDescriptorProto.MessageType {
+			addTypeNameToMessage(typeNameToMessage, getTypeNamePrefix(fileDescriptorProto), message)
+		}
+	}
+	var rootTypeNames []string
+	for _, protoSetFile := range descriptorFiles[0].ProtoSetFiles {
+		rootTypeNames = append(rootTypeNames, getUnusedRootTypeNames(protoSetFile.FileDescriptorProto, unusedRoots)...)
+	}
+	// a ProtoSet with only messages and no configured roots has
+	// nothing that can use its types
+	if len(rootTypeNames) == 0 {
+		return nil
+	}
+	usedTypeNames := getUsedTypeNames(typeNameToMessage, rootTypeNames)
+	for _, descriptorFile := range descriptorFiles {
+		prefix := getTypeNamePrefix(descriptorFile.FileDescriptorProto)
+		for i, message := range descriptorFile.MessageType {
+			checkFilesNoUnusedTypesForMessage(add, descriptorFile, usedTypeNames, prefix, message, []int32{desc.FileMessageTypeTag, int32(i)})
+		}
+		for i, enum := range descriptorFile.EnumType {
+			checkFilesNoUnusedTypesForEnum(add, descriptorFile, usedTypeNames, prefix, enum, []int32{desc.FileEnumTypeTag, int32(i)})
+		}
+	}
+	return nil
+}
+
+func checkFilesNoUnusedTypesForMessage(
+	add func(*text.Failure),
+	descriptorFile *DescriptorFile,
+	usedTypeNames map[string]struct{},
+	prefix string,
+	message *descriptor.DescriptorProto,
+	path []int32,
+) {
+	if message.GetOptions().GetMapEntry() {
+		return
+	}
+	name := prefix + "." + message.GetName()
+	if _, ok := usedTypeNames[name]; !ok {
+		// all nested types are unused as well, as a used nested type
+		// makes the message that contains it used
+		add(text.NewFailuref(
+			descriptorFile.Position(path...),
+			"",
+			"Message %q is not reachable from any service, extension, or configured root.",
+			trimLeadingDot(name),
+		))
+		return
+	}
+	for i, nestedMessage := range message.NestedType {
+		checkFilesNoUnusedTypesForMessage(add, descriptorFile, usedTypeNames, name, nestedMessage, desc.AppendPath(path, desc.MessageNestedTypeTag, int32(i)))
+	}
+	for i, enum := range message.EnumType {
+		checkFilesNoUnusedTypesForEnum(add, descriptorFile, usedTypeNames, name, enum, desc.AppendPath(path, desc.MessageEnumTypeTag, int32(i)))
+	}
+}
+
+func checkFilesNoUnusedTypesForEnum(
+	add func(*text.Failure),
+	descriptorFile *DescriptorFile,
+	usedTypeNames map[string]struct{},
+	prefix string,
+	enum *descriptor.EnumDescriptorProto,
+	path []int32,
+) {
+	name := prefix + "." + enum.GetName()
+	if _, ok := usedTypeNames[name]; !ok {
+		add(text.NewFailuref(
+			descriptorFile.Position(path...),
+			"",
+			"Enum %q is not reachable from any service, extension, or configured root.",
+			trimLeadingDot(name),
+		))
+	}
+}
+
+// getUnusedRootTypeNames returns the names of the types that are used by
+// the services and extensions in the file, and of the messages and enums
+// in the file that are configured roots.
+//
+// A message or enum is a configured root if its fully-qualified
+// name is a root, or if it is within a root package or message.
+//
+// Names have a leading dot to match field type names.
+func getUnusedRootTypeNames(fileDescriptorProto *descriptor.FileDescriptorProto, unusedRoots []string) []string {
+	var rootTypeNames []string
+	prefix := getTypeNamePrefix(fileDescriptorProto)
+	for _, service := range fileDescriptorProto.Service {
+		for _, method := range service.Method {
+			rootTypeNames = append(rootTypeNames, method.GetInputType(), method.GetOutputType())
+		}
+	}
+	rootTypeNames = append(rootTypeNames, getExtensionTypeNames(fileDescriptorProto.Extension)...)
+	for _, message := range fileDescriptorProto.MessageType {
+		rootTypeNames = append(rootTypeNames, getUnusedRootTypeNamesForMessage(prefix, message, unusedRoots)...)
+	}
+	for _, enum := range fileDescriptorProto.EnumType {
+		if name := prefix + "." + enum.GetName(); packageIn(trimLeadingDot(name), unusedRoots) {
+			rootTypeNames = append(rootTypeNames, name)
+		}
+	}
+	return rootTypeNames
+}
+
+func getUnusedRootTypeNamesForMessage(prefix string, message *descriptor.DescriptorProto, unusedRoots []string) []string {
+	var rootTypeNames []string
+	name := prefix + "." + message.GetName()
+	if packageIn(trimLeadingDot(name), unusedRoots) {
+		rootTypeNames = append(rootTypeNames, name)
+	}
+	rootTypeNames = append(rootTypeNames, getExtensionTypeNames(message.Extension)...)
+	for _, nestedMessage := range message.NestedType {
+		rootTypeNames = append(rootTypeNames, getUnusedRootTypeNamesForMessage(name, nestedMessage, unusedRoots)...)
+	}
+	for _, enum := range message.EnumType {
+		if enumName := name + "." + enum.GetName(); packageIn(trimLeadingDot(enumName), unusedRoots) {
+			rootTypeNames = append(rootTypeNames, enumName)
+		}
+	}
+	return rootTypeNames
+}
+
+// getExtensionTypeNames returns the types of the extensions and the
+// messages they extend.
+func getExtensionTypeNames(extensions []*descriptor.FieldDescriptorProto) []string {
+	var typeNames []string
+	for _, extension := range extensions {
+		typeNames = append(typeNames, extension.GetExtendee())
+		if typeName := extension.GetTypeName(); typeName != "" {
+			typeNames = append(typeNames, typeName)
+		}
+	}
+	return typeNames
+}
+
+// getUsedTypeNames returns the names of all types reachable from the root
+// types through message fields.
+//
+// The messages that contain a used nested type are used as well, but
+// the types of their fields are only used if they are reachable.
+func getUsedTypeNames(typeNameToMessage map[string]*descriptor.DescriptorProto, rootTypeNames []string) map[string]struct{} {
+	usedTypeNames := make(map[string]struct{})
+	reachableTypeNames := make(map[string]struct{})
+	queue := rootTypeNames
+	for len(queue) > 0 {
+		typeName := queue[0]
+		queue = queue[1:]
+		if _, ok := reachableTypeNames[typeName]; ok {
+			continue
+		}
+		reachableTypeNames[typeName] = struct{}{}
+		usedTypeNames[typeName] = struct{}{}
+		for parentTypeName := getParentTypeName(typeName); parentTypeName != ""; parentTypeName = getParentTypeName(parentTypeName) {
+			if _, ok := typeNameToMessage[parentTypeName]; !ok {
+				break
+			}
+			usedTypeNames[parentTypeName] = struct{}{}
+		}
+		for _, field := range typeNameToMessage[typeName].GetField() {
+			if fieldTypeName := field.GetTypeName(); fieldTypeName != "" {
+				queue = append(queue, fieldTypeName)
+			}
+		}
+	}
+	return usedTypeNames
+}
+
+// getTypeNamePrefix returns the prefix of the names of the top-level
+// types in the file, with a leading dot to match field type names.
+func getTypeNamePrefix(fileDescriptorProto *descriptor.FileDescriptorProto) string {
+	if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+		return "." + pkg
+	}
+	return ""
+}
+
+func addTypeNameToMessage(typeNameToMessage map[string]*descriptor.DescriptorProto, prefix string, message *descriptor.DescriptorProto) {
+	name := prefix + "." + message.GetName()
+	typeNameToMessage[name] = message
+	for _, nestedMessage := range message.NestedType {
+		addTypeNameToMessage(typeNameToMessage, name, nestedMessage)
+	}
+}
+
+// getParentTypeName returns the name with the last element removed, or
+// an empty string if there is only one element.
+func getParentTypeName(typeName string) string {
+	if i := strings.LastIndexByte(typeName, '.'); i > 0 {
+		return typeName[:i]
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var messageFieldTypesNotDeprecatedChecker = NewAddDescriptorChecker(
+	"MESSAGE_FIELD_TYPES_NOT_DEPRECATED",
+	"Verifies that message fields do not reference messages or enums that are deprecated or are in deprecated files.",
+	checkMessageFieldTypesNotDeprecated,
+)
+
+func checkMessageFieldTypesNotDeprecated(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	for _, descriptorFile := range descriptorFiles {
+		deprecatedTypeNames := getCachedDeprecatedTypeNames(descriptorFile)
+		for i, message := range descriptorFile.MessageType {
+			checkMessageFieldTypesNotDeprecatedForMessage(add, descriptorFile, deprecatedTypeNames, message, []int32{desc.FileMessageTypeTag, int32(i)})
+		}
+	}
+	return nil
+}
+
+func checkMessageFieldTypesNotDeprecatedForMessage(
+	add func(*text.Failure),
+	descriptorFile *DescriptorFile,
+	deprecatedTypeNames map[string]struct{},
+	message *descriptor.DescriptorProto,
+	path []int32,
+) {
+	for i, field := range message.Field {
+		// a deprecated field can reference a deprecated type
+		if field.GetOptions().GetDeprecated() {
+			continue
+		}
+		if _, ok := deprecatedTypeNames[field.GetTypeName()]; ok {
+			add(text.NewFailuref(
+				descriptorFile.Position(desc.AppendPath(path, desc.MessageFieldTag, int32(i), desc.FieldTypeNameTag)...),
+				"",
+				`Field %q references %q which is deprecated.`,
+				field.GetName(),
+				trimLeadingDot(field.GetTypeName()),
+			))
+		}
+	}
+	for i, nestedMessage := range message.NestedType {
+		checkMessageFieldTypesNotDeprecatedForMessage(add, descriptorFile, deprecatedTypeNames, nestedMessage, desc.AppendPath(path, desc.MessageNestedTypeTag, int32(i)))
+	}
+}
+
+// getCachedDeprecatedTypeNames returns getDeprecatedTypeNames for the
+// FileDescriptorSet of the file, computing it only once per FileDescriptorSet
+// as the files of a ProtoSet are usually compiled together.
+func getCachedDeprecatedTypeNames(descriptorFile *DescriptorFile) map[string]struct{} {
+	cache := descriptorFile.protoSetCache
+	if cache == nil {
+		return getDeprecatedTypeNames(descriptorFile.FileDescriptorSet)
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.fileDescriptorSetToDeprecatedTypeNames == nil {
+		cache.fileDescriptorSetToDeprecatedTypeNames = make(map[*descriptor.FileDescriptorSet]map[string]struct{})
+	}
+	deprecatedTypeNames, ok := cache.fileDescriptorSetToDeprecatedTypeNames[descriptorFile.FileDescriptorSet]
+	if !ok {
+		deprecatedTypeNames = getDeprecatedTypeNames(descriptorFile.FileDescriptorSet)
+		cache.fileDescriptorSetToDeprecatedTypeNames[descriptorFile.FileDescriptorSet] = deprecatedTypeNames
+	}
+	return deprecatedTypeNames
+}
+
+// getDeprecatedTypeNames returns the fully-qualified names of all messages
+// and enums in the FileDescriptorSet that are deprecated, are nested in a
+// deprecated message, or are in a deprecated file.
+//
+// Names have a leading dot to match field type names.
+func getDeprecatedTypeNames(fileDescriptorSet *descriptor.FileDescriptorSet) map[string]struct{} {
+	deprecatedTypeNames := make(map[string]struct{})
+	for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		prefix := ""
+		if pkg := fileDescriptorProto.GetPackage(); pkg != "" {
+			prefix = "." + pkg
+		}
+		fileDeprecated := fileDescriptorProto.GetOptions().GetDeprecated()
+		for _, message := range fileDescriptorProto.MessageType {
+			addDeprecatedTypeNamesForMessage(deprecatedTypeNames, prefix, message, fileDeprecated)
+		}
+		for _, enum := range fileDescriptorProto.EnumType {
+			if fileDeprecated || enum.GetOptions().GetDeprecated() {
+				deprecatedTypeNames[prefix+"."+enum.GetName()] = struct{}{}
+			}
+		}
+	}
+	return deprecatedTypeNames
+}
+
+func addDeprecatedTypeNamesForMessage(deprecatedTypeNames map[string]struct{}, prefix string, message *descriptor.DescriptorProto, parentDeprecated bool) {
+	name := prefix + "." + message.GetName()
+	deprecated := parentDeprecated || message.GetOptions().GetDeprecated()
+	if deprecated {
+		deprecatedTypeNames[name] = struct{}{}
+	}
+	for _, nestedMessage := range message.NestedType {
+		addDeprecatedTypeNamesForMessage(deprecatedTypeNames, name, nestedMessage, deprecated)
+	}
+	for _, enum := range message.EnumType {
+		if deprecated || enum.GetOptions().GetDeprecated() {
+			deprecatedTypeNames[name+"."+enum.GetName()] = struct{}{}
+		}
+	}
+}
+
+func trimLeadingDot(name string) string {
+	if len(name) > 0 && name[0] == '.' {
+		return name[1:]
+	}
+	return name
+}
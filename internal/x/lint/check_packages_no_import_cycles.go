@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var packagesNoImportCyclesChecker = NewAddDescriptorChecker(
+	"PACKAGES_NO_IMPORT_CYCLES",
+	"Verifies that no import creates an import cycle between packages.",
+	checkPackagesNoImportCycles,
+)
+
+func checkPackagesNoImportCycles(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	// files in other directories are needed to find cycles through them
+	allFileDescriptorProtos := getAllFileDescriptorProtos(descriptorFiles)
+	fileNameToPackage := getFileNameToPackage(allFileDescriptorProtos)
+	packageToImportPackages := getPackageToImportPackages(allFileDescriptorProtos, fileNameToPackage)
+	for _, descriptorFile := range descriptorFiles {
+		packageName := descriptorFile.GetPackage()
+		for i, dependency := range descriptorFile.GetDependency() {
+			importPackageName, ok := fileNameToPackage[dependency]
+			if !ok || importPackageName == packageName {
+				continue
+			}
+			if cycle := getPackageImportPath(packageToImportPackages, importPackageName, packageName); len(cycle) > 0 {
+				add(text.NewFailuref(
+					descriptorFile.Position(desc.FileDependencyTag, int32(i)),
+					"",
+					"Import %q creates the package import cycle %s.",
+					dependency,
+					strings.Join(append([]string{packageName}, cycle...), " -> "),
+				))
+			}
+		}
+	}
+	return nil
+}
+
+// getAllFileDescriptorProtos returns all files in the FileDescriptorSets of
+// the files and of all files in their ProtoSets, which includes all imports.
+func getAllFileDescriptorProtos(descriptorFiles []*DescriptorFile) []*descriptor.FileDescriptorProto {
+	var fileDescriptorProtos []*descriptor.FileDescriptorProto
+	seenFileNames := make(map[string]struct{})
+	addFileDescriptorSet := func(fileDescriptorSet *descriptor.FileDescriptorSet) {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			if _, ok := seenFileNames[fileDescriptorProto.GetName()]; !ok {
+				seenFileNames[fileDescriptorProto.GetName()] = struct{}{}
+				fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+			}
+		}
+	}
+	seenProtoSetFiles := make(map[*DescriptorFile]struct{})
+	for _, descriptorFile := range descriptorFiles {
+		addFileDescriptorSet(descriptorFile.FileDescriptorSet)
+		for _, protoSetFile := range descriptorFile.ProtoSetFiles {
+			if _, ok := seenProtoSetFiles[protoSetFile]; !ok {
+				seenProtoSetFiles[protoSetFile] = struct{}{}
+				addFileDescriptorSet(protoSetFile.FileDescriptorSet)
+			}
+		}
+	}
+	return fileDescriptorProtos
+}
+
+// getFileNameToPackage returns the map from file name to package.
+func getFileNameToPackage(fileDescriptorProtos []*descriptor.FileDescriptorProto) map[string]string {
+	fileNameToPackage := make(map[string]string, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fileNameToPackage[fileDescriptorProto.GetName()] = fileDescriptorProto.GetPackage()
+	}
+	return fileNameToPackage
+}
+
+// getPackageToImportPackages returns the map from package to the other
+// packages that files in the package import.
+func getPackageToImportPackages(fileDescriptorProtos []*descriptor.FileDescriptorProto, fileNameToPackage map[string]string) map[string][]string {
+	packageToImportPackageSet := make(map[string]map[string]struct{})
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		packageName := fileDescriptorProto.GetPackage()
+		for _, dependency := range fileDescriptorProto.GetDependency() {
+			importPackageName, ok := fileNameToPackage[dependency]
+			if !ok || importPackageName == packageName {
+				continue
+			}
+			if _, ok := packageToImportPackageSet[packageName]; !ok {
+				packageToImportPackageSet[packageName] = make(map[string]struct{})
+			}
+			packageToImportPackageSet[packageName][importPackageName] = struct{}{}
+		}
+	}
+	packageToImportPackages := make(map[string][]string, len(packageToImportPackageSet))
+	for packageName, importPackageSet := range packageToImportPackageSet {
+		importPackages := make([]string, 0, len(importPackageSet))
+		for importPackageName := range importPackageSet {
+			importPackages = append(importPackages, importPackageName)
+		}
+		// sorted so that the reported cycle is deterministic
+		sort.Strings(importPackages)
+		packageToImportPackages[packageName] = importPackages
+	}
+	return packageToImportPackages
+}
+
+// getPackageImportPath returns the shortest path of imports from one
+// package to another, including both packages, or nil if there is none.
+func getPackageImportPath(packageToImportPackages map[string][]string, from string, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		packageName := queue[0]
+		queue = queue[1:]
+		if packageName == to {
+			var path []string
+			for ; packageName != from; packageName = previous[packageName] {
+				path = append([]string{packageName}, path...)
+			}
+			return append([]string{from}, path...)
+		}
+		for _, importPackageName := range packageToImportPackages[packageName] {
+			if _, ok := previous[importPackageName]; !ok {
+				previous[importPackageName] = packageName
+				queue = append(queue, importPackageName)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var requestResponseTypesNotSharedAcrossServicesChecker = NewAddDescriptorChecker(
+	"REQUEST_RESPONSE_TYPES_NOT_SHARED_ACROSS_SERVICES",
+	"Verifies that request and response types are not used by rpcs in more than one service, including services in other files in the same ProtoSet.",
+	checkRequestResponseTypesNotSharedAcrossServices,
+)
+
+func checkRequestResponseTypesNotSharedAcrossServices(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+	if len(descriptorFiles) == 0 {
+		return nil
+	}
+	// the services of the entire ProtoSet are checked so that types
+	// shared with other directories are found, but failures are only
+	// added for the files in this directory
+	dirDescriptorFiles := make(map[*DescriptorFile]struct{}, len(descriptorFiles))
+	for _, descriptorFile := range descriptorFiles {
+		dirDescriptorFiles[descriptorFile] = struct{}{}
+	}
+	typeNameToServiceName := make(map[string]string)
+	// in the same order as the input and output types below
+	methodTypeTags := []int32{desc.MethodInputTypeTag, desc.MethodOutputTypeTag}
+	for _, descriptorFile := range descriptorFiles[0].ProtoSetFiles {
+		_, inDir := dirDescriptorFiles[descriptorFile]
+		prefix := ""
+		if pkg := descriptorFile.GetPackage(); pkg != "" {
+			prefix = pkg + "."
+		}
+		for i, service := range descriptorFile.Service {
+			serviceName := prefix + service.GetName()
+			for j, method := range service.Method {
+				for k, typeName := range []string{method.GetInputType(), method.GetOutputType()} {
+					otherServiceName, ok := typeNameToServiceName[typeName]
+					if !ok {
+						typeNameToServiceName[typeName] = serviceName
+						continue
+					}
+					if otherServiceName != serviceName && inDir {
+						add(text.NewFailuref(
+							descriptorFile.Position(desc.FileServiceTag, int32(i), desc.ServiceMethodTag, int32(j), methodTypeTags[k]),
+							"",
+							`Message %q is already used as a request or response type in service %q and request and response types must not be shared across services.`,
+							trimLeadingDot(typeName),
+							otherServiceName,
+						))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/file"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+// DescriptorChecker is a Checker that checks compiled descriptors instead
+// of parsed Protobuf files, which allows resolving types, imports, and
+// references across files.
+//
+// Check is a no-op for DescriptorCheckers, CheckDescriptors is called instead.
+type DescriptorChecker interface {
+	Checker
+
+	// Check the compiled files in a common directory.
+	// If there is a lint failure, this returns it in the
+	// slice and does not return an error. An error is returned if something
+	// unexpected happens.
+	CheckDescriptors(dirPath string, descriptorFiles []*DescriptorFile) ([]*text.Failure, error)
+}
+
+// NewDescriptorChecker is a convienence function that returns a new
+// DescriptorChecker for the given parameters.
+//
+// The ID will be upper-cased.
+//
+// Failures returned from check do not need to set the ID, this will be overwritten.
+func NewDescriptorChecker(id string, purpose string, check func(string, []*DescriptorFile) ([]*text.Failure, error)) DescriptorChecker {
+	return newBaseDescriptorChecker(id, purpose, check)
+}
+
+// NewAddDescriptorChecker is a convienence function that returns a new
+// DescriptorChecker for the given parameters, using a function to record failures.
+//
+// The ID will be upper-cased.
+//
+// Failures returned from check do not need to set the ID, this will be overwritten.
+func NewAddDescriptorChecker(id string, purpose string, addCheck func(func(*text.Failure), string, []*DescriptorFile) error) DescriptorChecker {
+	return newBaseAddDescriptorChecker(id, purpose, addCheck)
+}
+
+// DescriptorFile is a compiled Protobuf file.
+type DescriptorFile struct {
+	*descriptor.FileDescriptorProto
+
+	// The absolute path to the file.
+	Path string
+	// The path to display in output.
+	DisplayPath string
+	// The FileDescriptorSet the file was compiled in, which includes
+	// all imports of the file.
+	FileDescriptorSet *descriptor.FileDescriptorSet
+	// The compiled files for all files in the ProtoSet of the file,
+	// including this file, for checks that need the entire ProtoSet.
+	ProtoSetFiles []*DescriptorFile
+	// The lint config of the ProtoSet of the file, for checks that
+	// are configurable.
+	LintConfig settings.LintConfig
+
+	// shared by all files in the ProtoSet, nil if not created
+	// with GetDirPathToDescriptorFiles
+	protoSetCache *protoSetCache
+}
+
+// protoSetCache holds values that only need to be computed once
+// per ProtoSet for a lint run.
+type protoSetCache struct {
+	baselineOnce sync.Once
+	baseline     *baseline
+	baselineErr  error
+
+	lock                                   sync.Mutex
+	fileDescriptorSetToDeprecatedTypeNames map[*descriptor.FileDescriptorSet]map[string]struct{}
+}
+
+// Position returns the position of the element at the given source code info path.
+//
+// See the documentation for SourceCodeInfo in descriptor.proto for paths.
+// If there is no source code info for the path, the position will only have
+// the filename set.
+func (d *DescriptorFile) Position(path ...int32) scanner.Position {
+	position := scanner.Position{
+		Filename: d.DisplayPath,
+	}
+	if line, column, ok := desc.GetLineAndColumn(d.FileDescriptorProto, path...); ok {
+		position.Line = line
+		position.Column = column
+	}
+	return position
+}
+
+// GetDirPathToDescriptorFiles is a convienence function that gets the
+// compiled files for the given ProtoSet from the given FileDescriptorSets.
+//
+// The FileDescriptorSets are expected to be the result of compiling
+// the ProtoSet with source info.
+func GetDirPathToDescriptorFiles(protoSet *file.ProtoSet, fileDescriptorSets []*descriptor.FileDescriptorSet) (map[string][]*DescriptorFile, error) {
+	dirPathToDescriptorFiles := make(map[string][]*DescriptorFile, len(protoSet.DirPathToFiles))
+	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+		descriptorFiles := make([]*DescriptorFile, len(protoFiles))
+		for i, protoFile := range protoFiles {
+			fileDescriptorProto, fileDescriptorSet := findFileDescriptorProto(protoFile.Path, fileDescriptorSets)
+			if fileDescriptorProto == nil {
+				return nil, fmt.Errorf("could not find compiled descriptor for %s", protoFile.DisplayPath)
+			}
+			descriptorFiles[i] = &DescriptorFile{
+				FileDescriptorProto: fileDescriptorProto,
+				Path:                protoFile.Path,
+				DisplayPath:         protoFile.DisplayPath,
+				FileDescriptorSet:   fileDescriptorSet,
+				LintConfig:          protoSet.Config.Lint,
+			}
+		}
+		dirPathToDescriptorFiles[dirPath] = descriptorFiles
+	}
+	var protoSetFiles []*DescriptorFile
+	for _, descriptorFiles := range dirPathToDescriptorFiles {
+		protoSetFiles = append(protoSetFiles, descriptorFiles...)
+	}
+	sort.Slice(protoSetFiles, func(i int, j int) bool { return protoSetFiles[i].Path < protoSetFiles[j].Path })
+	protoSetCache := &protoSetCache{}
+	for _, descriptorFile := range protoSetFiles {
+		descriptorFile.ProtoSetFiles = protoSetFiles
+		descriptorFile.protoSetCache = protoSetCache
+	}
+	return dirPathToDescriptorFiles, nil
+}
+
+// CheckMultipleDescriptors is a convienence function that checks multiple
+// DescriptorCheckers and multiple compiled files.
+func CheckMultipleDescriptors(checkers []DescriptorChecker, dirPathToDescriptorFiles map[string][]*DescriptorFile, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
+	var allFailures []*text.Failure
+	for dirPath, descriptorFiles := range dirPathToDescriptorFiles {
+		for _, checker := range checkers {
+			failures, err := checker.CheckDescriptors(dirPath, filterDescriptorFileIgnores(checker, descriptorFiles, ignoreIDToFilePaths))
+			if err != nil {
+				return nil, err
+			}
+			allFailures = append(allFailures, failures...)
+		}
+	}
+	text.SortFailures(allFailures)
+	return allFailures, nil
+}
+
+// GetDescriptorCheckers returns the DescriptorCheckers in the given Checkers.
+func GetDescriptorCheckers(checkers []Checker) []DescriptorChecker {
+	var descriptorCheckers []DescriptorChecker
+	for _, checker := range checkers {
+		if descriptorChecker, ok := checker.(DescriptorChecker); ok {
+			descriptorCheckers = append(descriptorCheckers, descriptorChecker)
+		}
+	}
+	return descriptorCheckers
+}
+
+type baseDescriptorChecker struct {
+	*baseChecker
+	checkDescriptors func(string, []*DescriptorFile) ([]*text.Failure, error)
+}
+
+func newBaseAddDescriptorChecker(
+	id string,
+	purpose string,
+	addCheck func(func(*text.Failure), string, []*DescriptorFile) error,
+) *baseDescriptorChecker {
+	return newBaseDescriptorChecker(
+		id,
+		purpose,
+		func(dirPath string, descriptorFiles []*DescriptorFile) ([]*text.Failure, error) {
+			var failures []*text.Failure
+			var lock sync.Mutex
+			if err := addCheck(
+				func(failure *text.Failure) {
+					lock.Lock()
+					failures = append(failures, failure)
+					lock.Unlock()
+				},
+				dirPath,
+				descriptorFiles,
+			); err != nil {
+				return nil, err
+			}
+			return failures, nil
+		},
+	)
+}
+
+func newBaseDescriptorChecker(
+	id string,
+	purpose string,
+	checkDescriptors func(string, []*DescriptorFile) ([]*text.Failure, error),
+) *baseDescriptorChecker {
+	return &baseDescriptorChecker{
+		baseChecker: newBaseChecker(
+			id,
+			purpose,
+			func(string, []*proto.Proto) ([]*text.Failure, error) {
+				return nil, nil
+			},
+		),
+		checkDescriptors: checkDescriptors,
+	}
+}
+
+func (c *baseDescriptorChecker) CheckDescriptors(dirPath string, descriptorFiles []*DescriptorFile) ([]*text.Failure, error) {
+	failures, err := c.checkDescriptors(dirPath, descriptorFiles)
+	for _, failure := range failures {
+		failure.ID = c.id
+	}
+	return failures, err
+}
+
+func filterDescriptorFileIgnores(checker Checker, descriptorFiles []*DescriptorFile, ignoreIDToFilePaths map[string][]string) []*DescriptorFile {
+	ignoreFilePaths, ok := ignoreIDToFilePaths[checker.ID()]
+	if !ok {
+		return descriptorFiles
+	}
+	var filteredDescriptorFiles []*DescriptorFile
+	for _, descriptorFile := range descriptorFiles {
+		if !stringIn(descriptorFile.Path, ignoreFilePaths) {
+			filteredDescriptorFiles = append(filteredDescriptorFiles, descriptorFile)
+		}
+	}
+	return filteredDescriptorFiles
+}
+
+// the names in a FileDescriptorSet are relative to the include path
+// the file was found on, so we match on the longest name that is
+// a suffix of the file path
+func findFileDescriptorProto(filePath string, fileDescriptorSets []*descriptor.FileDescriptorSet) (*descriptor.FileDescriptorProto, *descriptor.FileDescriptorSet) {
+	filePath = filepath.ToSlash(filePath)
+	var bestFileDescriptorProto *descriptor.FileDescriptorProto
+	var bestFileDescriptorSet *descriptor.FileDescriptorSet
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.File {
+			name := fileDescriptorProto.GetName()
+			if filePath != name && !strings.HasSuffix(filePath, "/"+name) {
+				continue
+			}
+			if bestFileDescriptorProto == nil || len(name) > len(bestFileDescriptorProto.GetName()) {
+				bestFileDescriptorProto = fileDescriptorProto
+				bestFileDescriptorSet = fileDescriptorSet
+			}
+		}
+	}
+	return bestFileDescriptorProto, bestFileDescriptorSet
+}
+
+func stringIn(s string, slice []string) bool {
+	for _, e := range slice {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+const (
+	// DisableDirective is the comment directive that disables lint checks
+	// for the element it is attached to.
+	//
+	// The directive is followed by one or more lint IDs, for example:
+	//
+	//   // prototool:disable ENUM_ZERO_VALUES_INVALID
+	//   enum Foo {
+	DisableDirective = "prototool:disable"
+	// DisableFileDirective is the comment directive that disables lint
+	// checks for the entire file it is in.
+	//
+	// The directive is followed by one or more lint IDs, for example:
+	//
+	//   // prototool:disable-file ENUM_ZERO_VALUES_INVALID
+	DisableFileDirective = "prototool:disable-file"
+)
+
+var commentsNoUnusedDirectivesChecker = NewChecker(
+	"COMMENTS_NO_UNUSED_DIRECTIVES",
+	`Verifies that all "prototool:disable" and "prototool:disable-file" comment directives suppress a lint failure.`,
+	// this is handled in CheckMultiple, as all other failures are needed
+	func(string, []*proto.Proto) ([]*text.Failure, error) {
+		return nil, nil
+	},
+)
+
+// directive is a parsed disable comment directive for one lint ID.
+type directive struct {
+	// the position of the comment
+	position scanner.Position
+	id       string
+	file     bool
+	// the lines of the element the directive is attached to, including
+	// all nested elements
+	// 0 if this is not attached to an element, and is not a file directive
+	startLine int
+	endLine   int
+	used      bool
+}
+
+// suppresses returns true if the directive suppresses the failure.
+func (d *directive) suppresses(failure *text.Failure) bool {
+	if failure.ID != d.id || failure.Filename != d.position.Filename {
+		return false
+	}
+	return d.file || (d.startLine > 0 && failure.Line >= d.startLine && failure.Line <= d.endLine)
+}
+
+// applyDirectives removes the failures suppressed by the directives
+// in the descriptors, and adds failures for any directives that
+// do not suppress a failure if commentsNoUnusedDirectivesChecker
+// is in the checkers.
+//
+// allCheckers are all the Checkers that can be enabled, and are used
+// to tell if a directive refers to an unknown lint ID.
+func applyDirectives(checkers []Checker, allCheckers []Checker, descriptors []*proto.Proto, failures []*text.Failure, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
+	directives, err := getDirectives(checkers, descriptors, ignoreIDToFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(directives) == 0 {
+		return failures, nil
+	}
+	filteredFailures := make([]*text.Failure, 0, len(failures))
+	for _, failure := range failures {
+		suppressed := false
+		for _, directive := range directives {
+			if directive.suppresses(failure) {
+				directive.used = true
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			filteredFailures = append(filteredFailures, failure)
+		}
+	}
+	if !checkerIn(commentsNoUnusedDirectivesChecker, checkers) {
+		return filteredFailures, nil
+	}
+	var unusedFailures []*text.Failure
+	for _, directive := range directives {
+		if directive.used {
+			continue
+		}
+		var failure *text.Failure
+		switch {
+		case !checkerIDIn(directive.id, checkers) && !checkerIDIn(directive.id, allCheckers):
+			failure = text.NewFailuref(directive.position, commentsNoUnusedDirectivesChecker.ID(), "Directive refers to unknown lint ID %q.", directive.id)
+		case checkerIDIn(directive.id, checkers):
+			failure = text.NewFailuref(directive.position, commentsNoUnusedDirectivesChecker.ID(), "Directive for lint ID %q does not suppress any failures and should be removed.", directive.id)
+		default:
+			// the lint ID is not enabled, so we cannot tell if the directive is needed
+			continue
+		}
+		suppressed := false
+		for _, directive := range directives {
+			if directive.file && directive.suppresses(failure) {
+				suppressed = true
+			}
+		}
+		if !suppressed {
+			unusedFailures = append(unusedFailures, failure)
+		}
+	}
+	return append(filteredFailures, unusedFailures...), nil
+}
+
+// getDirectives gets the directives for the descriptors, not including
+// descriptors that are ignored for commentsNoUnusedDirectivesChecker.
+func getDirectives(checkers []Checker, descriptors []*proto.Proto, ignoreIDToFilePaths map[string][]string) ([]*directive, error) {
+	var directives []*directive
+	for _, descriptor := range descriptors {
+		ignore, err := shouldIgnore(commentsNoUnusedDirectivesChecker, descriptor, ignoreIDToFilePaths)
+		if err != nil {
+			return nil, err
+		}
+		visitor := &directivesVisitor{}
+		for _, element := range descriptor.Elements {
+			element.Accept(visitor)
+		}
+		for _, directive := range visitor.directives {
+			directive.position.Filename = descriptor.Filename
+			// if unused directives are ignored for this file, just treat
+			// the directives as used so no failures are produced
+			directive.used = ignore
+		}
+		directives = append(directives, visitor.directives...)
+	}
+	return directives, nil
+}
+
+// parseDirectives parses the directives in the comment.
+//
+// line is the line of the element the comment is attached to, or 0
+// if the comment is not attached to an element. The end line is set
+// to line, and should be updated for elements with nested elements.
+func parseDirectives(comment *proto.Comment, line int) []*directive {
+	if comment == nil {
+		return nil
+	}
+	var directives []*directive
+	for _, commentLine := range comment.Lines {
+		fields := strings.Fields(strings.Replace(commentLine, ",", " ", -1))
+		if len(fields) == 0 {
+			continue
+		}
+		var file bool
+		switch fields[0] {
+		case DisableDirective:
+		case DisableFileDirective:
+			file = true
+		default:
+			continue
+		}
+		for _, id := range fields[1:] {
+			directives = append(directives, &directive{
+				position:  comment.Position,
+				id:        strings.ToUpper(id),
+				file:      file,
+				startLine: line,
+				endLine:   line,
+			})
+		}
+	}
+	return directives
+}
+
+func checkerIDIn(id string, checkers []Checker) bool {
+	for _, checker := range checkers {
+		if checker.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+type directivesVisitor struct {
+	baseVisitor
+
+	directives []*directive
+	// the last line of an element seen, used to find
+	// the end line of elements with nested elements
+	lastLine int
+}
+
+func (v *directivesVisitor) VisitMessage(element *proto.Message) {
+	directives := v.addDirectives(element.Position, element.Comment)
+	for _, child := range element.Elements {
+		child.Accept(v)
+	}
+	v.setEndLine(directives)
+}
+
+func (v *directivesVisitor) VisitService(element *proto.Service) {
+	directives := v.addDirectives(element.Position, element.Comment)
+	for _, child := range element.Elements {
+		child.Accept(v)
+	}
+	v.setEndLine(directives)
+}
+
+func (v *directivesVisitor) VisitSyntax(element *proto.Syntax) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitPackage(element *proto.Package) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitOption(element *proto.Option) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitImport(element *proto.Import) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitNormalField(element *proto.NormalField) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitEnumField(element *proto.EnumField) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitEnum(element *proto.Enum) {
+	directives := v.addDirectives(element.Position, element.Comment)
+	for _, child := range element.Elements {
+		child.Accept(v)
+	}
+	v.setEndLine(directives)
+}
+
+func (v *directivesVisitor) VisitComment(element *proto.Comment) {
+	// a standalone comment is not attached to an element, so only
+	// file directives will have an effect
+	v.directives = append(v.directives, parseDirectives(element, 0)...)
+}
+
+func (v *directivesVisitor) VisitOneof(element *proto.Oneof) {
+	directives := v.addDirectives(element.Position, element.Comment)
+	for _, child := range element.Elements {
+		child.Accept(v)
+	}
+	v.setEndLine(directives)
+}
+
+func (v *directivesVisitor) VisitOneofField(element *proto.OneOfField) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitReserved(element *proto.Reserved) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitRPC(element *proto.RPC) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitMapField(element *proto.MapField) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) VisitGroup(element *proto.Group) {
+	directives := v.addDirectives(element.Position, element.Comment)
+	for _, child := range element.Elements {
+		child.Accept(v)
+	}
+	v.setEndLine(directives)
+}
+
+func (v *directivesVisitor) VisitExtensions(element *proto.Extensions) {
+	v.addDirectives(element.Position, element.Comment, element.InlineComment)
+}
+
+func (v *directivesVisitor) addDirectives(position scanner.Position, comments ...*proto.Comment) []*directive {
+	if position.Line > v.lastLine {
+		v.lastLine = position.Line
+	}
+	var directives []*directive
+	for _, comment := range comments {
+		directives = append(directives, parseDirectives(comment, position.Line)...)
+	}
+	v.directives = append(v.directives, directives...)
+	return directives
+}
+
+func (v *directivesVisitor) setEndLine(directives []*directive) {
+	for _, directive := range directives {
+		directive.endLine = v.lastLine
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"strings"
+
+	"github.com/emicklei/proto"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+// getEnumFieldRenameEdits returns the edits to rename the enum field,
+// including the proto2 default values in the descriptors that reference it.
+//
+// Only the descriptors given are searched for references, so references
+// in other directories are not renamed.
+func getEnumFieldRenameEdits(descriptors []*proto.Proto, enumField *proto.EnumField, newName string) []*text.Edit {
+	edits := []*text.Edit{
+		{
+			Line:    enumField.Position.Line,
+			Column:  enumField.Position.Column,
+			OldText: enumField.Name,
+			NewText: newName,
+		},
+	}
+	enum, ok := enumField.Parent.(*proto.Enum)
+	if !ok {
+		return edits
+	}
+	enumName := joinName(getScopeName(enum.Parent), enum.Name)
+	for _, descriptor := range descriptors {
+		walkFields(descriptor.Elements, getPackageName(descriptor), func(field *proto.Field, scope string) {
+			if !typeNameResolvesTo(scope, field.Type, enumName) {
+				return
+			}
+			for _, option := range field.Options {
+				if option.Name != "default" || option.Constant.IsString || option.Constant.Source != enumField.Name {
+					continue
+				}
+				edit := &text.Edit{
+					Line:    option.Constant.Position.Line,
+					Column:  option.Constant.Position.Column,
+					OldText: enumField.Name,
+					NewText: newName,
+				}
+				if option.Constant.Position.Filename != enumField.Position.Filename {
+					edit.Filename = option.Constant.Position.Filename
+				}
+				edits = append(edits, edit)
+			}
+		})
+	}
+	return edits
+}
+
+// walkFields calls f with each field in the elements and the name of
+// the scope that the type of the field is resolved in.
+func walkFields(elements []proto.Visitee, scope string, f func(*proto.Field, string)) {
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *proto.Message:
+			// fields in extend blocks are in the enclosing scope
+			if element.IsExtend {
+				walkFields(element.Elements, scope, f)
+			} else {
+				walkFields(element.Elements, joinName(scope, element.Name), f)
+			}
+		case *proto.Group:
+			walkFields(element.Elements, joinName(scope, element.Name), f)
+		case *proto.Oneof:
+			walkFields(element.Elements, scope, f)
+		case *proto.NormalField:
+			f(element.Field, scope)
+		case *proto.OneOfField:
+			f(element.Field, scope)
+		}
+	}
+}
+
+// getScopeName returns the fully-qualified name of the message or file,
+// without a leading dot.
+func getScopeName(visitee proto.Visitee) string {
+	switch visitee := visitee.(type) {
+	case *proto.Message:
+		return joinName(getScopeName(visitee.Parent), visitee.Name)
+	case *proto.Group:
+		return joinName(getScopeName(visitee.Parent), visitee.Name)
+	case *proto.Proto:
+		return getPackageName(visitee)
+	default:
+		return ""
+	}
+}
+
+func getPackageName(descriptor *proto.Proto) string {
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			return pkg.Name
+		}
+	}
+	return ""
+}
+
+// typeNameResolvesTo returns true if the type name used in the scope
+// can refer to the fully-qualified name, searching the scope and then
+// each enclosing scope like protoc does.
+func typeNameResolvesTo(scope string, typeName string, fullyQualifiedName string) bool {
+	if strings.HasPrefix(typeName, ".") {
+		return typeName[1:] == fullyQualifiedName
+	}
+	for {
+		if joinName(scope, typeName) == fullyQualifiedName {
+			return true
+		}
+		if scope == "" {
+			return false
+		}
+		if index := strings.LastIndex(scope, "."); index >= 0 {
+			scope = scope[:index]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
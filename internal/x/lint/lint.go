@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 
 	"github.com/emicklei/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/tgrpc/prototool/internal/x/file"
 	"github.com/tgrpc/prototool/internal/x/settings"
 	"github.com/tgrpc/prototool/internal/x/text"
@@ -36,6 +37,8 @@ var (
 	// AllCheckers is the slice of all known Checkers.
 	AllCheckers = []Checker{
 		commentsNoCStyleChecker,
+		commentsNoUnusedDirectivesChecker,
+		deletedFieldsReservedChecker,
 		enumFieldNamesUppercaseChecker,
 		enumFieldNamesUpperSnakeCaseChecker,
 		enumFieldPrefixesChecker,
@@ -51,8 +54,11 @@ var (
 		fileOptionsRequireGoPackageChecker,
 		fileOptionsRequireJavaMultipleFilesChecker,
 		fileOptionsRequireJavaPackageChecker,
+		filesNoUnusedImportsChecker,
+		filesNoUnusedTypesChecker,
 		messageFieldsNotFloatsChecker,
 		messageFieldNamesLowerSnakeCaseChecker,
+		messageFieldTypesNotDeprecatedChecker,
 		messageFieldNamesLowercaseChecker,
 		messageNamesCamelCaseChecker,
 		messageNamesCapitalizedChecker,
@@ -60,12 +66,14 @@ var (
 		messagesHaveCommentsExceptRequestResponseTypesChecker,
 		oneofNamesLowerSnakeCaseChecker,
 		packageLowerSnakeCaseChecker,
+		packagesNoImportCyclesChecker,
 		packagesSameInDirChecker,
 		rpcsHaveCommentsChecker,
 		rpcNamesCamelCaseChecker,
 		rpcNamesCapitalizedChecker,
 		requestResponseTypesInSameFileChecker,
 		requestResponseTypesUniqueChecker,
+		requestResponseTypesNotSharedAcrossServicesChecker,
 		requestResponseNamesMatchRPCChecker,
 		servicesHaveCommentsChecker,
 		serviceNamesCamelCaseChecker,
@@ -77,27 +85,102 @@ var (
 	// DefaultCheckers is the slice of default Checkers.
 	DefaultCheckers = copyCheckersWithout(
 		AllCheckers,
+		deletedFieldsReservedChecker,
 		enumFieldNamesUppercaseChecker,
 		enumsHaveCommentsChecker,
 		fileOptionsEqualJavaMultipleFilesTrueChecker,
 		fileOptionsEqualJavaOuterClassnameProtoSuffixChecker,
 		fileOptionsRequireJavaMultipleFilesChecker,
 		fileOptionsRequireJavaOuterClassnameChecker,
+		filesNoUnusedImportsChecker,
+		filesNoUnusedTypesChecker,
 		messageFieldsNotFloatsChecker,
 		messagesHaveCommentsChecker,
 		messagesHaveCommentsExceptRequestResponseTypesChecker,
 		messageFieldNamesLowercaseChecker,
+		messageFieldTypesNotDeprecatedChecker,
+		packagesNoImportCyclesChecker,
 		requestResponseNamesMatchRPCChecker,
+		requestResponseTypesNotSharedAcrossServicesChecker,
 		rpcsHaveCommentsChecker,
 		servicesHaveCommentsChecker,
 	)
 
+	// StrictCheckers is the slice of strict Checkers.
+	//
+	// This is all Checkers except those that are a weaker version
+	// of another Checker.
+	StrictCheckers = copyCheckersWithout(
+		AllCheckers,
+		enumFieldNamesUppercaseChecker,
+		messageFieldNamesLowercaseChecker,
+		messagesHaveCommentsExceptRequestResponseTypesChecker,
+	)
+
+	// GoogleCheckers is the slice of Checkers that follow the Google API
+	// style guide at https://cloud.google.com/apis/design.
+	GoogleCheckers = []Checker{
+		commentsNoCStyleChecker,
+		commentsNoUnusedDirectivesChecker,
+		deletedFieldsReservedChecker,
+		enumFieldNamesUpperSnakeCaseChecker,
+		enumFieldPrefixesChecker,
+		enumNamesCamelCaseChecker,
+		enumNamesCapitalizedChecker,
+		fileOptionsGoPackageSameInDirChecker,
+		fileOptionsJavaPackageSameInDirChecker,
+		messageFieldNamesLowerSnakeCaseChecker,
+		messageNamesCamelCaseChecker,
+		messageNamesCapitalizedChecker,
+		oneofNamesLowerSnakeCaseChecker,
+		packageLowerSnakeCaseChecker,
+		packagesSameInDirChecker,
+		rpcNamesCamelCaseChecker,
+		rpcNamesCapitalizedChecker,
+		requestResponseNamesMatchRPCChecker,
+		requestResponseTypesUniqueChecker,
+		serviceNamesCamelCaseChecker,
+		serviceNamesCapitalizedChecker,
+		syntaxProto3Checker,
+		wktDirectlyImportedChecker,
+	}
+
+	// MinimalCheckers is the slice of minimal Checkers.
+	//
+	// This is only the naming Checkers and the Checkers that
+	// catch files that will not work together.
+	MinimalCheckers = []Checker{
+		commentsNoUnusedDirectivesChecker,
+		deletedFieldsReservedChecker,
+		enumFieldNamesUpperSnakeCaseChecker,
+		enumNamesCamelCaseChecker,
+		messageFieldNamesLowerSnakeCaseChecker,
+		messageNamesCamelCaseChecker,
+		packageLowerSnakeCaseChecker,
+		packagesNoImportCyclesChecker,
+		packagesSameInDirChecker,
+		rpcNamesCamelCaseChecker,
+		serviceNamesCamelCaseChecker,
+	}
+
 	// DefaultGroup is the default group.
 	DefaultGroup = "default"
+	// StrictGroup is the strict group.
+	StrictGroup = "strict"
+	// GoogleGroup is the group for the Google API style guide.
+	GoogleGroup = "google"
+	// MinimalGroup is the minimal group.
+	MinimalGroup = "minimal"
 
 	// GroupToCheckers is the map from checker group to the corresponding slice of checkers.
+	//
+	// This only contains the built-in groups, use GetGroupToCheckers
+	// to also get the user-defined groups.
 	GroupToCheckers = map[string][]Checker{
 		DefaultGroup: DefaultCheckers,
+		StrictGroup:  StrictCheckers,
+		GoogleGroup:  GoogleCheckers,
+		MinimalGroup: MinimalCheckers,
 	}
 )
 
@@ -113,7 +196,11 @@ func init() {
 
 // Runner runs a lint job.
 type Runner interface {
-	Run(...*file.ProtoSet) ([]*text.Failure, error)
+	// Run the lint job for the ProtoSets.
+	//
+	// The FileDescriptorSets are the result of compiling the ProtoSets
+	// with source info, and are used for DescriptorCheckers.
+	Run(fileDescriptorSets []*descriptor.FileDescriptorSet, protoSets ...*file.ProtoSet) ([]*text.Failure, error)
 }
 
 // RunnerOption is an option for a new Runner.
@@ -177,15 +264,34 @@ func NewAddChecker(id string, purpose string, addCheck func(func(*text.Failure),
 // IncludeIDs and ExcludeIDs.
 //
 // If the config came from the settings package, this is already validated.
+//
+// The Checkers for the user-defined rules in the config are used in addition
+// to the Checkers of a built-in lint group, and can be selected or excluded
+// by ID. A user-defined lint group only uses the rules it adds.
+//
+// All user-defined lint groups in the config are validated, not just the
+// selected group, see GetGroupToCheckers.
 func GetCheckers(config settings.LintConfig) ([]Checker, error) {
+	ruleCheckers, err := GetRuleCheckers(config)
+	if err != nil {
+		return nil, err
+	}
+	groupToCheckers, err := GetGroupToCheckers(config)
+	if err != nil {
+		return nil, err
+	}
 	if len(config.IDs) == 0 && (len(config.Group) == 0 || config.Group == DefaultGroup) && len(config.IncludeIDs) == 0 && len(config.ExcludeIDs) == 0 {
-		return DefaultCheckers, nil
+		if len(ruleCheckers) == 0 {
+			return DefaultCheckers, nil
+		}
+		return append(copyCheckersWithout(DefaultCheckers), ruleCheckers...), nil
 	}
+	allCheckers := append(copyCheckersWithout(AllCheckers), ruleCheckers...)
 
 	if len(config.IDs) > 0 {
 		var checkers []Checker
 		// n^2 woot
-		for _, checker := range AllCheckers {
+		for _, checker := range allCheckers {
 			for _, id := range config.IDs {
 				if checker.ID() == id {
 					checkers = append(checkers, checker)
@@ -196,23 +302,31 @@ func GetCheckers(config settings.LintConfig) ([]Checker, error) {
 	}
 
 	baseCheckers := DefaultCheckers
-	var ok bool
+	baseRuleCheckers := ruleCheckers
 	if len(config.Group) > 0 && config.Group != DefaultGroup {
-		baseCheckers, ok = GroupToCheckers[config.Group]
+		var ok bool
+		baseCheckers, ok = groupToCheckers[config.Group]
 		if !ok {
 			return nil, fmt.Errorf("unknown lint group: %s", config.Group)
 		}
+		if _, ok := GroupToCheckers[config.Group]; !ok {
+			// user-defined groups already contain the rules they add
+			baseRuleCheckers = nil
+		}
 	}
 
-	checkersMap := make(map[string]Checker, len(baseCheckers))
+	checkersMap := make(map[string]Checker, len(baseCheckers)+len(baseRuleCheckers))
 	for _, checker := range baseCheckers {
 		checkersMap[checker.ID()] = checker
 	}
+	for _, checker := range baseRuleCheckers {
+		checkersMap[checker.ID()] = checker
+	}
 	for _, excludeID := range config.ExcludeIDs {
 		delete(checkersMap, excludeID)
 	}
 	// n^2 woot
-	for _, checker := range AllCheckers {
+	for _, checker := range allCheckers {
 		for _, id := range config.IncludeIDs {
 			if checker.ID() == id {
 				checkersMap[checker.ID()] = checker
@@ -226,6 +340,107 @@ func GetCheckers(config settings.LintConfig) ([]Checker, error) {
 	return checkers, nil
 }
 
+// GetAllCheckers returns AllCheckers and the Checkers for the user-defined
+// rules in the LintConfig, whether or not they are enabled.
+func GetAllCheckers(config settings.LintConfig) ([]Checker, error) {
+	ruleCheckers, err := GetRuleCheckers(config)
+	if err != nil {
+		return nil, err
+	}
+	return append(copyCheckersWithout(AllCheckers), ruleCheckers...), nil
+}
+
+// GetGroupToCheckers returns the map from lint group to the corresponding
+// slice of Checkers for the LintConfig.
+//
+// This contains the built-in groups in GroupToCheckers and the user-defined
+// groups in the config. User-defined groups are resolved by starting with
+// the Checkers of the base group, adding the Checkers for the added IDs,
+// and removing the Checkers for the removed IDs. The Checkers for the
+// user-defined rules in the config can be added by ID.
+//
+// The config is expected to be valid, see GetCheckers.
+func GetGroupToCheckers(config settings.LintConfig) (map[string][]Checker, error) {
+	groupToCheckers := make(map[string][]Checker, len(GroupToCheckers)+len(config.Groups))
+	for group, checkers := range GroupToCheckers {
+		groupToCheckers[group] = checkers
+	}
+	if len(config.Groups) == 0 {
+		return groupToCheckers, nil
+	}
+	ruleCheckers, err := GetRuleCheckers(config)
+	if err != nil {
+		return nil, err
+	}
+	allCheckers := append(copyCheckersWithout(AllCheckers), ruleCheckers...)
+	idToChecker := make(map[string]Checker, len(allCheckers))
+	for _, checker := range allCheckers {
+		idToChecker[checker.ID()] = checker
+	}
+	nameToGroup := make(map[string]settings.LintGroup, len(config.Groups))
+	for _, group := range config.Groups {
+		if _, ok := GroupToCheckers[group.Name]; ok {
+			return nil, fmt.Errorf("lint group %s is a built-in lint group and cannot be redefined", group.Name)
+		}
+		nameToGroup[group.Name] = group
+	}
+	resolving := make(map[string]struct{}, len(config.Groups))
+	var resolve func(string) ([]Checker, error)
+	resolve = func(name string) ([]Checker, error) {
+		if checkers, ok := groupToCheckers[name]; ok {
+			return checkers, nil
+		}
+		group, ok := nameToGroup[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown lint group: %s", name)
+		}
+		if _, ok := resolving[name]; ok {
+			return nil, fmt.Errorf("lint group %s has a cycle in its base groups", name)
+		}
+		resolving[name] = struct{}{}
+		var baseCheckers []Checker
+		if group.Base != "" {
+			var err error
+			baseCheckers, err = resolve(group.Base)
+			if err != nil {
+				return nil, err
+			}
+		}
+		checkersMap := make(map[string]Checker, len(baseCheckers)+len(group.Add))
+		for _, checker := range baseCheckers {
+			checkersMap[checker.ID()] = checker
+		}
+		for _, id := range group.Add {
+			checker, ok := idToChecker[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown linter %s added in lint group %s", id, name)
+			}
+			checkersMap[id] = checker
+		}
+		for _, id := range group.Remove {
+			if _, ok := idToChecker[id]; !ok {
+				return nil, fmt.Errorf("unknown linter %s removed in lint group %s", id, name)
+			}
+			delete(checkersMap, id)
+		}
+		// keep the order of AllCheckers and then the rules
+		checkers := make([]Checker, 0, len(checkersMap))
+		for _, checker := range allCheckers {
+			if _, ok := checkersMap[checker.ID()]; ok {
+				checkers = append(checkers, checker)
+			}
+		}
+		groupToCheckers[name] = checkers
+		return checkers, nil
+	}
+	for _, group := range config.Groups {
+		if _, err := resolve(group.Name); err != nil {
+			return nil, err
+		}
+	}
+	return groupToCheckers, nil
+}
+
 // GetDirPathToDescriptors is a convienence function that gets the
 // descriptors for the given ProtoSet.
 func GetDirPathToDescriptors(protoSet *file.ProtoSet) (map[string][]*proto.Proto, error) {
@@ -252,16 +467,38 @@ func GetDirPathToDescriptors(protoSet *file.ProtoSet) (map[string][]*proto.Proto
 }
 
 // CheckMultiple is a convienence function that checks multiple checkers and multiple descriptors.
-func CheckMultiple(checkers []Checker, dirPathToDescriptors map[string][]*proto.Proto, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
+//
+// DescriptorCheckers are checked against dirPathToDescriptorFiles, which can be
+// nil if there are no DescriptorCheckers.
+//
+// Failures suppressed by "prototool:disable" and "prototool:disable-file"
+// comment directives in the descriptors are removed. Directives can refer
+// to any of allCheckers, which are all the Checkers that can be enabled
+// including the Checkers for user-defined rules, see GetAllCheckers.
+func CheckMultiple(checkers []Checker, allCheckers []Checker, dirPathToDescriptors map[string][]*proto.Proto, dirPathToDescriptorFiles map[string][]*DescriptorFile, ignoreIDToFilePaths map[string][]string) ([]*text.Failure, error) {
+	descriptorCheckers := GetDescriptorCheckers(checkers)
 	var allFailures []*text.Failure
 	for dirPath, descriptors := range dirPathToDescriptors {
+		var dirFailures []*text.Failure
 		for _, checker := range checkers {
 			failures, err := checkOne(checker, dirPath, descriptors, ignoreIDToFilePaths)
 			if err != nil {
 				return nil, err
 			}
-			allFailures = append(allFailures, failures...)
+			dirFailures = append(dirFailures, failures...)
+		}
+		if descriptorFiles, ok := dirPathToDescriptorFiles[dirPath]; ok && len(descriptorCheckers) > 0 {
+			failures, err := CheckMultipleDescriptors(descriptorCheckers, map[string][]*DescriptorFile{dirPath: descriptorFiles}, ignoreIDToFilePaths)
+			if err != nil {
+				return nil, err
+			}
+			dirFailures = append(dirFailures, failures...)
+		}
+		dirFailures, err := applyDirectives(checkers, allCheckers, descriptors, dirFailures, ignoreIDToFilePaths)
+		if err != nil {
+			return nil, err
 		}
+		allFailures = append(allFailures, dirFailures...)
 	}
 	text.SortFailures(allFailures)
 	return allFailures, nil
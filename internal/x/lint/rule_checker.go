@@ -0,0 +1,272 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/scanner"
+
+	"github.com/emicklei/proto"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+// GetRuleCheckers returns the Checkers for the user-defined rules in the LintConfig.
+//
+// An error is returned if a rule has the same ID as a built-in Checker.
+func GetRuleCheckers(config settings.LintConfig) ([]Checker, error) {
+	checkers := make([]Checker, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		for _, checker := range AllCheckers {
+			if checker.ID() == rule.ID {
+				return nil, fmt.Errorf("lint rule %s has the same id as a built-in linter", rule.ID)
+			}
+		}
+		checker, err := newRuleChecker(rule)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, checker)
+	}
+	return checkers, nil
+}
+
+func newRuleChecker(rule settings.LintRule) (Checker, error) {
+	if len(rule.AllowedImportPackages) > 0 || len(rule.ForbiddenImportPackages) > 0 {
+		return newPackageImportRuleChecker(rule), nil
+	}
+	var namingRegexp *regexp.Regexp
+	if rule.NamingRegex != "" {
+		var err error
+		namingRegexp, err = regexp.Compile(rule.NamingRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid naming regex for lint rule %s: %v", rule.ID, err)
+		}
+	}
+	return NewAddChecker(
+		rule.ID,
+		rule.Message,
+		func(add func(*text.Failure), dirPath string, descriptors []*proto.Proto) error {
+			return runVisitor(&ruleVisitor{
+				baseAddVisitor: newBaseAddVisitor(add),
+				rule:           rule,
+				namingRegexp:   namingRegexp,
+			}, descriptors)
+		},
+	), nil
+}
+
+// newPackageImportRuleChecker returns a DescriptorChecker for a package
+// import rule, as the packages of imported files are only known from the
+// compiled descriptors.
+func newPackageImportRuleChecker(rule settings.LintRule) Checker {
+	return NewAddDescriptorChecker(
+		rule.ID,
+		rule.Message,
+		func(add func(*text.Failure), dirPath string, descriptorFiles []*DescriptorFile) error {
+			for _, descriptorFile := range descriptorFiles {
+				packageName := descriptorFile.GetPackage()
+				if len(rule.Packages) > 0 && !packageIn(packageName, rule.Packages) {
+					continue
+				}
+				fileNameToPackage := getFileNameToPackage(descriptorFile.FileDescriptorSet.GetFile())
+				for i, dependency := range descriptorFile.GetDependency() {
+					importPackageName, ok := fileNameToPackage[dependency]
+					if !ok || importPackageName == packageName {
+						continue
+					}
+					if (len(rule.AllowedImportPackages) > 0 && !packageIn(importPackageName, rule.AllowedImportPackages)) ||
+						packageIn(importPackageName, rule.ForbiddenImportPackages) {
+						add(text.NewFailuref(descriptorFile.Position(desc.FileDependencyTag, int32(i)), "", "%s", rule.Message))
+					}
+				}
+			}
+			return nil
+		},
+	)
+}
+
+type ruleVisitor struct {
+	baseAddVisitor
+
+	rule         settings.LintRule
+	namingRegexp *regexp.Regexp
+
+	filename    string
+	fileOptions []*proto.Option
+}
+
+func (v *ruleVisitor) OnStart(descriptor *proto.Proto) error {
+	v.filename = descriptor.Filename
+	v.fileOptions = nil
+	return nil
+}
+
+// options within other elements are handled by their parent,
+// so this is only called for file options
+func (v *ruleVisitor) VisitOption(element *proto.Option) {
+	v.fileOptions = append(v.fileOptions, element)
+}
+
+func (v *ruleVisitor) VisitImport(element *proto.Import) {
+	if len(v.rule.AllowedImports) > 0 && !hasPrefixIn(element.Filename, v.rule.AllowedImports) {
+		v.AddFailuref(element.Position, "%s", v.rule.Message)
+	}
+	if hasPrefixIn(element.Filename, v.rule.ForbiddenImports) {
+		v.AddFailuref(element.Position, "%s", v.rule.Message)
+	}
+}
+
+func (v *ruleVisitor) VisitPackage(element *proto.Package) {
+	v.checkElement(settings.LintRuleKindPackage, element.Position, element.Name, nil)
+}
+
+func (v *ruleVisitor) VisitMessage(element *proto.Message) {
+	options, children := splitOptions(element.Elements)
+	v.checkElement(settings.LintRuleKindMessage, element.Position, element.Name, options)
+	for _, child := range children {
+		child.Accept(v)
+	}
+}
+
+func (v *ruleVisitor) VisitGroup(element *proto.Group) {
+	_, children := splitOptions(element.Elements)
+	for _, child := range children {
+		child.Accept(v)
+	}
+}
+
+func (v *ruleVisitor) VisitOneof(element *proto.Oneof) {
+	_, children := splitOptions(element.Elements)
+	for _, child := range children {
+		child.Accept(v)
+	}
+}
+
+func (v *ruleVisitor) VisitNormalField(element *proto.NormalField) {
+	v.checkElement(settings.LintRuleKindField, element.Position, element.Name, element.Options)
+}
+
+func (v *ruleVisitor) VisitMapField(element *proto.MapField) {
+	v.checkElement(settings.LintRuleKindField, element.Position, element.Name, element.Options)
+}
+
+func (v *ruleVisitor) VisitOneofField(element *proto.OneOfField) {
+	v.checkElement(settings.LintRuleKindField, element.Position, element.Name, element.Options)
+}
+
+func (v *ruleVisitor) VisitEnum(element *proto.Enum) {
+	options, children := splitOptions(element.Elements)
+	v.checkElement(settings.LintRuleKindEnum, element.Position, element.Name, options)
+	for _, child := range children {
+		child.Accept(v)
+	}
+}
+
+func (v *ruleVisitor) VisitEnumField(element *proto.EnumField) {
+	var options []*proto.Option
+	if element.ValueOption != nil {
+		options = append(options, element.ValueOption)
+	}
+	v.checkElement(settings.LintRuleKindEnumValue, element.Position, element.Name, options)
+}
+
+func (v *ruleVisitor) VisitService(element *proto.Service) {
+	options, children := splitOptions(element.Elements)
+	v.checkElement(settings.LintRuleKindService, element.Position, element.Name, options)
+	for _, child := range children {
+		child.Accept(v)
+	}
+}
+
+func (v *ruleVisitor) VisitRPC(element *proto.RPC) {
+	v.checkElement(settings.LintRuleKindRPC, element.Position, element.Name, element.Options)
+}
+
+func (v *ruleVisitor) Finally() error {
+	v.checkElement(settings.LintRuleKindFile, scanner.Position{Filename: v.filename}, filepath.Base(v.filename), v.fileOptions)
+	return nil
+}
+
+func (v *ruleVisitor) checkElement(kind settings.LintRuleKind, position scanner.Position, name string, options []*proto.Option) {
+	if kind != v.rule.Kind {
+		return
+	}
+	if v.namingRegexp != nil && !v.namingRegexp.MatchString(name) {
+		v.AddFailuref(position, "%s", v.rule.Message)
+	}
+	for _, requiredOption := range v.rule.RequiredOptions {
+		if !optionIn(requiredOption, options) {
+			v.AddFailuref(position, "%s", v.rule.Message)
+		}
+	}
+	for _, option := range options {
+		if stringIn(option.Name, v.rule.ForbiddenOptions) {
+			v.AddFailuref(option.Position, "%s", v.rule.Message)
+		}
+	}
+}
+
+func splitOptions(elements []proto.Visitee) ([]*proto.Option, []proto.Visitee) {
+	var options []*proto.Option
+	var children []proto.Visitee
+	for _, element := range elements {
+		if option, ok := element.(*proto.Option); ok {
+			options = append(options, option)
+		} else {
+			children = append(children, element)
+		}
+	}
+	return options, children
+}
+
+func optionIn(name string, options []*proto.Option) bool {
+	for _, option := range options {
+		if option.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// packageIn returns true if the package is equal to or
+// a sub-package of one of the packages.
+func packageIn(packageName string, packageNames []string) bool {
+	for _, other := range packageNames {
+		if packageName == other || strings.HasPrefix(packageName, other+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixIn(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
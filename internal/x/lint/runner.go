@@ -21,6 +21,7 @@
 package lint
 
 import (
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/tgrpc/prototool/internal/x/file"
 	"github.com/tgrpc/prototool/internal/x/text"
 	"go.uber.org/zap"
@@ -40,22 +41,34 @@ func newRunner(options ...RunnerOption) *runner {
 	return runner
 }
 
-func (r *runner) Run(protoSets ...*file.ProtoSet) ([]*text.Failure, error) {
+func (r *runner) Run(fileDescriptorSets []*descriptor.FileDescriptorSet, protoSets ...*file.ProtoSet) ([]*text.Failure, error) {
 	var failures []*text.Failure
 	for _, protoSet := range protoSets {
 		checkers, err := GetCheckers(protoSet.Config.Lint)
 		if err != nil {
 			return nil, err
 		}
+		allCheckers, err := GetAllCheckers(protoSet.Config.Lint)
+		if err != nil {
+			return nil, err
+		}
 		dirPathToDescriptors, err := GetDirPathToDescriptors(protoSet)
 		if err != nil {
 			return nil, err
 		}
-		iFailures, err := CheckMultiple(checkers, dirPathToDescriptors, protoSet.Config.Lint.IgnoreIDToFilePaths)
+		var dirPathToDescriptorFiles map[string][]*DescriptorFile
+		if len(GetDescriptorCheckers(checkers)) > 0 {
+			dirPathToDescriptorFiles, err = GetDirPathToDescriptorFiles(protoSet, fileDescriptorSets)
+			if err != nil {
+				return nil, err
+			}
+		}
+		iFailures, err := CheckMultiple(checkers, allCheckers, dirPathToDescriptors, dirPathToDescriptorFiles, protoSet.Config.Lint.IgnoreIDToFilePaths)
 		if err != nil {
 			return nil, err
 		}
 		failures = append(failures, iFailures...)
 	}
+	text.SortFailures(failures)
 	return failures, nil
 }
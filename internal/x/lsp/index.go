@@ -0,0 +1,299 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/extract"
+	"github.com/tgrpc/prototool/internal/x/lint"
+)
+
+// occurrence is a definition of or reference to an element in a file.
+type occurrence struct {
+	// The fully-qualified name without a leading dot.
+	FullName     string
+	FilePath     string
+	Range        lspRange
+	IsDefinition bool
+	// The kind of element, such as message.
+	// Only set for definitions.
+	Kind string
+	// The leading comments, with the comment markers removed.
+	// Only set for definitions.
+	Comments string
+}
+
+// index is an index of the occurrences in compiled files.
+//
+// Elements are resolved to the files that define them with an extract.Getter.
+type index struct {
+	getter             extract.Getter
+	fileDescriptorSets []*descriptor.FileDescriptorSet
+	// from the name of a FileDescriptorProto to the absolute path of the file
+	fileNameToFilePath    map[string]string
+	filePathToOccurrences map[string][]*occurrence
+}
+
+func newIndex(getter extract.Getter, descriptorFiles []*lint.DescriptorFile, columnConverter *columnConverter) *index {
+	index := &index{
+		getter:                getter,
+		fileNameToFilePath:    make(map[string]string),
+		filePathToOccurrences: make(map[string][]*occurrence),
+	}
+	seenFileDescriptorSets := make(map[*descriptor.FileDescriptorSet]struct{})
+	for _, descriptorFile := range descriptorFiles {
+		index.fileNameToFilePath[descriptorFile.GetName()] = descriptorFile.Path
+		if descriptorFile.FileDescriptorSet != nil {
+			if _, ok := seenFileDescriptorSets[descriptorFile.FileDescriptorSet]; !ok {
+				seenFileDescriptorSets[descriptorFile.FileDescriptorSet] = struct{}{}
+				index.fileDescriptorSets = append(index.fileDescriptorSets, descriptorFile.FileDescriptorSet)
+			}
+		}
+		newIndexBuilder(index, descriptorFile, columnConverter).addFile(descriptorFile.FileDescriptorProto)
+	}
+	return index
+}
+
+// occurrenceAt returns the innermost occurrence at the position in the file,
+// or nil if there is no occurrence at the position.
+func (i *index) occurrenceAt(filePath string, position position) *occurrence {
+	var found *occurrence
+	for _, occurrence := range i.filePathToOccurrences[filePath] {
+		if !occurrence.Range.contains(position) {
+			continue
+		}
+		if found == nil || found.Range.Start.before(occurrence.Range.Start) {
+			found = occurrence
+		}
+	}
+	return found
+}
+
+// definition returns the definition of the element with the given name,
+// or nil if the element is not defined in the indexed files.
+func (i *index) definition(fullName string) *occurrence {
+	for _, fileName := range i.getDefinitionFileNames(fullName) {
+		for _, occurrence := range i.filePathToOccurrences[i.fileNameToFilePath[fileName]] {
+			if occurrence.IsDefinition && occurrence.FullName == fullName {
+				return occurrence
+			}
+		}
+	}
+	return nil
+}
+
+// getDefinitionFileNames returns the names of the files that may define
+// the element with the given name.
+func (i *index) getDefinitionFileNames(fullName string) []string {
+	if message, err := i.getter.GetMessage(i.fileDescriptorSets, fullName); err == nil {
+		return []string{message.FileDescriptorProto.GetName()}
+	}
+	if enum, err := i.getter.GetEnum(i.fileDescriptorSets, fullName); err == nil {
+		return []string{enum.FileDescriptorProto.GetName()}
+	}
+	if service, err := i.getter.GetService(i.fileDescriptorSets, fullName); err == nil {
+		return []string{service.FileDescriptorProto.GetName()}
+	}
+	if field, err := i.getter.GetField(i.fileDescriptorSets, fullName); err == nil {
+		return []string{field.FileDescriptorProto.GetName()}
+	}
+	// RPCs are in services, and enum values and extensions are in the
+	// scope of a message or a package
+	var scope string
+	if j := strings.LastIndex(fullName, "."); j > 0 {
+		scope = fullName[:j]
+	}
+	if service, err := i.getter.GetService(i.fileDescriptorSets, scope); err == nil {
+		return []string{service.FileDescriptorProto.GetName()}
+	}
+	if message, err := i.getter.GetMessage(i.fileDescriptorSets, scope); err == nil {
+		return []string{message.FileDescriptorProto.GetName()}
+	}
+	var fileNames []string
+	for _, fileDescriptorSet := range i.fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			if fileDescriptorProto.GetPackage() == scope {
+				fileNames = append(fileNames, fileDescriptorProto.GetName())
+			}
+		}
+	}
+	return fileNames
+}
+
+// references returns the occurrences of the element with the given name
+// in all files, sorted by file path and position.
+func (i *index) references(fullName string, includeDefinition bool) []*occurrence {
+	var references []*occurrence
+	for _, occurrences := range i.filePathToOccurrences {
+		for _, occurrence := range occurrences {
+			if occurrence.FullName != fullName || (occurrence.IsDefinition && !includeDefinition) {
+				continue
+			}
+			references = append(references, occurrence)
+		}
+	}
+	sort.Slice(references, func(j int, k int) bool {
+		if references[j].FilePath != references[k].FilePath {
+			return references[j].FilePath < references[k].FilePath
+		}
+		return references[j].Range.Start.before(references[k].Range.Start)
+	})
+	return references
+}
+
+type indexBuilder struct {
+	index           *index
+	filePath        string
+	columnConverter *columnConverter
+	pathToLocation  map[string]*descriptor.SourceCodeInfo_Location
+}
+
+func newIndexBuilder(index *index, descriptorFile *lint.DescriptorFile, columnConverter *columnConverter) *indexBuilder {
+	pathToLocation := desc.GetPathToLocation(descriptorFile.FileDescriptorProto)
+	return &indexBuilder{
+		index:           index,
+		filePath:        descriptorFile.Path,
+		columnConverter: columnConverter,
+		pathToLocation:  pathToLocation,
+	}
+}
+func (b *indexBuilder) addFile(fileDescriptorProto *descriptor.FileDescriptorProto) {
+	scope := fileDescriptorProto.GetPackage()
+	for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+		b.addMessage(scope, descriptorProto, []int32{desc.FileMessageTypeTag, int32(i)})
+	}
+	for i, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+		b.addEnum(scope, enumDescriptorProto, []int32{desc.FileEnumTypeTag, int32(i)})
+	}
+	for i, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		b.addService(scope, serviceDescriptorProto, []int32{desc.FileServiceTag, int32(i)})
+	}
+	for i, fieldDescriptorProto := range fileDescriptorProto.GetExtension() {
+		b.addField(scope, fieldDescriptorProto, []int32{desc.FileExtensionTag, int32(i)})
+	}
+}
+
+func (b *indexBuilder) addMessage(scope string, descriptorProto *descriptor.DescriptorProto, path []int32) {
+	fullName := desc.GetFullName(scope, descriptorProto.GetName())
+	b.addDefinition("message", fullName, path)
+	for i, fieldDescriptorProto := range descriptorProto.GetField() {
+		b.addField(fullName, fieldDescriptorProto, desc.AppendPath(path, desc.MessageFieldTag, int32(i)))
+	}
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		b.addMessage(fullName, nestedDescriptorProto, desc.AppendPath(path, desc.MessageNestedTypeTag, int32(i)))
+	}
+	for i, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		b.addEnum(fullName, enumDescriptorProto, desc.AppendPath(path, desc.MessageEnumTypeTag, int32(i)))
+	}
+	for i, fieldDescriptorProto := range descriptorProto.GetExtension() {
+		b.addField(fullName, fieldDescriptorProto, desc.AppendPath(path, desc.MessageExtensionTag, int32(i)))
+	}
+}
+
+func (b *indexBuilder) addField(scope string, fieldDescriptorProto *descriptor.FieldDescriptorProto, path []int32) {
+	b.addDefinition("field", desc.GetFullName(scope, fieldDescriptorProto.GetName()), path)
+	b.addReference(fieldDescriptorProto.GetTypeName(), desc.AppendPath(path, desc.FieldTypeNameTag))
+	b.addReference(fieldDescriptorProto.GetExtendee(), desc.AppendPath(path, desc.FieldExtendeeTag))
+}
+
+func (b *indexBuilder) addEnum(scope string, enumDescriptorProto *descriptor.EnumDescriptorProto, path []int32) {
+	b.addDefinition("enum", desc.GetFullName(scope, enumDescriptorProto.GetName()), path)
+	// enum values are siblings of their enum
+	for i, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		b.addDefinition("enum value", desc.GetFullName(scope, enumValueDescriptorProto.GetName()), desc.AppendPath(path, desc.EnumValueTag, int32(i)))
+	}
+}
+
+func (b *indexBuilder) addService(scope string, serviceDescriptorProto *descriptor.ServiceDescriptorProto, path []int32) {
+	fullName := desc.GetFullName(scope, serviceDescriptorProto.GetName())
+	b.addDefinition("service", fullName, path)
+	for i, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+		methodPath := desc.AppendPath(path, desc.ServiceMethodTag, int32(i))
+		b.addDefinition("rpc", desc.GetFullName(fullName, methodDescriptorProto.GetName()), methodPath)
+		b.addReference(methodDescriptorProto.GetInputType(), desc.AppendPath(methodPath, desc.MethodInputTypeTag))
+		b.addReference(methodDescriptorProto.GetOutputType(), desc.AppendPath(methodPath, desc.MethodOutputTypeTag))
+	}
+}
+
+// addDefinition adds a definition of the element at the path, with the
+// range of the name of the element.
+//
+// Elements without source code info, such as map entries, are not added.
+func (b *indexBuilder) addDefinition(kind string, fullName string, path []int32) {
+	location, ok := b.pathToLocation[desc.GetPathKey(path)]
+	if !ok {
+		return
+	}
+	nameLocation, ok := b.pathToLocation[desc.GetPathKey(desc.AppendPath(path, desc.NameTag))]
+	if !ok {
+		nameLocation = location
+	}
+	definition := b.addOccurrence(fullName, nameLocation, true)
+	definition.Kind = kind
+	definition.Comments = desc.GetComments(location.GetLeadingComments())
+}
+
+// addReference adds a reference to the fully-qualified type name at the path.
+func (b *indexBuilder) addReference(typeName string, path []int32) {
+	if typeName == "" {
+		return
+	}
+	location, ok := b.pathToLocation[desc.GetPathKey(path)]
+	if !ok {
+		return
+	}
+	b.addOccurrence(strings.TrimPrefix(typeName, "."), location, false)
+}
+
+func (b *indexBuilder) addOccurrence(fullName string, location *descriptor.SourceCodeInfo_Location, isDefinition bool) *occurrence {
+	occurrence := &occurrence{
+		FullName:     fullName,
+		FilePath:     b.filePath,
+		Range:        b.getSpanRange(location.GetSpan()),
+		IsDefinition: isDefinition,
+	}
+	b.index.filePathToOccurrences[b.filePath] = append(b.index.filePathToOccurrences[b.filePath], occurrence)
+	return occurrence
+}
+
+// getSpanRange returns the range for a span, which is either
+// [startLine, startColumn, endLine, endColumn] or
+// [startLine, startColumn, endColumn] if on one line.
+func (b *indexBuilder) getSpanRange(span []int32) lspRange {
+	switch len(span) {
+	case 3:
+		return lspRange{
+			Start: b.columnConverter.getPosition(b.filePath, int(span[0]), int(span[1])),
+			End:   b.columnConverter.getPosition(b.filePath, int(span[0]), int(span[2])),
+		}
+	case 4:
+		return lspRange{
+			Start: b.columnConverter.getPosition(b.filePath, int(span[0]), int(span[1])),
+			End:   b.columnConverter.getPosition(b.filePath, int(span[2]), int(span[3])),
+		}
+	default:
+		return lspRange{}
+	}
+}
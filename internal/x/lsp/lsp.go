@@ -0,0 +1,106 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package lsp implements a Language Server Protocol server for Protobuf files.
+//
+// The server communicates with JSON-RPC over a reader and writer, which
+// are usually stdin and stdout. Diagnostics are published when files are
+// opened and saved, and formatting, go to definition, hover and find
+// references are supported using the results of the last check of a file.
+// Configs are reloaded when the client reports that a prototool.yaml
+// file changed.
+//
+// See https://microsoft.github.io/language-server-protocol/specification.
+package lsp
+
+import (
+	"io"
+
+	"github.com/tgrpc/prototool/internal/x/lint"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"go.uber.org/zap"
+)
+
+// CheckResult is the result of a check.
+type CheckResult struct {
+	// The failures from compiling and linting.
+	// Filenames must be absolute paths.
+	Failures []*text.Failure
+	// The compiled files, which are used to find definitions and references.
+	// Paths must be absolute.
+	// Will not be set if there are any compile failures.
+	DescriptorFiles []*lint.DescriptorFile
+	// Whether columns count runes, as for the Golang compile backend,
+	// instead of bytes, as for protoc.
+	RuneColumns bool
+}
+
+// Handler does the work for a Server.
+type Handler interface {
+	// Check compiles and lints all files associated with the file
+	// at the given absolute path.
+	//
+	// Compile and lint failures are returned in the CheckResult
+	// and there will be no error.
+	Check(filePath string) (*CheckResult, error)
+	// Format formats the data for the file at the given absolute path.
+	//
+	// The file may have unsaved changes, so the data is not read from the file.
+	Format(filePath string, data []byte) ([]byte, []*text.Failure, error)
+	// ReloadConfigs clears any cached configs, so that the next
+	// check or format reads the prototool.yaml files again.
+	ReloadConfigs()
+}
+
+// Server is a language server.
+type Server interface {
+	// Serve reads requests from the reader and writes responses to the writer
+	// until an exit notification is received or the reader is closed.
+	//
+	// This is not thread-safe.
+	Serve(reader io.Reader, writer io.Writer) error
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithLogger returns a ServerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ServerWithLogger(logger *zap.Logger) ServerOption {
+	return func(server *server) {
+		server.logger = logger
+	}
+}
+
+// ServerWithFormatOnSave returns a ServerOption that formats files
+// before they are saved, for clients that support it.
+//
+// The default is to only format files when the client asks to.
+func ServerWithFormatOnSave() ServerOption {
+	return func(server *server) {
+		server.formatOnSave = true
+	}
+}
+
+// NewServer returns a new Server that uses the given Handler.
+func NewServer(handler Handler, options ...ServerOption) Server {
+	return newServer(handler, options...)
+}
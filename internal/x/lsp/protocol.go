@@ -0,0 +1,419 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// Values used in the protocol.
+const (
+	textDocumentSyncKindFull = 1
+
+	diagnosticSeverityError   = 1
+	diagnosticSeverityWarning = 2
+
+	messageTypeError = 1
+
+	markupKindMarkdown = "markdown"
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+func newResponseErrorf(code int, format string, args ...interface{}) *responseError {
+	return &responseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+func (p position) before(other position) bool {
+	return p.Line < other.Line || (p.Line == other.Line && p.Character < other.Character)
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// contains returns true if the position is within the range, including the end
+// so that a cursor directly after a name still refers to the name.
+func (r lspRange) contains(p position) bool {
+	return !p.before(r.Start) && !r.End.before(p)
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type textDocumentContentChangeEvent struct {
+	Range *lspRange `json:"range,omitempty"`
+	Text  string    `json:"text"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   versionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []textDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type didSaveTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type didCloseTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type willSaveTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Reason       int                    `json:"reason"`
+}
+
+type fileEvent struct {
+	URI  string `json:"uri"`
+	Type int    `json:"type"`
+}
+
+type didChangeWatchedFilesParams struct {
+	Changes []fileEvent `json:"changes"`
+}
+
+type fileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+type didChangeWatchedFilesRegistrationOptions struct {
+	Watchers []fileSystemWatcher `json:"watchers"`
+}
+
+type registration struct {
+	ID              string      `json:"id"`
+	Method          string      `json:"method"`
+	RegisterOptions interface{} `json:"registerOptions,omitempty"`
+}
+
+type registrationParams struct {
+	Registrations []registration `json:"registrations"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type referenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+type referenceParams struct {
+	textDocumentPositionParams
+	Context referenceContext `json:"context"`
+}
+
+type documentFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type showMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *lspRange     `json:"range,omitempty"`
+}
+
+type saveOptions struct {
+	IncludeText bool `json:"includeText"`
+}
+
+type textDocumentSyncOptions struct {
+	OpenClose         bool         `json:"openClose"`
+	Change            int          `json:"change"`
+	WillSaveWaitUntil bool         `json:"willSaveWaitUntil,omitempty"`
+	Save              *saveOptions `json:"save"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync           *textDocumentSyncOptions `json:"textDocumentSync"`
+	DocumentFormattingProvider bool                     `json:"documentFormattingProvider"`
+	DefinitionProvider         bool                     `json:"definitionProvider"`
+	HoverProvider              bool                     `json:"hoverProvider"`
+	ReferencesProvider         bool                     `json:"referencesProvider"`
+}
+
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type dynamicRegistrationCapabilities struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+type workspaceClientCapabilities struct {
+	DidChangeWatchedFiles dynamicRegistrationCapabilities `json:"didChangeWatchedFiles"`
+}
+
+type clientCapabilities struct {
+	Workspace workspaceClientCapabilities `json:"workspace"`
+}
+
+type initializeParams struct {
+	Capabilities clientCapabilities `json:"capabilities"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+	ServerInfo   serverInfo         `json:"serverInfo"`
+}
+
+// readMessage reads a message with a Content-Length header.
+//
+// Returns io.EOF if the reader is closed before a message is started.
+func readMessage(reader *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && contentLength == -1 {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		split := strings.SplitN(line, ":", 2)
+		if len(split) != 2 {
+			return nil, fmt.Errorf("invalid header: %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(split[0]), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(split[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %q", line)
+			}
+		}
+	}
+	if contentLength < 0 {
+		return nil, fmt.Errorf("no Content-Length header")
+	}
+	data := make([]byte, contentLength)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeMessage writes the value as JSON with a Content-Length header.
+func writeMessage(writer io.Writer, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// columnConverter converts the columns reported by compilers to characters,
+// which are counted in UTF-16 code units in the protocol.
+//
+// Columns count bytes for protoc, or runes if runeColumns is set, and tabs
+// advance the column to the next multiple of 8 for both.
+type columnConverter struct {
+	runeColumns bool
+	// gets the lines of the file at the absolute path, or nil if unknown
+	getLines        func(string) []string
+	filePathToLines map[string][]string
+}
+
+func newColumnConverter(runeColumns bool, getLines func(string) []string) *columnConverter {
+	return &columnConverter{
+		runeColumns:     runeColumns,
+		getLines:        getLines,
+		filePathToLines: make(map[string][]string),
+	}
+}
+
+// getPosition returns the position for the zero-based line and column
+// in the file.
+//
+// If the line is not known, the column is used as is.
+func (c *columnConverter) getPosition(filePath string, line int, column int) position {
+	lines, ok := c.filePathToLines[filePath]
+	if !ok {
+		lines = c.getLines(filePath)
+		c.filePathToLines[filePath] = lines
+	}
+	if line >= len(lines) {
+		return position{Line: line, Character: column}
+	}
+	character := 0
+	lineColumn := 0
+	for i := 0; i < len(lines[line]) && lineColumn < column; {
+		r, size := utf8.DecodeRuneInString(lines[line][i:])
+		switch {
+		case r == '\t':
+			lineColumn += 8 - lineColumn%8
+		case c.runeColumns:
+			lineColumn++
+		default:
+			lineColumn += size
+		}
+		character += getUTF16Length(r)
+		i += size
+	}
+	return position{Line: line, Character: character}
+}
+
+// getUTF16Character returns the character at the end of the line.
+func getUTF16Character(line string) int {
+	character := 0
+	for _, r := range line {
+		character += getUTF16Length(r)
+	}
+	return character
+}
+
+func getUTF16Length(r rune) int {
+	if r >= 0x10000 {
+		// encoded as a surrogate pair
+		return 2
+	}
+	return 1
+}
+
+func uriToFilePath(uri string) (string, error) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if parsedURI.Scheme != "file" {
+		return "", fmt.Errorf("only file URIs are supported but got %q", uri)
+	}
+	return filepath.Clean(filepath.FromSlash(parsedURI.Path)), nil
+}
+
+func filePathToURI(filePath string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filePath)}).String()
+}
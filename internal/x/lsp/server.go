@@ -0,0 +1,588 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tgrpc/prototool/internal/x/extract"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"github.com/tgrpc/prototool/internal/x/vars"
+	"go.uber.org/zap"
+)
+
+var errExitWithoutShutdown = errors.New("exit notification received without shutdown request")
+
+type server struct {
+	handler      Handler
+	logger       *zap.Logger
+	formatOnSave bool
+	getter       extract.Getter
+
+	writer io.Writer
+	// the contents of open files, which may have unsaved changes
+	filePathToData map[string]string
+	// the versions of open files
+	filePathToVersion map[string]int
+	// the index from the last successful compile of each file
+	filePathToIndex map[string]*index
+	// the version of each file at its last check, so that a file
+	// that failed to compile is not checked again until it changes
+	filePathToCheckedVersion map[string]int
+	// the files that have diagnostics published from the last check
+	diagnosedFilePaths map[string]struct{}
+	// whether the client can register for watched file changes
+	canRegisterWatchedFiles bool
+	shutdown                bool
+}
+
+func newServer(handler Handler, options ...ServerOption) *server {
+	server := &server{
+		handler: handler,
+		logger:  zap.NewNop(),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	server.getter = extract.NewGetter(
+		extract.GetterWithLogger(server.logger),
+	)
+	return server
+}
+
+func (s *server) Serve(reader io.Reader, writer io.Writer) error {
+	s.writer = writer
+	s.filePathToData = make(map[string]string)
+	s.filePathToVersion = make(map[string]int)
+	s.filePathToIndex = make(map[string]*index)
+	s.filePathToCheckedVersion = make(map[string]int)
+	s.diagnosedFilePaths = make(map[string]struct{})
+	s.canRegisterWatchedFiles = false
+	s.shutdown = false
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		data, err := readMessage(bufferedReader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		request := &request{}
+		if err := json.Unmarshal(data, request); err != nil {
+			if err := s.writeError(nil, newResponseErrorf(codeParseError, "%v", err)); err != nil {
+				return err
+			}
+			continue
+		}
+		// responses to our requests, which we do not need
+		if request.Method == "" {
+			continue
+		}
+		if request.Method == "exit" {
+			if !s.shutdown {
+				return errExitWithoutShutdown
+			}
+			return nil
+		}
+		s.logger.Debug("handling", zap.String("method", request.Method))
+		result, err := s.handle(request.Method, request.Params)
+		// notifications do not have responses
+		if request.ID == nil {
+			if err != nil {
+				s.logger.Error("notification failed", zap.String("method", request.Method), zap.Error(err))
+			}
+			continue
+		}
+		if err != nil {
+			responseErr, ok := err.(*responseError)
+			if !ok {
+				responseErr = newResponseErrorf(codeInternalError, "%v", err)
+			}
+			if err := s.writeError(request.ID, responseErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeMessage(s.writer, &response{JSONRPC: "2.0", ID: request.ID, Result: result}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle handles the method, returning the result for requests.
+func (s *server) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		initializeParams := &initializeParams{}
+		if err := unmarshalParams(params, initializeParams); err != nil {
+			return nil, err
+		}
+		return s.initialize(initializeParams)
+	case "initialized":
+		return nil, s.initialized()
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		didOpenParams := &didOpenTextDocumentParams{}
+		if err := unmarshalParams(params, didOpenParams); err != nil {
+			return nil, err
+		}
+		return nil, s.didOpen(didOpenParams)
+	case "textDocument/didChange":
+		didChangeParams := &didChangeTextDocumentParams{}
+		if err := unmarshalParams(params, didChangeParams); err != nil {
+			return nil, err
+		}
+		return nil, s.didChange(didChangeParams)
+	case "textDocument/didSave":
+		didSaveParams := &didSaveTextDocumentParams{}
+		if err := unmarshalParams(params, didSaveParams); err != nil {
+			return nil, err
+		}
+		return nil, s.didSave(didSaveParams)
+	case "textDocument/didClose":
+		didCloseParams := &didCloseTextDocumentParams{}
+		if err := unmarshalParams(params, didCloseParams); err != nil {
+			return nil, err
+		}
+		return nil, s.didClose(didCloseParams)
+	case "textDocument/willSaveWaitUntil":
+		willSaveParams := &willSaveTextDocumentParams{}
+		if err := unmarshalParams(params, willSaveParams); err != nil {
+			return nil, err
+		}
+		return s.formatting(&documentFormattingParams{TextDocument: willSaveParams.TextDocument})
+	case "workspace/didChangeWatchedFiles":
+		didChangeWatchedFilesParams := &didChangeWatchedFilesParams{}
+		if err := unmarshalParams(params, didChangeWatchedFilesParams); err != nil {
+			return nil, err
+		}
+		return nil, s.didChangeWatchedFiles(didChangeWatchedFilesParams)
+	case "textDocument/formatting":
+		formattingParams := &documentFormattingParams{}
+		if err := unmarshalParams(params, formattingParams); err != nil {
+			return nil, err
+		}
+		return s.formatting(formattingParams)
+	case "textDocument/definition":
+		positionParams := &textDocumentPositionParams{}
+		if err := unmarshalParams(params, positionParams); err != nil {
+			return nil, err
+		}
+		return s.definition(positionParams)
+	case "textDocument/hover":
+		positionParams := &textDocumentPositionParams{}
+		if err := unmarshalParams(params, positionParams); err != nil {
+			return nil, err
+		}
+		return s.hover(positionParams)
+	case "textDocument/references":
+		referenceParams := &referenceParams{}
+		if err := unmarshalParams(params, referenceParams); err != nil {
+			return nil, err
+		}
+		return s.references(referenceParams)
+	default:
+		return nil, newResponseErrorf(codeMethodNotFound, "method not supported: %s", method)
+	}
+}
+
+func (s *server) initialize(params *initializeParams) (*initializeResult, error) {
+	s.canRegisterWatchedFiles = params.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync: &textDocumentSyncOptions{
+				OpenClose:         true,
+				Change:            textDocumentSyncKindFull,
+				WillSaveWaitUntil: s.formatOnSave,
+				Save:              &saveOptions{},
+			},
+			DocumentFormattingProvider: true,
+			DefinitionProvider:         true,
+			HoverProvider:              true,
+			ReferencesProvider:         true,
+		},
+		ServerInfo: serverInfo{
+			Name:    "prototool",
+			Version: vars.Version,
+		},
+	}, nil
+}
+
+// initialized registers for changes to config files, so that cached
+// configs can be reloaded, if the client supports it.
+//
+// Clients that do not support registration may still send the changes.
+func (s *server) initialized() error {
+	if !s.canRegisterWatchedFiles {
+		return nil
+	}
+	params, err := json.Marshal(&registrationParams{
+		Registrations: []registration{
+			{
+				ID:     "prototool-watched-files",
+				Method: "workspace/didChangeWatchedFiles",
+				RegisterOptions: &didChangeWatchedFilesRegistrationOptions{
+					Watchers: []fileSystemWatcher{
+						{GlobPattern: "**/" + settings.DefaultConfigFilename},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	id := json.RawMessage(`"prototool-register-watched-files"`)
+	return writeMessage(s.writer, &request{
+		JSONRPC: "2.0",
+		ID:      &id,
+		Method:  "client/registerCapability",
+		Params:  params,
+	})
+}
+
+func (s *server) didOpen(params *didOpenTextDocumentParams) error {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	s.filePathToData[filePath] = params.TextDocument.Text
+	s.filePathToVersion[filePath] = params.TextDocument.Version
+	return s.check(filePath)
+}
+
+func (s *server) didChange(params *didChangeTextDocumentParams) error {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	// we only support full document sync, so the last change is the full contents
+	for _, contentChange := range params.ContentChanges {
+		if contentChange.Range != nil {
+			return fmt.Errorf("incremental changes are not supported")
+		}
+		s.filePathToData[filePath] = contentChange.Text
+	}
+	s.filePathToVersion[filePath] = params.TextDocument.Version
+	return nil
+}
+
+func (s *server) didSave(params *didSaveTextDocumentParams) error {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	return s.check(filePath)
+}
+
+func (s *server) didClose(params *didCloseTextDocumentParams) error {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return err
+	}
+	delete(s.filePathToData, filePath)
+	delete(s.filePathToVersion, filePath)
+	delete(s.filePathToCheckedVersion, filePath)
+	return nil
+}
+
+// didChangeWatchedFiles reloads the configs if a config file changed,
+// and checks the open files again with the new configs.
+func (s *server) didChangeWatchedFiles(params *didChangeWatchedFilesParams) error {
+	configChanged := false
+	for _, change := range params.Changes {
+		filePath, err := uriToFilePath(change.URI)
+		if err != nil {
+			return err
+		}
+		if filepath.Base(filePath) == settings.DefaultConfigFilename {
+			configChanged = true
+			break
+		}
+	}
+	if !configChanged {
+		return nil
+	}
+	s.logger.Debug("config changed, reloading configs")
+	s.handler.ReloadConfigs()
+	s.filePathToIndex = make(map[string]*index)
+	s.filePathToCheckedVersion = make(map[string]int)
+	filePaths := make([]string, 0, len(s.filePathToData))
+	for filePath := range s.filePathToData {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	for _, filePath := range filePaths {
+		if err := s.check(filePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) formatting(params *documentFormattingParams) ([]textEdit, error) {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := s.filePathToData[filePath]
+	if !ok {
+		fileData, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		data = string(fileData)
+	}
+	formattedData, failures, err := s.handler.Format(filePath, []byte(data))
+	if err != nil {
+		return nil, err
+	}
+	// the file does not parse, which will be reported by diagnostics
+	if len(failures) > 0 {
+		return nil, nil
+	}
+	if string(formattedData) == data {
+		return []textEdit{}, nil
+	}
+	lines := strings.Split(data, "\n")
+	lastLine := lines[len(lines)-1]
+	return []textEdit{
+		{
+			Range: lspRange{
+				End: position{
+					Line:      len(lines) - 1,
+					Character: getUTF16Character(lastLine),
+				},
+			},
+			NewText: string(formattedData),
+		},
+	}, nil
+}
+
+func (s *server) definition(params *textDocumentPositionParams) (*location, error) {
+	index, occurrence, err := s.getOccurrence(params)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+	definition := index.definition(occurrence.FullName)
+	if definition == nil {
+		return nil, nil
+	}
+	return &location{
+		URI:   filePathToURI(definition.FilePath),
+		Range: definition.Range,
+	}, nil
+}
+
+func (s *server) hover(params *textDocumentPositionParams) (*hover, error) {
+	index, occurrence, err := s.getOccurrence(params)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+	definition := index.definition(occurrence.FullName)
+	if definition == nil {
+		return nil, nil
+	}
+	value := fmt.Sprintf("```proto\n%s %s\n```", definition.Kind, definition.FullName)
+	if definition.Comments != "" {
+		value = value + "\n\n" + definition.Comments
+	}
+	return &hover{
+		Contents: markupContent{
+			Kind:  markupKindMarkdown,
+			Value: value,
+		},
+		Range: &occurrence.Range,
+	}, nil
+}
+
+func (s *server) references(params *referenceParams) ([]location, error) {
+	index, occurrence, err := s.getOccurrence(&params.textDocumentPositionParams)
+	if err != nil || occurrence == nil {
+		return nil, err
+	}
+	references := index.references(occurrence.FullName, params.Context.IncludeDeclaration)
+	locations := make([]location, len(references))
+	for i, reference := range references {
+		locations[i] = location{
+			URI:   filePathToURI(reference.FilePath),
+			Range: reference.Range,
+		}
+	}
+	return locations, nil
+}
+
+// getOccurrence gets the occurrence at the position, checking the file
+// if it has not been compiled yet.
+//
+// If the file did not compile at its last check, it is only checked again
+// if it changed since, so that failing compiles are not repeated.
+//
+// The occurrence is nil if there is none at the position.
+func (s *server) getOccurrence(params *textDocumentPositionParams) (*index, *occurrence, error) {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+	index, ok := s.filePathToIndex[filePath]
+	if !ok {
+		if checkedVersion, ok := s.filePathToCheckedVersion[filePath]; ok && checkedVersion == s.filePathToVersion[filePath] {
+			return nil, nil, nil
+		}
+		if err := s.check(filePath); err != nil {
+			return nil, nil, err
+		}
+		if index, ok = s.filePathToIndex[filePath]; !ok {
+			return nil, nil, nil
+		}
+	}
+	return index, index.occurrenceAt(filePath, params.Position), nil
+}
+
+// check checks the file and publishes diagnostics for all files that were
+// checked, clearing diagnostics for files that no longer have failures.
+//
+// If the check fails, the error is shown to the user and not returned,
+// as there is nothing more the client can do.
+func (s *server) check(filePath string) error {
+	s.filePathToCheckedVersion[filePath] = s.filePathToVersion[filePath]
+	checkResult, err := s.handler.Check(filePath)
+	if err != nil {
+		s.logger.Error("check failed", zap.String("file", filePath), zap.Error(err))
+		return s.notify("window/showMessage", &showMessageParams{
+			Type:    messageTypeError,
+			Message: fmt.Sprintf("prototool: %v", err),
+		})
+	}
+	columnConverter := newColumnConverter(checkResult.RuneColumns, s.getLines)
+	if len(checkResult.DescriptorFiles) > 0 {
+		index := newIndex(s.getter, checkResult.DescriptorFiles, columnConverter)
+		for _, descriptorFile := range checkResult.DescriptorFiles {
+			s.filePathToIndex[descriptorFile.Path] = index
+		}
+	}
+	filePathToDiagnostics := make(map[string][]diagnostic)
+	for _, failure := range checkResult.Failures {
+		if failure.Filename == "" {
+			s.logger.Warn("failure without filename", zap.String("failure", failure.String()))
+			continue
+		}
+		filePathToDiagnostics[failure.Filename] = append(filePathToDiagnostics[failure.Filename], getDiagnostic(failure, columnConverter))
+	}
+	for filePath := range s.diagnosedFilePaths {
+		if _, ok := filePathToDiagnostics[filePath]; !ok {
+			filePathToDiagnostics[filePath] = []diagnostic{}
+		}
+	}
+	s.diagnosedFilePaths = make(map[string]struct{})
+	for _, filePath := range getSortedKeys(filePathToDiagnostics) {
+		diagnostics := filePathToDiagnostics[filePath]
+		if len(diagnostics) > 0 {
+			s.diagnosedFilePaths[filePath] = struct{}{}
+		}
+		if err := s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         filePathToURI(filePath),
+			Diagnostics: diagnostics,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLines gets the lines of the file, which are the contents of the open
+// file if the file is open, or otherwise the contents of the file on disk.
+//
+// Returns nil if the file cannot be read.
+func (s *server) getLines(filePath string) []string {
+	data, ok := s.filePathToData[filePath]
+	if !ok {
+		fileData, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil
+		}
+		data = string(fileData)
+	}
+	return strings.Split(data, "\n")
+}
+
+func (s *server) notify(method string, params interface{}) error {
+	return writeMessage(s.writer, &notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *server) writeError(id *json.RawMessage, responseErr *responseError) error {
+	return writeMessage(s.writer, &errorResponse{JSONRPC: "2.0", ID: id, Error: responseErr})
+}
+
+// getDiagnostic returns the diagnostic for the failure.
+//
+// Failures with an ID are from linters and are warnings,
+// other failures are from compiling and are errors.
+func getDiagnostic(failure *text.Failure, columnConverter *columnConverter) diagnostic {
+	line := 0
+	if failure.Line > 0 {
+		line = failure.Line - 1
+	}
+	column := 0
+	if failure.Column > 0 {
+		column = failure.Column - 1
+	}
+	start := columnConverter.getPosition(failure.Filename, line, column)
+	severity := diagnosticSeverityError
+	if failure.ID != "" {
+		severity = diagnosticSeverityWarning
+	}
+	return diagnostic{
+		Range:    lspRange{Start: start, End: start},
+		Severity: severity,
+		Code:     failure.ID,
+		Source:   "prototool",
+		Message:  failure.Message,
+	}
+}
+
+func unmarshalParams(params json.RawMessage, value interface{}) error {
+	if err := json.Unmarshal(params, value); err != nil {
+		return newResponseErrorf(codeInvalidParams, "%v", err)
+	}
+	return nil
+}
+
+func getSortedKeys(m map[string][]diagnostic) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
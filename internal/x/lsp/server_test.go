@@ -0,0 +1,395 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tgrpc/prototool/internal/x/lint"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+const (
+	testFooFilePath    = "/tmp/lsp/foo.proto"
+	testBarFilePath    = "/tmp/lsp/bar.proto"
+	testConfigFilePath = "/tmp/lsp/prototool.yaml"
+)
+
+var testFilenameToData = map[string]string{
+	"foo.proto": `syntax = "proto3";
+
+package foo;
+
+// Foo is a foo.
+message Foo {
+  int64 id = 1;
+}
+`,
+	"bar.proto": `syntax = "proto3";
+
+package foo;
+
+import "foo.proto";
+
+message Bar {
+  Foo foo = 1;
+  repeated Foo foos = 2;
+  /* ü😀 */ Foo other_foo = 3;
+}
+`,
+}
+
+func TestServe(t *testing.T) {
+	handler := newTestHandler(t)
+	fooURI := filePathToURI(testFooFilePath)
+	barURI := filePathToURI(testBarFilePath)
+	messages := testServe(
+		t,
+		handler,
+		newTestRequest(1, "initialize", map[string]interface{}{}),
+		newTestRequest(0, "initialized", map[string]interface{}{}),
+		newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: barURI, Text: testFilenameToData["bar.proto"]},
+		}),
+		// Foo in "Foo foo = 1;"
+		newTestRequest(2, "textDocument/definition", newTestPositionParams(barURI, 7, 3)),
+		newTestRequest(3, "textDocument/hover", newTestPositionParams(barURI, 7, 3)),
+		newTestRequest(4, "textDocument/references", &referenceParams{
+			textDocumentPositionParams: newTestPositionParams(fooURI, 5, 9),
+			Context:                    referenceContext{IncludeDeclaration: true},
+		}),
+		// not on a name
+		newTestRequest(5, "textDocument/definition", newTestPositionParams(barURI, 1, 0)),
+		newTestRequest(6, "textDocument/formatting", &documentFormattingParams{
+			TextDocument: textDocumentIdentifier{URI: barURI},
+		}),
+		newTestRequest(7, "foo/bar", map[string]interface{}{}),
+		newTestRequest(8, "shutdown", nil),
+		newTestRequest(0, "exit", nil),
+	)
+	require.Len(t, messages, 9)
+
+	assert.Equal(t, "prototool", messages[0]["result"].(map[string]interface{})["serverInfo"].(map[string]interface{})["name"])
+
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[1]["method"])
+	assert.Equal(t, barURI, messages[1]["params"].(map[string]interface{})["uri"])
+	diagnostics := messages[1]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, "FOO_ID", diagnostics[0].(map[string]interface{})["code"])
+	assert.Equal(t, float64(diagnosticSeverityWarning), diagnostics[0].(map[string]interface{})["severity"])
+	assert.Equal(t, map[string]interface{}{"line": float64(6), "character": float64(0)}, diagnostics[0].(map[string]interface{})["range"].(map[string]interface{})["start"])
+
+	assert.Equal(t, newTestLocation(fooURI, 5, 8, 5, 11), messages[2]["result"])
+
+	hover := messages[3]["result"].(map[string]interface{})
+	assert.Equal(t, "```proto\nmessage foo.Foo\n```\n\nFoo is a foo.", hover["contents"].(map[string]interface{})["value"])
+
+	assert.Equal(
+		t,
+		[]interface{}{
+			newTestLocation(barURI, 7, 2, 7, 5),
+			newTestLocation(barURI, 8, 11, 8, 14),
+			newTestLocation(barURI, 9, 12, 9, 15),
+			newTestLocation(fooURI, 5, 8, 5, 11),
+		},
+		messages[4]["result"],
+	)
+
+	assert.Nil(t, messages[5]["result"])
+	assert.Contains(t, messages[5], "result")
+
+	edits := messages[6]["result"].([]interface{})
+	require.Len(t, edits, 1)
+	assert.Equal(t, "formatted", edits[0].(map[string]interface{})["newText"])
+	assert.Equal(t, newTestLocation(barURI, 0, 0, 11, 0)["range"], edits[0].(map[string]interface{})["range"])
+
+	assert.Equal(t, float64(codeMethodNotFound), messages[7]["error"].(map[string]interface{})["code"])
+	assert.Contains(t, messages[8], "result")
+
+	// exit without shutdown is an error
+	err := newServer(handler).Serve(
+		bytes.NewReader(newTestMessages(t, newTestRequest(0, "exit", nil))),
+		bytes.NewBuffer(nil),
+	)
+	assert.Equal(t, errExitWithoutShutdown, err)
+}
+
+func TestServeUTF16(t *testing.T) {
+	handler := newTestHandler(t)
+	barURI := filePathToURI(testBarFilePath)
+	// the line up to Foo is 11 runes and 12 UTF-16 code units
+	handler.failures = []*text.Failure{
+		{Filename: testBarFilePath, Line: 10, Column: 12, Message: "foo"},
+	}
+	messages := testServe(
+		t,
+		handler,
+		newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: barURI, Text: testFilenameToData["bar.proto"]},
+		}),
+		// Foo in "/* ü😀 */ Foo other_foo = 3;"
+		newTestRequest(1, "textDocument/hover", newTestPositionParams(barURI, 9, 13)),
+	)
+	require.Len(t, messages, 2)
+	diagnostics := messages[0]["params"].(map[string]interface{})["diagnostics"].([]interface{})
+	require.Len(t, diagnostics, 1)
+	assert.Equal(t, map[string]interface{}{"line": float64(9), "character": float64(12)}, diagnostics[0].(map[string]interface{})["range"].(map[string]interface{})["start"])
+	assert.Equal(t, newTestLocation(barURI, 9, 12, 9, 15)["range"], messages[1]["result"].(map[string]interface{})["range"])
+}
+
+func TestServeCachesFailedCheck(t *testing.T) {
+	handler := newTestHandler(t)
+	handler.descriptorFiles = nil
+	barURI := filePathToURI(testBarFilePath)
+	testServe(
+		t,
+		handler,
+		newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: barURI, Version: 1, Text: testFilenameToData["bar.proto"]},
+		}),
+		newTestRequest(1, "textDocument/hover", newTestPositionParams(barURI, 7, 3)),
+		newTestRequest(2, "textDocument/definition", newTestPositionParams(barURI, 7, 3)),
+		newTestRequest(0, "textDocument/didChange", &didChangeTextDocumentParams{
+			TextDocument:   versionedTextDocumentIdentifier{URI: barURI, Version: 2},
+			ContentChanges: []textDocumentContentChangeEvent{{Text: testFilenameToData["bar.proto"]}},
+		}),
+		newTestRequest(3, "textDocument/hover", newTestPositionParams(barURI, 7, 3)),
+		newTestRequest(4, "textDocument/hover", newTestPositionParams(barURI, 7, 3)),
+	)
+	// once when opened and once for the new version
+	assert.Equal(t, 2, handler.checks)
+}
+
+func TestServeReloadsConfigs(t *testing.T) {
+	handler := newTestHandler(t)
+	barURI := filePathToURI(testBarFilePath)
+	messages := testServe(
+		t,
+		handler,
+		newTestRequest(1, "initialize", &initializeParams{
+			Capabilities: clientCapabilities{
+				Workspace: workspaceClientCapabilities{
+					DidChangeWatchedFiles: dynamicRegistrationCapabilities{DynamicRegistration: true},
+				},
+			},
+		}),
+		newTestRequest(0, "initialized", map[string]interface{}{}),
+		// the response to the registration
+		&request{JSONRPC: "2.0", ID: newTestRawID(`"prototool-register-watched-files"`)},
+		newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+			TextDocument: textDocumentItem{URI: barURI, Text: testFilenameToData["bar.proto"]},
+		}),
+		newTestRequest(0, "workspace/didChangeWatchedFiles", &didChangeWatchedFilesParams{
+			Changes: []fileEvent{{URI: filePathToURI(testFooFilePath), Type: 2}},
+		}),
+		newTestRequest(0, "workspace/didChangeWatchedFiles", &didChangeWatchedFilesParams{
+			Changes: []fileEvent{{URI: filePathToURI(testConfigFilePath), Type: 2}},
+		}),
+	)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "client/registerCapability", messages[1]["method"])
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[2]["method"])
+	assert.Equal(t, "textDocument/publishDiagnostics", messages[3]["method"])
+	assert.Equal(t, 1, handler.reloads)
+	assert.Equal(t, 2, handler.checks)
+}
+
+func TestServeFormatOnSave(t *testing.T) {
+	handler := newTestHandler(t)
+	barURI := filePathToURI(testBarFilePath)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, newServer(handler, ServerWithFormatOnSave()).Serve(
+		bytes.NewReader(newTestMessages(
+			t,
+			newTestRequest(1, "initialize", map[string]interface{}{}),
+			newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+				TextDocument: textDocumentItem{URI: barURI, Text: testFilenameToData["bar.proto"]},
+			}),
+			newTestRequest(2, "textDocument/willSaveWaitUntil", &willSaveTextDocumentParams{
+				TextDocument: textDocumentIdentifier{URI: barURI},
+				Reason:       1,
+			}),
+		)),
+		buffer,
+	))
+	messages := readTestMessages(t, buffer)
+	require.Len(t, messages, 3)
+	textDocumentSync := messages[0]["result"].(map[string]interface{})["capabilities"].(map[string]interface{})["textDocumentSync"].(map[string]interface{})
+	assert.Equal(t, true, textDocumentSync["willSaveWaitUntil"])
+	edits := messages[2]["result"].([]interface{})
+	require.Len(t, edits, 1)
+	assert.Equal(t, "formatted", edits[0].(map[string]interface{})["newText"])
+}
+
+func TestServeClearsDiagnostics(t *testing.T) {
+	handler := newTestHandler(t)
+	barURI := filePathToURI(testBarFilePath)
+	handler.failures = []*text.Failure{
+		{Filename: testBarFilePath, Line: 7, Column: 1, Message: "foo"},
+	}
+	buffer := bytes.NewBuffer(nil)
+	server := newServer(handler)
+	require.NoError(t, server.Serve(
+		bytes.NewReader(newTestMessages(
+			t,
+			newTestRequest(0, "textDocument/didOpen", &didOpenTextDocumentParams{
+				TextDocument: textDocumentItem{URI: barURI, Text: testFilenameToData["bar.proto"]},
+			}),
+		)),
+		buffer,
+	))
+	// keep the state from the last Serve by checking directly
+	handler.failures = nil
+	require.NoError(t, server.check(testBarFilePath))
+	messages := readTestMessages(t, buffer)
+	require.Len(t, messages, 2)
+	assert.Len(t, messages[0]["params"].(map[string]interface{})["diagnostics"], 1)
+	assert.Equal(t, barURI, messages[1]["params"].(map[string]interface{})["uri"])
+	assert.Len(t, messages[1]["params"].(map[string]interface{})["diagnostics"], 0)
+}
+
+type testHandler struct {
+	failures        []*text.Failure
+	descriptorFiles []*lint.DescriptorFile
+	checks          int
+	reloads         int
+}
+
+func newTestHandler(t *testing.T) *testHandler {
+	parser := protoparse.Parser{
+		Accessor:              protoparse.FileContentsFromMap(testFilenameToData),
+		IncludeSourceCodeInfo: true,
+	}
+	fileDescriptors, err := parser.ParseFiles("foo.proto", "bar.proto")
+	require.NoError(t, err)
+	fileDescriptorSet := &descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			fileDescriptors[0].AsFileDescriptorProto(),
+			fileDescriptors[1].AsFileDescriptorProto(),
+		},
+	}
+	return &testHandler{
+		failures: []*text.Failure{
+			{Filename: testBarFilePath, Line: 7, Column: 1, ID: "FOO_ID", Message: "foo"},
+		},
+		descriptorFiles: []*lint.DescriptorFile{
+			{FileDescriptorProto: fileDescriptorSet.File[0], Path: testFooFilePath, FileDescriptorSet: fileDescriptorSet},
+			{FileDescriptorProto: fileDescriptorSet.File[1], Path: testBarFilePath, FileDescriptorSet: fileDescriptorSet},
+		},
+	}
+}
+
+func (h *testHandler) Check(filePath string) (*CheckResult, error) {
+	h.checks++
+	return &CheckResult{
+		Failures:        h.failures,
+		DescriptorFiles: h.descriptorFiles,
+		// the files are compiled with protoparse
+		RuneColumns: true,
+	}, nil
+}
+
+func (h *testHandler) Format(filePath string, data []byte) ([]byte, []*text.Failure, error) {
+	return []byte("formatted"), nil, nil
+}
+
+func (h *testHandler) ReloadConfigs() {
+	h.reloads++
+}
+
+func testServe(t *testing.T, handler Handler, requests ...*request) []map[string]interface{} {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, newServer(handler).Serve(bytes.NewReader(newTestMessages(t, requests...)), buffer))
+	return readTestMessages(t, buffer)
+}
+
+// newTestRequest returns a new request, or a notification if id is 0.
+func newTestRequest(id int, method string, params interface{}) *request {
+	request := &request{
+		JSONRPC: "2.0",
+		Method:  method,
+	}
+	if id != 0 {
+		request.ID = newTestRawID(fmt.Sprintf("%d", id))
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			panic(err)
+		}
+		request.Params = data
+	}
+	return request
+}
+
+func newTestRawID(id string) *json.RawMessage {
+	rawID := json.RawMessage(id)
+	return &rawID
+}
+
+func newTestPositionParams(uri string, line int, character int) textDocumentPositionParams {
+	return textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Position:     position{Line: line, Character: character},
+	}
+}
+
+func newTestLocation(uri string, startLine int, startCharacter int, endLine int, endCharacter int) map[string]interface{} {
+	return map[string]interface{}{
+		"uri": uri,
+		"range": map[string]interface{}{
+			"start": map[string]interface{}{"line": float64(startLine), "character": float64(startCharacter)},
+			"end":   map[string]interface{}{"line": float64(endLine), "character": float64(endCharacter)},
+		},
+	}
+}
+
+func newTestMessages(t *testing.T, requests ...*request) []byte {
+	buffer := bytes.NewBuffer(nil)
+	for _, request := range requests {
+		require.NoError(t, writeMessage(buffer, request))
+	}
+	return buffer.Bytes()
+}
+
+func readTestMessages(t *testing.T, buffer *bytes.Buffer) []map[string]interface{} {
+	reader := bufio.NewReader(buffer)
+	var messages []map[string]interface{}
+	for {
+		data, err := readMessage(reader)
+		if err != nil {
+			require.Equal(t, io.EOF, err)
+			return messages
+		}
+		message := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(data, &message))
+		messages = append(messages, message)
+	}
+}
@@ -0,0 +1,388 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+)
+
+var requestMatcherMarshaler = &jsonpb.Marshaler{EmitDefaults: true}
+
+type externalFixtureResponse struct {
+	Request   interface{}           `json:"request,omitempty" yaml:"request,omitempty"`
+	Response  interface{}           `json:"response,omitempty" yaml:"response,omitempty"`
+	Responses []interface{}         `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Error     *externalFixtureError `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+type externalFixtureError struct {
+	Code    string `json:"code,omitempty" yaml:"code,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+type fixture struct {
+	// keyed by full method, for example /foo.ExcitedService/Exclamation
+	methodToResponses map[string][]*fixtureResponse
+}
+
+type fixtureResponse struct {
+	// nil matches any request
+	// otherwise the JSON value of the request matcher with JSON field names
+	requestMatcher interface{}
+	// nil if status is set
+	responses []*dynamic.Message
+	status    *status.Status
+}
+
+// newFixture parses the fixture data, using the given MethodDescriptors
+// keyed by full method to verify the methods, request matchers, and responses.
+func newFixture(data []byte, methodToDescriptor map[string]*desc.MethodDescriptor) (*fixture, error) {
+	fixture := &fixture{
+		methodToResponses: make(map[string][]*fixtureResponse),
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return fixture, nil
+	}
+	// JSON is also valid YAML
+	externalFixture := make(map[string][]externalFixtureResponse)
+	if err := yaml.UnmarshalStrict(data, &externalFixture); err != nil {
+		return nil, err
+	}
+	for method, externalFixtureResponses := range externalFixture {
+		fullMethod := "/" + strings.TrimPrefix(method, "/")
+		if _, ok := fixture.methodToResponses[fullMethod]; ok {
+			return nil, fmt.Errorf("duplicate method in fixture: %s", method)
+		}
+		methodDescriptor, ok := methodToDescriptor[fullMethod]
+		if !ok {
+			return nil, fmt.Errorf("unknown method in fixture: %s", method)
+		}
+		fixtureResponses := make([]*fixtureResponse, 0, len(externalFixtureResponses))
+		for _, externalFixtureResponse := range externalFixtureResponses {
+			fixtureResponse, err := newFixtureResponse(externalFixtureResponse, methodDescriptor)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fixture response for %s: %v", method, err)
+			}
+			fixtureResponses = append(fixtureResponses, fixtureResponse)
+		}
+		fixture.methodToResponses[fullMethod] = fixtureResponses
+	}
+	return fixture, nil
+}
+
+// getResponse returns the first response for the method that matches the
+// request, or nil if there is no matching response.
+func (f *fixture) getResponse(method string, request *dynamic.Message) (*fixtureResponse, error) {
+	fixtureResponses := f.methodToResponses[method]
+	if len(fixtureResponses) == 0 {
+		return nil, nil
+	}
+	data, err := request.MarshalJSONPB(requestMatcherMarshaler)
+	if err != nil {
+		return nil, err
+	}
+	// numbers are decoded as json.Number so that large numbers
+	// are not formatted with exponents when compared
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var requestValue interface{}
+	if err := decoder.Decode(&requestValue); err != nil {
+		return nil, err
+	}
+	for _, fixtureResponse := range fixtureResponses {
+		if fixtureResponse.requestMatcher == nil || matchesRequestMatcher(fixtureResponse.requestMatcher, requestValue) {
+			return fixtureResponse, nil
+		}
+	}
+	return nil, nil
+}
+
+func newFixtureResponse(externalFixtureResponse externalFixtureResponse, methodDescriptor *desc.MethodDescriptor) (*fixtureResponse, error) {
+	numSet := 0
+	if externalFixtureResponse.Response != nil {
+		numSet++
+	}
+	if len(externalFixtureResponse.Responses) > 0 {
+		numSet++
+	}
+	if externalFixtureResponse.Error != nil {
+		numSet++
+	}
+	if numSet > 1 {
+		return nil, fmt.Errorf("only one of response, responses, or error can be set")
+	}
+	if len(externalFixtureResponse.Responses) > 1 && !methodDescriptor.IsServerStreaming() {
+		return nil, fmt.Errorf("responses can only have more than one response for server streaming and bidirectional streaming methods")
+	}
+	fixtureResponse := &fixtureResponse{}
+	if externalFixtureResponse.Request != nil {
+		requestMatcher, err := newRequestMatcher(externalFixtureResponse.Request, methodDescriptor.GetInputType())
+		if err != nil {
+			return nil, err
+		}
+		fixtureResponse.requestMatcher = requestMatcher
+	}
+	if externalFixtureResponse.Error != nil {
+		code, err := parseCode(externalFixtureResponse.Error.Code)
+		if err != nil {
+			return nil, err
+		}
+		fixtureResponse.status = status.New(code, externalFixtureResponse.Error.Message)
+		return fixtureResponse, nil
+	}
+	responseValues := externalFixtureResponse.Responses
+	if externalFixtureResponse.Response != nil {
+		responseValues = []interface{}{externalFixtureResponse.Response}
+	}
+	if len(responseValues) == 0 {
+		// a response with only a request matcher gets a zero-valued response
+		responseValues = []interface{}{map[string]interface{}{}}
+	}
+	for _, responseValue := range responseValues {
+		response, err := newDynamicMessage(responseValue, methodDescriptor.GetOutputType())
+		if err != nil {
+			return nil, fmt.Errorf("invalid response: %v", err)
+		}
+		fixtureResponse.responses = append(fixtureResponse.responses, response)
+	}
+	return fixtureResponse, nil
+}
+
+// newRequestMatcher returns the JSON value of the request matcher with
+// all field names converted to JSON field names, so that it can be
+// compared to the JSON value of requests.
+func newRequestMatcher(value interface{}, messageDescriptor *desc.MessageDescriptor) (interface{}, error) {
+	// verify the request matcher is a valid request
+	if _, err := newDynamicMessage(value, messageDescriptor); err != nil {
+		return nil, fmt.Errorf("invalid request: %v", err)
+	}
+	jsonValue, err := toJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeMessageValue(jsonValue, messageDescriptor)
+}
+
+func newDynamicMessage(value interface{}, messageDescriptor *desc.MessageDescriptor) (*dynamic.Message, error) {
+	jsonValue, err := toJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(jsonValue)
+	if err != nil {
+		return nil, err
+	}
+	dynamicMessage := dynamic.NewMessage(messageDescriptor)
+	if err := dynamicMessage.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return dynamicMessage, nil
+}
+
+// toJSONValue converts a value decoded from YAML to a value that can
+// be encoded as JSON.
+func toJSONValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, elem := range value {
+			jsonElem, err := toJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			object[fmt.Sprint(key)] = jsonElem
+		}
+		return object, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, elem := range value {
+			jsonElem, err := toJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = jsonElem
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, elem := range value {
+			jsonElem, err := toJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = jsonElem
+		}
+		return list, nil
+	default:
+		return value, nil
+	}
+}
+
+// normalizeMessageValue converts the field names in the JSON value of a
+// message to JSON field names, as the value can use either the field
+// names in the Protobuf file or the JSON field names.
+func normalizeMessageValue(value interface{}, messageDescriptor *desc.MessageDescriptor) (interface{}, error) {
+	object, ok := value.(map[string]interface{})
+	// well-known types such as google.protobuf.Timestamp have special JSON values
+	if !ok || strings.HasPrefix(messageDescriptor.GetFullyQualifiedName(), "google.protobuf.") {
+		return value, nil
+	}
+	normalizedObject := make(map[string]interface{}, len(object))
+	for key, elem := range object {
+		fieldDescriptor := messageDescriptor.FindFieldByJSONName(key)
+		if fieldDescriptor == nil {
+			fieldDescriptor = messageDescriptor.FindFieldByName(key)
+		}
+		if fieldDescriptor == nil {
+			return nil, fmt.Errorf("unknown field %s for message %s", key, messageDescriptor.GetFullyQualifiedName())
+		}
+		normalizedElem, err := normalizeFieldValue(elem, fieldDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		normalizedObject[fieldDescriptor.GetJSONName()] = normalizedElem
+	}
+	return normalizedObject, nil
+}
+
+func normalizeFieldValue(value interface{}, fieldDescriptor *desc.FieldDescriptor) (interface{}, error) {
+	if fieldDescriptor.IsMap() {
+		valueMessageDescriptor := fieldDescriptor.GetMapValueType().GetMessageType()
+		object, ok := value.(map[string]interface{})
+		if valueMessageDescriptor == nil || !ok {
+			return value, nil
+		}
+		normalizedObject := make(map[string]interface{}, len(object))
+		for key, elem := range object {
+			normalizedElem, err := normalizeMessageValue(elem, valueMessageDescriptor)
+			if err != nil {
+				return nil, err
+			}
+			normalizedObject[key] = normalizedElem
+		}
+		return normalizedObject, nil
+	}
+	messageDescriptor := fieldDescriptor.GetMessageType()
+	if messageDescriptor == nil {
+		return value, nil
+	}
+	list, ok := value.([]interface{})
+	if !fieldDescriptor.IsRepeated() || !ok {
+		return normalizeMessageValue(value, messageDescriptor)
+	}
+	normalizedList := make([]interface{}, len(list))
+	for i, elem := range list {
+		normalizedElem, err := normalizeMessageValue(elem, messageDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		normalizedList[i] = normalizedElem
+	}
+	return normalizedList, nil
+}
+
+// matchesRequestMatcher returns true if all fields in the request matcher
+// are equal to the fields in the request.
+//
+// Scalars are compared by their string values, as for example 64-bit
+// integers are strings in the JSON value of a request. Numbers in the
+// request are also compared by their values, so that for example a
+// matcher of 1.0 matches 1.
+func matchesRequestMatcher(requestMatcher interface{}, requestValue interface{}) bool {
+	switch requestMatcher := requestMatcher.(type) {
+	case map[string]interface{}:
+		object, ok := requestValue.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, elem := range requestMatcher {
+			if !matchesRequestMatcher(elem, object[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		list, ok := requestValue.([]interface{})
+		if !ok || len(list) != len(requestMatcher) {
+			return false
+		}
+		for i, elem := range requestMatcher {
+			if !matchesRequestMatcher(elem, list[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return requestValue == nil
+	default:
+		if requestValue == nil {
+			return false
+		}
+		if fmt.Sprint(requestMatcher) == fmt.Sprint(requestValue) {
+			return true
+		}
+		number, ok := requestValue.(json.Number)
+		if !ok {
+			return false
+		}
+		requestFloat, err := number.Float64()
+		if err != nil {
+			return false
+		}
+		matcherFloat, err := strconv.ParseFloat(fmt.Sprint(requestMatcher), 64)
+		return err == nil && matcherFloat == requestFloat
+	}
+}
+
+// parseCode parses a gRPC status code such as NotFound, NOT_FOUND, or 5.
+func parseCode(s string) (codes.Code, error) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if strings.EqualFold(code.String(), s) {
+			return checkErrorCode(code)
+		}
+	}
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(s)))); err != nil {
+		if number, err := strconv.ParseUint(s, 10, 32); err == nil && number <= uint64(codes.Unauthenticated) {
+			return checkErrorCode(codes.Code(number))
+		}
+		return 0, fmt.Errorf("unknown error code: %s", s)
+	}
+	return checkErrorCode(code)
+}
+
+func checkErrorCode(code codes.Code) (codes.Code, error) {
+	if code == codes.OK {
+		return 0, fmt.Errorf("error code cannot be OK")
+	}
+	return code, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mock implements a mock gRPC server for the services in
+// FileDescriptorSets.
+//
+// Every method of every service is served dynamically. Responses are taken
+// from a fixture keyed by method, where each response can have a request
+// matcher, and methods or requests without a matching response get a
+// zero-valued response.
+//
+// A fixture is YAML or JSON of the form:
+//
+//	foo.ExcitedService/Exclamation:
+//	  - request:
+//	      value: hello
+//	    response:
+//	      value: hello!
+//	  - error:
+//	      code: NotFound
+//	      message: not found
+//
+// Each method has a list of responses, and the first response with a
+// request matcher that matches the request is used, where a response with
+// no request matcher matches any request. A request matcher matches if
+// all the fields it has are equal to the fields of the request, and fields
+// not in the request matcher are ignored. A response has either a single
+// response message, a list of response messages with responses for server
+// streaming and bidirectional streaming methods, or an error, which is a
+// gRPC status code and message.
+//
+// For client streaming methods, the last request is matched. For
+// bidirectional streaming methods, each request is matched and responded
+// to as it is received.
+package mock
+
+import (
+	"context"
+	"net"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/zap"
+)
+
+// Server is a mock gRPC server.
+type Server interface {
+	// Serve serves all services in the FileDescriptorSets on the listener
+	// until the context is done.
+	//
+	// Each request is logged at the info level.
+	Serve(ctx context.Context, fileDescriptorSets []*descriptor.FileDescriptorSet, listener net.Listener) error
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithLogger returns a ServerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ServerWithLogger(logger *zap.Logger) ServerOption {
+	return func(server *server) {
+		server.logger = logger
+	}
+}
+
+// ServerWithFixtureData returns a ServerOption that uses the given YAML or
+// JSON fixture data for responses.
+//
+// The default is to have no fixture, so that all responses are zero-valued.
+func ServerWithFixtureData(fixtureData []byte) ServerOption {
+	return func(server *server) {
+		server.fixtureData = fixtureData
+	}
+}
+
+// NewServer returns a new Server.
+func NewServer(options ...ServerOption) Server {
+	return newServer(options...)
+}
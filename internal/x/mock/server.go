@@ -0,0 +1,233 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mock
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	intdesc "github.com/tgrpc/prototool/internal/x/desc"
+	"github.com/tgrpc/prototool/internal/x/extract"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type server struct {
+	logger      *zap.Logger
+	fixtureData []byte
+
+	getter extract.Getter
+}
+
+func newServer(options ...ServerOption) *server {
+	server := &server{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	server.getter = extract.NewGetter(
+		extract.GetterWithLogger(server.logger),
+	)
+	return server
+}
+
+func (s *server) Serve(ctx context.Context, fileDescriptorSets []*descriptor.FileDescriptorSet, listener net.Listener) error {
+	methodToDescriptor, err := s.getMethodToDescriptor(fileDescriptorSets)
+	if err != nil {
+		return err
+	}
+	if len(methodToDescriptor) == 0 {
+		return fmt.Errorf("no services to serve")
+	}
+	fixture, err := newFixture(s.fixtureData, methodToDescriptor)
+	if err != nil {
+		return err
+	}
+	methods := make([]string, 0, len(methodToDescriptor))
+	for method := range methodToDescriptor {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		s.logger.Debug("serving method", zap.String("method", method), zap.Int("fixture_responses", len(fixture.methodToResponses[method])))
+	}
+	streamHandler := newStreamHandler(s.logger, methodToDescriptor, fixture)
+	grpcServer := grpc.NewServer(grpc.UnknownServiceHandler(streamHandler.Handle))
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			grpcServer.Stop()
+		case <-done:
+		}
+	}()
+	s.logger.Info("serving", zap.String("address", listener.Addr().String()))
+	return grpcServer.Serve(listener)
+}
+
+// getMethodToDescriptor returns the MethodDescriptors for all services in
+// the FileDescriptorSets keyed by full method, for example
+// /foo.ExcitedService/Exclamation.
+func (s *server) getMethodToDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet) (map[string]*desc.MethodDescriptor, error) {
+	methodToDescriptor := make(map[string]*desc.MethodDescriptor)
+	seenServicePaths := make(map[string]struct{})
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.File {
+			for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+				servicePath := serviceDescriptorProto.GetName()
+				if fileDescriptorProto.GetPackage() != "" {
+					servicePath = fileDescriptorProto.GetPackage() + "." + servicePath
+				}
+				if _, ok := seenServicePaths[servicePath]; ok {
+					continue
+				}
+				seenServicePaths[servicePath] = struct{}{}
+				serviceDescriptor, err := s.getServiceDescriptor(fileDescriptorSets, servicePath)
+				if err != nil {
+					return nil, err
+				}
+				for _, methodDescriptor := range serviceDescriptor.GetMethods() {
+					methodToDescriptor["/"+servicePath+"/"+methodDescriptor.GetName()] = methodDescriptor
+				}
+			}
+		}
+	}
+	return methodToDescriptor, nil
+}
+
+func (s *server) getServiceDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, servicePath string) (*desc.ServiceDescriptor, error) {
+	service, err := s.getter.GetService(fileDescriptorSets, servicePath)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet, err := intdesc.SortFileDescriptorSet(service.FileDescriptorSet, service.FileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptor, err := desc.CreateFileDescriptorFromSet(fileDescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	serviceDescriptor := fileDescriptor.FindService(servicePath)
+	if serviceDescriptor == nil {
+		return nil, fmt.Errorf("no ServiceDescriptor for path %s", servicePath)
+	}
+	return serviceDescriptor, nil
+}
+
+type streamHandler struct {
+	logger             *zap.Logger
+	methodToDescriptor map[string]*desc.MethodDescriptor
+	fixture            *fixture
+}
+
+func newStreamHandler(logger *zap.Logger, methodToDescriptor map[string]*desc.MethodDescriptor, fixture *fixture) *streamHandler {
+	return &streamHandler{
+		logger:             logger,
+		methodToDescriptor: methodToDescriptor,
+		fixture:            fixture,
+	}
+}
+
+// Handle handles all calls to the server.
+func (h *streamHandler) Handle(_ interface{}, stream grpc.ServerStream) error {
+	method, ok := grpc.MethodFromServerStream(stream)
+	if !ok {
+		return status.Error(codes.Internal, "no method for stream")
+	}
+	methodDescriptor, ok := h.methodToDescriptor[method]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	if methodDescriptor.IsClientStreaming() && !methodDescriptor.IsServerStreaming() {
+		// respond once to the last request
+		request := dynamic.NewMessage(methodDescriptor.GetInputType())
+		for {
+			nextRequest, err := h.receive(stream, method, methodDescriptor)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			request = nextRequest
+		}
+		return h.respond(stream, method, methodDescriptor, request)
+	}
+	for {
+		request, err := h.receive(stream, method, methodDescriptor)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.respond(stream, method, methodDescriptor, request); err != nil {
+			return err
+		}
+		if !methodDescriptor.IsClientStreaming() {
+			return nil
+		}
+	}
+}
+
+func (h *streamHandler) receive(stream grpc.ServerStream, method string, methodDescriptor *desc.MethodDescriptor) (*dynamic.Message, error) {
+	request := dynamic.NewMessage(methodDescriptor.GetInputType())
+	if err := stream.RecvMsg(request); err != nil {
+		return nil, err
+	}
+	data, err := request.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	h.logger.Info("request", zap.String("method", method), zap.String("request", string(data)))
+	return request, nil
+}
+
+func (h *streamHandler) respond(stream grpc.ServerStream, method string, methodDescriptor *desc.MethodDescriptor, request *dynamic.Message) error {
+	fixtureResponse, err := h.fixture.getResponse(method, request)
+	if err != nil {
+		return err
+	}
+	if fixtureResponse == nil {
+		h.logger.Debug("no matching fixture response, sending zero-valued response", zap.String("method", method))
+		return stream.SendMsg(dynamic.NewMessage(methodDescriptor.GetOutputType()))
+	}
+	if fixtureResponse.status != nil {
+		return fixtureResponse.status.Err()
+	}
+	for _, response := range fixtureResponse.responses {
+		if err := stream.SendMsg(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mock
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testFilenameToData = map[string]string{
+	"foo.proto": `syntax = "proto3";
+
+package foo;
+
+message Request {
+  string value = 1;
+  int64 count = 2;
+  Inner inner_value = 3;
+  int32 size = 4;
+}
+
+message Response {
+  string value = 1;
+}
+
+message Inner {
+  string inner_value = 1;
+}
+
+service ExcitedService {
+  rpc Exclamation(Request) returns (Response);
+  rpc ExclamationClientStream(stream Request) returns (Response);
+  rpc ExclamationServerStream(Request) returns (stream Response);
+  rpc ExclamationBidiStream(stream Request) returns (stream Response);
+}
+`,
+}
+
+const testFixtureData = `
+foo.ExcitedService/Exclamation:
+  - request:
+      value: hello
+      count: 2
+    response:
+      value: hello hello!
+  - request:
+      value: hello
+    response:
+      value: hello!
+  - request:
+      inner_value:
+        innerValue: inner
+    response:
+      value: inner!
+  - request:
+      size: 1000000
+    response:
+      value: big!
+  - request:
+      value: error
+    error:
+      code: NOT_FOUND
+      message: not found
+/foo.ExcitedService/ExclamationClientStream:
+  - request:
+      value: last
+    response:
+      value: last!
+foo.ExcitedService/ExclamationServerStream:
+  - responses:
+      - value: one
+      - value: two
+foo.ExcitedService/ExclamationBidiStream:
+  - request:
+      value: hello
+    response:
+      value: hello!
+`
+
+func TestServe(t *testing.T) {
+	methodDescriptors, conn := testServe(t, testFixtureData)
+	stub := grpcdynamic.NewStub(conn)
+	ctx := context.Background()
+
+	for _, testCase := range []struct {
+		request  string
+		expected string
+		code     codes.Code
+	}{
+		{`{"value":"hello","count":2}`, `{"value":"hello hello!"}`, codes.OK},
+		{`{"value":"hello","count":"3"}`, `{"value":"hello!"}`, codes.OK},
+		{`{"innerValue":{"innerValue":"inner"}}`, `{"value":"inner!"}`, codes.OK},
+		// large numbers must not be compared in exponent form
+		{`{"size":1000000}`, `{"value":"big!"}`, codes.OK},
+		{`{"value":"goodbye"}`, `{}`, codes.OK},
+		{`{"value":"error"}`, ``, codes.NotFound},
+	} {
+		response, err := stub.InvokeRpc(ctx, methodDescriptors["Exclamation"], testNewMessage(t, methodDescriptors["Exclamation"].GetInputType(), testCase.request))
+		if testCase.code != codes.OK {
+			assert.Equal(t, testCase.code, status.Code(err), testCase.request)
+			continue
+		}
+		require.NoError(t, err, testCase.request)
+		assert.Equal(t, testCase.expected, testMarshalJSON(t, response), testCase.request)
+	}
+
+	clientStream, err := stub.InvokeRpcClientStream(ctx, methodDescriptors["ExclamationClientStream"])
+	require.NoError(t, err)
+	for _, request := range []string{`{"value":"first"}`, `{"value":"last"}`} {
+		require.NoError(t, clientStream.SendMsg(testNewMessage(t, methodDescriptors["ExclamationClientStream"].GetInputType(), request)))
+	}
+	response, err := clientStream.CloseAndReceive()
+	require.NoError(t, err)
+	assert.Equal(t, `{"value":"last!"}`, testMarshalJSON(t, response))
+
+	serverStream, err := stub.InvokeRpcServerStream(ctx, methodDescriptors["ExclamationServerStream"], testNewMessage(t, methodDescriptors["ExclamationServerStream"].GetInputType(), `{}`))
+	require.NoError(t, err)
+	var responses []string
+	for {
+		response, err := serverStream.RecvMsg()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		responses = append(responses, testMarshalJSON(t, response))
+	}
+	assert.Equal(t, []string{`{"value":"one"}`, `{"value":"two"}`}, responses)
+
+	bidiStream, err := stub.InvokeRpcBidiStream(ctx, methodDescriptors["ExclamationBidiStream"])
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		request  string
+		expected string
+	}{
+		{`{"value":"hello"}`, `{"value":"hello!"}`},
+		{`{"value":"goodbye"}`, `{}`},
+	} {
+		require.NoError(t, bidiStream.SendMsg(testNewMessage(t, methodDescriptors["ExclamationBidiStream"].GetInputType(), testCase.request)))
+		response, err := bidiStream.RecvMsg()
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, testMarshalJSON(t, response))
+	}
+	require.NoError(t, bidiStream.CloseSend())
+	_, err = bidiStream.RecvMsg()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServeNoFixture(t *testing.T) {
+	methodDescriptors, conn := testServe(t, "")
+	response, err := grpcdynamic.NewStub(conn).InvokeRpc(
+		context.Background(),
+		methodDescriptors["Exclamation"],
+		testNewMessage(t, methodDescriptors["Exclamation"].GetInputType(), `{"value":"hello"}`),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, testMarshalJSON(t, response))
+}
+
+func TestNewFixtureErrors(t *testing.T) {
+	fileDescriptorSets := testGetFileDescriptorSets(t)
+	methodToDescriptor, err := newServer().getMethodToDescriptor(fileDescriptorSets)
+	require.NoError(t, err)
+	for _, data := range []string{
+		// unknown method
+		`
+foo.ExcitedService/Unknown:
+  - response:
+      value: foo
+`,
+		// unknown request field
+		`
+foo.ExcitedService/Exclamation:
+  - request:
+      unknown: foo
+`,
+		// unknown response field
+		`
+foo.ExcitedService/Exclamation:
+  - response:
+      unknown: foo
+`,
+		// response and error
+		`
+foo.ExcitedService/Exclamation:
+  - response:
+      value: foo
+    error:
+      code: NotFound
+`,
+		// more than one response for a unary method
+		`
+foo.ExcitedService/Exclamation:
+  - responses:
+      - value: foo
+      - value: bar
+`,
+		// unknown error code
+		`
+foo.ExcitedService/Exclamation:
+  - error:
+      code: Foo
+`,
+		// OK error code
+		`
+foo.ExcitedService/Exclamation:
+  - error:
+      code: OK
+`,
+	} {
+		_, err := newFixture([]byte(data), methodToDescriptor)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	for _, s := range []string{"NotFound", "notfound", "NOT_FOUND", "5"} {
+		code, err := parseCode(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, codes.NotFound, code, s)
+	}
+	code, err := parseCode("CANCELLED")
+	assert.NoError(t, err)
+	assert.Equal(t, codes.Canceled, code)
+}
+
+func testServe(t *testing.T, fixtureData string) (map[string]*desc.MethodDescriptor, *grpc.ClientConn) {
+	fileDescriptorSets := testGetFileDescriptorSets(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() {
+		errC <- NewServer(ServerWithFixtureData([]byte(fixtureData))).Serve(ctx, fileDescriptorSets, listener)
+	}()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, conn.Close())
+		cancel()
+		assert.NoError(t, <-errC)
+	})
+	methodToDescriptor, err := newServer().getMethodToDescriptor(fileDescriptorSets)
+	require.NoError(t, err)
+	methodDescriptors := make(map[string]*desc.MethodDescriptor, len(methodToDescriptor))
+	for _, methodDescriptor := range methodToDescriptor {
+		methodDescriptors[methodDescriptor.GetName()] = methodDescriptor
+	}
+	return methodDescriptors, conn
+}
+
+func testGetFileDescriptorSets(t *testing.T) []*descriptor.FileDescriptorSet {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(testFilenameToData),
+	}
+	fileDescriptors, err := parser.ParseFiles("foo.proto")
+	require.NoError(t, err)
+	return []*descriptor.FileDescriptorSet{desc.ToFileDescriptorSet(fileDescriptors...)}
+}
+
+func testNewMessage(t *testing.T, messageDescriptor *desc.MessageDescriptor, data string) *dynamic.Message {
+	dynamicMessage := dynamic.NewMessage(messageDescriptor)
+	require.NoError(t, dynamicMessage.UnmarshalJSON([]byte(data)))
+	return dynamicMessage
+}
+
+func testMarshalJSON(t *testing.T, message interface{}) string {
+	dynamicMessage, ok := message.(*dynamic.Message)
+	require.True(t, ok)
+	data, err := dynamicMessage.MarshalJSON()
+	require.NoError(t, err)
+	return string(data)
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// compileCacheVersion is included in all keys, and should be changed
+// if the format of entries changes.
+const compileCacheVersion = "1"
+
+var importRegexp = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+
+// compileCache is a content-addressed cache of the results of protoc commands.
+//
+// The key for a command is a hash of the protoc binary, the arguments,
+// the compile settings, the contents of the files and their transitive
+// imports, and the plugin binary if generating. An entry is the FileDescriptorSet for compile
+// commands, and a zip file of the generated files for gen commands.
+//
+// Entries are written to a temporary file in the cache and then renamed,
+// so that multiple processes can use the cache at once.
+type compileCache struct {
+	logger  *zap.Logger
+	dirPath string
+
+	lock sync.Mutex
+	// hashes of binaries, which are the same for all commands
+	binaryPathToHash map[string]string
+}
+
+func newCompileCache(logger *zap.Logger, dirPath string) *compileCache {
+	return &compileCache{
+		logger:           logger,
+		dirPath:          dirPath,
+		binaryPathToHash: make(map[string]string),
+	}
+}
+
+// getKey returns the key for the command.
+//
+// An error is returned if the key cannot be computed, for example if an
+// import cannot be found, in which case the command should not be cached.
+func (c *compileCache) getKey(cmdMeta *cmdMeta) (string, error) {
+	keyHash := sha256.New()
+	writeKeyParts(keyHash, "version", compileCacheVersion)
+	protocHash, err := c.getBinaryHash(cmdMeta.execCmd.Path)
+	if err != nil {
+		return "", err
+	}
+	writeKeyParts(keyHash, "protoc", protocHash)
+	args := cmdMeta.execCmd.Args[1:]
+	for i, arg := range args {
+		// the output path for the FileDescriptorSet is a temporary file
+		if i > 0 && args[i-1] == "-o" {
+			if cmdMeta.descriptorSetTempFilePath != "" {
+				arg = "temp"
+			}
+		}
+		writeKeyParts(keyHash, "arg", arg)
+	}
+	// the compile settings can change how the output of protoc is
+	// interpreted, for example whether unused imports are failures
+	compileConfig := cmdMeta.protoSet.Config.Compile
+	writeKeyParts(
+		keyHash,
+		"compile",
+		compileConfig.ProtobufVersion,
+		strconv.FormatBool(compileConfig.IncludeWellKnownTypes),
+		strconv.FormatBool(compileConfig.AllowUnusedImports),
+		compileConfig.Backend.String(),
+	)
+	for _, includePath := range compileConfig.IncludePaths {
+		writeKeyParts(keyHash, "include", includePath)
+	}
+	if cmdMeta.genPlugin != nil {
+		pluginPath := cmdMeta.genPlugin.Path
+		if pluginPath == "" {
+			// plugins built into protoc will not be found, and are
+			// covered by the protoc hash
+			pluginPath, _ = exec.LookPath("protoc-gen-" + cmdMeta.genPlugin.Name)
+		}
+		if pluginPath != "" {
+			pluginHash, err := c.getBinaryHash(pluginPath)
+			if err != nil {
+				return "", err
+			}
+			writeKeyParts(keyHash, "plugin", pluginHash)
+		}
+	}
+	filePaths := make([]string, len(cmdMeta.protoFiles))
+	for i, protoFile := range cmdMeta.protoFiles {
+		filePaths[i] = protoFile.Path
+	}
+	seenFilePaths := make(map[string]struct{})
+	for len(filePaths) > 0 {
+		filePath := filePaths[0]
+		filePaths = filePaths[1:]
+		if _, ok := seenFilePaths[filePath]; ok {
+			continue
+		}
+		seenFilePaths[filePath] = struct{}{}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return "", err
+		}
+		writeKeyParts(keyHash, "file", filePath, getHash(data))
+		for _, match := range importRegexp.FindAllSubmatch(data, -1) {
+			importFilePath, err := resolveImport(cmdMeta.includes, string(match[1]))
+			if err != nil {
+				return "", err
+			}
+			filePaths = append(filePaths, importFilePath)
+		}
+	}
+	return hex.EncodeToString(keyHash.Sum(nil)), nil
+}
+
+// get returns the data for the key, or false if there is no entry.
+func (c *compileCache) get(key string) ([]byte, bool, error) {
+	data, err := ioutil.ReadFile(c.getEntryFilePath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// put stores the data for the key.
+func (c *compileCache) put(key string, data []byte) error {
+	tempFilePath, err := c.newTempFilePath("")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tempFilePath, data, 0644); err != nil {
+		tryRemoveTempFile(tempFilePath)
+		return err
+	}
+	return c.putFile(key, tempFilePath)
+}
+
+// putFile stores the file for the key by renaming it, so the file
+// should have been created with newTempFilePath.
+func (c *compileCache) putFile(key string, tempFilePath string) error {
+	entryFilePath := c.getEntryFilePath(key)
+	if err := os.MkdirAll(filepath.Dir(entryFilePath), 0755); err != nil {
+		tryRemoveTempFile(tempFilePath)
+		return err
+	}
+	if err := os.Rename(tempFilePath, entryFilePath); err != nil {
+		tryRemoveTempFile(tempFilePath)
+		return err
+	}
+	c.logger.Debug("stored compile cache entry", zap.String("key", key))
+	return nil
+}
+
+// newTempFilePath returns the path to a new empty file with the given suffix
+// in the cache directory, so that it can be renamed into the cache.
+func (c *compileCache) newTempFilePath(suffix string) (string, error) {
+	tempDirPath := filepath.Join(c.dirPath, "tmp")
+	if err := os.MkdirAll(tempDirPath, 0755); err != nil {
+		return "", err
+	}
+	tempFile, err := ioutil.TempFile(tempDirPath, "entry")
+	if err != nil {
+		return "", err
+	}
+	tempFilePath := tempFile.Name()
+	if err := tempFile.Close(); err != nil {
+		return "", err
+	}
+	if suffix == "" {
+		return tempFilePath, nil
+	}
+	// protoc decides to write a zip file based on the extension
+	if err := os.Rename(tempFilePath, tempFilePath+suffix); err != nil {
+		tryRemoveTempFile(tempFilePath)
+		return "", err
+	}
+	return tempFilePath + suffix, nil
+}
+
+func (c *compileCache) getEntryFilePath(key string) string {
+	return filepath.Join(c.dirPath, key[:2], key)
+}
+
+func (c *compileCache) getBinaryHash(filePath string) (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if binaryHash, ok := c.binaryPathToHash[filePath]; ok {
+		return binaryHash, nil
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	binaryHash := getHash(data)
+	c.binaryPathToHash[filePath] = binaryHash
+	return binaryHash, nil
+}
+
+// resolveImport returns the path of the first file in the includes
+// with the import name, as protoc does.
+func resolveImport(includes []string, importName string) (string, error) {
+	for _, include := range includes {
+		filePath := filepath.Join(include, filepath.FromSlash(importName))
+		if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.Mode().IsRegular() {
+			return filePath, nil
+		}
+	}
+	return "", fmt.Errorf("could not find import %s in %v", importName, includes)
+}
+
+// extractZip writes the files in the zip data to the directory.
+//
+// Files that already have the same contents are not written, so that
+// their modification times do not change.
+func extractZip(data []byte, dirPath string) error {
+	// protoc does not write the zip file if there were no files generated
+	if len(data) == 0 {
+		return nil
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, zipFile := range zipReader.File {
+		if strings.HasSuffix(zipFile.Name, "/") {
+			continue
+		}
+		filePath := filepath.Join(dirPath, filepath.FromSlash(zipFile.Name))
+		if !strings.HasPrefix(filePath, filepath.Clean(dirPath)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file name in generated output: %s", zipFile.Name)
+		}
+		fileData, err := readZipFile(zipFile)
+		if err != nil {
+			return err
+		}
+		if existingData, err := ioutil.ReadFile(filePath); err == nil && bytes.Equal(existingData, fileData) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filePath, fileData, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(zipFile *zip.File) ([]byte, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = readCloser.Close() }()
+	return ioutil.ReadAll(readCloser)
+}
+
+func writeKeyParts(hash hash.Hash, parts ...string) {
+	for _, part := range parts {
+		// the separator makes sure that different parts cannot produce the same key
+		_, _ = io.WriteString(hash, part)
+		_, _ = hash.Write([]byte{0})
+	}
+}
+
+func getHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"go.uber.org/zap"
+)
+
+// testProtocScript is a fake protoc that records each run, writes "fds"
+// to the FileDescriptorSet output, and copies a zip file to the output
+// of the test plugin.
+const testProtocScript = `#!/bin/sh
+echo run >> %s
+prev=""
+for arg in "$@"; do
+  if [ "$prev" = "-o" ]; then
+    printf fds > "$arg"
+  fi
+  case "$arg" in
+    --test_out=*) cp %s "${arg#--test_out=}";;
+  esac
+  prev="$arg"
+done
+`
+
+func TestCompileCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake protoc is a shell script")
+	}
+	dirPath, protoSet := newTestGoProtoSet(t, map[string]string{
+		"foo/foo.proto": `syntax = "proto3";
+
+package foo;
+
+import "bar/bar.proto";
+`,
+		"bar/bar.proto": `syntax = "proto3";
+
+package bar;
+`,
+	})
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	runsFilePath := filepath.Join(dirPath, "runs")
+	zipFilePath := filepath.Join(dirPath, "gen.zip")
+	require.NoError(t, ioutil.WriteFile(zipFilePath, newTestZip(t, map[string]string{"foo/foo.txt": "foo"}), 0644))
+	protocPath := filepath.Join(dirPath, "protoc")
+	require.NoError(t, ioutil.WriteFile(protocPath, []byte(fmt.Sprintf(testProtocScript, runsFilePath, zipFilePath)), 0755))
+	compiler := newCompiler()
+	compileCache := newCompileCache(zap.NewNop(), filepath.Join(dirPath, "cache"))
+	fooDirPath := filepath.Join(dirPath, "foo")
+	getRuns := func() int {
+		data, err := ioutil.ReadFile(runsFilePath)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		require.NoError(t, err)
+		return strings.Count(string(data), "run")
+	}
+
+	runCompile := func() string {
+		descriptorSetFilePath := filepath.Join(dirPath, "descriptor_set")
+		require.NoError(t, os.RemoveAll(descriptorSetFilePath))
+		failures, err := compiler.runCmdMetaWithCache(compileCache, &cmdMeta{
+			execCmd:                   exec.Command(protocPath, "-I", dirPath, "-o", descriptorSetFilePath, filepath.Join(fooDirPath, "foo.proto")),
+			protoSet:                  protoSet,
+			protoFiles:                protoSet.DirPathToFiles[fooDirPath],
+			dirPath:                   fooDirPath,
+			includes:                  []string{dirPath},
+			descriptorSetTempFilePath: descriptorSetFilePath,
+		})
+		require.NoError(t, err)
+		require.Empty(t, failures)
+		data, err := ioutil.ReadFile(descriptorSetFilePath)
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "fds", runCompile())
+	assert.Equal(t, 1, getRuns())
+	assert.Equal(t, "fds", runCompile())
+	assert.Equal(t, 1, getRuns())
+	// changing an import changes the key
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "bar", "bar.proto"), []byte("syntax = \"proto3\";\n\npackage baz;\n"), 0644))
+	assert.Equal(t, "fds", runCompile())
+	assert.Equal(t, 2, getRuns())
+	// changing a compile setting changes the key, as failures
+	// depend on it even if the arguments are the same
+	protoSet.Config.Compile.AllowUnusedImports = !protoSet.Config.Compile.AllowUnusedImports
+	assert.Equal(t, "fds", runCompile())
+	assert.Equal(t, 3, getRuns())
+	assert.Equal(t, "fds", runCompile())
+	assert.Equal(t, 3, getRuns())
+
+	outputPath := filepath.Join(dirPath, "gen")
+	runGen := func() {
+		cmdMeta := &cmdMeta{
+			protoSet:   protoSet,
+			protoFiles: protoSet.DirPathToFiles[fooDirPath],
+			dirPath:    fooDirPath,
+			includes:   []string{dirPath},
+			genPlugin: &settings.GenPlugin{
+				Name:       "test",
+				OutputPath: settings.OutputPath{AbsPath: outputPath},
+			},
+		}
+		execCmd, err := newGenExecCmd(protocPath, cmdMeta, outputPath)
+		require.NoError(t, err)
+		cmdMeta.execCmd = execCmd
+		failures, err := compiler.runCmdMetaWithCache(compileCache, cmdMeta)
+		require.NoError(t, err)
+		require.Empty(t, failures)
+		data, err := ioutil.ReadFile(filepath.Join(outputPath, "foo", "foo.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo", string(data))
+	}
+	runGen()
+	assert.Equal(t, 4, getRuns())
+	require.NoError(t, os.RemoveAll(outputPath))
+	runGen()
+	assert.Equal(t, 4, getRuns())
+}
+
+func TestCompileCacheGetKeyMissingImport(t *testing.T) {
+	dirPath, protoSet := newTestGoProtoSet(t, map[string]string{
+		"foo/foo.proto": `syntax = "proto3";
+
+package foo;
+
+// import "bar/baz.proto";
+import "bar/bar.proto";
+`,
+	})
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	fooDirPath := filepath.Join(dirPath, "foo")
+	_, err := newCompileCache(zap.NewNop(), filepath.Join(dirPath, "cache")).getKey(&cmdMeta{
+		execCmd:    exec.Command(os.Args[0]),
+		protoSet:   protoSet,
+		protoFiles: protoSet.DirPathToFiles[fooDirPath],
+		includes:   []string{dirPath},
+	})
+	assert.Error(t, err)
+}
+
+func TestExtractZip(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	require.NoError(t, extractZip(newTestZip(t, map[string]string{"foo/bar.txt": "bar", "baz.txt": "baz"}), dirPath))
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, "foo", "bar.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(data))
+	require.NoError(t, extractZip(nil, dirPath))
+	assert.Error(t, extractZip(newTestZip(t, map[string]string{"../foo.txt": "foo"}), dirPath))
+}
+
+func newTestZip(t *testing.T, filenameToContents map[string]string) []byte {
+	buffer := bytes.NewBuffer(nil)
+	zipWriter := zip.NewWriter(buffer)
+	for filename, contents := range filenameToContents {
+		writer, err := zipWriter.Create(filename)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+	return buffer.Bytes()
+}
@@ -30,6 +30,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -69,11 +70,13 @@ type compiler struct {
 	protocURL           string
 	doGen               bool
 	doFileDescriptorSet bool
+	jobs                int
 }
 
 func newCompiler(options ...CompilerOption) *compiler {
 	compiler := &compiler{
 		logger: zap.NewNop(),
+		jobs:   runtime.NumCPU(),
 	}
 	for _, option := range options {
 		option(compiler)
@@ -82,8 +85,46 @@ func newCompiler(options ...CompilerOption) *compiler {
 }
 
 func (c *compiler) Compile(protoSets ...*file.ProtoSet) (*CompileResult, error) {
+	var protocProtoSets []*file.ProtoSet
+	var goProtoSets []*file.ProtoSet
+	for _, protoSet := range protoSets {
+		switch protoSet.Config.Compile.Backend {
+		case settings.CompileBackendProtoc:
+			protocProtoSets = append(protocProtoSets, protoSet)
+		case settings.CompileBackendGo:
+			goProtoSets = append(goProtoSets, protoSet)
+		default:
+			return nil, fmt.Errorf("unknown CompileBackend: %v", protoSet.Config.Compile.Backend)
+		}
+	}
+	if len(goProtoSets) == 0 {
+		return c.protocCompile(protocProtoSets...)
+	}
+	if len(protocProtoSets) == 0 {
+		return c.goCompile(goProtoSets...)
+	}
+	protocCompileResult, err := c.protocCompile(protocProtoSets...)
+	if err != nil {
+		return nil, err
+	}
+	goCompileResult, err := c.goCompile(goProtoSets...)
+	if err != nil {
+		return nil, err
+	}
+	if failures := append(protocCompileResult.Failures, goCompileResult.Failures...); len(failures) > 0 {
+		text.SortFailures(failures)
+		return &CompileResult{
+			Failures: failures,
+		}, nil
+	}
+	return &CompileResult{
+		FileDescriptorSets: append(protocCompileResult.FileDescriptorSets, goCompileResult.FileDescriptorSets...),
+	}, nil
+}
+
+func (c *compiler) protocCompile(protoSets ...*file.ProtoSet) (*CompileResult, error) {
 	var allCmdMetas []*cmdMeta
-	defer cleanCmdMetas(allCmdMetas)
+	defer func() { cleanCmdMetas(allCmdMetas) }()
 	for _, protoSet := range protoSets {
 		cmdMetas, err := c.getCmdMetas(protoSet)
 		if err != nil {
@@ -96,25 +137,25 @@ func (c *compiler) Compile(protoSets ...*file.ProtoSet) (*CompileResult, error)
 			return nil, err
 		}
 	}
+	// results are stored by index so that they are in a deterministic order
+	cmdMetaFailures := make([][]*text.Failure, len(allCmdMetas))
+	cmdMetaErrs := make([]error, len(allCmdMetas))
+	compileCache := c.getCompileCache()
+	c.runJobs(len(allCmdMetas), func(i int) {
+		if compileCache != nil {
+			cmdMetaFailures[i], cmdMetaErrs[i] = c.runCmdMetaWithCache(compileCache, allCmdMetas[i])
+		} else {
+			cmdMetaFailures[i], cmdMetaErrs[i] = c.runCmdMeta(allCmdMetas[i])
+		}
+	})
 	var failures []*text.Failure
 	var errs []error
-	var lock sync.Mutex
-	var wg sync.WaitGroup
-	for _, cmdMeta := range allCmdMetas {
-		cmdMeta := cmdMeta
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			iFailures, iErr := c.runCmdMeta(cmdMeta)
-			lock.Lock()
-			failures = append(failures, iFailures...)
-			if iErr != nil {
-				errs = append(errs, iErr)
-			}
-			lock.Unlock()
-		}()
+	for i := range allCmdMetas {
+		failures = append(failures, cmdMetaFailures[i]...)
+		if cmdMetaErrs[i] != nil {
+			errs = append(errs, cmdMetaErrs[i])
+		}
 	}
-	wg.Wait()
 	if len(errs) > 0 {
 		// I want newlines instead of spaces so not using multierr
 		errStrings := make([]string, 0, len(errs))
@@ -147,9 +188,45 @@ func (c *compiler) Compile(protoSets ...*file.ProtoSet) (*CompileResult, error)
 	}, nil
 }
 
+// runJobs calls f for each index from 0 to numJobs-1, with at most
+// c.jobs calls running at once.
+func (c *compiler) runJobs(numJobs int, f func(int)) {
+	jobs := c.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	semaphore := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := 0; i < numJobs; i++ {
+		i := i
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			f(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *compiler) DeleteCache() error {
+	compileCachePath, err := getCompileCachePath(c.cachePath)
+	if err != nil {
+		return err
+	}
+	c.logger.Debug("deleting", zap.String("path", compileCachePath))
+	return os.RemoveAll(compileCachePath)
+}
+
 func (c *compiler) ProtocCommands(protoSets ...*file.ProtoSet) ([]string, error) {
 	var cmdMetaStrings []string
 	for _, protoSet := range protoSets {
+		if protoSet.Config.Compile.Backend != settings.CompileBackendProtoc {
+			return nil, fmt.Errorf("there are no protoc commands for the %v compile backend for %s", protoSet.Config.Compile.Backend, protoSet.Config.DirPath)
+		}
 		cmdMetas, err := c.getCmdMetas(protoSet)
 		if err != nil {
 			return nil, err
@@ -211,6 +288,84 @@ func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	return failures, nil
 }
 
+// runCmdMetaWithCache runs the command, or restores the results of the
+// command from the compile cache if it was already run with the same inputs.
+//
+// Commands with failures are not cached. If the cache cannot be used,
+// the command is run without the cache.
+func (c *compiler) runCmdMetaWithCache(compileCache *compileCache, cmdMeta *cmdMeta) ([]*text.Failure, error) {
+	key, err := compileCache.getKey(cmdMeta)
+	if err != nil {
+		c.logger.Debug("not using compile cache", zap.String("command", cmdMeta.String()), zap.Error(err))
+		return c.runCmdMeta(cmdMeta)
+	}
+	data, ok, err := compileCache.get(key)
+	if err != nil {
+		c.logger.Warn("could not read compile cache entry", zap.String("key", key), zap.Error(err))
+		return c.runCmdMeta(cmdMeta)
+	}
+	if ok {
+		c.logger.Debug("using compile cache", zap.String("command", cmdMeta.String()), zap.String("key", key))
+		return nil, restoreCmdMeta(cmdMeta, data)
+	}
+	if cmdMeta.genPlugin == nil {
+		failures, err := c.runCmdMeta(cmdMeta)
+		if err != nil || len(failures) > 0 {
+			return failures, err
+		}
+		var data []byte
+		if cmdMeta.descriptorSetTempFilePath != "" {
+			data, err = ioutil.ReadFile(cmdMeta.descriptorSetTempFilePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err := compileCache.put(key, data); err != nil {
+			c.logger.Warn("could not store compile cache entry", zap.String("key", key), zap.Error(err))
+		}
+		return nil, nil
+	}
+	// generate to a zip file in the cache so that the generated files can be stored
+	zipFilePath, err := compileCache.newTempFilePath(".zip")
+	if err != nil {
+		c.logger.Warn("could not create compile cache entry", zap.String("key", key), zap.Error(err))
+		return c.runCmdMeta(cmdMeta)
+	}
+	defer tryRemoveTempFile(zipFilePath)
+	execCmd, err := newGenExecCmd(cmdMeta.execCmd.Path, cmdMeta, zipFilePath)
+	if err != nil {
+		return nil, err
+	}
+	zipCmdMeta := *cmdMeta
+	zipCmdMeta.execCmd = execCmd
+	failures, err := c.runCmdMeta(&zipCmdMeta)
+	if err != nil || len(failures) > 0 {
+		return failures, err
+	}
+	data, err = ioutil.ReadFile(zipFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := restoreCmdMeta(cmdMeta, data); err != nil {
+		return nil, err
+	}
+	if err := compileCache.putFile(key, zipFilePath); err != nil {
+		c.logger.Warn("could not store compile cache entry", zap.String("key", key), zap.Error(err))
+	}
+	return nil, nil
+}
+
+// getCompileCache returns the compile cache, or nil if the cache path
+// cannot be determined.
+func (c *compiler) getCompileCache() *compileCache {
+	compileCachePath, err := getCompileCachePath(c.cachePath)
+	if err != nil {
+		c.logger.Debug("not using compile cache", zap.Error(err))
+		return nil
+	}
+	return newCompileCache(c.logger, compileCachePath)
+}
+
 func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, retErr error) {
 	defer func() {
 		if retErr != nil {
@@ -222,7 +377,14 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 	if _, err := downloader.Download(); err != nil {
 		return cmdMetas, err
 	}
-	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+	dirPaths := make([]string, 0, len(protoSet.DirPathToFiles))
+	for dirPath := range protoSet.DirPathToFiles {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	// sorted so that the commands and FileDescriptorSets are in a deterministic order
+	sort.Strings(dirPaths)
+	for _, dirPath := range dirPaths {
+		protoFiles := protoSet.DirPathToFiles[dirPath]
 		// best effort to make sure we have the a parent directory of the file
 		configDirPath := protoSet.Config.DirPath
 		if configDirPath == "" {
@@ -251,8 +413,8 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 			}
 			iArgs := append(args, "-o", descriptorSetFilePath)
 			if descriptorSetTempFilePath != "" {
-				// TODO(pedge): we will need source info if we switch out emicklei/proto
-				//iArgs = append(iArgs, "--include_source_info")
+				// source info is needed for positions in lint.DescriptorCheckers
+				iArgs = append(iArgs, "--include_source_info")
 				iArgs = append(iArgs, "--include_imports")
 			}
 			for _, protoFile := range protoFiles {
@@ -262,28 +424,52 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 				execCmd:                   exec.Command(protocPath, iArgs...),
 				protoSet:                  protoSet,
 				protoFiles:                protoFiles,
+				dirPath:                   dirPath,
+				includes:                  includes,
 				descriptorSetTempFilePath: descriptorSetTempFilePath,
 			})
 		}
-		pluginFlagSets, err := c.getPluginFlagSets(protoSet, dirPath)
-		if err != nil {
-			return cmdMetas, err
+		if !c.doGen {
+			continue
 		}
-		for _, pluginFlagSet := range pluginFlagSets {
-			iArgs := append(args, pluginFlagSet...)
-			for _, protoFile := range protoFiles {
-				iArgs = append(iArgs, protoFile.Path)
-			}
-			cmdMetas = append(cmdMetas, &cmdMeta{
-				execCmd:    exec.Command(protocPath, iArgs...),
+		for _, genPlugin := range protoSet.Config.Gen.Plugins {
+			genPlugin := genPlugin
+			cmdMeta := &cmdMeta{
 				protoSet:   protoSet,
 				protoFiles: protoFiles,
-			})
+				dirPath:    dirPath,
+				includes:   includes,
+				genPlugin:  &genPlugin,
+			}
+			execCmd, err := newGenExecCmd(protocPath, cmdMeta, genPlugin.OutputPath.AbsPath)
+			if err != nil {
+				return cmdMetas, err
+			}
+			cmdMeta.execCmd = execCmd
+			cmdMetas = append(cmdMetas, cmdMeta)
 		}
 	}
 	return cmdMetas, nil
 }
 
+// newGenExecCmd returns the command to generate the files for the
+// cmdMeta's GenPlugin to the output path.
+func newGenExecCmd(protocPath string, cmdMeta *cmdMeta, outputPath string) (*exec.Cmd, error) {
+	pluginFlagSet, err := getPluginFlagSet(cmdMeta.protoSet, cmdMeta.dirPath, *cmdMeta.genPlugin, outputPath)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for _, include := range cmdMeta.includes {
+		args = append(args, "-I", include)
+	}
+	args = append(args, pluginFlagSet...)
+	for _, protoFile := range cmdMeta.protoFiles {
+		args = append(args, protoFile.Path)
+	}
+	return exec.Command(protocPath, args...), nil
+}
+
 func (c *compiler) newDownloader(config settings.Config) Downloader {
 	downloaderOptions := []DownloaderOption{
 		DownloaderWithLogger(c.logger),
@@ -319,29 +505,14 @@ func (c *compiler) getDescriptorSetFilePath(protoSet *file.ProtoSet) (string, bo
 	return devNullFilePath, false, err
 }
 
-func (c *compiler) getPluginFlagSets(protoSet *file.ProtoSet, dirPath string) ([][]string, error) {
-	if !c.doGen || len(protoSet.Config.Gen.Plugins) == 0 {
-		return nil, nil
-	}
-	pluginFlagSets := make([][]string, 0, len(protoSet.Config.Gen.Plugins))
-	for _, genPlugin := range protoSet.Config.Gen.Plugins {
-		pluginFlagSet, err := getPluginFlagSet(protoSet, dirPath, genPlugin)
-		if err != nil {
-			return nil, err
-		}
-		pluginFlagSets = append(pluginFlagSets, pluginFlagSet)
-	}
-	return pluginFlagSets, nil
-}
-
-func getPluginFlagSet(protoSet *file.ProtoSet, dirPath string, genPlugin settings.GenPlugin) ([]string, error) {
+func getPluginFlagSet(protoSet *file.ProtoSet, dirPath string, genPlugin settings.GenPlugin, outputPath string) ([]string, error) {
 	protoFlags, err := getPluginFlagSetProtoFlags(protoSet, dirPath, genPlugin)
 	if err != nil {
 		return nil, err
 	}
-	flagSet := []string{fmt.Sprintf("--%s_out=%s", genPlugin.Name, genPlugin.OutputPath.AbsPath)}
+	flagSet := []string{fmt.Sprintf("--%s_out=%s", genPlugin.Name, outputPath)}
 	if len(protoFlags) > 0 {
-		flagSet = []string{fmt.Sprintf("--%s_out=%s:%s", genPlugin.Name, protoFlags, genPlugin.OutputPath.AbsPath)}
+		flagSet = []string{fmt.Sprintf("--%s_out=%s:%s", genPlugin.Name, protoFlags, outputPath)}
 	}
 	if genPlugin.Path != "" {
 		flagSet = append(flagSet, fmt.Sprintf("--plugin=protoc-gen-%s=%s", genPlugin.Name, genPlugin.Path))
@@ -376,9 +547,7 @@ func getPluginFlagSetProtoFlags(protoSet *file.ProtoSet, dirPath string, genPlug
 				}
 			}
 		}
-		for key, value := range modifiers {
-			goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, value))
-		}
+		goFlags = append(goFlags, getModifierFlags(modifiers)...)
 		if protoSet.Config.Compile.IncludeWellKnownTypes {
 			// one of these two must be true, we validate this above
 			if genPlugin.Type.IsGo() {
@@ -387,17 +556,24 @@ func getPluginFlagSetProtoFlags(protoSet *file.ProtoSet, dirPath string, genPlug
 			if genPlugin.Type.IsGogo() {
 				modifiers = wkt.FilenameToGogoModifierMap
 			}
-			for key, value := range modifiers {
-				goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, value))
-			}
+			goFlags = append(goFlags, getModifierFlags(modifiers)...)
 		}
 	}
-	for key, value := range genGoPluginOptions.ExtraModifiers {
-		goFlags = append(goFlags, fmt.Sprintf("M%s=%s", key, value))
-	}
+	goFlags = append(goFlags, getModifierFlags(genGoPluginOptions.ExtraModifiers)...)
 	return strings.Join(goFlags, ","), nil
 }
 
+// getModifierFlags returns the M flags for the modifiers, sorted so that
+// the commands are the same for every run.
+func getModifierFlags(modifiers map[string]string) []string {
+	modifierFlags := make([]string, 0, len(modifiers))
+	for key, value := range modifiers {
+		modifierFlags = append(modifierFlags, fmt.Sprintf("M%s=%s", key, value))
+	}
+	sort.Strings(modifierFlags)
+	return modifierFlags
+}
+
 func getIncludes(
 	downloader Downloader,
 	config settings.Config,
@@ -598,6 +774,18 @@ func getDisplayFilePath(cmdMeta *cmdMeta, match string) (string, error) {
 	return matchingFile, nil
 }
 
+// restoreCmdMeta writes the results of the command from the data of
+// a compile cache entry.
+func restoreCmdMeta(cmdMeta *cmdMeta, data []byte) error {
+	if cmdMeta.genPlugin != nil {
+		return extractZip(data, cmdMeta.genPlugin.OutputPath.AbsPath)
+	}
+	if cmdMeta.descriptorSetTempFilePath != "" {
+		return ioutil.WriteFile(cmdMeta.descriptorSetTempFilePath, data, 0644)
+	}
+	return nil
+}
+
 func getFileDescriptorSet(cmdMeta *cmdMeta) (*descriptor.FileDescriptorSet, error) {
 	if cmdMeta.descriptorSetTempFilePath == "" {
 		return nil, nil
@@ -650,7 +838,11 @@ type cmdMeta struct {
 	execCmd                   *exec.Cmd
 	protoSet                  *file.ProtoSet
 	protoFiles                []*file.ProtoFile
+	dirPath                   string
+	includes                  []string
 	descriptorSetTempFilePath string
+	// only set for commands that generate with a plugin
+	genPlugin *settings.GenPlugin
 }
 
 func (c *cmdMeta) String() string {
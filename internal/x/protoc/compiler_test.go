@@ -0,0 +1,55 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunJobs(t *testing.T) {
+	for _, jobs := range []int{1, 2, 5} {
+		compiler := newCompiler(CompilerWithJobs(jobs))
+		var running int32
+		var maxRunning int32
+		var lock sync.Mutex
+		called := make([]bool, 20)
+		compiler.runJobs(len(called), func(i int) {
+			iRunning := atomic.AddInt32(&running, 1)
+			lock.Lock()
+			if iRunning > maxRunning {
+				maxRunning = iRunning
+			}
+			called[i] = true
+			lock.Unlock()
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+		assert.True(t, maxRunning <= int32(jobs), "jobs %d ran %d at once", jobs, maxRunning)
+		for i, iCalled := range called {
+			assert.True(t, iCalled, "jobs %d did not call %d", jobs, i)
+		}
+	}
+}
@@ -252,33 +252,52 @@ func (d *downloader) getBasePath() (string, error) {
 }
 
 func (d *downloader) getBasePathNoVersion() (string, error) {
-	basePath := d.cachePath
+	basePath, err := getCachePath(d.cachePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(basePath, "protobuf"), nil
+}
+
+func (d *downloader) getBasePathVersionPart() string {
+	if d.protocURL != "" {
+		// we don't know the version or what is going on here
+		hash := sha512.New()
+		_, _ = hash.Write([]byte(d.protocURL))
+		return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+	}
+	return d.config.Compile.ProtobufVersion
+}
+
+// getCachePath returns the absolute cache path, which is the given
+// cachePath if set, or the default base path otherwise.
+func getCachePath(cachePath string) (string, error) {
 	var err error
-	if basePath == "" {
-		basePath, err = getDefaultBasePath()
+	if cachePath == "" {
+		cachePath, err = getDefaultBasePath()
 		if err != nil {
 			return "", err
 		}
 	} else {
-		basePath, err = absClean(basePath)
+		cachePath, err = absClean(cachePath)
 		if err != nil {
 			return "", err
 		}
 	}
-	if err := checkAbs(basePath); err != nil {
+	if err := checkAbs(cachePath); err != nil {
 		return "", err
 	}
-	return filepath.Join(basePath, "protobuf"), nil
+	return cachePath, nil
 }
 
-func (d *downloader) getBasePathVersionPart() string {
-	if d.protocURL != "" {
-		// we don't know the version or what is going on here
-		hash := sha512.New()
-		_, _ = hash.Write([]byte(d.protocURL))
-		return base64.URLEncoding.EncodeToString(hash.Sum(nil))
+// getCompileCachePath returns the path to the compile cache
+// for the given cachePath.
+func getCompileCachePath(cachePath string) (string, error) {
+	basePath, err := getCachePath(cachePath)
+	if err != nil {
+		return "", err
 	}
-	return d.config.Compile.ProtobufVersion
+	return filepath.Join(basePath, "compile"), nil
 }
 
 func getDefaultBasePath() (string, error) {
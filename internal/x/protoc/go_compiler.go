@@ -0,0 +1,230 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/tgrpc/prototool/internal/x/file"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"github.com/tgrpc/prototool/internal/x/wkt"
+	"go.uber.org/zap"
+
+	// the Well-Known Types are loaded from the registered Golang types
+	// so that they are available without a protoc download
+	_ "github.com/golang/protobuf/protoc-gen-go/plugin"
+	_ "github.com/golang/protobuf/ptypes/any"
+	_ "github.com/golang/protobuf/ptypes/duration"
+	_ "github.com/golang/protobuf/ptypes/empty"
+	_ "github.com/golang/protobuf/ptypes/struct"
+	_ "github.com/golang/protobuf/ptypes/timestamp"
+	_ "github.com/golang/protobuf/ptypes/wrappers"
+	_ "google.golang.org/genproto/protobuf/api"
+	_ "google.golang.org/genproto/protobuf/field_mask"
+	_ "google.golang.org/genproto/protobuf/ptype"
+	_ "google.golang.org/genproto/protobuf/source_context"
+)
+
+var goCompileErrorRegexp = regexp.MustCompile(`^(.*):(\d+):(\d+): (.*)$`)
+
+// goCompile compiles the ProtoSets in-process with protoparse.
+//
+// The returned CompileResult has the same semantics as Compile.
+// Generation is not supported, and unused imports are not reported.
+func (c *compiler) goCompile(protoSets ...*file.ProtoSet) (*CompileResult, error) {
+	type goCompileJob struct {
+		protoSet   *file.ProtoSet
+		dirPath    string
+		protoFiles []*file.ProtoFile
+
+		failures          []*text.Failure
+		fileDescriptorSet *descriptor.FileDescriptorSet
+		err               error
+	}
+	var goCompileJobs []*goCompileJob
+	for _, protoSet := range protoSets {
+		if c.doGen && len(protoSet.Config.Gen.Plugins) > 0 {
+			return nil, fmt.Errorf("cannot generate with the %v compile backend for %s", protoSet.Config.Compile.Backend, protoSet.Config.DirPath)
+		}
+		dirPaths := make([]string, 0, len(protoSet.DirPathToFiles))
+		for dirPath := range protoSet.DirPathToFiles {
+			dirPaths = append(dirPaths, dirPath)
+		}
+		// sorted so that the FileDescriptorSets are in a deterministic order
+		sort.Strings(dirPaths)
+		for _, dirPath := range dirPaths {
+			goCompileJobs = append(goCompileJobs, &goCompileJob{
+				protoSet:   protoSet,
+				dirPath:    dirPath,
+				protoFiles: protoSet.DirPathToFiles[dirPath],
+			})
+		}
+	}
+	c.runJobs(len(goCompileJobs), func(i int) {
+		job := goCompileJobs[i]
+		job.failures, job.fileDescriptorSet, job.err = c.goCompileDir(job.protoSet, job.dirPath, job.protoFiles)
+	})
+	var failures []*text.Failure
+	var fileDescriptorSets []*descriptor.FileDescriptorSet
+	var errs []error
+	for _, job := range goCompileJobs {
+		failures = append(failures, job.failures...)
+		if job.fileDescriptorSet != nil {
+			fileDescriptorSets = append(fileDescriptorSets, job.fileDescriptorSet)
+		}
+		if job.err != nil {
+			errs = append(errs, job.err)
+		}
+	}
+	if len(errs) > 0 {
+		errStrings := make([]string, 0, len(errs))
+		for _, err := range errs {
+			errStrings = append(errStrings, err.Error())
+		}
+		return nil, errors.New(strings.Join(errStrings, "\n"))
+	}
+	if len(failures) > 0 {
+		text.SortFailures(failures)
+		return &CompileResult{
+			Failures: failures,
+		}, nil
+	}
+	if !c.doFileDescriptorSet {
+		return &CompileResult{}, nil
+	}
+	return &CompileResult{
+		FileDescriptorSets: fileDescriptorSets,
+	}, nil
+}
+
+func (c *compiler) goCompileDir(protoSet *file.ProtoSet, dirPath string, protoFiles []*file.ProtoFile) ([]*text.Failure, *descriptor.FileDescriptorSet, error) {
+	configDirPath := protoSet.Config.DirPath
+	if configDirPath == "" {
+		configDirPath = protoSet.WorkDirPath
+	}
+	// the Well-Known Types are not on the include path for
+	// this backend, so we do not want getIncludes to download them
+	config := protoSet.Config
+	config.Compile.IncludeWellKnownTypes = false
+	includes, err := getIncludes(nil, config, dirPath, configDirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	filenames := make([]string, 0, len(protoFiles))
+	filenameToDisplayPath := make(map[string]string, len(protoFiles))
+	for _, protoFile := range protoFiles {
+		filename, err := getImportFilename(includes, protoFile.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		filenames = append(filenames, filename)
+		filenameToDisplayPath[filename] = protoFile.DisplayPath
+	}
+	parser := protoparse.Parser{
+		ImportPaths:           includes,
+		IncludeSourceCodeInfo: true,
+	}
+	if protoSet.Config.Compile.IncludeWellKnownTypes {
+		parser.LookupImport = lookupWellKnownType
+	}
+	c.logger.Debug("compiling with go backend", zap.String("dirPath", dirPath), zap.Strings("includes", includes), zap.Strings("filenames", filenames))
+	fileDescriptors, err := parser.ParseFiles(filenames...)
+	if err != nil {
+		return []*text.Failure{getGoCompileFailure(filenameToDisplayPath, err)}, nil, nil
+	}
+	return nil, getGoFileDescriptorSet(fileDescriptors), nil
+}
+
+// getImportFilename gets the filename relative to the first
+// include path that contains the file, as protoc does.
+func getImportFilename(includes []string, filePath string) (string, error) {
+	for _, include := range includes {
+		rel, err := filepath.Rel(include, filePath)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not within any include path", filePath)
+}
+
+func lookupWellKnownType(filename string) (*desc.FileDescriptor, error) {
+	if _, ok := wkt.FilenameMap[filename]; !ok {
+		return nil, fmt.Errorf("%s is not a Well-Known Type", filename)
+	}
+	return desc.LoadFileDescriptor(filename)
+}
+
+func getGoCompileFailure(filenameToDisplayPath map[string]string, err error) *text.Failure {
+	message := strings.TrimSpace(err.Error())
+	matches := goCompileErrorRegexp.FindStringSubmatch(message)
+	if len(matches) != 5 {
+		return &text.Failure{
+			Message: message,
+		}
+	}
+	// the regexp guarantees these are integers
+	line, _ := strconv.Atoi(matches[2])
+	column, _ := strconv.Atoi(matches[3])
+	filename := matches[1]
+	if displayPath, ok := filenameToDisplayPath[filename]; ok {
+		filename = displayPath
+	}
+	return &text.Failure{
+		Filename: filename,
+		Line:     line,
+		Column:   column,
+		Message:  matches[4],
+	}
+}
+
+// getGoFileDescriptorSet returns a FileDescriptorSet that includes
+// all imports in topological order, as protoc --include_imports does.
+func getGoFileDescriptorSet(fileDescriptors []*desc.FileDescriptor) *descriptor.FileDescriptorSet {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+	var add func(*desc.FileDescriptor)
+	add = func(fileDescriptor *desc.FileDescriptor) {
+		if _, ok := seen[fileDescriptor.GetName()]; ok {
+			return
+		}
+		seen[fileDescriptor.GetName()] = struct{}{}
+		for _, dependency := range fileDescriptor.GetDependencies() {
+			add(dependency)
+		}
+		fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptor.AsFileDescriptorProto())
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		add(fileDescriptor)
+	}
+	return fileDescriptorSet
+}
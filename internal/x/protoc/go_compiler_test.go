@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tgrpc/prototool/internal/x/file"
+	"github.com/tgrpc/prototool/internal/x/settings"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"github.com/tgrpc/prototool/internal/x/wkt"
+)
+
+func TestGoCompile(t *testing.T) {
+	dirPath, protoSet := newTestGoProtoSet(t, map[string]string{
+		"foo/foo.proto": `syntax = "proto3";
+
+package foo;
+
+import "google/protobuf/timestamp.proto";
+
+message Foo {
+  google.protobuf.Timestamp time = 1;
+}
+`,
+		"foo/bar.proto": `syntax = "proto3";
+
+package foo;
+
+import "foo/foo.proto";
+
+message Bar {
+  Foo foo = 1;
+}
+`,
+	})
+	defer func() { _ = os.RemoveAll(dirPath) }()
+
+	compileResult, err := NewCompiler(CompilerWithFileDescriptorSet()).Compile(protoSet)
+	require.NoError(t, err)
+	require.Empty(t, compileResult.Failures)
+	require.Len(t, compileResult.FileDescriptorSets, 1)
+	var names []string
+	for _, fileDescriptorProto := range compileResult.FileDescriptorSets[0].File {
+		names = append(names, fileDescriptorProto.GetName())
+		if _, ok := wkt.FilenameMap[fileDescriptorProto.GetName()]; !ok {
+			assert.NotNil(t, fileDescriptorProto.SourceCodeInfo)
+		}
+	}
+	assert.Equal(t, []string{"google/protobuf/timestamp.proto", "foo/foo.proto", "foo/bar.proto"}, names)
+
+	compileResult, err = NewCompiler().Compile(protoSet)
+	require.NoError(t, err)
+	assert.Empty(t, compileResult.Failures)
+	assert.Empty(t, compileResult.FileDescriptorSets)
+}
+
+func TestGoCompileFailure(t *testing.T) {
+	dirPath, protoSet := newTestGoProtoSet(t, map[string]string{
+		"foo/foo.proto": `syntax = "proto3";
+
+package foo;
+
+message Foo {
+  Bar bar = 1;
+}
+`,
+	})
+	defer func() { _ = os.RemoveAll(dirPath) }()
+
+	compileResult, err := NewCompiler(CompilerWithFileDescriptorSet()).Compile(protoSet)
+	require.NoError(t, err)
+	require.Len(t, compileResult.Failures, 1)
+	assert.Empty(t, compileResult.FileDescriptorSets)
+	failure := compileResult.Failures[0]
+	assert.Equal(t, "foo/foo.proto", failure.Filename)
+	assert.Equal(t, 6, failure.Line)
+	assert.Equal(t, 3, failure.Column)
+}
+
+func TestGoCompileGenNotSupported(t *testing.T) {
+	dirPath, protoSet := newTestGoProtoSet(t, map[string]string{
+		"foo/foo.proto": `syntax = "proto3";
+
+package foo;
+`,
+	})
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	protoSet.Config.Gen.Plugins = []settings.GenPlugin{
+		{
+			Name: "java",
+			OutputPath: settings.OutputPath{
+				RelPath: "gen",
+				AbsPath: filepath.Join(dirPath, "gen"),
+			},
+		},
+	}
+
+	_, err := NewCompiler(CompilerWithGen()).Compile(protoSet)
+	assert.Error(t, err)
+	_, err = NewCompiler().ProtocCommands(protoSet)
+	assert.Error(t, err)
+}
+
+func TestGetGoCompileFailure(t *testing.T) {
+	filenameToDisplayPath := map[string]string{
+		"foo/foo.proto": "proto/foo/foo.proto",
+	}
+	assert.Equal(
+		t,
+		&text.Failure{
+			Filename: "proto/foo/foo.proto",
+			Line:     3,
+			Column:   5,
+			Message:  "syntax error: unexpected identifier",
+		},
+		getGoCompileFailure(filenameToDisplayPath, testError("foo/foo.proto:3:5: syntax error: unexpected identifier")),
+	)
+	assert.Equal(
+		t,
+		&text.Failure{
+			Message: "foo/bar.proto: file not found",
+		},
+		getGoCompileFailure(filenameToDisplayPath, testError("foo/bar.proto: file not found")),
+	)
+}
+
+type testError string
+
+func (e testError) Error() string {
+	return string(e)
+}
+
+func newTestGoProtoSet(t *testing.T, filenameToContents map[string]string) (string, *file.ProtoSet) {
+	dirPath, err := ioutil.TempDir("", "prototool")
+	require.NoError(t, err)
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	dirPathToFiles := make(map[string][]*file.ProtoFile)
+	for filename, contents := range filenameToContents {
+		filePath := filepath.Join(dirPath, filename)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(contents), 0644))
+		dirPathToFiles[filepath.Dir(filePath)] = append(
+			dirPathToFiles[filepath.Dir(filePath)],
+			&file.ProtoFile{
+				Path:        filePath,
+				DisplayPath: filename,
+			},
+		)
+	}
+	return dirPath, &file.ProtoSet{
+		WorkDirPath:    dirPath,
+		DirPath:        dirPath,
+		DirPathToFiles: dirPathToFiles,
+		Config: settings.Config{
+			DirPath: dirPath,
+			Compile: settings.CompileConfig{
+				IncludeWellKnownTypes: true,
+				Backend:               settings.CompileBackendGo,
+			},
+		},
+	}
+}
@@ -114,6 +114,9 @@ type CompileResult struct {
 type Compiler interface {
 	// Compile the protobuf files with protoc.
 	//
+	// If a ProtoSet's config uses settings.CompileBackendGo, the files
+	// are instead compiled in-process, and protoc is not downloaded.
+	//
 	// If there are compile failures, they will be returned in the slice
 	// and there will be no error. The caller can determine if this is
 	// an error case. If there is any other type of error, or some output
@@ -121,11 +124,25 @@ type Compiler interface {
 	//
 	// FileDescriptorSet will only be set if the CompilerWithFileDescriptorSet
 	// option is used.
+	//
+	// The results of protoc commands without failures are stored in a
+	// compile cache in the cache path, keyed on the inputs to the command,
+	// which are the protoc binary, the arguments, the contents of the files
+	// and their transitive imports, and the plugin binary if generating.
+	// If a command was already run with the same inputs, protoc is not run,
+	// and the FileDescriptorSet or generated files are restored from the cache.
 	Compile(...*file.ProtoSet) (*CompileResult, error)
 
+	// Delete the compile cache.
+	//
+	// This is not thread-safe and no calls to Compile can be reliably
+	// made simultaneously.
+	DeleteCache() error
+
 	// Return the protoc commands that would be run on Compile.
 	//
 	// This will ignore the CompilerWithFileDescriptorSet option.
+	// This will return an error if any ProtoSet's config uses settings.CompileBackendGo.
 	ProtocCommands(...*file.ProtoSet) ([]string, error)
 }
 
@@ -174,6 +191,18 @@ func CompilerWithFileDescriptorSet() CompilerOption {
 	}
 }
 
+// CompilerWithJobs returns a CompilerOption that runs at most the given
+// number of protoc commands or directory compiles at once.
+//
+// The default is runtime.NumCPU(). Values less than 1 are ignored.
+func CompilerWithJobs(jobs int) CompilerOption {
+	return func(compiler *compiler) {
+		if jobs > 0 {
+			compiler.jobs = jobs
+		}
+	}
+}
+
 // NewCompiler returns a new Compiler.
 func NewCompiler(options ...CompilerOption) Compiler {
 	return newCompiler(options...)
@@ -0,0 +1,178 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reflect
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// exampleWellKnownTypeValues are the example values for the well-known
+// types that have special JSON representations.
+var exampleWellKnownTypeValues = map[string]interface{}{
+	"google.protobuf.Any":         exampleObject{{name: "@type", value: ""}},
+	"google.protobuf.BoolValue":   false,
+	"google.protobuf.BytesValue":  "",
+	"google.protobuf.DoubleValue": 0,
+	"google.protobuf.Duration":    "0s",
+	"google.protobuf.Empty":       exampleObject{},
+	"google.protobuf.FieldMask":   "",
+	"google.protobuf.FloatValue":  0,
+	"google.protobuf.Int32Value":  0,
+	"google.protobuf.Int64Value":  "0",
+	"google.protobuf.ListValue":   []interface{}{},
+	"google.protobuf.StringValue": "",
+	"google.protobuf.Struct":      exampleObject{},
+	"google.protobuf.Timestamp":   "1970-01-01T00:00:00Z",
+	"google.protobuf.UInt32Value": 0,
+	"google.protobuf.UInt64Value": "0",
+	"google.protobuf.Value":       nil,
+}
+
+// exampleObject is a JSON object that keeps the order of its fields,
+// so that fields are printed in the order they are declared.
+type exampleObject []*exampleField
+
+type exampleField struct {
+	name  string
+	value interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o exampleObject) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	_, _ = buffer.WriteString("{")
+	for i, field := range o {
+		if i > 0 {
+			_, _ = buffer.WriteString(",")
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = buffer.Write(name)
+		_, _ = buffer.WriteString(":")
+		_, _ = buffer.Write(value)
+	}
+	_, _ = buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+func getExampleJSON(messageDescriptor *desc.MessageDescriptor) ([]byte, error) {
+	data, err := json.MarshalIndent(getExampleMessageValue(messageDescriptor, make(map[string]struct{})), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// getExampleMessageValue gets the example value for the message.
+//
+// The names of the messages being populated are in seen, so that
+// recursive messages are populated with an empty object.
+func getExampleMessageValue(messageDescriptor *desc.MessageDescriptor, seen map[string]struct{}) interface{} {
+	name := messageDescriptor.GetFullyQualifiedName()
+	if value, ok := exampleWellKnownTypeValues[name]; ok {
+		return value
+	}
+	if _, ok := seen[name]; ok {
+		return exampleObject{}
+	}
+	seen[name] = struct{}{}
+	defer delete(seen, name)
+	object := exampleObject{}
+	seenOneOfs := make(map[*desc.OneOfDescriptor]struct{})
+	for _, fieldDescriptor := range messageDescriptor.GetFields() {
+		if oneOfDescriptor := fieldDescriptor.GetOneOf(); oneOfDescriptor != nil {
+			// only one field of a oneof can be set
+			if _, ok := seenOneOfs[oneOfDescriptor]; ok {
+				continue
+			}
+			seenOneOfs[oneOfDescriptor] = struct{}{}
+		}
+		object = append(object, &exampleField{
+			name:  fieldDescriptor.GetJSONName(),
+			value: getExampleFieldValue(fieldDescriptor, seen),
+		})
+	}
+	return object
+}
+
+func getExampleFieldValue(fieldDescriptor *desc.FieldDescriptor, seen map[string]struct{}) interface{} {
+	if fieldDescriptor.IsMap() {
+		return exampleObject{
+			{
+				name:  getExampleMapKey(fieldDescriptor.GetMapKeyType()),
+				value: getExampleSingularFieldValue(fieldDescriptor.GetMapValueType(), seen),
+			},
+		}
+	}
+	value := getExampleSingularFieldValue(fieldDescriptor, seen)
+	if fieldDescriptor.IsRepeated() {
+		return []interface{}{value}
+	}
+	return value
+}
+
+func getExampleSingularFieldValue(fieldDescriptor *desc.FieldDescriptor, seen map[string]struct{}) interface{} {
+	switch fieldDescriptor.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		return getExampleMessageValue(fieldDescriptor.GetMessageType(), seen)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		if values := fieldDescriptor.GetEnumType().GetValues(); len(values) > 0 {
+			return values[0].GetName()
+		}
+		return 0
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		// 64-bit integers are strings in JSON
+		return "0"
+	default:
+		return 0
+	}
+}
+
+// getExampleMapKey gets the example map key, as map keys are
+// always strings in JSON.
+func getExampleMapKey(fieldDescriptor *desc.FieldDescriptor) string {
+	switch fieldDescriptor.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return ""
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return "false"
+	default:
+		return "0"
+	}
+}
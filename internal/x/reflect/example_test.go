@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reflect
+
+import (
+	"testing"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExampleProto = `syntax = "proto3";
+
+package foo;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+enum Hello {
+  HELLO_INVALID = 0;
+  HELLO_WORLD = 1;
+}
+
+message Foo {
+  string string_value = 1;
+  int32 int32_value = 2;
+  int64 int64_value = 3;
+  uint64 uint64_value = 4;
+  double double_value = 5;
+  bool bool_value = 6;
+  bytes bytes_value = 7;
+  Hello hello = 8;
+  repeated string strings = 9;
+  map<string, Bar> string_to_bar = 10;
+  map<int64, Hello> int64_to_hello = 11;
+  oneof one {
+    Bar bar = 12;
+    string one_string = 13;
+  }
+  Foo recursive = 14;
+  repeated Bar bars = 15;
+  google.protobuf.Timestamp timestamp = 16;
+  google.protobuf.Duration duration = 17;
+  google.protobuf.StringValue string_wrapper = 18;
+  google.protobuf.Int64Value int64_wrapper = 19;
+  google.protobuf.Struct struct_value = 20;
+  google.protobuf.Value value = 21;
+  google.protobuf.Any any = 22;
+}
+
+message Bar {
+  Foo foo = 1;
+  string bar = 2;
+}
+`
+
+func TestGetExampleJSON(t *testing.T) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"foo.proto": testExampleProto}),
+	}
+	fileDescriptors, err := parser.ParseFiles("foo.proto")
+	require.NoError(t, err)
+	messageDescriptor := fileDescriptors[0].FindMessage("foo.Foo")
+	require.NotNil(t, messageDescriptor)
+	data, err := getExampleJSON(messageDescriptor)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{
+  "stringValue": "",
+  "int32Value": 0,
+  "int64Value": "0",
+  "uint64Value": "0",
+  "doubleValue": 0,
+  "boolValue": false,
+  "bytesValue": "",
+  "hello": "HELLO_INVALID",
+  "strings": [
+    ""
+  ],
+  "stringToBar": {
+    "": {
+      "foo": {},
+      "bar": ""
+    }
+  },
+  "int64ToHello": {
+    "0": "HELLO_INVALID"
+  },
+  "bar": {
+    "foo": {},
+    "bar": ""
+  },
+  "recursive": {},
+  "bars": [
+    {
+      "foo": {},
+      "bar": ""
+    }
+  ],
+  "timestamp": "1970-01-01T00:00:00Z",
+  "duration": "0s",
+  "stringWrapper": "",
+  "int64Wrapper": "0",
+  "structValue": {},
+  "value": null,
+  "any": {
+    "@type": ""
+  }
+}
+`,
+		string(data),
+	)
+}
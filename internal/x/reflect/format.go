@@ -0,0 +1,236 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reflect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/dynamic"
+	"gopkg.in/yaml.v2"
+)
+
+// Format is a format to convert messages from and to.
+type Format int
+
+const (
+	// FormatBinary is the Protobuf binary format.
+	FormatBinary Format = iota
+	// FormatJSON is the Protobuf JSON format.
+	FormatJSON
+	// FormatText is the Protobuf text format.
+	FormatText
+	// FormatYAML is YAML with the same structure as the Protobuf JSON format.
+	FormatYAML
+)
+
+var (
+	formatToString = map[Format]string{
+		FormatBinary: "binary",
+		FormatJSON:   "json",
+		FormatText:   "text",
+		FormatYAML:   "yaml",
+	}
+	stringToFormat = map[string]Format{
+		"binary": FormatBinary,
+		"bin":    FormatBinary,
+		"json":   FormatJSON,
+		"text":   FormatText,
+		"txt":    FormatText,
+		"yaml":   FormatYAML,
+		"yml":    FormatYAML,
+	}
+)
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := formatToString[f]; ok {
+		return s
+	}
+	return strconv.Itoa(int(f))
+}
+
+// ParseFormat parses the Format.
+//
+// The empty string is not a valid Format.
+func ParseFormat(s string) (Format, error) {
+	format, ok := stringToFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown format %q, must be one of binary, json, text, or yaml", s)
+	}
+	return format, nil
+}
+
+func unmarshalFormat(dynamicMessage *dynamic.Message, data []byte, format Format) error {
+	switch format {
+	case FormatBinary:
+		return dynamicMessage.Unmarshal(data)
+	case FormatJSON:
+		return dynamicMessage.UnmarshalJSON(data)
+	case FormatText:
+		return dynamicMessage.UnmarshalText(data)
+	case FormatYAML:
+		jsonData, err := yamlToJSON(data)
+		if err != nil {
+			return err
+		}
+		return dynamicMessage.UnmarshalJSON(jsonData)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func marshalFormat(dynamicMessage *dynamic.Message, format Format, jsonMarshaler *jsonpb.Marshaler) ([]byte, error) {
+	switch format {
+	case FormatBinary:
+		return dynamicMessage.Marshal()
+	case FormatJSON:
+		return dynamicMessage.MarshalJSONPB(jsonMarshaler)
+	case FormatText:
+		data, err := dynamicMessage.MarshalTextIndent()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		return data, nil
+	case FormatYAML:
+		jsonData, err := dynamicMessage.MarshalJSONPB(jsonMarshaler)
+		if err != nil {
+			return nil, err
+		}
+		return jsonToYAML(jsonData)
+	default:
+		return nil, fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+// jsonToYAML converts the JSON data to YAML, keeping the order of the fields.
+func jsonToYAML(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// decodeJSONValue decodes the next JSON value from the decoder, where
+// objects are decoded as yaml.MapSlice values to keep the order of the fields.
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			mapSlice := yaml.MapSlice{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				mapSlice = append(mapSlice, yaml.MapItem{Key: key, Value: value})
+			}
+			// the closing delimiter
+			_, err := decoder.Token()
+			return mapSlice, err
+		case '[':
+			list := []interface{}{}
+			for decoder.More() {
+				value, err := decodeJSONValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			// the closing delimiter
+			_, err := decoder.Token()
+			return list, err
+		default:
+			return nil, fmt.Errorf("unexpected JSON delimiter: %v", token)
+		}
+	case json.Number:
+		if i, err := token.Int64(); err == nil {
+			return i, nil
+		}
+		return token.Float64()
+	default:
+		return token, nil
+	}
+}
+
+// yamlToJSON converts the YAML data to JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		// empty YAML is an empty message
+		return []byte("{}"), nil
+	}
+	jsonValue, err := yamlValueToJSONValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue)
+}
+
+func yamlValueToJSONValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, elem := range value {
+			jsonElem, err := yamlValueToJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			// map keys can be numbers or booleans
+			object[fmt.Sprint(key)] = jsonElem
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, elem := range value {
+			jsonElem, err := yamlValueToJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = jsonElem
+		}
+		return list, nil
+	default:
+		return value, nil
+	}
+}
@@ -22,7 +22,9 @@ package reflect
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/dynamic"
@@ -32,14 +34,16 @@ import (
 )
 
 type handler struct {
-	logger *zap.Logger
+	logger        *zap.Logger
+	jsonMarshaler *jsonpb.Marshaler
 
 	getter extract.Getter
 }
 
 func newHandler(options ...HandlerOption) *handler {
 	handler := &handler{
-		logger: zap.NewNop(),
+		logger:        zap.NewNop(),
+		jsonMarshaler: &jsonpb.Marshaler{},
 	}
 	for _, option := range options {
 		option(handler)
@@ -52,28 +56,47 @@ func newHandler(options ...HandlerOption) *handler {
 }
 
 func (h *handler) BinaryToJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error) {
+	return h.Convert(fileDescriptorSets, messagePath, binaryData, FormatBinary, FormatJSON)
+}
+
+func (h *handler) JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error) {
+	return h.Convert(fileDescriptorSets, messagePath, jsonData, FormatJSON, FormatBinary)
+}
+
+func (h *handler) Convert(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, data []byte, from Format, to Format) ([]byte, error) {
 	dynamicMessage, err := h.getDynamicMessage(fileDescriptorSets, messagePath)
 	if err != nil {
 		return nil, err
 	}
-	if err := dynamicMessage.Unmarshal(binaryData); err != nil {
+	if err := unmarshalFormat(dynamicMessage, data, from); err != nil {
 		return nil, err
 	}
-	return dynamicMessage.MarshalJSON()
+	return marshalFormat(dynamicMessage, to, h.jsonMarshaler)
 }
 
-func (h *handler) JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error) {
-	dynamicMessage, err := h.getDynamicMessage(fileDescriptorSets, messagePath)
+func (h *handler) ExampleJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) ([]byte, error) {
+	var messageDescriptor *desc.MessageDescriptor
+	var err error
+	if strings.Contains(path, "/") {
+		messageDescriptor, err = h.getMethodInputMessageDescriptor(fileDescriptorSets, path)
+	} else {
+		messageDescriptor, err = h.getMessageDescriptor(fileDescriptorSets, path)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if err := dynamicMessage.UnmarshalJSON(jsonData); err != nil {
+	return getExampleJSON(messageDescriptor)
+}
+
+func (h *handler) getDynamicMessage(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string) (*dynamic.Message, error) {
+	messageDescriptor, err := h.getMessageDescriptor(fileDescriptorSets, messagePath)
+	if err != nil {
 		return nil, err
 	}
-	return dynamicMessage.Marshal()
+	return dynamic.NewMessage(messageDescriptor), nil
 }
 
-func (h *handler) getDynamicMessage(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string) (*dynamic.Message, error) {
+func (h *handler) getMessageDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string) (*desc.MessageDescriptor, error) {
 	message, err := h.getter.GetMessage(fileDescriptorSets, messagePath)
 	if err != nil {
 		return nil, err
@@ -93,5 +116,38 @@ func (h *handler) getDynamicMessage(fileDescriptorSets []*descriptor.FileDescrip
 	if messageDescriptor == nil {
 		return nil, fmt.Errorf("no MessageDescriptor for path %s", message.FullyQualifiedPath)
 	}
-	return dynamic.NewMessage(messageDescriptor), nil
+	return messageDescriptor, nil
+}
+
+// getMethodInputMessageDescriptor gets the MessageDescriptor for the
+// request of the method at the path, for example foo.ExcitedService/Exclamation.
+func (h *handler) getMethodInputMessageDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, methodPath string) (*desc.MessageDescriptor, error) {
+	split := strings.Split(methodPath, "/")
+	if len(split) != 2 {
+		return nil, fmt.Errorf("invalid method path: %s", methodPath)
+	}
+	service, err := h.getter.GetService(fileDescriptorSets, split[0])
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet, err := intdesc.SortFileDescriptorSet(service.FileDescriptorSet, service.FileDescriptorProto)
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptor, err := desc.CreateFileDescriptorFromSet(fileDescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	if len(service.FullyQualifiedPath) == 0 || service.FullyQualifiedPath[0] != '.' {
+		return nil, fmt.Errorf("malformed FullyQualifiedPath: %s", service.FullyQualifiedPath)
+	}
+	serviceDescriptor := fileDescriptor.FindService(service.FullyQualifiedPath[1:])
+	if serviceDescriptor == nil {
+		return nil, fmt.Errorf("no ServiceDescriptor for path %s", service.FullyQualifiedPath)
+	}
+	methodDescriptor := serviceDescriptor.FindMethodByName(split[1])
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("service %s does not have method %s", service.FullyQualifiedPath[1:], split[1])
+	}
+	return methodDescriptor.GetInputType(), nil
 }
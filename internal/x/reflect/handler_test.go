@@ -0,0 +1,168 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package reflect
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConvertProto = `syntax = "proto3";
+
+package foo;
+
+enum Hello {
+  HELLO_INVALID = 0;
+  HELLO_WORLD = 1;
+}
+
+message Foo {
+  int64 int64_value = 1;
+  string string_value = 2;
+  Hello hello = 3;
+  repeated Bar bars = 4;
+  map<string, int32> string_to_int32 = 5;
+  double double_value = 6;
+  bool bool_value = 7;
+}
+
+message Bar {
+  string bar_value = 1;
+}
+`
+
+const testConvertJSON = `{"int64Value":"100","stringValue":"foo","hello":"HELLO_WORLD","bars":[{"barValue":"one"},{"barValue":"two"}],"stringToInt32":{"a":1},"doubleValue":1.5}`
+
+func TestConvert(t *testing.T) {
+	fileDescriptorSets := testGetFileDescriptorSets(t, testConvertProto)
+	handler := NewHandler()
+	formatToData := make(map[Format][]byte)
+	for _, format := range []Format{FormatBinary, FormatJSON, FormatText, FormatYAML} {
+		data, err := handler.Convert(fileDescriptorSets, "foo.Foo", []byte(testConvertJSON), FormatJSON, format)
+		require.NoError(t, err, format.String())
+		formatToData[format] = data
+	}
+	assert.Equal(t, testConvertJSON, string(formatToData[FormatJSON]))
+	assert.Equal(
+		t,
+		`int64_value: 100
+string_value: "foo"
+hello: HELLO_WORLD
+bars: <
+  bar_value: "one"
+>
+bars: <
+  bar_value: "two"
+>
+string_to_int32: <
+  key: "a"
+  value: 1
+>
+double_value: 1.5
+`,
+		string(formatToData[FormatText]),
+	)
+	assert.Equal(
+		t,
+		`int64Value: "100"
+stringValue: foo
+hello: HELLO_WORLD
+bars:
+- barValue: one
+- barValue: two
+stringToInt32:
+  a: 1
+doubleValue: 1.5
+`,
+		string(formatToData[FormatYAML]),
+	)
+	// every format converts to every other format
+	for from, fromData := range formatToData {
+		for to, toData := range formatToData {
+			data, err := handler.Convert(fileDescriptorSets, "foo.Foo", fromData, from, to)
+			require.NoError(t, err, "%v to %v", from, to)
+			if to == FormatBinary {
+				// binary map entries are not ordered
+				jsonData, err := handler.Convert(fileDescriptorSets, "foo.Foo", data, to, FormatJSON)
+				require.NoError(t, err)
+				assert.Equal(t, testConvertJSON, string(jsonData), "%v to %v", from, to)
+			} else {
+				assert.Equal(t, string(toData), string(data), "%v to %v", from, to)
+			}
+		}
+	}
+
+	data, err := NewHandler(
+		HandlerWithJSONOrigName(),
+		HandlerWithJSONEnumsAsInts(),
+		HandlerWithJSONEmitDefaults(),
+	).Convert(fileDescriptorSets, "foo.Foo", []byte(`{"hello":"HELLO_WORLD"}`), FormatJSON, FormatYAML)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`int64_value: "0"
+string_value: ""
+hello: 1
+bars: []
+string_to_int32: {}
+double_value: 0
+bool_value: false
+`,
+		string(data),
+	)
+
+	_, err = handler.Convert(fileDescriptorSets, "foo.Foo", []byte(`unknown: 1`), FormatYAML, FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, expected := range map[string]Format{
+		"binary": FormatBinary,
+		"bin":    FormatBinary,
+		"JSON":   FormatJSON,
+		"text":   FormatText,
+		"txt":    FormatText,
+		"yaml":   FormatYAML,
+		"yml":    FormatYAML,
+	} {
+		format, err := ParseFormat(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, format, s)
+	}
+	for _, s := range []string{"", "proto", "xml"} {
+		_, err := ParseFormat(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func testGetFileDescriptorSets(t *testing.T, data string) []*descriptor.FileDescriptorSet {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"foo.proto": data}),
+	}
+	fileDescriptors, err := parser.ParseFiles("foo.proto")
+	require.NoError(t, err)
+	return []*descriptor.FileDescriptorSet{desc.ToFileDescriptorSet(fileDescriptors...)}
+}
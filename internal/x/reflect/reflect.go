@@ -29,6 +29,15 @@ import (
 type Handler interface {
 	BinaryToJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, binaryData []byte) ([]byte, error)
 	JSONToBinary(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, jsonData []byte) ([]byte, error)
+	// Convert converts the data for the message at the path from one Format to another.
+	Convert(fileDescriptorSets []*descriptor.FileDescriptorSet, messagePath string, data []byte, from Format, to Format) ([]byte, error)
+	// ExampleJSON returns example JSON for the message at the path, or for the
+	// request of the method if the path is a method path such as
+	// foo.ExcitedService/Exclamation.
+	//
+	// Every field has a placeholder value for its type, repeated fields and
+	// maps have one element, and only the first field of each oneof is set.
+	ExampleJSON(fileDescriptorSets []*descriptor.FileDescriptorSet, path string) ([]byte, error)
 }
 
 // HandlerOption is an option for a new Handler.
@@ -43,6 +52,37 @@ func HandlerWithLogger(logger *zap.Logger) HandlerOption {
 	}
 }
 
+// HandlerWithJSONOrigName returns a HandlerOption that uses the field names
+// in the Protobuf files instead of the lowerCamelCase JSON names when
+// converting to JSON or YAML.
+//
+// The default is to use the JSON names.
+func HandlerWithJSONOrigName() HandlerOption {
+	return func(handler *handler) {
+		handler.jsonMarshaler.OrigName = true
+	}
+}
+
+// HandlerWithJSONEnumsAsInts returns a HandlerOption that prints enum values
+// as numbers instead of names when converting to JSON or YAML.
+//
+// The default is to print enum value names.
+func HandlerWithJSONEnumsAsInts() HandlerOption {
+	return func(handler *handler) {
+		handler.jsonMarshaler.EnumsAsInts = true
+	}
+}
+
+// HandlerWithJSONEmitDefaults returns a HandlerOption that prints fields
+// with default values when converting to JSON or YAML.
+//
+// The default is to omit fields with default values.
+func HandlerWithJSONEmitDefaults() HandlerOption {
+	return func(handler *handler) {
+		handler.jsonMarshaler.EmitDefaults = true
+	}
+}
+
 // NewHandler returns a new Handler.
 func NewHandler(options ...HandlerOption) Handler {
 	return newHandler(options...)
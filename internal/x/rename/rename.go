@@ -0,0 +1,77 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rename
+
+import (
+	"github.com/emicklei/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"go.uber.org/zap"
+)
+
+// File is a parsed Protobuf file that can be edited by a rename.
+type File struct {
+	// The parsed file. The Filename is used as the key of the edits
+	// returned by Rename.
+	Descriptor *proto.Proto
+	// The contents of the file that were parsed.
+	Data []byte
+}
+
+// Renamer renames Protobuf elements and all references to them.
+type Renamer interface {
+	// Rename returns the edits for each file to rename the message, enum,
+	// enum value, field, service, or RPC with the fully-qualified name to
+	// the new name, and to update all references to it in the files.
+	//
+	// Enum values and fields are given by the fully-qualified name of their
+	// enum or message, followed by their name. The element must be defined
+	// in one of the files, and the FileDescriptorSets must be compiled from
+	// the files.
+	//
+	// Renames that change the JSON name of a field or enum value are
+	// refused unless force is set, as these are not backwards-compatible
+	// for clients that use JSON.
+	Rename(
+		fileDescriptorSets []*descriptor.FileDescriptorSet,
+		files []*File,
+		fullyQualifiedName string,
+		newName string,
+		force bool,
+	) (map[string][]*text.Edit, error)
+}
+
+// RenamerOption is an option for a new Renamer.
+type RenamerOption func(*renamer)
+
+// RenamerWithLogger returns a RenamerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func RenamerWithLogger(logger *zap.Logger) RenamerOption {
+	return func(renamer *renamer) {
+		renamer.logger = logger
+	}
+}
+
+// NewRenamer returns a new Renamer.
+func NewRenamer(options ...RenamerOption) Renamer {
+	return newRenamer(options...)
+}
@@ -0,0 +1,599 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emicklei/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/tgrpc/prototool/internal/x/extract"
+	"github.com/tgrpc/prototool/internal/x/text"
+	"go.uber.org/zap"
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type kind int
+
+const (
+	kindMessage kind = iota + 1
+	kindEnum
+	kindEnumValue
+	kindField
+	kindService
+	kindRPC
+)
+
+var kindToString = map[kind]string{
+	kindMessage:   "message",
+	kindEnum:      "enum",
+	kindEnumValue: "enum value",
+	kindField:     "field",
+	kindService:   "service",
+	kindRPC:       "RPC",
+}
+
+func (k kind) String() string {
+	if s, ok := kindToString[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("%d", k)
+}
+
+// target is the element to rename.
+//
+// All names are without a leading dot.
+type target struct {
+	kind kind
+	// The fully-qualified name of the element.
+	fullName string
+	// The fully-qualified name of the message, enum, or service that
+	// contains the element, or of the package or message for messages,
+	// enums, and services.
+	parentName string
+	// The name of the element.
+	name string
+	// The names of the other fields and oneofs of the message of a field,
+	// RPCs of the service of an RPC, or values of the enum of an enum value.
+	siblingNames map[string]struct{}
+	// The name of the file that defines the element.
+	fileName string
+}
+
+type renamer struct {
+	logger *zap.Logger
+	getter extract.Getter
+}
+
+func newRenamer(options ...RenamerOption) *renamer {
+	renamer := &renamer{
+		logger: zap.NewNop(),
+	}
+	for _, option := range options {
+		option(renamer)
+	}
+	renamer.getter = extract.NewGetter(
+		extract.GetterWithLogger(renamer.logger),
+	)
+	return renamer
+}
+
+func (r *renamer) Rename(
+	fileDescriptorSets []*descriptor.FileDescriptorSet,
+	files []*File,
+	fullyQualifiedName string,
+	newName string,
+	force bool,
+) (map[string][]*text.Edit, error) {
+	if !nameRegexp.MatchString(newName) {
+		return nil, fmt.Errorf("invalid name %q", newName)
+	}
+	target, err := r.getTarget(fileDescriptorSets, strings.TrimPrefix(fullyQualifiedName, "."))
+	if err != nil {
+		return nil, err
+	}
+	if target.name == newName {
+		return nil, fmt.Errorf("%s %s is already named %s", target.kind, target.fullName, newName)
+	}
+	symbols := newSymbols(fileDescriptorSets)
+	if _, ok := target.siblingNames[newName]; ok || symbols.isDefined(joinName(target.parentName, newName)) {
+		return nil, fmt.Errorf("cannot rename %s %s to %s as %s already exists", target.kind, target.fullName, newName, joinName(target.parentName, newName))
+	}
+	// enum values are serialized by name in JSON
+	if target.kind == kindEnumValue && !force {
+		return nil, fmt.Errorf("renaming enum value %s to %s changes its JSON name, use --force to rename anyway", target.fullName, newName)
+	}
+	r.logger.Debug("renaming", zap.Stringer("kind", target.kind), zap.String("name", target.fullName), zap.String("new_name", newName))
+	displayPathToEdits := make(map[string][]*text.Edit)
+	defined := false
+	for _, file := range files {
+		fileRenamer := newFileRenamer(symbols, target, newName, force, file.Data)
+		if err := fileRenamer.renameFile(file.Descriptor); err != nil {
+			return nil, err
+		}
+		if fileRenamer.defined {
+			defined = true
+		}
+		if len(fileRenamer.edits) > 0 {
+			displayPathToEdits[file.Descriptor.Filename] = fileRenamer.edits
+		}
+	}
+	if !defined {
+		return nil, fmt.Errorf("%s %s is defined in %s which is not in the given files", target.kind, target.fullName, target.fileName)
+	}
+	return displayPathToEdits, nil
+}
+
+func (r *renamer) getTarget(fileDescriptorSets []*descriptor.FileDescriptorSet, fullName string) (*target, error) {
+	parentName, name := splitName(fullName)
+	newTarget := func(kind kind, fileDescriptorProto *descriptor.FileDescriptorProto, siblingNames map[string]struct{}) *target {
+		return &target{
+			kind:         kind,
+			fullName:     fullName,
+			parentName:   parentName,
+			name:         name,
+			siblingNames: siblingNames,
+			fileName:     fileDescriptorProto.GetName(),
+		}
+	}
+	if service, err := r.getter.GetService(fileDescriptorSets, fullName); err == nil {
+		return newTarget(kindService, service.FileDescriptorProto, nil), nil
+	}
+	if message, err := r.getter.GetMessage(fileDescriptorSets, fullName); err == nil {
+		return newTarget(kindMessage, message.FileDescriptorProto, nil), nil
+	}
+	if enum, err := r.getter.GetEnum(fileDescriptorSets, fullName); err == nil {
+		return newTarget(kindEnum, enum.FileDescriptorProto, nil), nil
+	}
+	if parentName == "" {
+		return nil, fmt.Errorf("no message, enum, enum value, field, service, or RPC for path %s", fullName)
+	}
+	if service, err := r.getter.GetService(fileDescriptorSets, parentName); err == nil {
+		siblingNames := make(map[string]struct{})
+		for _, method := range service.GetMethod() {
+			siblingNames[method.GetName()] = struct{}{}
+		}
+		if _, ok := siblingNames[name]; ok {
+			return newTarget(kindRPC, service.FileDescriptorProto, siblingNames), nil
+		}
+	}
+	if field, err := r.getter.GetField(fileDescriptorSets, fullName); err == nil {
+		siblingNames := make(map[string]struct{})
+		for _, otherField := range field.DescriptorProto.GetField() {
+			siblingNames[otherField.GetName()] = struct{}{}
+		}
+		for _, oneof := range field.DescriptorProto.GetOneofDecl() {
+			siblingNames[oneof.GetName()] = struct{}{}
+		}
+		return newTarget(kindField, field.FileDescriptorProto, siblingNames), nil
+	}
+	if enum, err := r.getter.GetEnum(fileDescriptorSets, parentName); err == nil {
+		siblingNames := make(map[string]struct{})
+		for _, value := range enum.GetValue() {
+			siblingNames[value.GetName()] = struct{}{}
+		}
+		if _, ok := siblingNames[name]; ok {
+			return newTarget(kindEnumValue, enum.FileDescriptorProto, siblingNames), nil
+		}
+	}
+	return nil, fmt.Errorf("no message, enum, enum value, field, service, or RPC for path %s", fullName)
+}
+
+// fileRenamer gets the edits for a single file.
+type fileRenamer struct {
+	symbols *symbols
+	target  *target
+	newName string
+	force   bool
+	data    []byte
+
+	edits []*text.Edit
+	// defined is set if the target is defined in the file
+	defined bool
+}
+
+func newFileRenamer(symbols *symbols, target *target, newName string, force bool, data []byte) *fileRenamer {
+	return &fileRenamer{
+		symbols: symbols,
+		target:  target,
+		newName: newName,
+		force:   force,
+		data:    data,
+	}
+}
+
+func (f *fileRenamer) renameFile(descriptor *proto.Proto) error {
+	packageName := ""
+	for _, element := range descriptor.Elements {
+		if pkg, ok := element.(*proto.Package); ok {
+			packageName = pkg.Name
+		}
+	}
+	return f.renameElements(descriptor.Elements, packageName, false)
+}
+
+// renameElements renames the elements within the scope, which is the
+// fully-qualified name of the package or message that contains them.
+//
+// If isExtension is set, the elements are in an extend block.
+func (f *fileRenamer) renameElements(elements []proto.Visitee, scope string, isExtension bool) error {
+	for _, element := range elements {
+		var err error
+		switch element := element.(type) {
+		case *proto.Message:
+			err = f.renameMessage(element, scope)
+		case *proto.Enum:
+			err = f.renameEnum(element, scope)
+		case *proto.Service:
+			err = f.renameService(element, scope)
+		case *proto.Group:
+			err = f.renameElements(element.Elements, joinName(scope, element.Name), false)
+		case *proto.Oneof:
+			err = f.renameElements(element.Elements, scope, isExtension)
+		case *proto.NormalField:
+			err = f.renameField(element.Field, scope, isExtension, `(?:^|[^\w.])(%s)\s+`+regexp.QuoteMeta(element.Name)+`\s*=`)
+		case *proto.OneOfField:
+			err = f.renameField(element.Field, scope, isExtension, `(?:^|[^\w.])(%s)\s+`+regexp.QuoteMeta(element.Name)+`\s*=`)
+		case *proto.MapField:
+			err = f.renameField(element.Field, scope, isExtension, `,\s*(%s)\s*>\s*`+regexp.QuoteMeta(element.Name)+`\s*=`)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileRenamer) renameMessage(message *proto.Message, scope string) error {
+	if message.IsExtend {
+		if err := f.renameTypeReference(message.Position, scope, message.Name, `extend\s+(%s)\s*\{`); err != nil {
+			return err
+		}
+		// extensions are in the scope of the extend block
+		return f.renameElements(message.Elements, scope, true)
+	}
+	fullName := joinName(scope, message.Name)
+	if f.target.kind == kindMessage && f.target.fullName == fullName {
+		if err := f.renameDefinition(message.Position, `message\s+(%s)\b`, message.Name); err != nil {
+			return err
+		}
+	}
+	return f.renameElements(message.Elements, fullName, false)
+}
+
+func (f *fileRenamer) renameEnum(enum *proto.Enum, scope string) error {
+	fullName := joinName(scope, enum.Name)
+	if f.target.kind == kindEnum && f.target.fullName == fullName {
+		if err := f.renameDefinition(enum.Position, `enum\s+(%s)\b`, enum.Name); err != nil {
+			return err
+		}
+	}
+	if f.target.kind == kindEnumValue && f.target.parentName == fullName {
+		for _, element := range enum.Elements {
+			if enumField, ok := element.(*proto.EnumField); ok && enumField.Name == f.target.name {
+				if err := f.renameDefinition(enumField.Position, `\b(%s)\s*=`, enumField.Name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fileRenamer) renameService(service *proto.Service, scope string) error {
+	fullName := joinName(scope, service.Name)
+	if f.target.kind == kindService && f.target.fullName == fullName {
+		if err := f.renameDefinition(service.Position, `service\s+(%s)\b`, service.Name); err != nil {
+			return err
+		}
+	}
+	for _, element := range service.Elements {
+		rpc, ok := element.(*proto.RPC)
+		if !ok {
+			continue
+		}
+		if f.target.kind == kindRPC && f.target.parentName == fullName && f.target.name == rpc.Name {
+			if err := f.renameDefinition(rpc.Position, `rpc\s+(%s)\b`, rpc.Name); err != nil {
+				return err
+			}
+		}
+		requestTypeExpr := `rpc\s+` + regexp.QuoteMeta(rpc.Name) + `\s*\(\s*(?:stream\s+)?(%s)\s*\)`
+		if err := f.renameTypeReference(rpc.Position, scope, rpc.RequestType, requestTypeExpr); err != nil {
+			return err
+		}
+		if err := f.renameTypeReference(rpc.Position, scope, rpc.ReturnsType, `returns\s*\(\s*(?:stream\s+)?(%s)\s*\)`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameField renames the field if it is the target, and the type of the
+// field if it references the target. The typeExpr finds the type of the
+// field, with %s for the quoted type.
+func (f *fileRenamer) renameField(field *proto.Field, scope string, isExtension bool, typeExpr string) error {
+	if f.target.kind == kindField && f.target.parentName == scope && f.target.name == field.Name {
+		// references to extensions in options are not renamed
+		if isExtension {
+			return fmt.Errorf("renaming extension %s is not supported", f.target.fullName)
+		}
+		if err := f.checkFieldJSONName(field); err != nil {
+			return err
+		}
+		if err := f.renameDefinition(field.Position, `\b(%s)\s*=`, field.Name); err != nil {
+			return err
+		}
+	}
+	if err := f.renameTypeReference(field.Position, scope, field.Type, typeExpr); err != nil {
+		return err
+	}
+	// proto2 default values of enum fields reference enum values
+	if f.target.kind == kindEnumValue && f.symbols.resolve(scope, field.Type) == f.target.parentName {
+		for _, option := range field.Options {
+			if option.Name == "default" && option.Constant.Source == f.target.name {
+				if err := f.addEdit(field.Position, `\bdefault\s*=\s*(%s)\b`, f.target.name, f.newName); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fileRenamer) checkFieldJSONName(field *proto.Field) error {
+	if f.force {
+		return nil
+	}
+	for _, option := range field.Options {
+		if option.Name == "json_name" {
+			return nil
+		}
+	}
+	if oldJSONName, newJSONName := getJSONName(field.Name), getJSONName(f.newName); oldJSONName != newJSONName {
+		return fmt.Errorf(
+			"renaming field %s to %s changes its JSON name from %q to %q, set the json_name option to keep the JSON name or use --force to rename anyway",
+			f.target.fullName,
+			f.newName,
+			oldJSONName,
+			newJSONName,
+		)
+	}
+	return nil
+}
+
+func (f *fileRenamer) renameDefinition(position scanner.Position, expr string, name string) error {
+	f.defined = true
+	return f.addEdit(position, expr, name, f.newName)
+}
+
+// renameTypeReference renames the part of the type reference that
+// refers to the target, if the target is a message or enum.
+func (f *fileRenamer) renameTypeReference(position scanner.Position, scope string, typeName string, expr string) error {
+	if f.target.kind != kindMessage && f.target.kind != kindEnum {
+		return nil
+	}
+	newTypeName, ok := getRenamedTypeName(typeName, f.symbols.resolve(scope, typeName), f.target.fullName, f.newName)
+	if !ok {
+		return nil
+	}
+	return f.addEdit(position, expr, typeName, newTypeName)
+}
+
+// addEdit adds an edit that replaces oldText with newText, where oldText
+// is found with the first match of expr after the position. The expr
+// has %s for the quoted oldText, which must be in the first group.
+func (f *fileRenamer) addEdit(position scanner.Position, expr string, oldText string, newText string) error {
+	offset, err := getOffset(f.data, position)
+	if err != nil {
+		return err
+	}
+	match := regexp.MustCompile(fmt.Sprintf(expr, regexp.QuoteMeta(oldText))).FindSubmatchIndex(f.data[offset:])
+	if match == nil {
+		return fmt.Errorf("%s:%d:%d: could not find %q", position.Filename, position.Line, position.Column, oldText)
+	}
+	start := offset + match[2]
+	line, column := getLineColumn(f.data, start)
+	f.edits = append(f.edits, &text.Edit{
+		Line:    line,
+		Column:  column,
+		OldText: oldText,
+		NewText: newText,
+	})
+	return nil
+}
+
+// getRenamedTypeName returns the type name with the target renamed, and
+// true if the type name refers to the target or to a type nested in it.
+//
+// The parts of a type name are the last parts of the fully-qualified name
+// it resolves to, so the target only needs to be renamed if it is in the
+// type name and not in the scope of the reference.
+func getRenamedTypeName(typeName string, resolvedName string, fullName string, newName string) (string, bool) {
+	if resolvedName != fullName && !strings.HasPrefix(resolvedName, fullName+".") {
+		return "", false
+	}
+	names := strings.Split(strings.TrimPrefix(typeName, "."), ".")
+	index := len(strings.Split(fullName, ".")) - 1 - (len(strings.Split(resolvedName, ".")) - len(names))
+	if index < 0 {
+		return "", false
+	}
+	names[index] = newName
+	if strings.HasPrefix(typeName, ".") {
+		return "." + strings.Join(names, "."), true
+	}
+	return strings.Join(names, "."), true
+}
+
+// symbols are the fully-qualified names of the types, services, and
+// packages in the compiled files, without leading dots.
+type symbols struct {
+	typeNames    map[string]struct{}
+	serviceNames map[string]struct{}
+	packageNames map[string]struct{}
+}
+
+func newSymbols(fileDescriptorSets []*descriptor.FileDescriptorSet) *symbols {
+	symbols := &symbols{
+		typeNames:    make(map[string]struct{}),
+		serviceNames: make(map[string]struct{}),
+		packageNames: make(map[string]struct{}),
+	}
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			packageName := fileDescriptorProto.GetPackage()
+			// a package also defines all of its parent packages
+			for name := packageName; name != ""; name, _ = splitName(name) {
+				symbols.packageNames[name] = struct{}{}
+			}
+			for _, message := range fileDescriptorProto.GetMessageType() {
+				symbols.addMessage(packageName, message)
+			}
+			for _, enum := range fileDescriptorProto.GetEnumType() {
+				symbols.typeNames[joinName(packageName, enum.GetName())] = struct{}{}
+			}
+			for _, service := range fileDescriptorProto.GetService() {
+				symbols.serviceNames[joinName(packageName, service.GetName())] = struct{}{}
+			}
+		}
+	}
+	return symbols
+}
+
+func (s *symbols) addMessage(scope string, message *descriptor.DescriptorProto) {
+	fullName := joinName(scope, message.GetName())
+	s.typeNames[fullName] = struct{}{}
+	for _, nestedMessage := range message.GetNestedType() {
+		s.addMessage(fullName, nestedMessage)
+	}
+	for _, enum := range message.GetEnumType() {
+		s.typeNames[joinName(fullName, enum.GetName())] = struct{}{}
+	}
+}
+
+func (s *symbols) isDefined(name string) bool {
+	_, isType := s.typeNames[name]
+	_, isService := s.serviceNames[name]
+	_, isPackage := s.packageNames[name]
+	return isType || isService || isPackage
+}
+
+// resolve returns the fully-qualified name of the type that the type name
+// refers to from within the scope, or an empty string if there is none,
+// such as for scalar types.
+//
+// Like protoc, the first part of the type name is searched for from the
+// innermost scope outwards, and the rest of the type name must be within
+// the first match.
+func (s *symbols) resolve(scope string, typeName string) string {
+	if strings.HasPrefix(typeName, ".") {
+		if _, ok := s.typeNames[typeName[1:]]; ok {
+			return typeName[1:]
+		}
+		return ""
+	}
+	firstName := typeName
+	if i := strings.IndexByte(typeName, '.'); i >= 0 {
+		firstName = typeName[:i]
+	}
+	for {
+		_, isType := s.typeNames[joinName(scope, firstName)]
+		_, isPackage := s.packageNames[joinName(scope, firstName)]
+		if isType || (isPackage && firstName != typeName) {
+			if _, ok := s.typeNames[joinName(scope, typeName)]; ok {
+				return joinName(scope, typeName)
+			}
+			return ""
+		}
+		if scope == "" {
+			return ""
+		}
+		scope, _ = splitName(scope)
+	}
+}
+
+// getJSONName returns the JSON name of the field name as protoc does,
+// which removes underscores and capitalizes the letters after them.
+func getJSONName(name string) string {
+	buffer := bytes.NewBuffer(nil)
+	capitalizeNext := false
+	for _, c := range name {
+		if c == '_' {
+			capitalizeNext = true
+			continue
+		}
+		if capitalizeNext {
+			c = unicode.ToUpper(c)
+			capitalizeNext = false
+		}
+		_, _ = buffer.WriteRune(c)
+	}
+	return buffer.String()
+}
+
+// getOffset returns the byte offset of the position in data.
+func getOffset(data []byte, position scanner.Position) (int, error) {
+	offset := 0
+	for line := 1; line < position.Line; line++ {
+		index := bytes.IndexByte(data[offset:], '\n')
+		if index < 0 {
+			return 0, fmt.Errorf("%s:%d:%d: line is out of range", position.Filename, position.Line, position.Column)
+		}
+		offset += index + 1
+	}
+	for column := 1; column < position.Column; column++ {
+		if offset >= len(data) || data[offset] == '\n' {
+			return 0, fmt.Errorf("%s:%d:%d: column is out of range", position.Filename, position.Line, position.Column)
+		}
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset, nil
+}
+
+// getLineColumn returns the line and column of the byte offset in data,
+// where the column is the character count like scanner.Position.
+func getLineColumn(data []byte, offset int) (int, int) {
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return line, utf8.RuneCount(data[lineStart:offset]) + 1
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+// splitName returns the name without the last part, and the last part.
+func splitName(name string) (string, string) {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rename
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emicklei/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tgrpc/prototool/internal/x/text"
+)
+
+var testRenameProtos = map[string]string{
+	"a/v1/a.proto": `syntax = "proto3";
+
+package a.v1;
+
+import "b/v1/b.proto";
+
+service FooAPI {
+  rpc GetFoo(GetFooRequest) returns (stream Foo);
+}
+
+message GetFooRequest {
+  string foo_id = 1;
+  string bar_name = 2 [json_name = "barName"];
+}
+
+message Foo {
+  message Nested {
+    Status status = 1;
+  }
+  Nested nested = 1;
+  map<string, Foo.Nested> nested_map = 2;
+  .a.v1.Foo.Nested full = 3;
+  b.v1.Bar bar = 4;
+  oneof value {
+    Foo.Nested oneof_nested = 5;
+  }
+}
+
+enum Status {
+  STATUS_INVALID = 0;
+  STATUS_OK = 1;
+}
+`,
+	"b/v1/b.proto": `syntax = "proto3";
+
+package b.v1;
+
+message Bar {
+  string name = 1;
+}
+`,
+	"c/v1/c.proto": `syntax = "proto3";
+
+package c.v1;
+
+import "a/v1/a.proto";
+
+message Baz {
+  a.v1.Foo.Nested nested = 1;
+  repeated a.v1.Status statuses = 2;
+}
+`,
+}
+
+func TestRenameMessage(t *testing.T) {
+	files, err := testRename("a.v1.Foo", "Qux", false, "a/v1/a.proto", "c/v1/c.proto")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]string{
+			"a/v1/a.proto": `syntax = "proto3";
+
+package a.v1;
+
+import "b/v1/b.proto";
+
+service FooAPI {
+  rpc GetFoo(GetFooRequest) returns (stream Qux);
+}
+
+message GetFooRequest {
+  string foo_id = 1;
+  string bar_name = 2 [json_name = "barName"];
+}
+
+message Qux {
+  message Nested {
+    Status status = 1;
+  }
+  Nested nested = 1;
+  map<string, Qux.Nested> nested_map = 2;
+  .a.v1.Qux.Nested full = 3;
+  b.v1.Bar bar = 4;
+  oneof value {
+    Qux.Nested oneof_nested = 5;
+  }
+}
+
+enum Status {
+  STATUS_INVALID = 0;
+  STATUS_OK = 1;
+}
+`,
+			"c/v1/c.proto": `syntax = "proto3";
+
+package c.v1;
+
+import "a/v1/a.proto";
+
+message Baz {
+  a.v1.Qux.Nested nested = 1;
+  repeated a.v1.Status statuses = 2;
+}
+`,
+		},
+		files,
+	)
+}
+
+func TestRenameNestedMessageAndEnum(t *testing.T) {
+	files, err := testRename(".a.v1.Foo.Nested", "Inner", false, "a/v1/a.proto", "c/v1/c.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "  message Inner {\n")
+	assert.Contains(t, files["a/v1/a.proto"], "  Inner nested = 1;\n")
+	assert.Contains(t, files["a/v1/a.proto"], "  map<string, Foo.Inner> nested_map = 2;\n")
+	assert.Contains(t, files["a/v1/a.proto"], "  .a.v1.Foo.Inner full = 3;\n")
+	assert.Contains(t, files["a/v1/a.proto"], "    Foo.Inner oneof_nested = 5;\n")
+	assert.Contains(t, files["c/v1/c.proto"], "  a.v1.Foo.Inner nested = 1;\n")
+
+	files, err = testRename("a.v1.Status", "State", false, "a/v1/a.proto", "c/v1/c.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "    State status = 1;\n")
+	assert.Contains(t, files["a/v1/a.proto"], "enum State {\n")
+	assert.Contains(t, files["c/v1/c.proto"], "  repeated a.v1.State statuses = 2;\n")
+}
+
+func TestRenameServiceAndRPC(t *testing.T) {
+	files, err := testRename("a.v1.FooAPI", "BarAPI", false, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "service BarAPI {\n")
+
+	files, err = testRename("a.v1.FooAPI.GetFoo", "FetchFoo", false, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "  rpc FetchFoo(GetFooRequest) returns (stream Foo);\n")
+}
+
+func TestRenameFieldJSONName(t *testing.T) {
+	// the JSON name does not change
+	files, err := testRename("a.v1.GetFooRequest.foo_id", "fooId", false, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "  string fooId = 1;\n")
+	// the JSON name is set with an option
+	files, err = testRename("a.v1.GetFooRequest.bar_name", "name", false, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], `  string name = 2 [json_name = "barName"];`)
+
+	_, err = testRename("a.v1.GetFooRequest.foo_id", "id", false, "a/v1/a.proto")
+	assert.Error(t, err)
+	files, err = testRename("a.v1.GetFooRequest.foo_id", "id", true, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "  string id = 1;\n")
+}
+
+func TestRenameEnumValue(t *testing.T) {
+	_, err := testRename("a.v1.Status.STATUS_OK", "STATUS_SUCCESS", false, "a/v1/a.proto")
+	assert.Error(t, err)
+	files, err := testRename("a.v1.Status.STATUS_OK", "STATUS_SUCCESS", true, "a/v1/a.proto")
+	require.NoError(t, err)
+	assert.Contains(t, files["a/v1/a.proto"], "  STATUS_SUCCESS = 1;\n")
+}
+
+func TestRenameErrors(t *testing.T) {
+	for _, args := range [][2]string{
+		// unknown element
+		{"a.v1.Unknown", "Foo"},
+		// invalid name
+		{"a.v1.Foo", "1Foo"},
+		// same name
+		{"a.v1.Foo", "Foo"},
+		// already exists
+		{"a.v1.Foo", "GetFooRequest"},
+		{"a.v1.GetFooRequest.foo_id", "bar_name"},
+		// not in the files
+		{"b.v1.Bar", "Baz"},
+	} {
+		_, err := testRename(args[0], args[1], true, "a/v1/a.proto", "c/v1/c.proto")
+		assert.Error(t, err, args[0])
+	}
+}
+
+func TestGetRenamedTypeName(t *testing.T) {
+	for _, testCase := range []struct {
+		typeName     string
+		resolvedName string
+		expected     string
+		expectedOK   bool
+	}{
+		{"Foo", "a.v1.Foo", "Bar", true},
+		{"Foo.Nested", "a.v1.Foo.Nested", "Bar.Nested", true},
+		{"v1.Foo", "a.v1.Foo", "v1.Bar", true},
+		{".a.v1.Foo.Nested", "a.v1.Foo.Nested", ".a.v1.Bar.Nested", true},
+		// within the scope of the renamed message
+		{"Nested", "a.v1.Foo.Nested", "", false},
+		{"FooBar", "a.v1.FooBar", "", false},
+	} {
+		actual, ok := getRenamedTypeName(testCase.typeName, testCase.resolvedName, "a.v1.Foo", "Bar")
+		assert.Equal(t, testCase.expectedOK, ok, testCase.typeName)
+		assert.Equal(t, testCase.expected, actual, testCase.typeName)
+	}
+}
+
+// testRename renames in the given files, and returns the contents of the
+// files that were changed.
+func testRename(fullyQualifiedName string, newName string, force bool, filePaths ...string) (map[string]string, error) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(testRenameProtos),
+	}
+	fileDescriptors, err := parser.ParseFiles("a/v1/a.proto", "b/v1/b.proto", "c/v1/c.proto")
+	if err != nil {
+		return nil, err
+	}
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	for _, fileDescriptor := range fileDescriptors {
+		fileDescriptorSet.File = append(fileDescriptorSet.File, fileDescriptor.AsFileDescriptorProto())
+	}
+	var files []*File
+	for _, filePath := range filePaths {
+		data := testRenameProtos[filePath]
+		protoParser := proto.NewParser(strings.NewReader(data))
+		protoParser.Filename(filePath)
+		protoDescriptor, err := protoParser.Parse()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &File{
+			Descriptor: protoDescriptor,
+			Data:       []byte(data),
+		})
+	}
+	filePathToEdits, err := newRenamer().Rename([]*descriptor.FileDescriptorSet{fileDescriptorSet}, files, fullyQualifiedName, newName, force)
+	if err != nil {
+		return nil, err
+	}
+	filePathToData := make(map[string]string, len(filePathToEdits))
+	for filePath, edits := range filePathToEdits {
+		data, _, err := text.ApplyEdits([]byte(testRenameProtos[filePath]), edits)
+		if err != nil {
+			return nil, err
+		}
+		filePathToData[filePath] = string(data)
+	}
+	return filePathToData, nil
+}
@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -230,6 +231,23 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 			ignoreIDToFilePaths[id] = append(ignoreIDToFilePaths[id], protoFilePath)
 		}
 	}
+	lintRules, err := getLintRules(e)
+	if err != nil {
+		return Config{}, err
+	}
+	lintGroups, err := getLintGroups(e)
+	if err != nil {
+		return Config{}, err
+	}
+	// leave this nil if unset instead of an empty slice
+	var unusedRoots []string
+	if len(e.Lint.UnusedRoots) > 0 {
+		unusedRoots = strs.DedupeSortSlice(e.Lint.UnusedRoots, trimLeadingDot)
+	}
+	compileBackend, err := ParseCompileBackend(e.Compile.Backend)
+	if err != nil {
+		return Config{}, err
+	}
 	var indent string
 	if len(e.Format.Indent) > 0 {
 		indent, err = getIndent(e.Format.Indent)
@@ -277,6 +295,7 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 			IncludePaths:          includePaths,
 			IncludeWellKnownTypes: e.ProtocIncludeWKT,
 			AllowUnusedImports:    e.AllowUnusedImports,
+			Backend:               compileBackend,
 		},
 		Lint: LintConfig{
 			IDs:                 strs.DedupeSortSlice(e.Lint.IDs, strings.ToUpper),
@@ -284,6 +303,10 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 			IncludeIDs:          strs.DedupeSortSlice(e.Lint.IncludeIDs, strings.ToUpper),
 			ExcludeIDs:          strs.DedupeSortSlice(e.Lint.ExcludeIDs, strings.ToUpper),
 			IgnoreIDToFilePaths: ignoreIDToFilePaths,
+			Rules:               lintRules,
+			Groups:              lintGroups,
+			UnusedRoots:         unusedRoots,
+			BaselinePath:        getAbsPath(dirPath, e.Lint.Baseline),
 		},
 		Format: FormatConfig{
 			Indent:           indent,
@@ -298,6 +321,16 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 			},
 			Plugins: genPlugins,
 		},
+		GRPC: GRPCConfig{
+			TLS: GRPCTLSConfig{
+				Enabled:            e.GRPC.TLS.Enabled,
+				CACertPath:         getAbsPath(dirPath, e.GRPC.TLS.CACert),
+				CertPath:           getAbsPath(dirPath, e.GRPC.TLS.Cert),
+				KeyPath:            getAbsPath(dirPath, e.GRPC.TLS.Key),
+				ServerName:         e.GRPC.TLS.ServerName,
+				InsecureSkipVerify: e.GRPC.TLS.InsecureSkipVerify,
+			},
+		},
 	}
 
 	for _, genPlugin := range config.Gen.Plugins {
@@ -324,9 +357,128 @@ func externalConfigToConfig(e ExternalConfig, dirPath string) (Config, error) {
 	if intersection := strs.IntersectionSlice(config.Lint.IncludeIDs, config.Lint.ExcludeIDs); len(intersection) > 0 {
 		return Config{}, fmt.Errorf("config had intersection of %v between lint_include and lint_exclude", intersection)
 	}
+	if (config.GRPC.TLS.CertPath == "") != (config.GRPC.TLS.KeyPath == "") {
+		return Config{}, fmt.Errorf("grpc tls cert and key must either both be set or both be unset")
+	}
 	return config, nil
 }
 
+// getAbsPath returns the absolute path for the path relative
+// to dirPath, or empty if the path is empty.
+func getAbsPath(dirPath string, path string) string {
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dirPath, path)
+	}
+	return filepath.Clean(path)
+}
+
+func trimLeadingDot(s string) string {
+	return strings.TrimPrefix(s, ".")
+}
+
+func getLintRules(e ExternalConfig) ([]LintRule, error) {
+	var lintRules []LintRule
+	seenIDs := make(map[string]struct{}, len(e.Lint.Rules))
+	for _, rule := range e.Lint.Rules {
+		id := strings.ToUpper(rule.ID)
+		if id == "" {
+			return nil, fmt.Errorf("lint rule id required")
+		}
+		if _, ok := seenIDs[id]; ok {
+			return nil, fmt.Errorf("duplicate lint rule id %s", id)
+		}
+		seenIDs[id] = struct{}{}
+		if rule.Message == "" {
+			return nil, fmt.Errorf("message required for lint rule %s", id)
+		}
+		kind, err := ParseLintRuleKind(rule.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kind for lint rule %s: %v", id, err)
+		}
+		isNaming := rule.NamingRegex != ""
+		isOptions := len(rule.RequiredOptions) > 0 || len(rule.ForbiddenOptions) > 0
+		isImports := len(rule.AllowedImports) > 0 || len(rule.ForbiddenImports) > 0
+		isPackageImports := len(rule.AllowedImportPackages) > 0 || len(rule.ForbiddenImportPackages) > 0
+		if len(rule.Packages) > 0 && !isPackageImports {
+			return nil, fmt.Errorf("packages can only be set for package import lint rule %s", id)
+		}
+		switch {
+		case isNaming && !isOptions && !isImports && !isPackageImports:
+			if kind == LintRuleKindNone {
+				return nil, fmt.Errorf("kind required for naming lint rule %s", id)
+			}
+			if _, err := regexp.Compile(rule.NamingRegex); err != nil {
+				return nil, fmt.Errorf("invalid naming_regex for lint rule %s: %v", id, err)
+			}
+		case isOptions && !isNaming && !isImports && !isPackageImports:
+			if kind == LintRuleKindNone || kind == LintRuleKindPackage {
+				return nil, fmt.Errorf("kind for option lint rule %s must be one of file, message, field, enum, enum_value, service, rpc", id)
+			}
+			if intersection := strs.IntersectionSlice(strs.DedupeSortSlice(rule.RequiredOptions, nil), strs.DedupeSortSlice(rule.ForbiddenOptions, nil)); len(intersection) > 0 {
+				return nil, fmt.Errorf("lint rule %s had intersection of %v between required_options and forbidden_options", id, intersection)
+			}
+		case isImports && !isNaming && !isOptions && !isPackageImports:
+			if kind != LintRuleKindNone {
+				return nil, fmt.Errorf("kind cannot be set for import lint rule %s", id)
+			}
+		case isPackageImports && !isNaming && !isOptions && !isImports:
+			if kind != LintRuleKindNone {
+				return nil, fmt.Errorf("kind cannot be set for package import lint rule %s", id)
+			}
+		default:
+			return nil, fmt.Errorf("lint rule %s must set exactly one of naming_regex, required_options/forbidden_options, allowed_imports/forbidden_imports, or allowed_import_packages/forbidden_import_packages", id)
+		}
+		lintRules = append(lintRules, LintRule{
+			ID:                      id,
+			Message:                 rule.Message,
+			Kind:                    kind,
+			NamingRegex:             rule.NamingRegex,
+			RequiredOptions:         strs.DedupeSortSlice(rule.RequiredOptions, nil),
+			ForbiddenOptions:        strs.DedupeSortSlice(rule.ForbiddenOptions, nil),
+			AllowedImports:          strs.DedupeSortSlice(rule.AllowedImports, nil),
+			ForbiddenImports:        strs.DedupeSortSlice(rule.ForbiddenImports, nil),
+			Packages:                strs.DedupeSortSlice(rule.Packages, nil),
+			AllowedImportPackages:   strs.DedupeSortSlice(rule.AllowedImportPackages, nil),
+			ForbiddenImportPackages: strs.DedupeSortSlice(rule.ForbiddenImportPackages, nil),
+		})
+	}
+	return lintRules, nil
+}
+
+func getLintGroups(e ExternalConfig) ([]LintGroup, error) {
+	var lintGroups []LintGroup
+	seenNames := make(map[string]struct{}, len(e.Lint.Groups))
+	for _, group := range e.Lint.Groups {
+		name := strings.ToLower(group.Name)
+		if name == "" {
+			return nil, fmt.Errorf("lint group name required")
+		}
+		if _, ok := seenNames[name]; ok {
+			return nil, fmt.Errorf("duplicate lint group name %s", name)
+		}
+		seenNames[name] = struct{}{}
+		base := strings.ToLower(group.Base)
+		if base == name {
+			return nil, fmt.Errorf("lint group %s cannot use itself as a base", name)
+		}
+		add := strs.DedupeSortSlice(group.Add, strings.ToUpper)
+		remove := strs.DedupeSortSlice(group.Remove, strings.ToUpper)
+		if intersection := strs.IntersectionSlice(add, remove); len(intersection) > 0 {
+			return nil, fmt.Errorf("lint group %s had intersection of %v between add and remove", name, intersection)
+		}
+		lintGroups = append(lintGroups, LintGroup{
+			Name:   name,
+			Base:   base,
+			Add:    add,
+			Remove: remove,
+		})
+	}
+	return lintGroups, nil
+}
+
 func getExcludePrefixesForDir(dirPath string) ([]string, error) {
 	filePath := filepath.Join(dirPath, DefaultConfigFilename)
 	if _, err := os.Stat(filePath); err != nil {
@@ -24,6 +24,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
 func TestGetIndent(t *testing.T) {
@@ -48,3 +50,360 @@ func testGetIndent(t *testing.T, spec string, expected string, expectError bool)
 	}
 	assert.Equal(t, expected, indent)
 }
+
+func TestExternalConfigToConfigLintRules(t *testing.T) {
+	config, err := testExternalConfigToConfig(`
+lint:
+  rules:
+    - id: service_names_api_suffix
+      message: Service names must end with API.
+      kind: Service
+      naming_regex: API$
+    - id: IMPORTS_NOT_INTERNAL
+      message: Files in internal must not be imported.
+      forbidden_imports:
+        - internal/
+        - internal/
+    - id: public_not_internal
+      message: Public packages must not import internal packages.
+      packages:
+        - b.v1
+        - a.v1
+      forbidden_import_packages:
+        - b.internal
+`)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]LintRule{
+			{
+				ID:                      "SERVICE_NAMES_API_SUFFIX",
+				Message:                 "Service names must end with API.",
+				Kind:                    LintRuleKindService,
+				NamingRegex:             "API$",
+				RequiredOptions:         []string{},
+				ForbiddenOptions:        []string{},
+				AllowedImports:          []string{},
+				ForbiddenImports:        []string{},
+				Packages:                []string{},
+				AllowedImportPackages:   []string{},
+				ForbiddenImportPackages: []string{},
+			},
+			{
+				ID:                      "IMPORTS_NOT_INTERNAL",
+				Message:                 "Files in internal must not be imported.",
+				RequiredOptions:         []string{},
+				ForbiddenOptions:        []string{},
+				AllowedImports:          []string{},
+				ForbiddenImports:        []string{"internal/"},
+				Packages:                []string{},
+				AllowedImportPackages:   []string{},
+				ForbiddenImportPackages: []string{},
+			},
+			{
+				ID:                      "PUBLIC_NOT_INTERNAL",
+				Message:                 "Public packages must not import internal packages.",
+				RequiredOptions:         []string{},
+				ForbiddenOptions:        []string{},
+				AllowedImports:          []string{},
+				ForbiddenImports:        []string{},
+				Packages:                []string{"a.v1", "b.v1"},
+				AllowedImportPackages:   []string{},
+				ForbiddenImportPackages: []string{"b.internal"},
+			},
+		},
+		config.Lint.Rules,
+	)
+
+	for _, data := range []string{
+		// no id
+		`
+lint:
+  rules:
+    - message: foo
+      kind: service
+      naming_regex: API$
+`,
+		// no message
+		`
+lint:
+  rules:
+    - id: FOO
+      kind: service
+      naming_regex: API$
+`,
+		// duplicate id
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: service
+      naming_regex: API$
+    - id: foo
+      message: foo
+      kind: rpc
+      naming_regex: ^Get
+`,
+		// unknown kind
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: bar
+      naming_regex: API$
+`,
+		// no kind for naming rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      naming_regex: API$
+`,
+		// invalid regex
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: service
+      naming_regex: (
+`,
+		// kind for import rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: file
+      forbidden_imports:
+        - internal/
+`,
+		// packages do not have options
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: package
+      required_options:
+        - go_package
+`,
+		// required and forbidden overlap
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: file
+      required_options:
+        - go_package
+      forbidden_options:
+        - go_package
+`,
+		// more than one type of rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: file
+      naming_regex: ^foo
+      required_options:
+        - go_package
+`,
+		// no type of rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: file
+`,
+		// kind for package import rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      kind: file
+      forbidden_import_packages:
+        - foo.internal
+`,
+		// packages for import rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      packages:
+        - foo
+      forbidden_imports:
+        - internal/
+`,
+		// import and package import rule
+		`
+lint:
+  rules:
+    - id: FOO
+      message: foo
+      forbidden_imports:
+        - internal/
+      forbidden_import_packages:
+        - foo.internal
+`,
+	} {
+		_, err := testExternalConfigToConfig(data)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestExternalConfigToConfigLintGroups(t *testing.T) {
+	config, err := testExternalConfigToConfig(`
+lint:
+  group: Team
+  groups:
+    - name: Team
+      base: Google
+      add:
+        - enums_have_comments
+        - ENUMS_HAVE_COMMENTS
+      remove:
+        - SYNTAX_PROTO3
+    - name: empty
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "team", config.Lint.Group)
+	assert.Equal(
+		t,
+		[]LintGroup{
+			{
+				Name:   "team",
+				Base:   "google",
+				Add:    []string{"ENUMS_HAVE_COMMENTS"},
+				Remove: []string{"SYNTAX_PROTO3"},
+			},
+			{
+				Name:   "empty",
+				Add:    []string{},
+				Remove: []string{},
+			},
+		},
+		config.Lint.Groups,
+	)
+
+	for _, data := range []string{
+		// no name
+		`
+lint:
+  groups:
+    - base: default
+`,
+		// duplicate name
+		`
+lint:
+  groups:
+    - name: foo
+    - name: FOO
+`,
+		// base is itself
+		`
+lint:
+  groups:
+    - name: foo
+      base: foo
+`,
+		// add and remove overlap
+		`
+lint:
+  groups:
+    - name: foo
+      add:
+        - SYNTAX_PROTO3
+      remove:
+        - syntax_proto3
+`,
+	} {
+		_, err := testExternalConfigToConfig(data)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestExternalConfigToConfigLintUnusedRoots(t *testing.T) {
+	config, err := testExternalConfigToConfig(`
+lint:
+  unused_roots:
+    - foo.v1
+    - .bar.v1.Baz
+    - foo.v1
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar.v1.Baz", "foo.v1"}, config.Lint.UnusedRoots)
+}
+
+func TestExternalConfigToConfigLintBaseline(t *testing.T) {
+	config, err := testExternalConfigToConfig(`
+lint:
+  baseline: baseline.bin
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/baseline.bin", config.Lint.BaselinePath)
+}
+
+func TestExternalConfigToConfigGRPCTLS(t *testing.T) {
+	config, err := testExternalConfigToConfig(`
+grpc:
+  tls:
+    ca_cert: certs/ca.pem
+    cert: /etc/certs/client.pem
+    key: /etc/certs/client.key
+    server_name: foo.example.com
+`)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		GRPCTLSConfig{
+			CACertPath: "/tmp/certs/ca.pem",
+			CertPath:   "/etc/certs/client.pem",
+			KeyPath:    "/etc/certs/client.key",
+			ServerName: "foo.example.com",
+		},
+		config.GRPC.TLS,
+	)
+	assert.True(t, config.GRPC.TLS.IsEnabled())
+
+	config, err = testExternalConfigToConfig(`
+lint:
+  group: default
+`)
+	require.NoError(t, err)
+	assert.False(t, config.GRPC.TLS.IsEnabled())
+
+	for _, data := range []string{
+		// cert without key
+		`
+grpc:
+  tls:
+    cert: client.pem
+`,
+		// key without cert
+		`
+grpc:
+  tls:
+    key: client.key
+`,
+	} {
+		_, err := testExternalConfigToConfig(data)
+		assert.Error(t, err, data)
+	}
+}
+
+func testExternalConfigToConfig(data string) (Config, error) {
+	externalConfig := ExternalConfig{}
+	if err := yaml.UnmarshalStrict([]byte(data), &externalConfig); err != nil {
+		return Config{}, err
+	}
+	return externalConfigToConfig(externalConfig, "/tmp")
+}
@@ -44,6 +44,39 @@ const (
 	GenPluginTypeGogo
 )
 
+const (
+	// CompileBackendProtoc says to compile with protoc, downloading
+	// protoc if necessary.
+	CompileBackendProtoc CompileBackend = iota
+	// CompileBackendGo says to compile in-process with a pure Golang
+	// parser and compiler. This does not need protoc, but cannot be
+	// used for generation.
+	CompileBackendGo
+)
+
+const (
+	// LintRuleKindNone says there is no element kind.
+	// This is only valid for import rules.
+	LintRuleKindNone LintRuleKind = iota
+	// LintRuleKindFile says the rule applies to files.
+	// For naming rules, the base name of the file is checked.
+	LintRuleKindFile
+	// LintRuleKindPackage says the rule applies to packages.
+	LintRuleKindPackage
+	// LintRuleKindMessage says the rule applies to messages.
+	LintRuleKindMessage
+	// LintRuleKindField says the rule applies to message fields.
+	LintRuleKindField
+	// LintRuleKindEnum says the rule applies to enums.
+	LintRuleKindEnum
+	// LintRuleKindEnumValue says the rule applies to enum values.
+	LintRuleKindEnumValue
+	// LintRuleKindService says the rule applies to services.
+	LintRuleKindService
+	// LintRuleKindRPC says the rule applies to RPCs.
+	LintRuleKindRPC
+)
+
 var (
 	// DefaultExcludePrefixes are the default prefixes to exclude.
 	DefaultExcludePrefixes = []string{
@@ -61,6 +94,39 @@ var (
 		"gogo": GenPluginTypeGogo,
 	}
 
+	_compileBackendToString = map[CompileBackend]string{
+		CompileBackendProtoc: "protoc",
+		CompileBackendGo:     "go",
+	}
+	_stringToCompileBackend = map[string]CompileBackend{
+		"":       CompileBackendProtoc,
+		"protoc": CompileBackendProtoc,
+		"go":     CompileBackendGo,
+	}
+
+	_lintRuleKindToString = map[LintRuleKind]string{
+		LintRuleKindNone:      "",
+		LintRuleKindFile:      "file",
+		LintRuleKindPackage:   "package",
+		LintRuleKindMessage:   "message",
+		LintRuleKindField:     "field",
+		LintRuleKindEnum:      "enum",
+		LintRuleKindEnumValue: "enum_value",
+		LintRuleKindService:   "service",
+		LintRuleKindRPC:       "rpc",
+	}
+	_stringToLintRuleKind = map[string]LintRuleKind{
+		"":           LintRuleKindNone,
+		"file":       LintRuleKindFile,
+		"package":    LintRuleKindPackage,
+		"message":    LintRuleKindMessage,
+		"field":      LintRuleKindField,
+		"enum":       LintRuleKindEnum,
+		"enum_value": LintRuleKindEnumValue,
+		"service":    LintRuleKindService,
+		"rpc":        LintRuleKindRPC,
+	}
+
 	_genPluginTypeToIsGo = map[GenPluginType]bool{
 		GenPluginTypeNone: false,
 		GenPluginTypeGo:   true,
@@ -111,6 +177,50 @@ func ParseGenPluginType(s string) (GenPluginType, error) {
 	return genPluginType, nil
 }
 
+// CompileBackend is a backend used to compile Protobuf files.
+type CompileBackend int
+
+// String implements fmt.Stringer.
+func (c CompileBackend) String() string {
+	if s, ok := _compileBackendToString[c]; ok {
+		return s
+	}
+	return strconv.Itoa(int(c))
+}
+
+// ParseCompileBackend parses the CompileBackend from the given string.
+//
+// Input is case-insensitive. The empty string parses to CompileBackendProtoc.
+func ParseCompileBackend(s string) (CompileBackend, error) {
+	compileBackend, ok := _stringToCompileBackend[strings.ToLower(s)]
+	if !ok {
+		return CompileBackendProtoc, fmt.Errorf("could not parse %s to a CompileBackend", s)
+	}
+	return compileBackend, nil
+}
+
+// LintRuleKind is the kind of element a LintRule applies to.
+type LintRuleKind int
+
+// String implements fmt.Stringer.
+func (l LintRuleKind) String() string {
+	if s, ok := _lintRuleKindToString[l]; ok {
+		return s
+	}
+	return strconv.Itoa(int(l))
+}
+
+// ParseLintRuleKind parses the LintRuleKind from the given string.
+//
+// Input is case-insensitive.
+func ParseLintRuleKind(s string) (LintRuleKind, error) {
+	lintRuleKind, ok := _stringToLintRuleKind[strings.ToLower(s)]
+	if !ok {
+		return LintRuleKindNone, fmt.Errorf("could not parse %s to a LintRuleKind", s)
+	}
+	return lintRuleKind, nil
+}
+
 // Config is the main config.
 //
 // Configs are derived from ExternalConfigs, which represent the Config
@@ -141,6 +251,8 @@ type Config struct {
 	Format FormatConfig
 	// The gen config.
 	Gen GenConfig
+	// The grpc config.
+	GRPC GRPCConfig
 }
 
 // CompileConfig is the compile config.
@@ -157,6 +269,9 @@ type CompileConfig struct {
 	IncludeWellKnownTypes bool
 	// AllowUnusedImports says to not error when an import is not used.
 	AllowUnusedImports bool
+	// Backend is the backend to compile with.
+	// The default is CompileBackendProtoc.
+	Backend CompileBackend
 }
 
 // LintConfig is the lint config.
@@ -188,6 +303,91 @@ type LintConfig struct {
 	// IDs expected to be all upper-case.
 	// File paths expected to be absolute paths.
 	IgnoreIDToFilePaths map[string][]string
+	// Rules are the user-defined lint rules.
+	// These are used in addition to the linters of the lint group, and
+	// can be referenced in IDs, IncludeIDs, ExcludeIDs and IgnoreIDToFilePaths.
+	// IDs expected to be unique.
+	Rules []LintRule
+	// Groups are the user-defined lint groups.
+	// These can be used as Group, and as the Base of other groups.
+	// Names expected to be unique.
+	Groups []LintGroup
+	// UnusedRoots are the fully-qualified names of the messages, enums,
+	// and packages that are used even if no service or extension reaches
+	// them, for the FILES_NO_UNUSED_TYPES linter. A package or message
+	// includes all types within it.
+	// Expected to not have a leading dot.
+	// Expected to be unique.
+	UnusedRoots []string
+	// BaselinePath is the path to a file with a serialized FileDescriptorSet
+	// to compare the files with for the DELETED_FIELDS_RESERVED linter.
+	// Expected to be absolute.
+	BaselinePath string
+}
+
+// LintGroup is a user-defined lint group.
+//
+// The linters of the group are the linters of Base, plus Add, minus Remove.
+type LintGroup struct {
+	// The name of the group.
+	// Expected to be all lowercase.
+	Name string
+	// The name of the group to start from.
+	// This can be a built-in group or another user-defined group.
+	// If empty, the group starts with no linters.
+	// Expected to be all lowercase.
+	Base string
+	// The linter IDs to add to Base.
+	// Expected to be all uppercase.
+	// Expected to be unique.
+	// Expected to have no overlap with Remove.
+	Add []string
+	// The linter IDs to remove from Base.
+	// Expected to be all uppercase.
+	// Expected to be unique.
+	// Expected to have no overlap with Add.
+	Remove []string
+}
+
+// LintRule is a user-defined lint rule.
+//
+// Exactly one of NamingRegex, RequiredOptions/ForbiddenOptions,
+// AllowedImports/ForbiddenImports, or
+// AllowedImportPackages/ForbiddenImportPackages is set.
+type LintRule struct {
+	// The ID of the rule.
+	// Expected to be all uppercase.
+	ID string
+	// The message to use for lint failures.
+	Message string
+	// The kind of element the rule applies to.
+	// Expected to be set for naming and option rules, and
+	// to be LintRuleKindNone for import and package import rules.
+	Kind LintRuleKind
+	// The regex that all names of elements of Kind must match.
+	// Expected to be a valid regex.
+	NamingRegex string
+	// The options that must be set on all elements of Kind.
+	// Custom options are specified with parentheses, for example "(foo.bar)".
+	RequiredOptions []string
+	// The options that must not be set on any element of Kind.
+	// Custom options are specified with parentheses, for example "(foo.bar)".
+	ForbiddenOptions []string
+	// The import prefixes that all imports must match one of.
+	AllowedImports []string
+	// The import prefixes that no import can match.
+	ForbiddenImports []string
+	// The packages whose files package import rules apply to.
+	// A package matches if it is equal to or a sub-package of one of these.
+	// If empty, package import rules apply to all files.
+	// Expected to only be set for package import rules.
+	Packages []string
+	// The packages that all imported files must be in one of,
+	// matched the same as Packages. Imports of files in the same
+	// package are always allowed.
+	AllowedImportPackages []string
+	// The packages that no imported file can be in, matched the same as Packages.
+	ForbiddenImportPackages []string
 }
 
 // FormatConfig is the format config.
@@ -229,6 +429,48 @@ type GenGoPluginOptions struct {
 	ExtraModifiers map[string]string
 }
 
+// GRPCConfig is the grpc config.
+type GRPCConfig struct {
+	// The TLS config.
+	TLS GRPCTLSConfig
+}
+
+// GRPCTLSConfig is the TLS config for gRPC calls.
+//
+// TLS is used if Enabled is true or any other field is set, see IsEnabled.
+type GRPCTLSConfig struct {
+	// Use TLS with the system root CA certificates.
+	Enabled bool
+	// The path to the PEM-encoded CA certificates to verify the server with
+	// instead of the system root CA certificates.
+	// Expected to be an absolute path if set.
+	CACertPath string
+	// The path to the PEM-encoded client certificate for mutual TLS.
+	// Expected to be an absolute path if set.
+	// Expected to be set if and only if KeyPath is set.
+	CertPath string
+	// The path to the PEM-encoded client private key for mutual TLS.
+	// Expected to be an absolute path if set.
+	// Expected to be set if and only if CertPath is set.
+	KeyPath string
+	// The name to verify the server certificate against
+	// instead of the host of the server address.
+	ServerName string
+	// Do not verify the server certificate.
+	// This should only be used for testing.
+	InsecureSkipVerify bool
+}
+
+// IsEnabled returns true if TLS should be used.
+func (c GRPCTLSConfig) IsEnabled() bool {
+	return c.Enabled ||
+		c.CACertPath != "" ||
+		c.CertPath != "" ||
+		c.KeyPath != "" ||
+		c.ServerName != "" ||
+		c.InsecureSkipVerify
+}
+
 // GenPlugin is a plugin to use.
 type GenPlugin struct {
 	// The name of the plugin. For example, if you want to use
@@ -271,12 +513,36 @@ type ExternalConfig struct {
 	ProtocIncludes     []string `json:"protoc_includes,omitempty" yaml:"protoc_includes,omitempty"`
 	ProtocIncludeWKT   bool     `json:"protoc_include_wkt,omitempty" yaml:"protoc_include_wkt,omitempty"`
 	AllowUnusedImports bool     `json:"allow_unused_imports,omitempty" yaml:"allow_unused_imports,omitempty"`
-	Lint               struct {
+	Compile            struct {
+		Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	} `json:"compile,omitempty" yaml:"compile,omitempty"`
+	Lint struct {
 		IDs             []string            `json:"ids,omitempty" yaml:"ids,omitempty"`
 		Group           string              `json:"group,omitempty" yaml:"group,omitempty"`
 		IncludeIDs      []string            `json:"include_ids,omitempty" yaml:"include_ids,omitempty"`
 		ExcludeIDs      []string            `json:"exclude_ids,omitempty" yaml:"exclude_ids,omitempty"`
 		IgnoreIDToFiles map[string][]string `json:"ignore_id_to_files,omitempty" yaml:"ignore_id_to_files,omitempty"`
+		Rules           []struct {
+			ID                      string   `json:"id,omitempty" yaml:"id,omitempty"`
+			Message                 string   `json:"message,omitempty" yaml:"message,omitempty"`
+			Kind                    string   `json:"kind,omitempty" yaml:"kind,omitempty"`
+			NamingRegex             string   `json:"naming_regex,omitempty" yaml:"naming_regex,omitempty"`
+			RequiredOptions         []string `json:"required_options,omitempty" yaml:"required_options,omitempty"`
+			ForbiddenOptions        []string `json:"forbidden_options,omitempty" yaml:"forbidden_options,omitempty"`
+			AllowedImports          []string `json:"allowed_imports,omitempty" yaml:"allowed_imports,omitempty"`
+			ForbiddenImports        []string `json:"forbidden_imports,omitempty" yaml:"forbidden_imports,omitempty"`
+			Packages                []string `json:"packages,omitempty" yaml:"packages,omitempty"`
+			AllowedImportPackages   []string `json:"allowed_import_packages,omitempty" yaml:"allowed_import_packages,omitempty"`
+			ForbiddenImportPackages []string `json:"forbidden_import_packages,omitempty" yaml:"forbidden_import_packages,omitempty"`
+		} `json:"rules,omitempty" yaml:"rules,omitempty"`
+		Groups []struct {
+			Name   string   `json:"name,omitempty" yaml:"name,omitempty"`
+			Base   string   `json:"base,omitempty" yaml:"base,omitempty"`
+			Add    []string `json:"add,omitempty" yaml:"add,omitempty"`
+			Remove []string `json:"remove,omitempty" yaml:"remove,omitempty"`
+		} `json:"groups,omitempty" yaml:"groups,omitempty"`
+		UnusedRoots []string `json:"unused_roots,omitempty" yaml:"unused_roots,omitempty"`
+		Baseline    string   `json:"baseline,omitempty" yaml:"baseline,omitempty"`
 	} `json:"lint,omitempty" yaml:"lint,omitempty"`
 	Format struct {
 		Indent           string `json:"indent,omitempty" yaml:"indent,omitempty"`
@@ -297,6 +563,16 @@ type ExternalConfig struct {
 			Output string `json:"output,omitempty" yaml:"output,omitempty"`
 		} `json:"plugins,omitempty" yaml:"plugins,omitempty"`
 	} `json:"gen,omitempty" yaml:"gen,omitempty"`
+	GRPC struct {
+		TLS struct {
+			Enabled            bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+			CACert             string `json:"ca_cert,omitempty" yaml:"ca_cert,omitempty"`
+			Cert               string `json:"cert,omitempty" yaml:"cert,omitempty"`
+			Key                string `json:"key,omitempty" yaml:"key,omitempty"`
+			ServerName         string `json:"server_name,omitempty" yaml:"server_name,omitempty"`
+			InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
+		} `json:"tls,omitempty" yaml:"tls,omitempty"`
+	} `json:"grpc,omitempty" yaml:"grpc,omitempty"`
 }
 
 // ConfigProvider provides Configs.
@@ -0,0 +1,158 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Edit is a suggested edit to a file.
+type Edit struct {
+	// Line is the line of the start of OldText, starting at 1.
+	Line int
+	// Column is the column of the start of OldText, starting at 1.
+	// This is the character count per line, like scanner.Position.
+	Column int
+	// OldText is the text to replace. If empty, NewText is inserted
+	// at the position.
+	OldText string
+	// NewText is the replacement text.
+	NewText string
+	// Filename is the file to edit if it is not the file of the
+	// Failure that the edit fixes, for example to fix a reference
+	// to a renamed element.
+	Filename string
+}
+
+// String implements fmt.Stringer.
+func (e *Edit) String() string {
+	return fmt.Sprintf("%d:%d:%q->%q", e.Line, e.Column, e.OldText, e.NewText)
+}
+
+// ApplyEdits applies the edits to the data, and returns the resulting
+// data and the number of edits applied.
+//
+// Edits whose OldText does not match the data at their position are skipped,
+// as are edits that overlap an edit that starts earlier in the data, or that
+// start at the same position as an edit earlier in the slice. Edits at the
+// same position can therefore be used as alternatives, where the first edit
+// with matching OldText is applied. Callers that want all edits applied
+// should get new edits for the new data and apply them until no edits
+// are applied.
+//
+// Edits can insert text at the start of the line after the last line. If
+// the data does not end with a newline, a newline is added for these edits
+// so that the inserted text starts on its own line.
+//
+// An error is returned if an edit has a position that is not in the data.
+func ApplyEdits(data []byte, edits []*Edit) ([]byte, int, error) {
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		numLines := bytes.Count(data, []byte("\n")) + 1
+		for _, edit := range edits {
+			if edit.Line == numLines+1 && edit.Column <= 1 && edit.OldText == "" {
+				data = append(append(make([]byte, 0, len(data)+1), data...), '\n')
+				break
+			}
+		}
+	}
+	offsetEdits := make([]*offsetEdit, 0, len(edits))
+	for _, edit := range edits {
+		offset, err := getOffset(data, edit.Line, edit.Column)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid edit %v: %v", edit, err)
+		}
+		if !bytes.HasPrefix(data[offset:], []byte(edit.OldText)) {
+			continue
+		}
+		offsetEdits = append(offsetEdits, &offsetEdit{
+			Edit:   edit,
+			offset: offset,
+		})
+	}
+	sort.SliceStable(offsetEdits, func(i int, j int) bool {
+		return offsetEdits[i].offset < offsetEdits[j].offset
+	})
+	var appliedEdits []*offsetEdit
+	for _, edit := range offsetEdits {
+		if len(appliedEdits) > 0 {
+			previous := appliedEdits[len(appliedEdits)-1]
+			if edit.offset < previous.end() || edit.offset == previous.offset {
+				continue
+			}
+		}
+		appliedEdits = append(appliedEdits, edit)
+	}
+	if len(appliedEdits) == 0 {
+		return data, 0, nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	start := 0
+	for _, edit := range appliedEdits {
+		_, _ = buffer.Write(data[start:edit.offset])
+		_, _ = buffer.WriteString(edit.NewText)
+		start = edit.end()
+	}
+	_, _ = buffer.Write(data[start:])
+	return buffer.Bytes(), len(appliedEdits), nil
+}
+
+type offsetEdit struct {
+	*Edit
+	offset int
+}
+
+func (e *offsetEdit) end() int {
+	return e.offset + len(e.OldText)
+}
+
+// getOffset returns the byte offset of the line and column in data.
+//
+// A line or column of 0 is treated as 1, like when printing a Failure.
+func getOffset(data []byte, line int, column int) (int, error) {
+	if line == 0 {
+		line = 1
+	}
+	if column == 0 {
+		column = 1
+	}
+	if line < 0 || column < 0 {
+		return 0, fmt.Errorf("negative position %d:%d", line, column)
+	}
+	offset := 0
+	for i := 1; i < line; i++ {
+		index := bytes.IndexByte(data[offset:], '\n')
+		if index < 0 {
+			return 0, fmt.Errorf("line %d is out of range", line)
+		}
+		offset += index + 1
+	}
+	for i := 1; i < column; i++ {
+		if offset >= len(data) || data[offset] == '\n' {
+			return 0, fmt.Errorf("column %d is out of range for line %d", column, line)
+		}
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	return offset, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEdits(t *testing.T) {
+	testApplyEdits(t, "foo\nbar\n", "foo\nbaz\n", 1, &Edit{Line: 2, Column: 3, OldText: "r", NewText: "z"})
+	testApplyEdits(t, "foo\nbar\n", "foo\nbar\nbaz\n", 1, &Edit{Line: 3, Column: 1, NewText: "baz\n"})
+	// a newline is added if the last line does not end with one
+	testApplyEdits(t, "foo\nbar", "foo\nbar\nbaz\n", 1, &Edit{Line: 3, Column: 1, NewText: "baz\n"})
+	testApplyEdits(t, "foo\nbar\n", "FOO\nBAR\n", 2,
+		&Edit{Line: 2, Column: 1, OldText: "bar", NewText: "BAR"},
+		&Edit{Line: 1, Column: 1, OldText: "foo", NewText: "FOO"},
+	)
+	// unicode columns are characters
+	testApplyEdits(t, "é foo\n", "é bar\n", 1, &Edit{Line: 1, Column: 3, OldText: "foo", NewText: "bar"})
+	// mismatched old text is skipped
+	testApplyEdits(t, "foo\nbar\n", "foo\nbar\n", 0, &Edit{Line: 1, Column: 1, OldText: "bar", NewText: "baz"})
+	// overlapping edits are skipped
+	testApplyEdits(t, "foo\nbar\n", "FOO\nbar\n", 1,
+		&Edit{Line: 1, Column: 2, OldText: "oo", NewText: "OO"},
+		&Edit{Line: 1, Column: 1, OldText: "foo", NewText: "FOO"},
+	)
+	testApplyEdits(t, "foo\n", "afoo\n", 1,
+		&Edit{Line: 1, Column: 1, NewText: "a"},
+		&Edit{Line: 1, Column: 1, NewText: "b"},
+	)
+
+	_, _, err := ApplyEdits([]byte("foo\n"), []*Edit{{Line: 3, Column: 1, NewText: "bar"}})
+	assert.Error(t, err)
+	_, _, err = ApplyEdits([]byte("foo\n"), []*Edit{{Line: 1, Column: 5, NewText: "bar"}})
+	assert.Error(t, err)
+	_, _, err = ApplyEdits([]byte("foo"), []*Edit{{Line: 3, Column: 1, NewText: "bar"}})
+	assert.Error(t, err)
+}
+
+func testApplyEdits(t *testing.T, input string, expected string, expectedApplied int, edits ...*Edit) {
+	data, applied, err := ApplyEdits([]byte(input), edits)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+	assert.Equal(t, expectedApplied, applied)
+}
@@ -0,0 +1,355 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ErrorFormatText writes each Failure on a line with colon-separated fields.
+	ErrorFormatText ErrorFormat = iota
+	// ErrorFormatJSON writes the Failures as a JSON array.
+	ErrorFormatJSON
+	// ErrorFormatJSONLines writes each Failure as a JSON object on a line.
+	ErrorFormatJSONLines
+	// ErrorFormatSARIF writes the Failures as a SARIF 2.1.0 log.
+	ErrorFormatSARIF
+	// ErrorFormatCheckstyle writes the Failures as checkstyle XML.
+	ErrorFormatCheckstyle
+)
+
+var (
+	_errorFormatToString = map[ErrorFormat]string{
+		ErrorFormatText:       "text",
+		ErrorFormatJSON:       "json",
+		ErrorFormatJSONLines:  "json-lines",
+		ErrorFormatSARIF:      "sarif",
+		ErrorFormatCheckstyle: "checkstyle",
+	}
+	_stringToErrorFormat = map[string]ErrorFormat{
+		"text":       ErrorFormatText,
+		"json":       ErrorFormatJSON,
+		"json-lines": ErrorFormatJSONLines,
+		"sarif":      ErrorFormatSARIF,
+		"checkstyle": ErrorFormatCheckstyle,
+	}
+)
+
+// ErrorFormat is a format to write Failures in.
+type ErrorFormat int
+
+// String implements fmt.Stringer.
+func (e ErrorFormat) String() string {
+	if s, ok := _errorFormatToString[e]; ok {
+		return s
+	}
+	return strconv.Itoa(int(e))
+}
+
+// ParseErrorFormat parses the ErrorFormat from the given string.
+//
+// Input is case-insensitive. If the string is empty, ErrorFormatText
+// will be returned.
+func ParseErrorFormat(s string) (ErrorFormat, error) {
+	if s == "" {
+		return ErrorFormatText, nil
+	}
+	errorFormat, ok := _stringToErrorFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to an ErrorFormat", s)
+	}
+	return errorFormat, nil
+}
+
+// IsDocument returns true if the ErrorFormat is a single document that
+// has to be written with all Failures at once, as opposed to writing
+// each Failure separately.
+func (e ErrorFormat) IsDocument() bool {
+	switch e {
+	case ErrorFormatJSON, ErrorFormatSARIF, ErrorFormatCheckstyle:
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteFailures writes the Failures to the writer in the ErrorFormat.
+//
+// The fields are only used for ErrorFormatText.
+func WriteFailures(writer io.Writer, errorFormat ErrorFormat, failures []*Failure, fields ...FailureField) error {
+	bufWriter := bufio.NewWriter(writer)
+	if err := writeFailures(bufWriter, errorFormat, failures, fields...); err != nil {
+		return err
+	}
+	return bufWriter.Flush()
+}
+
+func writeFailures(writer *bufio.Writer, errorFormat ErrorFormat, failures []*Failure, fields ...FailureField) error {
+	switch errorFormat {
+	case ErrorFormatText:
+		for _, failure := range failures {
+			if err := failure.Fprintln(writer, fields...); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ErrorFormatJSON:
+		jsonFailures := make([]*jsonFailure, len(failures))
+		for i, failure := range failures {
+			jsonFailures[i] = newJSONFailure(failure)
+		}
+		return writeJSON(writer, jsonFailures, "  ")
+	case ErrorFormatJSONLines:
+		for _, failure := range failures {
+			if err := writeJSON(writer, newJSONFailure(failure), ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ErrorFormatSARIF:
+		return writeJSON(writer, newSARIFLog(failures), "  ")
+	case ErrorFormatCheckstyle:
+		if _, err := writer.WriteString(xml.Header); err != nil {
+			return err
+		}
+		encoder := xml.NewEncoder(writer)
+		encoder.Indent("", "  ")
+		if err := encoder.Encode(newCheckstyle(failures)); err != nil {
+			return err
+		}
+		_, err := writer.WriteRune('\n')
+		return err
+	default:
+		return fmt.Errorf("unknown ErrorFormat: %v", errorFormat)
+	}
+}
+
+func writeJSON(writer io.Writer, value interface{}, indent string) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", indent)
+	// messages often contain quoted names, do not escape <, >, and &
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(value)
+}
+
+// getFilename, getLine, and getColumn mirror what is printed by Fprintln.
+
+func (f *Failure) getFilename() string {
+	if f.Filename == "" {
+		return "<input>"
+	}
+	return f.Filename
+}
+
+func (f *Failure) getLine() int {
+	if f.Line == 0 {
+		return 1
+	}
+	return f.Line
+}
+
+func (f *Failure) getColumn() int {
+	if f.Column == 0 {
+		return 1
+	}
+	return f.Column
+}
+
+type jsonFailure struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	ID       string `json:"id,omitempty"`
+	Message  string `json:"message"`
+}
+
+func newJSONFailure(failure *Failure) *jsonFailure {
+	return &jsonFailure{
+		Filename: failure.getFilename(),
+		Line:     failure.getLine(),
+		Column:   failure.getColumn(),
+		ID:       failure.ID,
+		Message:  failure.Message,
+	}
+}
+
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+// only the fields we need are included.
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    *sarifTool     `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver *sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId,omitempty"`
+	Level     string           `json:"level"`
+	Message   *sarifMessage    `json:"message"`
+	Locations []*sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+func newSARIFLog(failures []*Failure) *sarifLog {
+	ids := make(map[string]struct{})
+	results := make([]*sarifResult, len(failures))
+	for i, failure := range failures {
+		if failure.ID != "" {
+			ids[failure.ID] = struct{}{}
+		}
+		results[i] = &sarifResult{
+			RuleID: failure.ID,
+			Level:  "error",
+			Message: &sarifMessage{
+				Text: failure.Message,
+			},
+			Locations: []*sarifLocation{
+				{
+					PhysicalLocation: &sarifPhysicalLocation{
+						ArtifactLocation: &sarifArtifactLocation{
+							URI: failure.getFilename(),
+						},
+						Region: &sarifRegion{
+							StartLine:   failure.getLine(),
+							StartColumn: failure.getColumn(),
+						},
+					},
+				},
+			},
+		}
+	}
+	rules := make([]*sarifRule, 0, len(ids))
+	for id := range ids {
+		rules = append(rules, &sarifRule{ID: id})
+	}
+	sort.Slice(rules, func(i int, j int) bool { return rules[i].ID < rules[j].ID })
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []*sarifRun{
+			{
+				Tool: &sarifTool{
+					Driver: &sarifDriver{
+						Name:           "prototool",
+						InformationURI: "https://github.com/uber/prototool",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+type checkstyle struct {
+	XMLName xml.Name          `xml:"checkstyle"`
+	Version string            `xml:"version,attr"`
+	Files   []*checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string             `xml:"name,attr"`
+	Errors []*checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr,omitempty"`
+}
+
+// the files are in the order of the first failure for each file
+func newCheckstyle(failures []*Failure) *checkstyle {
+	var files []*checkstyleFile
+	filenameToFile := make(map[string]*checkstyleFile)
+	for _, failure := range failures {
+		filename := failure.getFilename()
+		file, ok := filenameToFile[filename]
+		if !ok {
+			file = &checkstyleFile{
+				Name: filename,
+			}
+			filenameToFile[filename] = file
+			files = append(files, file)
+		}
+		file.Errors = append(file.Errors, &checkstyleError{
+			Line:     failure.getLine(),
+			Column:   failure.getColumn(),
+			Severity: "error",
+			Message:  failure.Message,
+			Source:   failure.ID,
+		})
+	}
+	return &checkstyle{
+		Version: "4.3",
+		Files:   files,
+	}
+}
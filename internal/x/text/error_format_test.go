@@ -0,0 +1,164 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErrorFormat(t *testing.T) {
+	for s, expected := range map[string]ErrorFormat{
+		"":           ErrorFormatText,
+		"text":       ErrorFormatText,
+		"JSON":       ErrorFormatJSON,
+		"json-lines": ErrorFormatJSONLines,
+		"sarif":      ErrorFormatSARIF,
+		"checkstyle": ErrorFormatCheckstyle,
+	} {
+		errorFormat, err := ParseErrorFormat(s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, errorFormat)
+	}
+	_, err := ParseErrorFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestWriteFailures(t *testing.T) {
+	failures := []*Failure{
+		newTestFailure("foo.proto", 2, 3, "FOO", `Field "a" is <bad>.`),
+		newTestFailure("foo.proto", 4, 0, "", "hello"),
+		newTestFailure("bar.proto", 1, 1, "BAR", "bye"),
+	}
+	testWriteFailures(t, ErrorFormatText, failures, `foo.proto:2:3:Field "a" is <bad>.
+foo.proto:4:1:hello
+bar.proto:1:1:bye
+`)
+	testWriteFailures(t, ErrorFormatJSON, failures, `[
+  {
+    "filename": "foo.proto",
+    "line": 2,
+    "column": 3,
+    "id": "FOO",
+    "message": "Field \"a\" is <bad>."
+  },
+  {
+    "filename": "foo.proto",
+    "line": 4,
+    "column": 1,
+    "message": "hello"
+  },
+  {
+    "filename": "bar.proto",
+    "line": 1,
+    "column": 1,
+    "id": "BAR",
+    "message": "bye"
+  }
+]
+`)
+	testWriteFailures(t, ErrorFormatJSON, nil, "[]\n")
+	testWriteFailures(t, ErrorFormatJSONLines, failures, `{"filename":"foo.proto","line":2,"column":3,"id":"FOO","message":"Field \"a\" is <bad>."}
+{"filename":"foo.proto","line":4,"column":1,"message":"hello"}
+{"filename":"bar.proto","line":1,"column":1,"id":"BAR","message":"bye"}
+`)
+	testWriteFailures(t, ErrorFormatSARIF, failures[1:], `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "prototool",
+          "informationUri": "https://github.com/uber/prototool",
+          "rules": [
+            {
+              "id": "BAR"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "level": "error",
+          "message": {
+            "text": "hello"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "foo.proto"
+                },
+                "region": {
+                  "startLine": 4,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "BAR",
+          "level": "error",
+          "message": {
+            "text": "bye"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "bar.proto"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`)
+	testWriteFailures(t, ErrorFormatCheckstyle, failures, `<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="foo.proto">
+    <error line="2" column="3" severity="error" message="Field &#34;a&#34; is &lt;bad&gt;." source="FOO"></error>
+    <error line="4" column="1" severity="error" message="hello"></error>
+  </file>
+  <file name="bar.proto">
+    <error line="1" column="1" severity="error" message="bye" source="BAR"></error>
+  </file>
+</checkstyle>
+`)
+}
+
+func testWriteFailures(t *testing.T, errorFormat ErrorFormat, failures []*Failure, expected string) {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteFailures(buffer, errorFormat, failures))
+	assert.Equal(t, expected, buffer.String())
+}
@@ -113,6 +113,8 @@ type Failure struct {
 	Column   int
 	ID       string
 	Message  string
+	// Edits are suggested edits to the file that fix the failure, if any.
+	Edits []*Edit
 }
 
 // FailureWriter is a writer that Failure.Println can accept.
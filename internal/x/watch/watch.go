@@ -0,0 +1,114 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package watch watches directories for file changes.
+//
+// This polls the file system instead of using OS-specific notifications,
+// so that it works the same on all platforms and with network and
+// container file systems, at the cost of some latency.
+package watch
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultInterval is the default interval to poll for changes.
+	DefaultInterval = 250 * time.Millisecond
+	// DefaultDebounce is the default duration to wait with no further
+	// changes before reporting changes.
+	DefaultDebounce = 500 * time.Millisecond
+)
+
+// Watcher watches directories for file changes.
+type Watcher interface {
+	// Watch watches the directory and all its subdirectories for files that are
+	// added, modified, or removed, until the context is done or onChange returns
+	// an error.
+	//
+	// Bursts of changes are debounced, and onChange is called with the sorted
+	// absolute paths of all files that changed once there are no further changes
+	// for the debounce duration. onChange is never called concurrently.
+	//
+	// Hidden directories are not watched.
+	Watch(ctx context.Context, dirPath string, onChange func(filePaths []string) error) error
+}
+
+// WatcherOption is an option for a new Watcher.
+type WatcherOption func(*watcher)
+
+// WatcherWithLogger returns a WatcherOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func WatcherWithLogger(logger *zap.Logger) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.logger = logger
+	}
+}
+
+// WatcherWithInterval returns a WatcherOption that polls for changes
+// at the given interval.
+//
+// The default is to use DefaultInterval.
+func WatcherWithInterval(interval time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.interval = interval
+	}
+}
+
+// WatcherWithDebounce returns a WatcherOption that waits for the
+// given duration with no further changes before reporting changes.
+//
+// The default is to use DefaultDebounce.
+func WatcherWithDebounce(debounce time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.debounce = debounce
+	}
+}
+
+// WatcherWithFilter returns a WatcherOption that only watches files
+// for which filter returns true. The filter is called with the
+// absolute path of each file.
+//
+// The default is to watch all files.
+func WatcherWithFilter(filter func(filePath string) bool) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.filter = filter
+	}
+}
+
+// WatcherWithFilePaths returns a WatcherOption that also watches the
+// given files, which may be outside of the watched directory and may not
+// exist yet. The filter is not applied to these files.
+//
+// The default is to only watch the files in the directory.
+func WatcherWithFilePaths(filePaths ...string) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.filePaths = append(watcher.filePaths, filePaths...)
+	}
+}
+
+// NewWatcher returns a new Watcher.
+func NewWatcher(options ...WatcherOption) Watcher {
+	return newWatcher(options...)
+}
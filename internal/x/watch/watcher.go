@@ -0,0 +1,169 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type watcher struct {
+	logger   *zap.Logger
+	interval time.Duration
+	debounce time.Duration
+	filter   func(string) bool
+	// extra files to watch, outside of the filter
+	filePaths []string
+}
+
+func newWatcher(options ...WatcherOption) *watcher {
+	watcher := &watcher{
+		logger:   zap.NewNop(),
+		interval: DefaultInterval,
+		debounce: DefaultDebounce,
+	}
+	for _, option := range options {
+		option(watcher)
+	}
+	return watcher
+}
+
+func (w *watcher) Watch(ctx context.Context, dirPath string, onChange func([]string) error) error {
+	dirPath, err := filepath.Abs(dirPath)
+	if err != nil {
+		return err
+	}
+	snapshot, err := w.getSnapshot(dirPath)
+	if err != nil {
+		return err
+	}
+	w.logger.Debug("watching", zap.String("dirPath", dirPath), zap.Int("files", len(snapshot)))
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	pending := make(map[string]struct{})
+	var lastChangeTime time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		newSnapshot, err := w.getSnapshot(dirPath)
+		if err != nil {
+			return err
+		}
+		changedFilePaths := getChangedFilePaths(snapshot, newSnapshot)
+		snapshot = newSnapshot
+		if len(changedFilePaths) > 0 {
+			w.logger.Debug("changed", zap.Strings("filePaths", changedFilePaths))
+			for _, filePath := range changedFilePaths {
+				pending[filePath] = struct{}{}
+			}
+			lastChangeTime = time.Now()
+			continue
+		}
+		if len(pending) == 0 || time.Since(lastChangeTime) < w.debounce {
+			continue
+		}
+		filePaths := make([]string, 0, len(pending))
+		for filePath := range pending {
+			filePaths = append(filePaths, filePath)
+		}
+		sort.Strings(filePaths)
+		pending = make(map[string]struct{})
+		if err := onChange(filePaths); err != nil {
+			return err
+		}
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *watcher) getSnapshot(dirPath string) (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	walkErr := filepath.Walk(dirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			// files can be removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fileInfo.IsDir() {
+			if filePath != dirPath && strings.HasPrefix(fileInfo.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.filter != nil && !w.filter(filePath) {
+			return nil
+		}
+		snapshot[filePath] = fileState{
+			modTime: fileInfo.ModTime(),
+			size:    fileInfo.Size(),
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	for _, filePath := range w.filePaths {
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		snapshot[filePath] = fileState{
+			modTime: fileInfo.ModTime(),
+			size:    fileInfo.Size(),
+		}
+	}
+	return snapshot, nil
+}
+
+// getChangedFilePaths returns the sorted file paths that were added,
+// modified, or removed between the snapshots.
+func getChangedFilePaths(snapshot map[string]fileState, newSnapshot map[string]fileState) []string {
+	var changedFilePaths []string
+	for filePath, state := range newSnapshot {
+		if oldState, ok := snapshot[filePath]; !ok || !oldState.modTime.Equal(state.modTime) || oldState.size != state.size {
+			changedFilePaths = append(changedFilePaths, filePath)
+		}
+	}
+	for filePath := range snapshot {
+		if _, ok := newSnapshot[filePath]; !ok {
+			changedFilePaths = append(changedFilePaths, filePath)
+		}
+	}
+	sort.Strings(changedFilePaths)
+	return changedFilePaths
+}
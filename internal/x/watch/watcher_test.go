@@ -0,0 +1,108 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "prototool-watch")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dirPath) }()
+	// resolve symlinks such as /tmp on darwin so paths match
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	outsideDirPath, err := ioutil.TempDir("", "prototool-watch-outside")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(outsideDirPath) }()
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, "foo"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dirPath, ".hidden"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "a.proto"), []byte("a"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "b.proto"), []byte("b"), 0644))
+
+	watcher := NewWatcher(
+		WatcherWithInterval(10*time.Millisecond),
+		WatcherWithDebounce(50*time.Millisecond),
+		WatcherWithFilter(func(filePath string) bool {
+			return strings.HasSuffix(filePath, ".proto")
+		}),
+		WatcherWithFilePaths(filepath.Join(outsideDirPath, "f.yaml")),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []string)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- watcher.Watch(ctx, dirPath, func(filePaths []string) error {
+			changes <- filePaths
+			return nil
+		})
+	}()
+	// wait for the initial snapshot
+	time.Sleep(50 * time.Millisecond)
+
+	// a burst of changes is reported once
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "a.proto"), []byte("aa"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "c.proto"), []byte("c"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "foo", "b.proto")))
+	// filtered and hidden files are not watched
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "d.txt"), []byte("d"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, ".hidden", "e.proto"), []byte("e"), 0644))
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "foo", "a.proto"),
+			filepath.Join(dirPath, "foo", "b.proto"),
+			filepath.Join(dirPath, "foo", "c.proto"),
+		},
+		receiveChanges(t, changes),
+	)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "foo", "c.proto"), []byte("cc"), 0644))
+	assert.Equal(t, []string{filepath.Join(dirPath, "foo", "c.proto")}, receiveChanges(t, changes))
+
+	// extra files are watched outside of the directory without the filter
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outsideDirPath, "f.yaml"), []byte("f"), 0644))
+	assert.Equal(t, []string{filepath.Join(outsideDirPath, "f.yaml")}, receiveChanges(t, changes))
+
+	cancel()
+	assert.NoError(t, <-errC)
+}
+
+func receiveChanges(t *testing.T, changes <-chan []string) []string {
+	select {
+	case filePaths := <-changes:
+		return filePaths
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+		return nil
+	}
+}